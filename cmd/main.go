@@ -1,23 +1,60 @@
 package main
 
 import (
+	"context"
+	"flag"
 	"fmt"
+	"log/slog"
 	"net/http"
+	"os"
+	"time"
 
 	"github.com/tam-code/image-upload/config"
+	"github.com/tam-code/image-upload/src/broadcasters"
+	"github.com/tam-code/image-upload/src/buses"
 	"github.com/tam-code/image-upload/src/consumers"
 	"github.com/tam-code/image-upload/src/databases"
-	"github.com/tam-code/image-upload/src/kafka"
+	"github.com/tam-code/image-upload/src/healthcheckers"
+	"github.com/tam-code/image-upload/src/janitors"
+	"github.com/tam-code/image-upload/src/loggers"
+	"github.com/tam-code/image-upload/src/models"
 	"github.com/tam-code/image-upload/src/producers"
+	"github.com/tam-code/image-upload/src/ratelimiters"
 	"github.com/tam-code/image-upload/src/repositories"
 	"github.com/tam-code/image-upload/src/routes"
+	"github.com/tam-code/image-upload/src/tracers"
 )
 
 func main() {
-	config, err := config.LoadConfig()
+	if len(os.Args) > 1 && os.Args[1] == "replay" {
+		if err := replay(os.Args[2:]); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	configPath := flag.String("config", os.Getenv(config.PathEnv), "configuration file merged over the defaults")
+	seekTime := flag.String("seek-time", "", "move the consumer group to the first events produced at or after this RFC 3339 time before consuming")
+	seekOffset := flag.Int64("seek-offset", -1, "move the consumer group to this offset of every partition before consuming, disabled when negative")
+	flag.Parse()
+
+	config, err := config.LoadConfig(*configPath)
+	if err != nil {
+		panic(err)
+	}
+
+	logger, err := loggers.NewLogger(config.Logging)
+	if err != nil {
+		panic(err)
+	}
+	slog.SetDefault(logger)
+
+	shutdownTracing, err := tracers.NewTracerProvider(config.Tracing)
 	if err != nil {
 		panic(err)
 	}
+	defer shutdownTracing(context.Background())
 
 	mongodb, err := databases.NewMongoDB(config.MongoDB)
 	if err != nil {
@@ -25,11 +62,96 @@ func main() {
 	}
 
 	repositories := repositories.NewRepositories(mongodb)
+	if err := repositories.EnsureIndexes(); err != nil {
+		panic(err)
+	}
+
+	// the admin key is only provisioned when configured, the bootstrap keys no
+	// longer configured are revoked
+	var bootstrapHash string
+	if config.APIKeys.BootstrapAdminKey != "" {
+		bootstrapHash = models.HashAPIKey(config.APIKeys.BootstrapAdminKey)
+		err = repositories.APIKey.EnsureAPIKey(models.APIKey{
+			Name:      models.BootstrapAPIKeyName,
+			Tenant:    models.DefaultTenant,
+			Prefix:    config.APIKeys.BootstrapAdminKey[:min(4, len(config.APIKeys.BootstrapAdminKey))],
+			Hash:      bootstrapHash,
+			Scopes:    models.AllScopes,
+			CreatedAt: time.Now(),
+			Bootstrap: true,
+		})
+		if err != nil {
+			panic(err)
+		}
+	}
+
+	revoked, err := repositories.APIKey.RevokeBootstrapAPIKeys(bootstrapHash, time.Now())
+	if err != nil {
+		panic(err)
+	}
+	if revoked > 0 {
+		slog.Warn("bootstrap api keys revoked", "count", revoked)
+	}
+
+	broadcasters := broadcasters.NewBroadcasters(config.StatisticsStream)
+
+	bus, err := buses.NewBus(config.Bus, config.Kafka)
+	if err != nil {
+		panic(err)
+	}
+
+	// the events fetched again after a seek are consumed as a replay, the
+	// group must have no other member
+	var seekEnds map[int]int64
+	if *seekTime != "" || *seekOffset >= 0 {
+		position := buses.Position{Offset: *seekOffset}
+		if position.Time, err = parseTimeFlag("seek-time", *seekTime); err != nil {
+			panic(err)
+		}
+
+		if seekEnds, err = bus.Seek(context.Background(), config.Kafka.Topic, config.Kafka.Group, position); err != nil {
+			panic(err)
+		}
+		slog.Info("consumer group moved", "group", config.Kafka.Group, "seek_time", *seekTime, "seek_offset", *seekOffset)
+	}
+
+	imageEventsReader, err := bus.Consumer(config.Kafka.Topic, config.Kafka.Group)
+	if err != nil {
+		panic(err)
+	}
+
+	if seekEnds != nil {
+		imageEventsReader = buses.NewReplayConsumer(imageEventsReader, seekEnds, "seek-"+time.Now().UTC().Format("20060102T150405Z"))
+	}
 
-	consumers := consumers.NewConsumers(kafka.NewConsumer(kafka.NewKafkaReader(config.Kafka)), repositories)
+	consumers := consumers.NewConsumers(config, imageEventsReader, repositories, broadcasters)
 	consumers.Run()
 
-	producers := producers.NewProducers(kafka.NewProducer(kafka.NewKafkaWriter(config.Kafka)))
+	var auditWriter buses.Producer
+	if config.Audit.ExportTopic != "" {
+		if auditWriter, err = bus.Producer(config.Audit.ExportTopic); err != nil {
+			panic(err)
+		}
+	}
+
+	imageEventsWriter, err := bus.Producer(config.Kafka.Topic)
+	if err != nil {
+		panic(err)
+	}
+
+	producers := producers.NewProducers(config.Events, imageEventsWriter, auditWriter)
+
+	janitors := janitors.NewJanitors(config, repositories, producers)
+	janitors.Run()
+
+	var rateLimitStore ratelimiters.Store
+	if config.RateLimit.Enabled {
+		if rateLimitStore, err = ratelimiters.NewStore(config.RateLimit.Store, repositories); err != nil {
+			panic(err)
+		}
+	}
+
+	healthChecker := healthcheckers.NewHealthChecker(config, mongodb, imageEventsReader)
 
-	http.ListenAndServe(fmt.Sprintf(":%v", config.APIPort), routes.SetupRoutes(repositories, producers))
+	http.ListenAndServe(fmt.Sprintf(":%v", config.APIPort), routes.SetupRoutes(config, repositories, producers, broadcasters, janitors, rateLimitStore, healthChecker))
 }
@@ -0,0 +1,235 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/tam-code/image-upload/config"
+	"github.com/tam-code/image-upload/src/buses"
+	"github.com/tam-code/image-upload/src/databases"
+	"github.com/tam-code/image-upload/src/loggers"
+	"github.com/tam-code/image-upload/src/models"
+	"github.com/tam-code/image-upload/src/producers"
+	"github.com/tam-code/image-upload/src/repositories"
+)
+
+// replayPageSize is how many images are read from the database at once.
+const replayPageSize = 500
+
+// replay publishes again the image_uploaded events of the stored images
+// matching the flags, marked with the id of the replay:
+//
+//	image-upload replay -from 2024-01-01T00:00:00Z -link <upload link id> -rate 50
+//
+// With -reset-statistics the statistics of the tenant are deleted and its
+// images unmarked first, so the replayed events rebuild them.
+func replay(args []string) error {
+	flags := flag.NewFlagSet("replay", flag.ExitOnError)
+	configPath := flags.String("config", os.Getenv(config.PathEnv), "configuration file merged over the defaults")
+	from := flags.String("from", "", "replay the images uploaded at or after this RFC 3339 time")
+	to := flags.String("to", "", "replay the images uploaded at or before this RFC 3339 time")
+	tenant := flags.String("tenant", "", "replay the images of this tenant, all tenants when empty")
+	link := flags.String("link", "", "replay the images of this upload link")
+	format := flags.String("format", "", "replay the images of this format, e.g. jpeg")
+	rate := flags.Float64("rate", 10, "events published by second, unlimited when 0")
+	batch := flags.Int("batch", 50, "images by event at most")
+	replayID := flags.String("id", "replay-"+time.Now().UTC().Format("20060102T150405Z"), "id of the replay, carried by the events")
+	dryRun := flags.Bool("dry-run", false, "count the events without publishing them")
+	resetStatistics := flags.Bool("reset-statistics", false, "delete the statistics of the tenant, of all tenants when empty, and rebuild them from the replayed events")
+	flags.Parse(args)
+
+	if *batch <= 0 {
+		return fmt.Errorf("-batch must be positive")
+	}
+
+	// the rebuilt statistics would only count the images of the filters
+	if *resetStatistics && (*from != "" || *to != "" || *link != "" || *format != "") {
+		return fmt.Errorf("-reset-statistics replays every image of the tenant, it can't be used with -from, -to, -link or -format")
+	}
+
+	uploadedFrom, err := parseTimeFlag("from", *from)
+	if err != nil {
+		return err
+	}
+
+	uploadedTo, err := parseTimeFlag("to", *to)
+	if err != nil {
+		return err
+	}
+
+	filter := models.ImageFilter{Tenant: *tenant, UploadLinkID: *link, ImageFormat: *format, UploadedFrom: uploadedFrom, UploadedTo: uploadedTo}
+
+	config, err := config.LoadConfig(*configPath)
+	if err != nil {
+		return err
+	}
+
+	logger, err := loggers.NewLogger(config.Logging)
+	if err != nil {
+		return err
+	}
+	slog.SetDefault(logger.With(slog.String("replay", *replayID)))
+
+	mongodb, err := databases.NewMongoDB(config.MongoDB)
+	if err != nil {
+		return err
+	}
+
+	bus, err := buses.NewBus(config.Bus, config.Kafka)
+	if err != nil {
+		return err
+	}
+
+	writer, err := bus.Producer(config.Kafka.Topic)
+	if err != nil {
+		return err
+	}
+	defer writer.Close()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	repositories := repositories.NewRepositories(mongodb)
+	r := replayer{
+		images:     repositories.Image,
+		statistics: repositories.Statistics,
+		producer:   producers.NewImageUploadedProducer(writer, config.Events.Version),
+		batch:      *batch,
+		dryRun:     *dryRun,
+	}
+	if *rate > 0 {
+		ticker := time.NewTicker(time.Duration(float64(time.Second) / *rate))
+		defer ticker.Stop()
+		r.ticks = ticker.C
+	}
+
+	if *resetStatistics {
+		if err := r.resetStatistics(ctx, *tenant); err != nil {
+			return err
+		}
+	}
+
+	return r.replay(buses.WithReplay(ctx, *replayID), filter)
+}
+
+type replayer struct {
+	images     repositories.ImageRepository
+	statistics repositories.StatisticsRepository
+	producer   producers.ImageUploadedProducer
+	batch      int
+	dryRun     bool
+	// ticks paces the events, they aren't paced when nil
+	ticks <-chan time.Time
+}
+
+// replay pages through the images matching the filter in id order, the
+// images of a page sharing their upload link are published in the same
+// events.
+func (r *replayer) replay(ctx context.Context, filter models.ImageFilter) error {
+	var afterID string
+	var imageCount, eventCount int
+	for {
+		images, err := r.images.GetImagesAfter(ctx, filter, afterID, replayPageSize)
+		if err != nil {
+			return err
+		}
+
+		for _, event := range replayEvents(images, r.batch) {
+			if r.ticks != nil {
+				select {
+				case <-ctx.Done():
+					return ctx.Err()
+				case <-r.ticks:
+				}
+			}
+
+			if !r.dryRun {
+				if err := r.producer.Publish(ctx, event); err != nil {
+					return fmt.Errorf("error publishing the event of %d images after %s: %w", len(event.Images), afterID, err)
+				}
+			}
+
+			eventCount++
+			imageCount += len(event.Images)
+		}
+
+		if len(images) < replayPageSize {
+			break
+		}
+
+		afterID = images[len(images)-1].ID
+		slog.Info("replay progress", "images", imageCount, "events", eventCount, "after_id", afterID)
+	}
+
+	slog.Info("replay done", "images", imageCount, "events", eventCount, "dry_run", r.dryRun)
+
+	return nil
+}
+
+// resetStatistics deletes the statistics of the tenant, of all tenants when
+// empty, and marks its images not counted, the replayed events count them
+// again. An event handled while they are reset may be counted twice or not
+// at all, so the consumers are best stopped until it is done.
+func (r *replayer) resetStatistics(ctx context.Context, tenant string) error {
+	if r.dryRun {
+		slog.Info("statistics not reset, dry run", "tenant", tenant)
+		return nil
+	}
+
+	buckets, err := r.statistics.DeleteStatistics(ctx, tenant)
+	if err != nil {
+		return err
+	}
+
+	images, err := r.images.ResetImagesCounted(ctx, tenant)
+	if err != nil {
+		return err
+	}
+
+	slog.Info("statistics reset", "tenant", tenant, "buckets", buckets, "images", images)
+
+	return nil
+}
+
+// replayEvents groups the images by tenant and upload link, in the order of
+// their first image, with batch images by event at most.
+func replayEvents(images []models.Image, batch int) []models.ImageUploadedEvent {
+	type uploadKey struct{ tenant, uploadLinkID string }
+
+	var events []models.ImageUploadedEvent
+	open := map[uploadKey]int{}
+	for _, image := range images {
+		key := uploadKey{image.Tenant, image.UploadLinkID}
+
+		i, ok := open[key]
+		if !ok || len(events[i].Images) >= batch {
+			events = append(events, models.ImageUploadedEvent{UploadLinkID: image.UploadLinkID, Tenant: image.Tenant})
+			i = len(events) - 1
+			open[key] = i
+		}
+
+		events[i].Images = append(events[i].Images, image)
+	}
+
+	return events
+}
+
+// parseTimeFlag parses the RFC 3339 time of the flag, the zero time when it
+// is empty.
+func parseTimeFlag(name string, value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("-%s: %w", name, err)
+	}
+
+	return t, nil
+}
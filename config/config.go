@@ -1,20 +1,55 @@
 package config
 
 import (
-	"path/filepath"
-	"runtime"
+	"bytes"
+	_ "embed"
+	"errors"
+	"fmt"
 	"strconv"
 
 	"github.com/spf13/viper"
 )
 
-const DefaultYmlFile = "default.config.yml"
+const (
+	DefaultYmlFile = "default.config.yml"
+
+	// EnvPrefix prefixes the environment variables overriding the settings,
+	// e.g. IMAGE_UPLOAD_MONGODB_PASSWORD overrides mongoDB.password
+	EnvPrefix = "IMAGE_UPLOAD"
+	// PathEnv is the configuration file read when no path is given
+	PathEnv = EnvPrefix + "_CONFIG"
+)
+
+// defaultYml holds the defaults, so a binary deployed without the sources has
+// a complete configuration.
+//
+//go:embed default.config.yml
+var defaultYml []byte
 
 type (
 	Config struct {
-		APIPort int           `mapstructure:"apiPort" validate:"required"`
+		APIPort int           `mapstructure:"apiPort" validate:"required,min=1,max=65535"`
 		Kafka   KafkaConfig   `mapstructure:"kafka" validate:"required"`
+		Bus     BusConfig     `mapstructure:"bus"`
+		Events  EventsConfig  `mapstructure:"events"`
 		MongoDB MongoDBConfig `mapstructure:"mongoDB" validate:"required"`
+
+		StatisticsStream StatisticsStreamConfig `mapstructure:"statisticsStream"`
+		Privacy          PrivacyConfig          `mapstructure:"privacy"`
+		Orientation      OrientationConfig      `mapstructure:"orientation"`
+		Deletion         DeletionConfig         `mapstructure:"deletion"`
+		GarbageCollector GarbageCollectorConfig `mapstructure:"garbageCollector"`
+		Idempotency      IdempotencyConfig      `mapstructure:"idempotency"`
+		APIKeys          APIKeysConfig          `mapstructure:"apiKeys"`
+		JWT              JWTConfig              `mapstructure:"jwt"`
+		Tenancy          TenancyConfig          `mapstructure:"tenancy"`
+		Audit            AuditConfig            `mapstructure:"audit"`
+		RateLimit        RateLimitConfig        `mapstructure:"rateLimit"`
+		Metrics          MetricsConfig          `mapstructure:"metrics"`
+		Tracing          TracingConfig          `mapstructure:"tracing"`
+		Logging          LoggingConfig          `mapstructure:"logging"`
+		Health           HealthConfig           `mapstructure:"health"`
+		Handlers         HandlersConfig         `mapstructure:"handlers"`
 	}
 
 	KafkaConfig struct {
@@ -22,42 +57,284 @@ type (
 		Topic                      string `mapstructure:"topic" validate:"required"`
 		Group                      string `mapstructure:"group" validate:"required"`
 		WithTls                    bool   `mapstructure:"withTls"`
-		DialerTimeoutMilliseconds  int    `mapstructure:"dialerTimeoutMilliseconds"`
+		DialerTimeoutMilliseconds  int    `mapstructure:"dialerTimeoutMilliseconds" validate:"min=0"`
 		MaxWaitTimeoutMilliseconds int    `mapstructure:"maxWaitTimeoutMilliseconds"`
 		NumConsumers               int    `mapstructure:"numConsumers"`
+
+		TLS      KafkaTLSConfig      `mapstructure:"tls"`
+		SASL     KafkaSASLConfig     `mapstructure:"sasl"`
+		Producer KafkaProducerConfig `mapstructure:"producer"`
+	}
+
+	// KafkaTLSConfig is used when WithTls is enabled, the system roots are
+	// trusted when no CA file is set
+	KafkaTLSConfig struct {
+		CAFile   string `mapstructure:"caFile"`
+		CertFile string `mapstructure:"certFile" validate:"required_with=KeyFile"`
+		KeyFile  string `mapstructure:"keyFile" validate:"required_with=CertFile"`
+		// InsecureSkipVerify doesn't verify the certificates of the brokers, only use it in development
+		InsecureSkipVerify bool `mapstructure:"insecureSkipVerify"`
+	}
+
+	KafkaSASLConfig struct {
+		// Mechanism is plain, scram-sha-256 or scram-sha-512, empty disables SASL
+		Mechanism string `mapstructure:"mechanism" validate:"omitempty,oneof=plain scram-sha-256 scram-sha-512"`
+		Username  string `mapstructure:"username" validate:"required_with=Mechanism"`
+		Password  string `mapstructure:"password" validate:"required_with=Mechanism" secret:"true"`
+	}
+
+	KafkaProducerConfig struct {
+		// RequiredAcks is none, one or all
+		RequiredAcks string `mapstructure:"requiredAcks" validate:"omitempty,oneof=none one all"`
+		// Compression is none, gzip, snappy, lz4 or zstd
+		Compression              string `mapstructure:"compression" validate:"omitempty,oneof=none gzip snappy lz4 zstd"`
+		BatchSize                int    `mapstructure:"batchSize" validate:"min=0"`
+		BatchTimeoutMilliseconds int    `mapstructure:"batchTimeoutMilliseconds" validate:"min=0"`
+		MaxAttempts              int    `mapstructure:"maxAttempts" validate:"min=0"`
+		// Idempotent requires the acks of all the replicas and stamps a message id the consumers drop the redeliveries of
+		Idempotent bool `mapstructure:"idempotent"`
+	}
+
+	BusConfig struct {
+		// Type is kafka, or memory to run as a single process without brokers, the events of the memory bus are lost on restart
+		Type string `mapstructure:"type" validate:"required,oneof=kafka memory"`
+		// Partitions of the topics of the memory bus, the consumers of a group share them
+		Partitions int `mapstructure:"partitions" validate:"min=0"`
+	}
+
+	EventsConfig struct {
+		// Version of the published image events, 1 is the bare JSON array of the image ids and 2 the CloudEvents
+		// envelope whose image_uploaded carries the images, keep 1 until every consumer is able to read 2
+		Version int `mapstructure:"version" validate:"oneof=1 2"`
+	}
+
+	HandlersConfig struct {
+		// Disabled names the handlers not to run on the consumed events
+		Disabled []string           `mapstructure:"disabled" validate:"dive,oneof=statistics webhooks"`
+		Retry    HandlerRetryConfig `mapstructure:"retry"`
+		Webhooks WebhooksConfig     `mapstructure:"webhooks"`
+	}
+
+	// WebhooksConfig posts the image events to every URL, the replayed events
+	// aren't posted again
+	WebhooksConfig struct {
+		URLs []string `mapstructure:"urls" validate:"dive,http_url"`
+		// Secret signs the bodies with HMAC-SHA256, empty doesn't sign them
+		Secret              string `mapstructure:"secret" secret:"true"`
+		TimeoutMilliseconds int    `mapstructure:"timeoutMilliseconds" validate:"min=1"`
+	}
+
+	// HandlerRetryConfig retries a failed handler, the backoff doubles after
+	// every attempt, the event is skipped by the handler once they are exhausted
+	HandlerRetryConfig struct {
+		MaxAttempts         int `mapstructure:"maxAttempts" validate:"min=1"`
+		BackoffMilliseconds int `mapstructure:"backoffMilliseconds" validate:"min=0"`
 	}
 
 	MongoDBConfig struct {
 		Host     string `mapstructure:"host" validate:"required"`
 		User     string `mapstructure:"user" validate:"required"`
-		Password string `mapstructure:"password" validate:"required"`
+		Password string `mapstructure:"password" validate:"required" secret:"true"`
 		Database string `mapstructure:"database" validate:"required"`
-		Port     int    `mapstructure:"port"`
+		Port     int    `mapstructure:"port" validate:"min=1,max=65535"`
 		Options  string `mapstructure:"options"`
 	}
+
+	StatisticsStreamConfig struct {
+		HeartbeatSeconds int `mapstructure:"heartbeatSeconds"`
+		HistorySize      int `mapstructure:"historySize"`
+		SubscriberBuffer int `mapstructure:"subscriberBuffer"`
+	}
+
+	PrivacyConfig struct {
+		// DefaultMode is one of keep, strip_gps or strip_all, upload links can override it
+		DefaultMode string `mapstructure:"defaultMode" validate:"privacyMode"`
+	}
+
+	OrientationConfig struct {
+		// Normalize rotates stored JPEG pixels upright and resets the EXIF orientation
+		Normalize   bool `mapstructure:"normalize"`
+		JpegQuality int  `mapstructure:"jpegQuality" validate:"min=1,max=100"`
+	}
+
+	DeletionConfig struct {
+		// PurgeDelaySeconds is how long soft deleted images are kept before their files and records are removed
+		PurgeDelaySeconds    int `mapstructure:"purgeDelaySeconds"`
+		PurgeIntervalSeconds int `mapstructure:"purgeIntervalSeconds"`
+		PurgeBatchSize       int `mapstructure:"purgeBatchSize"`
+	}
+
+	GarbageCollectorConfig struct {
+		Enabled         bool `mapstructure:"enabled"`
+		IntervalSeconds int  `mapstructure:"intervalSeconds"`
+		// ExpiredLinkRetentionSeconds is how long expired upload links and their images are kept, 0 keeps them forever
+		ExpiredLinkRetentionSeconds int `mapstructure:"expiredLinkRetentionSeconds"`
+		// OrphanGraceSeconds skips recent files whose image records may still be being inserted
+		OrphanGraceSeconds int `mapstructure:"orphanGraceSeconds"`
+		// DryRun only reports what would be deleted
+		DryRun bool `mapstructure:"dryRun"`
+	}
+
+	IdempotencyConfig struct {
+		// TTLSeconds is how long responses are kept for replay
+		TTLSeconds int `mapstructure:"ttlSeconds"`
+		// LockTimeoutSeconds is after how long a request that never completed is considered abandoned
+		LockTimeoutSeconds int `mapstructure:"lockTimeoutSeconds"`
+	}
+
+	APIKeysConfig struct {
+		// BootstrapAdminKey is granted every scope on startup so the first keys can be minted, the bootstrap keys are
+		// revoked when it is empty or changed
+		BootstrapAdminKey string `mapstructure:"bootstrapAdminKey" secret:"true"`
+	}
+
+	JWTConfig struct {
+		Enabled  bool   `mapstructure:"enabled"`
+		JWKSURL  string `mapstructure:"jwksUrl" validate:"required_if=Enabled true"`
+		Issuer   string `mapstructure:"issuer" validate:"required_if=Enabled true"`
+		Audience string `mapstructure:"audience" validate:"required_if=Enabled true"`
+		// ScopesClaim holds the granted scopes, as a space delimited string or an array
+		ScopesClaim string `mapstructure:"scopesClaim"`
+		// TenantClaim holds the caller tenant, tokens without it are rejected, empty puts every caller in the default tenant
+		TenantClaim string `mapstructure:"tenantClaim"`
+		// RolesClaim holds the caller roles, granted the scopes of their RoleScopes entry
+		RolesClaim string               `mapstructure:"rolesClaim"`
+		RoleScopes []JWTRoleScopeConfig `mapstructure:"roleScopes" validate:"dive"`
+		// JWKSCacheSeconds is how long fetched keys are trusted before the JWKS is fetched again
+		JWKSCacheSeconds int `mapstructure:"jwksCacheSeconds"`
+		// JWKSMinRefreshSeconds limits the refreshes triggered by tokens signed with unknown keys
+		JWKSMinRefreshSeconds   int `mapstructure:"jwksMinRefreshSeconds"`
+		JWKSTimeoutMilliseconds int `mapstructure:"jwksTimeoutMilliseconds"`
+		ClockSkewSeconds        int `mapstructure:"clockSkewSeconds"`
+	}
+
+	JWTRoleScopeConfig struct {
+		Role   string   `mapstructure:"role" validate:"required"`
+		Scopes []string `mapstructure:"scopes" validate:"dive,scope"`
+	}
+
+	TenancyConfig struct {
+		Tenants []TenantConfig `mapstructure:"tenants" validate:"dive"`
+	}
+
+	// TenantConfig overrides whole sections of the deployment configuration
+	// for a tenant, nil sections are inherited.
+	TenantConfig struct {
+		ID          string             `mapstructure:"id" validate:"tenant"`
+		Privacy     *PrivacyConfig     `mapstructure:"privacy"`
+		Orientation *OrientationConfig `mapstructure:"orientation"`
+	}
+
+	AuditConfig struct {
+		// ExportTopic also publishes the audit events to this Kafka topic, empty disables the export
+		ExportTopic string `mapstructure:"exportTopic"`
+		// TrustForwardedFor records the first X-Forwarded-For address, only enable it behind a proxy setting the header
+		TrustForwardedFor bool `mapstructure:"trustForwardedFor"`
+	}
+
+	RateLimitConfig struct {
+		Enabled bool `mapstructure:"enabled"`
+		// Store is memory for a single instance or mongo to share the counters between instances
+		Store string `mapstructure:"store" validate:"required_if=Enabled true,omitempty,oneof=memory mongo"`
+		// TrustForwardedFor counts clients by their first X-Forwarded-For address, only enable it behind a proxy setting the header
+		TrustForwardedFor bool                 `mapstructure:"trustForwardedFor"`
+		Public            RateLimitGroupConfig `mapstructure:"public"`
+		Secret            RateLimitGroupConfig `mapstructure:"secret"`
+	}
+
+	// RateLimitGroupConfig limits the routes of a group, each key gets a bucket
+	// of Burst requests refilled at RequestsPerMinute. 0 requests per minute
+	// disables the limit.
+	RateLimitGroupConfig struct {
+		RequestsPerMinute int `mapstructure:"requestsPerMinute" validate:"min=0"`
+		Burst             int `mapstructure:"burst" validate:"min=0"`
+		// Keys are any of apiKey, clientIP and uploadLink
+		Keys []string `mapstructure:"keys" validate:"dive,rateLimitKey"`
+	}
+
+	MetricsConfig struct {
+		Enabled bool `mapstructure:"enabled"`
+		// Path serves the Prometheus metrics, outside of the api prefix and without authentication
+		Path string `mapstructure:"path" validate:"required_if=Enabled true"`
+	}
+
+	TracingConfig struct {
+		// Exporter is none, stdout or otlp
+		Exporter    string `mapstructure:"exporter" validate:"omitempty,oneof=none stdout otlp"`
+		ServiceName string `mapstructure:"serviceName"`
+		// OTLPEndpoint is the host:port of the OTLP/HTTP collector
+		OTLPEndpoint string `mapstructure:"otlpEndpoint" validate:"required_if=Exporter otlp"`
+		OTLPInsecure bool   `mapstructure:"otlpInsecure"`
+		// SampleRatio of the traces started by the service, the callers' sampling decision is followed
+		SampleRatio float64 `mapstructure:"sampleRatio" validate:"min=0,max=1"`
+	}
+
+	LoggingConfig struct {
+		// Level is debug, info, warn or error
+		Level string `mapstructure:"level" validate:"omitempty,oneof=debug info warn error"`
+		// Format is json or text
+		Format string `mapstructure:"format" validate:"omitempty,oneof=json text"`
+	}
+
+	HealthConfig struct {
+		// CheckTimeoutMilliseconds bounds each dependency check of the readiness probe
+		CheckTimeoutMilliseconds int `mapstructure:"checkTimeoutMilliseconds" validate:"min=0"`
+	}
 )
 
-func getFilePath(fileName string) string {
-	_, b, _, _ := runtime.Caller(0)
-	basepath := filepath.Dir(b)
-	return basepath + "/" + fileName
-}
+// LoadConfig reads the defaults, merges the file at path over them when path
+// isn't empty and applies the environment overrides and secret files. Every
+// invalid setting is reported in the returned error.
+func LoadConfig(path string) (*Config, error) {
+	v := viper.New()
+	v.SetConfigType("yaml")
+	if err := v.ReadConfig(bytes.NewReader(defaultYml)); err != nil {
+		return nil, fmt.Errorf("error reading default config: %w", err)
+	}
 
-func LoadConfig() (*Config, error) {
-	config := &Config{}
-	viper.SetConfigFile(getFilePath(DefaultYmlFile))
+	if path != "" {
+		v.SetConfigFile(path)
+		if err := v.MergeInConfig(); err != nil {
+			return nil, fmt.Errorf("error reading config file %s: %w", path, err)
+		}
+	}
 
-	if err := viper.MergeInConfig(); err != nil {
-		return nil, err
+	errs := bindEnv(v)
+
+	config := &Config{}
+	if err := v.Unmarshal(config); err != nil {
+		errs = append(errs, err)
+	} else if err := config.Validate(); err != nil {
+		errs = append(errs, err)
 	}
 
-	if err := viper.Unmarshal(&config); err != nil {
-		return nil, err
+	if len(errs) > 0 {
+		return nil, fmt.Errorf("invalid configuration:\n%w", errors.Join(errs...))
 	}
 
 	return config, nil
 }
 
+// ForTenant returns the configuration with the overrides of the tenant applied.
+func (c *Config) ForTenant(tenant string) *Config {
+	tenantConfig := *c
+	for _, override := range c.Tenancy.Tenants {
+		if override.ID != tenant {
+			continue
+		}
+
+		if override.Privacy != nil {
+			tenantConfig.Privacy = *override.Privacy
+		}
+
+		if override.Orientation != nil {
+			tenantConfig.Orientation = *override.Orientation
+		}
+	}
+
+	return &tenantConfig
+}
+
 func (m *MongoDBConfig) MongoURI() string {
 	return "mongodb://" + m.User + ":" + m.Password + "@" + m.Host + ":" + strconv.Itoa(m.Port) + "/" + m.Database + "?" + m.Options
 }
@@ -0,0 +1,114 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoadConfig(t *testing.T) {
+	dir := t.TempDir()
+
+	overrides := filepath.Join(dir, "config.yml")
+	os.WriteFile(overrides, []byte("apiPort: 9000\nmongoDb:\n  host: \"db.internal\"\n"), 0o600)
+
+	passwordFile := filepath.Join(dir, "mongo-password")
+	os.WriteFile(passwordFile, []byte("from-file\n"), 0o600)
+
+	invalid := filepath.Join(dir, "invalid.yml")
+	os.WriteFile(invalid, []byte("kafka:\n  topic: \"\"\n  sasl:\n    mechanism: \"scram-sha-256\"\n  producer:\n    compression: \"brotli\"\nlogging:\n  format: \"xml\"\nprivacy:\n  defaultMode: \"blur\"\ntenancy:\n  tenants:\n    - id: \"Not A Tenant\"\njwt:\n  enabled: true\n  jwksUrl: \"https://idp.example.com/jwks\"\nhandlers:\n  webhooks:\n    urls: [\"ftp://hooks.example.com\"]\n"), 0o600)
+
+	tests := []struct {
+		name        string
+		path        string
+		env         map[string]string
+		check       func(t *testing.T, cfg *Config)
+		expectedErr []string
+	}{
+		{
+			name: "defaults",
+			check: func(t *testing.T, cfg *Config) {
+				assert.Equal(t, 8080, cfg.APIPort)
+				assert.Equal(t, "mongo", cfg.MongoDB.Host)
+				assert.Equal(t, 1, cfg.Events.Version)
+			},
+		},
+		{
+			name: "file merged over the defaults",
+			path: overrides,
+			check: func(t *testing.T, cfg *Config) {
+				assert.Equal(t, 9000, cfg.APIPort)
+				assert.Equal(t, "db.internal", cfg.MongoDB.Host)
+				assert.Equal(t, "image-upload", cfg.MongoDB.Database)
+			},
+		},
+		{
+			name: "environment overrides",
+			path: overrides,
+			env: map[string]string{
+				"IMAGE_UPLOAD_APIPORT":                         "9100",
+				"IMAGE_UPLOAD_MONGODB_PASSWORD":                "from-env",
+				"IMAGE_UPLOAD_RATELIMIT_PUBLIC_KEYS":           "clientIP",
+				"IMAGE_UPLOAD_HEALTH_CHECKTIMEOUTMILLISECONDS": "500",
+			},
+			check: func(t *testing.T, cfg *Config) {
+				assert.Equal(t, 9100, cfg.APIPort)
+				assert.Equal(t, "from-env", cfg.MongoDB.Password)
+				assert.Equal(t, []string{"clientIP"}, cfg.RateLimit.Public.Keys)
+				assert.Equal(t, 500, cfg.Health.CheckTimeoutMilliseconds)
+			},
+		},
+		{
+			name: "secret from file",
+			env:  map[string]string{"IMAGE_UPLOAD_MONGODB_PASSWORD_FILE": passwordFile},
+			check: func(t *testing.T, cfg *Config) {
+				assert.Equal(t, "from-file", cfg.MongoDB.Password)
+			},
+		},
+		{
+			name: "secret set twice",
+			env: map[string]string{
+				"IMAGE_UPLOAD_MONGODB_PASSWORD":      "from-env",
+				"IMAGE_UPLOAD_MONGODB_PASSWORD_FILE": passwordFile,
+			},
+			expectedErr: []string{"IMAGE_UPLOAD_MONGODB_PASSWORD and IMAGE_UPLOAD_MONGODB_PASSWORD_FILE are both set"},
+		},
+		{
+			name: "every invalid setting reported",
+			path: invalid,
+			expectedErr: []string{
+				"kafka.topic is required",
+				"kafka.sasl.username is required when mechanism is set",
+				`kafka.producer.compression is "brotli", it must be one of none, gzip, snappy, lz4, zstd`,
+				`logging.format is "xml", it must be one of json, text`,
+				`privacy.defaultMode is "blur", it must be keep, strip_gps or strip_all`,
+				`tenancy.tenants[0].id is "Not A Tenant", it isn't a valid tenant id`,
+				`handlers.webhooks.urls[0] is "ftp://hooks.example.com", it must be an http or https URL`,
+				"jwt.issuer is required",
+				"jwt.audience is required",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for name, value := range tt.env {
+				t.Setenv(name, value)
+			}
+
+			cfg, err := LoadConfig(tt.path)
+			if tt.expectedErr != nil {
+				assert.Error(t, err)
+				for _, expected := range tt.expectedErr {
+					assert.Contains(t, err.Error(), expected)
+				}
+				return
+			}
+
+			assert.NoError(t, err)
+			tt.check(t, cfg)
+		})
+	}
+}
@@ -0,0 +1,72 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+// SecretFileSuffix names the variables holding the path of a file to read a
+// secret from, e.g. IMAGE_UPLOAD_MONGODB_PASSWORD_FILE.
+const SecretFileSuffix = "_FILE"
+
+// bindEnv lets an environment variable override every setting, lists are
+// comma separated. The lists of sections, like the tenant overrides, can only
+// be set in a file. The settings tagged secret:"true" can also be read from
+// the file their _FILE variable points to.
+func bindEnv(v *viper.Viper) []error {
+	v.SetEnvPrefix(EnvPrefix)
+	v.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
+
+	return bindSectionEnv(v, reflect.TypeOf(Config{}), "")
+}
+
+func bindSectionEnv(v *viper.Viper, section reflect.Type, prefix string) []error {
+	var errs []error
+	for i := 0; i < section.NumField(); i++ {
+		field := section.Field(i)
+		key := prefix + strings.ToLower(field.Tag.Get("mapstructure"))
+
+		switch {
+		case field.Type.Kind() == reflect.Struct:
+			errs = append(errs, bindSectionEnv(v, field.Type, key+".")...)
+			continue
+		case field.Type.Kind() == reflect.Slice && field.Type.Elem().Kind() == reflect.Struct:
+			continue
+		}
+
+		v.BindEnv(key)
+
+		if field.Tag.Get("secret") != "true" {
+			continue
+		}
+
+		fileEnv := envName(key) + SecretFileSuffix
+		path := os.Getenv(fileEnv)
+		if path == "" {
+			continue
+		}
+
+		if _, ok := os.LookupEnv(envName(key)); ok {
+			errs = append(errs, fmt.Errorf("%s and %s are both set", envName(key), fileEnv))
+			continue
+		}
+
+		content, err := os.ReadFile(path)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("error reading %s: %w", fileEnv, err))
+			continue
+		}
+
+		v.Set(key, strings.TrimRight(string(content), "\r\n"))
+	}
+
+	return errs
+}
+
+func envName(key string) string {
+	return EnvPrefix + "_" + strings.ToUpper(strings.ReplaceAll(key, ".", "_"))
+}
@@ -0,0 +1,40 @@
+package config
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"reflect"
+)
+
+// RedactedValue replaces the value of the secrets set in a redacted configuration.
+const RedactedValue = "REDACTED"
+
+// Redacted returns a copy of the configuration whose string fields tagged
+// secret:"true" are replaced, so it can be reported or fingerprinted. Secrets
+// are looked up in the nested structs, not behind pointers or in slices.
+func (c *Config) Redacted() Config {
+	redacted := *c
+	redactSecrets(reflect.ValueOf(&redacted).Elem())
+	return redacted
+}
+
+// Hash fingerprints the redacted configuration, instances reporting the same
+// hash run with the same settings.
+func (c *Config) Hash() string {
+	content, _ := json.Marshal(c.Redacted())
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
+
+func redactSecrets(v reflect.Value) {
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Field(i)
+		switch {
+		case field.Kind() == reflect.Struct:
+			redactSecrets(field)
+		case field.Kind() == reflect.String && v.Type().Field(i).Tag.Get("secret") == "true" && field.String() != "":
+			field.SetString(RedactedValue)
+		}
+	}
+}
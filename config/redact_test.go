@@ -0,0 +1,28 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRedacted(t *testing.T) {
+	cfg := &Config{
+		MongoDB: MongoDBConfig{User: "root", Password: "password"},
+		APIKeys: APIKeysConfig{BootstrapAdminKey: "secret-key"},
+	}
+
+	redacted := cfg.Redacted()
+
+	assert.Equal(t, "root", redacted.MongoDB.User)
+	assert.Equal(t, RedactedValue, redacted.MongoDB.Password)
+	assert.Equal(t, RedactedValue, redacted.APIKeys.BootstrapAdminKey)
+	assert.Equal(t, "password", cfg.MongoDB.Password, "the configuration itself is left untouched")
+
+	rotated := *cfg
+	rotated.MongoDB.Password = "rotated"
+	assert.Equal(t, cfg.Hash(), rotated.Hash(), "the hash doesn't depend on the secrets")
+
+	rotated.MongoDB.User = "admin"
+	assert.NotEqual(t, cfg.Hash(), rotated.Hash())
+}
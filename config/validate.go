@@ -0,0 +1,86 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+
+	"github.com/tam-code/image-upload/src/models"
+)
+
+var validate = newValidator()
+
+func newValidator() *validator.Validate {
+	validate := validator.New()
+
+	// errors name the settings as they are written in the yml file
+	validate.RegisterTagNameFunc(func(field reflect.StructField) string {
+		return field.Tag.Get("mapstructure")
+	})
+
+	validate.RegisterValidation("privacyMode", func(fl validator.FieldLevel) bool {
+		return models.PrivacyMode(fl.Field().String()).IsValid()
+	})
+	validate.RegisterValidation("tenant", func(fl validator.FieldLevel) bool {
+		return models.IsValidTenant(fl.Field().String())
+	})
+	validate.RegisterValidation("scope", func(fl validator.FieldLevel) bool {
+		return models.Scope(fl.Field().String()).IsValid()
+	})
+	validate.RegisterValidation("rateLimitKey", func(fl validator.FieldLevel) bool {
+		return models.RateLimitKey(fl.Field().String()).IsValid()
+	})
+
+	return validate
+}
+
+// Validate checks the settings against their validate tags, every invalid
+// setting is reported in the returned error.
+func (c *Config) Validate() error {
+	err := validate.Struct(c)
+
+	var validationErrors validator.ValidationErrors
+	if !errors.As(err, &validationErrors) {
+		return err
+	}
+
+	errs := make([]error, 0, len(validationErrors))
+	for _, fieldError := range validationErrors {
+		// the namespace starts with the Config struct name
+		key := fieldError.Namespace()[strings.Index(fieldError.Namespace(), ".")+1:]
+		errs = append(errs, fmt.Errorf("%s %s", key, describeValidationError(fieldError)))
+	}
+
+	return errors.Join(errs...)
+}
+
+func describeValidationError(fieldError validator.FieldError) string {
+	switch fieldError.Tag() {
+	case "required", "required_if":
+		return "is required"
+	case "required_with":
+		// the param is the name of the sibling field, e.g. KeyFile for kafka.tls.keyFile
+		return "is required when " + strings.ToLower(fieldError.Param()[:1]) + fieldError.Param()[1:] + " is set"
+	case "oneof":
+		return fmt.Sprintf("is %q, it must be one of %s", fieldError.Value(), strings.Join(strings.Fields(fieldError.Param()), ", "))
+	case "http_url":
+		return fmt.Sprintf("is %q, it must be an http or https URL", fieldError.Value())
+	case "min":
+		return "must be at least " + fieldError.Param()
+	case "max":
+		return "must be at most " + fieldError.Param()
+	case "privacyMode":
+		return fmt.Sprintf("is %q, it must be keep, strip_gps or strip_all", fieldError.Value())
+	case "tenant":
+		return fmt.Sprintf("is %q, it isn't a valid tenant id", fieldError.Value())
+	case "scope":
+		return fmt.Sprintf("is %q, it isn't a known scope", fieldError.Value())
+	case "rateLimitKey":
+		return fmt.Sprintf("is %q, it must be apiKey, clientIP or uploadLink", fieldError.Value())
+	default:
+		return "is invalid"
+	}
+}
@@ -0,0 +1,50 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: src/authenticators/jwt.go
+
+// Package mocks is a generated GoMock package.
+package mocks
+
+import (
+	reflect "reflect"
+
+	gomock "github.com/golang/mock/gomock"
+	models "github.com/tam-code/image-upload/src/models"
+)
+
+// MockJWTAuthenticator is a mock of JWTAuthenticator interface.
+type MockJWTAuthenticator struct {
+	ctrl     *gomock.Controller
+	recorder *MockJWTAuthenticatorMockRecorder
+}
+
+// MockJWTAuthenticatorMockRecorder is the mock recorder for MockJWTAuthenticator.
+type MockJWTAuthenticatorMockRecorder struct {
+	mock *MockJWTAuthenticator
+}
+
+// NewMockJWTAuthenticator creates a new mock instance.
+func NewMockJWTAuthenticator(ctrl *gomock.Controller) *MockJWTAuthenticator {
+	mock := &MockJWTAuthenticator{ctrl: ctrl}
+	mock.recorder = &MockJWTAuthenticatorMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockJWTAuthenticator) EXPECT() *MockJWTAuthenticatorMockRecorder {
+	return m.recorder
+}
+
+// Authenticate mocks base method.
+func (m *MockJWTAuthenticator) Authenticate(token string) (*models.Principal, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Authenticate", token)
+	ret0, _ := ret[0].(*models.Principal)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Authenticate indicates an expected call of Authenticate.
+func (mr *MockJWTAuthenticatorMockRecorder) Authenticate(token interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Authenticate", reflect.TypeOf((*MockJWTAuthenticator)(nil).Authenticate), token)
+}
@@ -0,0 +1,77 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: src/janitors/garbage_collector.go
+
+// Package mocks is a generated GoMock package.
+package mocks
+
+import (
+	context "context"
+	reflect "reflect"
+
+	gomock "github.com/golang/mock/gomock"
+	models "github.com/tam-code/image-upload/src/models"
+)
+
+// MockGarbageCollector is a mock of GarbageCollector interface.
+type MockGarbageCollector struct {
+	ctrl     *gomock.Controller
+	recorder *MockGarbageCollectorMockRecorder
+}
+
+// MockGarbageCollectorMockRecorder is the mock recorder for MockGarbageCollector.
+type MockGarbageCollectorMockRecorder struct {
+	mock *MockGarbageCollector
+}
+
+// NewMockGarbageCollector creates a new mock instance.
+func NewMockGarbageCollector(ctrl *gomock.Controller) *MockGarbageCollector {
+	mock := &MockGarbageCollector{ctrl: ctrl}
+	mock.recorder = &MockGarbageCollectorMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockGarbageCollector) EXPECT() *MockGarbageCollectorMockRecorder {
+	return m.recorder
+}
+
+// Collect mocks base method.
+func (m *MockGarbageCollector) Collect(ctx context.Context, dryRun bool) (*models.GarbageCollectionReport, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Collect", ctx, dryRun)
+	ret0, _ := ret[0].(*models.GarbageCollectionReport)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Collect indicates an expected call of Collect.
+func (mr *MockGarbageCollectorMockRecorder) Collect(ctx, dryRun interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Collect", reflect.TypeOf((*MockGarbageCollector)(nil).Collect), ctx, dryRun)
+}
+
+// DryRun mocks base method.
+func (m *MockGarbageCollector) DryRun() bool {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DryRun")
+	ret0, _ := ret[0].(bool)
+	return ret0
+}
+
+// DryRun indicates an expected call of DryRun.
+func (mr *MockGarbageCollectorMockRecorder) DryRun() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DryRun", reflect.TypeOf((*MockGarbageCollector)(nil).DryRun))
+}
+
+// Run mocks base method.
+func (m *MockGarbageCollector) Run(ctx context.Context) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "Run", ctx)
+}
+
+// Run indicates an expected call of Run.
+func (mr *MockGarbageCollectorMockRecorder) Run(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Run", reflect.TypeOf((*MockGarbageCollector)(nil).Run), ctx)
+}
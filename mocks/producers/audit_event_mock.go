@@ -0,0 +1,50 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: src/producers/audit_event.go
+
+// Package mocks is a generated GoMock package.
+package mocks
+
+import (
+	context "context"
+	reflect "reflect"
+
+	gomock "github.com/golang/mock/gomock"
+	models "github.com/tam-code/image-upload/src/models"
+)
+
+// MockAuditEventProducer is a mock of AuditEventProducer interface.
+type MockAuditEventProducer struct {
+	ctrl     *gomock.Controller
+	recorder *MockAuditEventProducerMockRecorder
+}
+
+// MockAuditEventProducerMockRecorder is the mock recorder for MockAuditEventProducer.
+type MockAuditEventProducerMockRecorder struct {
+	mock *MockAuditEventProducer
+}
+
+// NewMockAuditEventProducer creates a new mock instance.
+func NewMockAuditEventProducer(ctrl *gomock.Controller) *MockAuditEventProducer {
+	mock := &MockAuditEventProducer{ctrl: ctrl}
+	mock.recorder = &MockAuditEventProducerMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockAuditEventProducer) EXPECT() *MockAuditEventProducerMockRecorder {
+	return m.recorder
+}
+
+// Publish mocks base method.
+func (m *MockAuditEventProducer) Publish(ctx context.Context, event models.AuditEvent) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Publish", ctx, event)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Publish indicates an expected call of Publish.
+func (mr *MockAuditEventProducerMockRecorder) Publish(ctx, event interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Publish", reflect.TypeOf((*MockAuditEventProducer)(nil).Publish), ctx, event)
+}
@@ -0,0 +1,49 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: src/producers/image_deleted.go
+
+// Package mocks is a generated GoMock package.
+package mocks
+
+import (
+	context "context"
+	reflect "reflect"
+
+	gomock "github.com/golang/mock/gomock"
+)
+
+// MockImageDeletedProducer is a mock of ImageDeletedProducer interface.
+type MockImageDeletedProducer struct {
+	ctrl     *gomock.Controller
+	recorder *MockImageDeletedProducerMockRecorder
+}
+
+// MockImageDeletedProducerMockRecorder is the mock recorder for MockImageDeletedProducer.
+type MockImageDeletedProducerMockRecorder struct {
+	mock *MockImageDeletedProducer
+}
+
+// NewMockImageDeletedProducer creates a new mock instance.
+func NewMockImageDeletedProducer(ctrl *gomock.Controller) *MockImageDeletedProducer {
+	mock := &MockImageDeletedProducer{ctrl: ctrl}
+	mock.recorder = &MockImageDeletedProducerMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockImageDeletedProducer) EXPECT() *MockImageDeletedProducerMockRecorder {
+	return m.recorder
+}
+
+// Publish mocks base method.
+func (m *MockImageDeletedProducer) Publish(ctx context.Context, tenant string, images []string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Publish", ctx, tenant, images)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Publish indicates an expected call of Publish.
+func (mr *MockImageDeletedProducerMockRecorder) Publish(ctx, tenant, images interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Publish", reflect.TypeOf((*MockImageDeletedProducer)(nil).Publish), ctx, tenant, images)
+}
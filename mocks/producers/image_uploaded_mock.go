@@ -5,9 +5,11 @@
 package mocks
 
 import (
+	context "context"
 	reflect "reflect"
 
 	gomock "github.com/golang/mock/gomock"
+	models "github.com/tam-code/image-upload/src/models"
 )
 
 // MockImageUploadedProducer is a mock of ImageUploadedProducer interface.
@@ -34,15 +36,15 @@ func (m *MockImageUploadedProducer) EXPECT() *MockImageUploadedProducerMockRecor
 }
 
 // Publish mocks base method.
-func (m *MockImageUploadedProducer) Publish(images []string) error {
+func (m *MockImageUploadedProducer) Publish(ctx context.Context, event models.ImageUploadedEvent) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Publish", images)
+	ret := m.ctrl.Call(m, "Publish", ctx, event)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // Publish indicates an expected call of Publish.
-func (mr *MockImageUploadedProducerMockRecorder) Publish(images interface{}) *gomock.Call {
+func (mr *MockImageUploadedProducerMockRecorder) Publish(ctx, event interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Publish", reflect.TypeOf((*MockImageUploadedProducer)(nil).Publish), images)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Publish", reflect.TypeOf((*MockImageUploadedProducer)(nil).Publish), ctx, event)
 }
@@ -5,7 +5,9 @@
 package mocks
 
 import (
+	context "context"
 	reflect "reflect"
+	time "time"
 
 	gomock "github.com/golang/mock/gomock"
 	models "github.com/tam-code/image-upload/src/models"
@@ -34,91 +36,328 @@ func (m *MockImageRepository) EXPECT() *MockImageRepositoryMockRecorder {
 	return m.recorder
 }
 
+// CountImagesByUploadLinkID mocks base method.
+func (m *MockImageRepository) CountImagesByUploadLinkID(arg0 context.Context, arg1, arg2 string) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CountImagesByUploadLinkID", arg0, arg1, arg2)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CountImagesByUploadLinkID indicates an expected call of CountImagesByUploadLinkID.
+func (mr *MockImageRepositoryMockRecorder) CountImagesByUploadLinkID(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CountImagesByUploadLinkID", reflect.TypeOf((*MockImageRepository)(nil).CountImagesByUploadLinkID), arg0, arg1, arg2)
+}
+
+// DeleteImage mocks base method.
+func (m *MockImageRepository) DeleteImage(arg0 context.Context, arg1, arg2 string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteImage", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteImage indicates an expected call of DeleteImage.
+func (mr *MockImageRepositoryMockRecorder) DeleteImage(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteImage", reflect.TypeOf((*MockImageRepository)(nil).DeleteImage), arg0, arg1, arg2)
+}
+
+// EnsureIndexes mocks base method.
+func (m *MockImageRepository) EnsureIndexes() error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "EnsureIndexes")
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// EnsureIndexes indicates an expected call of EnsureIndexes.
+func (mr *MockImageRepositoryMockRecorder) EnsureIndexes() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "EnsureIndexes", reflect.TypeOf((*MockImageRepository)(nil).EnsureIndexes))
+}
+
+// GetGeotaggedImagesByUploadLinkID mocks base method.
+func (m *MockImageRepository) GetGeotaggedImagesByUploadLinkID(arg0 context.Context, arg1, arg2 string) ([]models.Image, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetGeotaggedImagesByUploadLinkID", arg0, arg1, arg2)
+	ret0, _ := ret[0].([]models.Image)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetGeotaggedImagesByUploadLinkID indicates an expected call of GetGeotaggedImagesByUploadLinkID.
+func (mr *MockImageRepositoryMockRecorder) GetGeotaggedImagesByUploadLinkID(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetGeotaggedImagesByUploadLinkID", reflect.TypeOf((*MockImageRepository)(nil).GetGeotaggedImagesByUploadLinkID), arg0, arg1, arg2)
+}
+
 // GetImageByID mocks base method.
-func (m *MockImageRepository) GetImageByID(arg0 string) (*models.Image, error) {
+func (m *MockImageRepository) GetImageByID(arg0 context.Context, arg1, arg2 string) (*models.Image, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetImageByID", arg0)
+	ret := m.ctrl.Call(m, "GetImageByID", arg0, arg1, arg2)
 	ret0, _ := ret[0].(*models.Image)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // GetImageByID indicates an expected call of GetImageByID.
-func (mr *MockImageRepositoryMockRecorder) GetImageByID(arg0 interface{}) *gomock.Call {
+func (mr *MockImageRepositoryMockRecorder) GetImageByID(arg0, arg1, arg2 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetImageByID", reflect.TypeOf((*MockImageRepository)(nil).GetImageByID), arg0)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetImageByID", reflect.TypeOf((*MockImageRepository)(nil).GetImageByID), arg0, arg1, arg2)
 }
 
 // GetImageByName mocks base method.
-func (m *MockImageRepository) GetImageByName(arg0 string) (*models.Image, error) {
+func (m *MockImageRepository) GetImageByName(arg0 context.Context, arg1, arg2 string) (*models.Image, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetImageByName", arg0)
+	ret := m.ctrl.Call(m, "GetImageByName", arg0, arg1, arg2)
 	ret0, _ := ret[0].(*models.Image)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // GetImageByName indicates an expected call of GetImageByName.
-func (mr *MockImageRepositoryMockRecorder) GetImageByName(arg0 interface{}) *gomock.Call {
+func (mr *MockImageRepositoryMockRecorder) GetImageByName(arg0, arg1, arg2 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetImageByName", reflect.TypeOf((*MockImageRepository)(nil).GetImageByName), arg0)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetImageByName", reflect.TypeOf((*MockImageRepository)(nil).GetImageByName), arg0, arg1, arg2)
 }
 
 // GetImageByNameAndUploadLinkID mocks base method.
-func (m *MockImageRepository) GetImageByNameAndUploadLinkID(arg0, arg1 string) (*models.Image, error) {
+func (m *MockImageRepository) GetImageByNameAndUploadLinkID(arg0 context.Context, arg1, arg2, arg3 string) (*models.Image, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetImageByNameAndUploadLinkID", arg0, arg1)
+	ret := m.ctrl.Call(m, "GetImageByNameAndUploadLinkID", arg0, arg1, arg2, arg3)
 	ret0, _ := ret[0].(*models.Image)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // GetImageByNameAndUploadLinkID indicates an expected call of GetImageByNameAndUploadLinkID.
-func (mr *MockImageRepositoryMockRecorder) GetImageByNameAndUploadLinkID(arg0, arg1 interface{}) *gomock.Call {
+func (mr *MockImageRepositoryMockRecorder) GetImageByNameAndUploadLinkID(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetImageByNameAndUploadLinkID", reflect.TypeOf((*MockImageRepository)(nil).GetImageByNameAndUploadLinkID), arg0, arg1)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetImageByNameAndUploadLinkID", reflect.TypeOf((*MockImageRepository)(nil).GetImageByNameAndUploadLinkID), arg0, arg1, arg2, arg3)
+}
+
+// GetImagePathsByUploadLinkID mocks base method.
+func (m *MockImageRepository) GetImagePathsByUploadLinkID(arg0 context.Context, arg1 string) ([]string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetImagePathsByUploadLinkID", arg0, arg1)
+	ret0, _ := ret[0].([]string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetImagePathsByUploadLinkID indicates an expected call of GetImagePathsByUploadLinkID.
+func (mr *MockImageRepositoryMockRecorder) GetImagePathsByUploadLinkID(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetImagePathsByUploadLinkID", reflect.TypeOf((*MockImageRepository)(nil).GetImagePathsByUploadLinkID), arg0, arg1)
+}
+
+// GetImagesAfter mocks base method.
+func (m *MockImageRepository) GetImagesAfter(arg0 context.Context, arg1 models.ImageFilter, arg2 string, arg3 int) ([]models.Image, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetImagesAfter", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].([]models.Image)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetImagesAfter indicates an expected call of GetImagesAfter.
+func (mr *MockImageRepositoryMockRecorder) GetImagesAfter(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetImagesAfter", reflect.TypeOf((*MockImageRepository)(nil).GetImagesAfter), arg0, arg1, arg2, arg3)
 }
 
 // GetImagesByIDs mocks base method.
-func (m *MockImageRepository) GetImagesByIDs(arg0 []string) ([]models.Image, error) {
+func (m *MockImageRepository) GetImagesByIDs(arg0 context.Context, arg1 string, arg2 []string) ([]models.Image, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetImagesByIDs", arg0)
+	ret := m.ctrl.Call(m, "GetImagesByIDs", arg0, arg1, arg2)
 	ret0, _ := ret[0].([]models.Image)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // GetImagesByIDs indicates an expected call of GetImagesByIDs.
-func (mr *MockImageRepositoryMockRecorder) GetImagesByIDs(arg0 interface{}) *gomock.Call {
+func (mr *MockImageRepositoryMockRecorder) GetImagesByIDs(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetImagesByIDs", reflect.TypeOf((*MockImageRepository)(nil).GetImagesByIDs), arg0, arg1, arg2)
+}
+
+// GetImagesDeletedBefore mocks base method.
+func (m *MockImageRepository) GetImagesDeletedBefore(arg0 context.Context, arg1 time.Time, arg2 int) ([]models.Image, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetImagesDeletedBefore", arg0, arg1, arg2)
+	ret0, _ := ret[0].([]models.Image)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetImagesDeletedBefore indicates an expected call of GetImagesDeletedBefore.
+func (mr *MockImageRepositoryMockRecorder) GetImagesDeletedBefore(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetImagesDeletedBefore", reflect.TypeOf((*MockImageRepository)(nil).GetImagesDeletedBefore), arg0, arg1, arg2)
+}
+
+// GetImagesInBoundingBox mocks base method.
+func (m *MockImageRepository) GetImagesInBoundingBox(arg0 context.Context, arg1 string, arg2 *models.BoundingBox, arg3 int) ([]models.Image, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetImagesInBoundingBox", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].([]models.Image)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetImagesInBoundingBox indicates an expected call of GetImagesInBoundingBox.
+func (mr *MockImageRepositoryMockRecorder) GetImagesInBoundingBox(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetImagesByIDs", reflect.TypeOf((*MockImageRepository)(nil).GetImagesByIDs), arg0)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetImagesInBoundingBox", reflect.TypeOf((*MockImageRepository)(nil).GetImagesInBoundingBox), arg0, arg1, arg2, arg3)
+}
+
+// GetImagesNear mocks base method.
+func (m *MockImageRepository) GetImagesNear(arg0 context.Context, arg1 string, arg2 *models.GeoPoint, arg3 float64, arg4 int) ([]models.Image, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetImagesNear", arg0, arg1, arg2, arg3, arg4)
+	ret0, _ := ret[0].([]models.Image)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetImagesNear indicates an expected call of GetImagesNear.
+func (mr *MockImageRepositoryMockRecorder) GetImagesNear(arg0, arg1, arg2, arg3, arg4 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetImagesNear", reflect.TypeOf((*MockImageRepository)(nil).GetImagesNear), arg0, arg1, arg2, arg3, arg4)
+}
+
+// GetImagesWithin mocks base method.
+func (m *MockImageRepository) GetImagesWithin(arg0 context.Context, arg1 string, arg2 *models.GeoPolygon, arg3 int) ([]models.Image, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetImagesWithin", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].([]models.Image)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetImagesWithin indicates an expected call of GetImagesWithin.
+func (mr *MockImageRepositoryMockRecorder) GetImagesWithin(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetImagesWithin", reflect.TypeOf((*MockImageRepository)(nil).GetImagesWithin), arg0, arg1, arg2, arg3)
 }
 
 // InsertImages mocks base method.
-func (m *MockImageRepository) InsertImages(arg0 []interface{}) ([]string, error) {
+func (m *MockImageRepository) InsertImages(arg0 context.Context, arg1 []interface{}) ([]string, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "InsertImages", arg0)
+	ret := m.ctrl.Call(m, "InsertImages", arg0, arg1)
 	ret0, _ := ret[0].([]string)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // InsertImages indicates an expected call of InsertImages.
-func (mr *MockImageRepositoryMockRecorder) InsertImages(arg0 interface{}) *gomock.Call {
+func (mr *MockImageRepositoryMockRecorder) InsertImages(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "InsertImages", reflect.TypeOf((*MockImageRepository)(nil).InsertImages), arg0, arg1)
+}
+
+// ListImages mocks base method.
+func (m *MockImageRepository) ListImages(arg0 context.Context, arg1 models.ImageFilter) (*models.ImagePage, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListImages", arg0, arg1)
+	ret0, _ := ret[0].(*models.ImagePage)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListImages indicates an expected call of ListImages.
+func (mr *MockImageRepositoryMockRecorder) ListImages(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListImages", reflect.TypeOf((*MockImageRepository)(nil).ListImages), arg0, arg1)
+}
+
+// MarkImagesCounted mocks base method.
+func (m *MockImageRepository) MarkImagesCounted(arg0 context.Context, arg1 string, arg2 []string, arg3 bool) ([]string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "MarkImagesCounted", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].([]string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// MarkImagesCounted indicates an expected call of MarkImagesCounted.
+func (mr *MockImageRepositoryMockRecorder) MarkImagesCounted(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MarkImagesCounted", reflect.TypeOf((*MockImageRepository)(nil).MarkImagesCounted), arg0, arg1, arg2, arg3)
+}
+
+// ResetImagesCounted mocks base method.
+func (m *MockImageRepository) ResetImagesCounted(arg0 context.Context, arg1 string) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ResetImagesCounted", arg0, arg1)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ResetImagesCounted indicates an expected call of ResetImagesCounted.
+func (mr *MockImageRepositoryMockRecorder) ResetImagesCounted(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ResetImagesCounted", reflect.TypeOf((*MockImageRepository)(nil).ResetImagesCounted), arg0, arg1)
+}
+
+// RestoreImagesCounted mocks base method.
+func (m *MockImageRepository) RestoreImagesCounted(arg0 context.Context, arg1 string, arg2 []string, arg3 bool) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RestoreImagesCounted", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RestoreImagesCounted indicates an expected call of RestoreImagesCounted.
+func (mr *MockImageRepositoryMockRecorder) RestoreImagesCounted(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RestoreImagesCounted", reflect.TypeOf((*MockImageRepository)(nil).RestoreImagesCounted), arg0, arg1, arg2, arg3)
+}
+
+// SoftDeleteImage mocks base method.
+func (m *MockImageRepository) SoftDeleteImage(arg0 context.Context, arg1, arg2 string, arg3 time.Time) (*models.Image, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SoftDeleteImage", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].(*models.Image)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SoftDeleteImage indicates an expected call of SoftDeleteImage.
+func (mr *MockImageRepositoryMockRecorder) SoftDeleteImage(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SoftDeleteImage", reflect.TypeOf((*MockImageRepository)(nil).SoftDeleteImage), arg0, arg1, arg2, arg3)
+}
+
+// SoftDeleteImagesByUploadLinkID mocks base method.
+func (m *MockImageRepository) SoftDeleteImagesByUploadLinkID(arg0 context.Context, arg1, arg2 string, arg3 time.Time) ([]string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SoftDeleteImagesByUploadLinkID", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].([]string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SoftDeleteImagesByUploadLinkID indicates an expected call of SoftDeleteImagesByUploadLinkID.
+func (mr *MockImageRepositoryMockRecorder) SoftDeleteImagesByUploadLinkID(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "InsertImages", reflect.TypeOf((*MockImageRepository)(nil).InsertImages), arg0)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SoftDeleteImagesByUploadLinkID", reflect.TypeOf((*MockImageRepository)(nil).SoftDeleteImagesByUploadLinkID), arg0, arg1, arg2, arg3)
 }
 
 // UpdateImage mocks base method.
-func (m *MockImageRepository) UpdateImage(arg0 *models.Image) error {
+func (m *MockImageRepository) UpdateImage(arg0 context.Context, arg1 string, arg2 *models.Image) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "UpdateImage", arg0)
+	ret := m.ctrl.Call(m, "UpdateImage", arg0, arg1, arg2)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // UpdateImage indicates an expected call of UpdateImage.
-func (mr *MockImageRepositoryMockRecorder) UpdateImage(arg0 interface{}) *gomock.Call {
+func (mr *MockImageRepositoryMockRecorder) UpdateImage(arg0, arg1, arg2 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateImage", reflect.TypeOf((*MockImageRepository)(nil).UpdateImage), arg0)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateImage", reflect.TypeOf((*MockImageRepository)(nil).UpdateImage), arg0, arg1, arg2)
 }
@@ -5,6 +5,7 @@
 package mocks
 
 import (
+	context "context"
 	reflect "reflect"
 
 	gomock "github.com/golang/mock/gomock"
@@ -34,75 +35,76 @@ func (m *MockStatisticsRepository) EXPECT() *MockStatisticsRepositoryMockRecorde
 	return m.recorder
 }
 
-// GetStatistics mocks base method.
-func (m *MockStatisticsRepository) GetStatistics(statisticsType models.StatisticsType, name string) (*models.Statistics, error) {
+// DeleteStatistics mocks base method.
+func (m *MockStatisticsRepository) DeleteStatistics(ctx context.Context, tenant string) (int, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetStatistics", statisticsType, name)
-	ret0, _ := ret[0].(*models.Statistics)
+	ret := m.ctrl.Call(m, "DeleteStatistics", ctx, tenant)
+	ret0, _ := ret[0].(int)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// GetStatistics indicates an expected call of GetStatistics.
-func (mr *MockStatisticsRepositoryMockRecorder) GetStatistics(statisticsType, name interface{}) *gomock.Call {
+// DeleteStatistics indicates an expected call of DeleteStatistics.
+func (mr *MockStatisticsRepositoryMockRecorder) DeleteStatistics(ctx, tenant interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetStatistics", reflect.TypeOf((*MockStatisticsRepository)(nil).GetStatistics), statisticsType, name)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteStatistics", reflect.TypeOf((*MockStatisticsRepository)(nil).DeleteStatistics), ctx, tenant)
+}
+
+// EnsureIndexes mocks base method.
+func (m *MockStatisticsRepository) EnsureIndexes() error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "EnsureIndexes")
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// EnsureIndexes indicates an expected call of EnsureIndexes.
+func (mr *MockStatisticsRepositoryMockRecorder) EnsureIndexes() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "EnsureIndexes", reflect.TypeOf((*MockStatisticsRepository)(nil).EnsureIndexes))
 }
 
 // GetStatisticsFrequency mocks base method.
-func (m *MockStatisticsRepository) GetStatisticsFrequency(statisticsType models.StatisticsType, limit int) ([]models.Statistics, error) {
+func (m *MockStatisticsRepository) GetStatisticsFrequency(ctx context.Context, tenant string, statisticsType models.StatisticsType, limit int) ([]models.Statistics, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetStatisticsFrequency", statisticsType, limit)
+	ret := m.ctrl.Call(m, "GetStatisticsFrequency", ctx, tenant, statisticsType, limit)
 	ret0, _ := ret[0].([]models.Statistics)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // GetStatisticsFrequency indicates an expected call of GetStatisticsFrequency.
-func (mr *MockStatisticsRepositoryMockRecorder) GetStatisticsFrequency(statisticsType, limit interface{}) *gomock.Call {
+func (mr *MockStatisticsRepositoryMockRecorder) GetStatisticsFrequency(ctx, tenant, statisticsType, limit interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetStatisticsFrequency", reflect.TypeOf((*MockStatisticsRepository)(nil).GetStatisticsFrequency), statisticsType, limit)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetStatisticsFrequency", reflect.TypeOf((*MockStatisticsRepository)(nil).GetStatisticsFrequency), ctx, tenant, statisticsType, limit)
 }
 
 // GetStatisticsSortedByCount mocks base method.
-func (m *MockStatisticsRepository) GetStatisticsSortedByCount(statisticsType models.StatisticsType, limit int) ([]models.Statistics, error) {
+func (m *MockStatisticsRepository) GetStatisticsSortedByCount(ctx context.Context, tenant string, statisticsType models.StatisticsType, limit int) ([]models.Statistics, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetStatisticsSortedByCount", statisticsType, limit)
+	ret := m.ctrl.Call(m, "GetStatisticsSortedByCount", ctx, tenant, statisticsType, limit)
 	ret0, _ := ret[0].([]models.Statistics)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // GetStatisticsSortedByCount indicates an expected call of GetStatisticsSortedByCount.
-func (mr *MockStatisticsRepositoryMockRecorder) GetStatisticsSortedByCount(statisticsType, limit interface{}) *gomock.Call {
-	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetStatisticsSortedByCount", reflect.TypeOf((*MockStatisticsRepository)(nil).GetStatisticsSortedByCount), statisticsType, limit)
-}
-
-// InsertStatistics mocks base method.
-func (m *MockStatisticsRepository) InsertStatistics(statistics *models.Statistics) error {
-	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "InsertStatistics", statistics)
-	ret0, _ := ret[0].(error)
-	return ret0
-}
-
-// InsertStatistics indicates an expected call of InsertStatistics.
-func (mr *MockStatisticsRepositoryMockRecorder) InsertStatistics(statistics interface{}) *gomock.Call {
+func (mr *MockStatisticsRepositoryMockRecorder) GetStatisticsSortedByCount(ctx, tenant, statisticsType, limit interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "InsertStatistics", reflect.TypeOf((*MockStatisticsRepository)(nil).InsertStatistics), statistics)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetStatisticsSortedByCount", reflect.TypeOf((*MockStatisticsRepository)(nil).GetStatisticsSortedByCount), ctx, tenant, statisticsType, limit)
 }
 
-// UpdateStatistics mocks base method.
-func (m *MockStatisticsRepository) UpdateStatistics(statistics *models.Statistics) error {
+// IncrementStatistics mocks base method.
+func (m *MockStatisticsRepository) IncrementStatistics(ctx context.Context, tenant string, statisticsType models.StatisticsType, name string, delta int) (int, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "UpdateStatistics", statistics)
-	ret0, _ := ret[0].(error)
-	return ret0
+	ret := m.ctrl.Call(m, "IncrementStatistics", ctx, tenant, statisticsType, name, delta)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
 }
 
-// UpdateStatistics indicates an expected call of UpdateStatistics.
-func (mr *MockStatisticsRepositoryMockRecorder) UpdateStatistics(statistics interface{}) *gomock.Call {
+// IncrementStatistics indicates an expected call of IncrementStatistics.
+func (mr *MockStatisticsRepositoryMockRecorder) IncrementStatistics(ctx, tenant, statisticsType, name, delta interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateStatistics", reflect.TypeOf((*MockStatisticsRepository)(nil).UpdateStatistics), statistics)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IncrementStatistics", reflect.TypeOf((*MockStatisticsRepository)(nil).IncrementStatistics), ctx, tenant, statisticsType, name, delta)
 }
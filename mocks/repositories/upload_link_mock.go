@@ -5,7 +5,9 @@
 package mocks
 
 import (
+	context "context"
 	reflect "reflect"
+	time "time"
 
 	gomock "github.com/golang/mock/gomock"
 	models "github.com/tam-code/image-upload/src/models"
@@ -35,31 +37,89 @@ func (m *MockUploadLinkRepository) EXPECT() *MockUploadLinkRepositoryMockRecorde
 }
 
 // CreateUploadLink mocks base method.
-func (m *MockUploadLinkRepository) CreateUploadLink(arg0 models.UploadLink) (*models.UploadLink, error) {
+func (m *MockUploadLinkRepository) CreateUploadLink(arg0 context.Context, arg1 models.UploadLink) (*models.UploadLink, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "CreateUploadLink", arg0)
+	ret := m.ctrl.Call(m, "CreateUploadLink", arg0, arg1)
 	ret0, _ := ret[0].(*models.UploadLink)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // CreateUploadLink indicates an expected call of CreateUploadLink.
-func (mr *MockUploadLinkRepositoryMockRecorder) CreateUploadLink(arg0 interface{}) *gomock.Call {
+func (mr *MockUploadLinkRepositoryMockRecorder) CreateUploadLink(arg0, arg1 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateUploadLink", reflect.TypeOf((*MockUploadLinkRepository)(nil).CreateUploadLink), arg0)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateUploadLink", reflect.TypeOf((*MockUploadLinkRepository)(nil).CreateUploadLink), arg0, arg1)
+}
+
+// DeleteUploadLink mocks base method.
+func (m *MockUploadLinkRepository) DeleteUploadLink(arg0 context.Context, arg1, arg2 string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteUploadLink", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteUploadLink indicates an expected call of DeleteUploadLink.
+func (mr *MockUploadLinkRepositoryMockRecorder) DeleteUploadLink(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteUploadLink", reflect.TypeOf((*MockUploadLinkRepository)(nil).DeleteUploadLink), arg0, arg1, arg2)
+}
+
+// EnsureIndexes mocks base method.
+func (m *MockUploadLinkRepository) EnsureIndexes() error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "EnsureIndexes")
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// EnsureIndexes indicates an expected call of EnsureIndexes.
+func (mr *MockUploadLinkRepositoryMockRecorder) EnsureIndexes() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "EnsureIndexes", reflect.TypeOf((*MockUploadLinkRepository)(nil).EnsureIndexes))
+}
+
+// GetTenantUploadLinkByID mocks base method.
+func (m *MockUploadLinkRepository) GetTenantUploadLinkByID(arg0 context.Context, arg1, arg2 string) (*models.UploadLink, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetTenantUploadLinkByID", arg0, arg1, arg2)
+	ret0, _ := ret[0].(*models.UploadLink)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetTenantUploadLinkByID indicates an expected call of GetTenantUploadLinkByID.
+func (mr *MockUploadLinkRepositoryMockRecorder) GetTenantUploadLinkByID(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetTenantUploadLinkByID", reflect.TypeOf((*MockUploadLinkRepository)(nil).GetTenantUploadLinkByID), arg0, arg1, arg2)
 }
 
 // GetUploadLinkByID mocks base method.
-func (m *MockUploadLinkRepository) GetUploadLinkByID(arg0 string) (*models.UploadLink, error) {
+func (m *MockUploadLinkRepository) GetUploadLinkByID(arg0 context.Context, arg1 string) (*models.UploadLink, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetUploadLinkByID", arg0)
+	ret := m.ctrl.Call(m, "GetUploadLinkByID", arg0, arg1)
 	ret0, _ := ret[0].(*models.UploadLink)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // GetUploadLinkByID indicates an expected call of GetUploadLinkByID.
-func (mr *MockUploadLinkRepositoryMockRecorder) GetUploadLinkByID(arg0 interface{}) *gomock.Call {
+func (mr *MockUploadLinkRepositoryMockRecorder) GetUploadLinkByID(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUploadLinkByID", reflect.TypeOf((*MockUploadLinkRepository)(nil).GetUploadLinkByID), arg0, arg1)
+}
+
+// GetUploadLinksExpiredBefore mocks base method.
+func (m *MockUploadLinkRepository) GetUploadLinksExpiredBefore(arg0 context.Context, arg1 time.Time) ([]models.UploadLink, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetUploadLinksExpiredBefore", arg0, arg1)
+	ret0, _ := ret[0].([]models.UploadLink)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetUploadLinksExpiredBefore indicates an expected call of GetUploadLinksExpiredBefore.
+func (mr *MockUploadLinkRepositoryMockRecorder) GetUploadLinksExpiredBefore(arg0, arg1 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUploadLinkByID", reflect.TypeOf((*MockUploadLinkRepository)(nil).GetUploadLinkByID), arg0)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUploadLinksExpiredBefore", reflect.TypeOf((*MockUploadLinkRepository)(nil).GetUploadLinksExpiredBefore), arg0, arg1)
 }
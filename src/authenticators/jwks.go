@@ -0,0 +1,214 @@
+package authenticators
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/big"
+	"net/http"
+	"sync"
+	"time"
+)
+
+var ErrUnknownKey = errors.New("unknown signing key")
+
+type jsonWebKey struct {
+	Kid string `json:"kid"`
+	Kty string `json:"kty"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+type jsonWebKeySet struct {
+	Keys []jsonWebKey `json:"keys"`
+}
+
+// keySet caches the keys of a JWKS endpoint. The keys are fetched again once
+// the cache expired, or early when a token is signed with an unknown key so
+// rotated keys are picked up, no more than once per minRefresh. The keys are
+// fetched without holding the lock, the tokens signed with a cached key don't
+// wait for a slow endpoint.
+type keySet struct {
+	url        string
+	client     *http.Client
+	ttl        time.Duration
+	minRefresh time.Duration
+
+	mu        sync.Mutex
+	keys      map[string]crypto.PublicKey
+	fetchedAt time.Time
+	// fetching is closed once the keys being fetched are stored, nil when no
+	// fetch is running
+	fetching chan struct{}
+	// err is the error of the last fetch
+	err error
+	now func() time.Time
+}
+
+func newKeySet(url string, client *http.Client, ttl time.Duration, minRefresh time.Duration) *keySet {
+	return &keySet{
+		url:        url,
+		client:     client,
+		ttl:        ttl,
+		minRefresh: minRefresh,
+		now:        time.Now,
+	}
+}
+
+func (s *keySet) Key(kid string) (crypto.PublicKey, error) {
+	s.mu.Lock()
+
+	now := s.now()
+	_, found := s.keys[kid]
+
+	expired := s.keys == nil || now.Sub(s.fetchedAt) >= s.ttl
+	if expired || (!found && now.Sub(s.fetchedAt) >= s.minRefresh) {
+		switch {
+		case s.fetching == nil:
+			s.fetching = make(chan struct{})
+			s.mu.Unlock()
+			s.refresh(now)
+			s.mu.Lock()
+		case !found:
+			// the key may be among the keys being fetched
+			fetching := s.fetching
+			s.mu.Unlock()
+			<-fetching
+			s.mu.Lock()
+		}
+		// the expired keys keep being served while they are fetched again
+	}
+	defer s.mu.Unlock()
+
+	if s.keys == nil {
+		return nil, s.err
+	}
+
+	key, found := s.keys[kid]
+	if !found {
+		return nil, ErrUnknownKey
+	}
+
+	return key, nil
+}
+
+// refresh fetches the keys and stores them, the cached keys are kept while
+// the endpoint is unavailable.
+func (s *keySet) refresh(now time.Time) {
+	keys, err := s.fetch()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.err = err
+	if err == nil {
+		s.keys = keys
+	} else if s.keys != nil {
+		slog.Warn("error refreshing jwks, using cached keys", "url", s.url, "error", err)
+	}
+	s.fetchedAt = now
+
+	close(s.fetching)
+	s.fetching = nil
+}
+
+func (s *keySet) fetch() (map[string]crypto.PublicKey, error) {
+	resp, err := s.client.Get(s.url)
+	if err != nil {
+		return nil, fmt.Errorf("fetching jwks: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetching jwks: unexpected status %d", resp.StatusCode)
+	}
+
+	var set jsonWebKeySet
+	if err := json.NewDecoder(resp.Body).Decode(&set); err != nil {
+		return nil, fmt.Errorf("decoding jwks: %w", err)
+	}
+
+	keys := make(map[string]crypto.PublicKey, len(set.Keys))
+	for _, jwk := range set.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+
+		key, err := jwk.publicKey()
+		if err != nil {
+			slog.Warn("skipping jwks key", "kid", jwk.Kid, "error", err)
+			continue
+		}
+
+		keys[jwk.Kid] = key
+	}
+
+	return keys, nil
+}
+
+func (k jsonWebKey) publicKey() (crypto.PublicKey, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeBigInt(k.N)
+		if err != nil {
+			return nil, err
+		}
+
+		e, err := decodeBigInt(k.E)
+		if err != nil {
+			return nil, err
+		}
+
+		if !e.IsInt64() || e.Int64() > 1<<31-1 {
+			return nil, errors.New("invalid rsa exponent")
+		}
+
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		if k.Crv != "P-256" {
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+
+		x, err := decodeBigInt(k.X)
+		if err != nil {
+			return nil, err
+		}
+
+		y, err := decodeBigInt(k.Y)
+		if err != nil {
+			return nil, err
+		}
+
+		if !elliptic.P256().IsOnCurve(x, y) {
+			return nil, errors.New("point not on curve")
+		}
+
+		return &ecdsa.PublicKey{Curve: elliptic.P256(), X: x, Y: y}, nil
+	default:
+		return nil, fmt.Errorf("unsupported key type %q", k.Kty)
+	}
+}
+
+func decodeBigInt(value string) (*big.Int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(b) == 0 {
+		return nil, errors.New("empty key parameter")
+	}
+
+	return new(big.Int).SetBytes(b), nil
+}
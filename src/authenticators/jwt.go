@@ -0,0 +1,273 @@
+package authenticators
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/tam-code/image-upload/config"
+	"github.com/tam-code/image-upload/src/models"
+)
+
+var (
+	ErrMalformedToken   = errors.New("malformed token")
+	ErrUnsupportedAlg   = errors.New("unsupported signing algorithm")
+	ErrInvalidSignature = errors.New("invalid token signature")
+	ErrInvalidIssuer    = errors.New("invalid token issuer")
+	ErrInvalidAudience  = errors.New("invalid token audience")
+	ErrTokenExpired     = errors.New("token expired")
+	ErrTokenNotYetValid = errors.New("token not yet valid")
+	ErrMissingSubject   = errors.New("token has no subject")
+	ErrInvalidTenant    = errors.New("token has no valid tenant")
+)
+
+type JWTAuthenticator interface {
+	// Authenticate verifies a bearer token and returns its principal.
+	Authenticate(token string) (*models.Principal, error)
+}
+
+type jwtAuthenticator struct {
+	keys        *keySet
+	issuer      string
+	audience    string
+	scopesClaim string
+	tenantClaim string
+	rolesClaim  string
+	roleScopes  map[string][]models.Scope
+	clockSkew   time.Duration
+	now         func() time.Time
+}
+
+type jwtHeader struct {
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+}
+
+type jwtClaims map[string]interface{}
+
+func NewJWTAuthenticator(cfg config.JWTConfig) JWTAuthenticator {
+	roleScopes := make(map[string][]models.Scope, len(cfg.RoleScopes))
+	for _, roleScope := range cfg.RoleScopes {
+		for _, scope := range roleScope.Scopes {
+			roleScopes[roleScope.Role] = append(roleScopes[roleScope.Role], models.Scope(scope))
+		}
+	}
+
+	client := &http.Client{Timeout: time.Duration(cfg.JWKSTimeoutMilliseconds) * time.Millisecond}
+
+	return &jwtAuthenticator{
+		keys: newKeySet(cfg.JWKSURL, client,
+			time.Duration(cfg.JWKSCacheSeconds)*time.Second,
+			time.Duration(cfg.JWKSMinRefreshSeconds)*time.Second,
+		),
+		issuer:      cfg.Issuer,
+		audience:    cfg.Audience,
+		scopesClaim: cfg.ScopesClaim,
+		tenantClaim: cfg.TenantClaim,
+		rolesClaim:  cfg.RolesClaim,
+		roleScopes:  roleScopes,
+		clockSkew:   time.Duration(cfg.ClockSkewSeconds) * time.Second,
+		now:         time.Now,
+	}
+}
+
+func (a *jwtAuthenticator) Authenticate(token string) (*models.Principal, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, ErrMalformedToken
+	}
+
+	var header jwtHeader
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, ErrMalformedToken
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, ErrMalformedToken
+	}
+
+	// the algorithm is checked against the key type, a token can't downgrade
+	// to another algorithm or to none
+	if header.Alg != "RS256" && header.Alg != "ES256" {
+		return nil, ErrUnsupportedAlg
+	}
+
+	key, err := a.keys.Key(header.Kid)
+	if err != nil {
+		return nil, err
+	}
+
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	if err := verifySignature(header.Alg, key, digest[:], signature); err != nil {
+		return nil, err
+	}
+
+	var claims jwtClaims
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, ErrMalformedToken
+	}
+
+	if err := a.validateClaims(claims); err != nil {
+		return nil, err
+	}
+
+	subject, _ := claims["sub"].(string)
+	if subject == "" {
+		return nil, ErrMissingSubject
+	}
+
+	tenant := models.DefaultTenant
+	if a.tenantClaim != "" {
+		tenant, _ = claims[a.tenantClaim].(string)
+		if !models.IsValidTenant(tenant) {
+			return nil, ErrInvalidTenant
+		}
+	}
+
+	return &models.Principal{Subject: subject, Tenant: tenant, Scopes: a.scopes(claims)}, nil
+}
+
+func (a *jwtAuthenticator) validateClaims(claims jwtClaims) error {
+	// the configuration requires both, an empty one never matches
+	if issuer, _ := claims["iss"].(string); issuer == "" || issuer != a.issuer {
+		return ErrInvalidIssuer
+	}
+
+	if a.audience == "" || !contains(claims.stringList("aud"), a.audience) {
+		return ErrInvalidAudience
+	}
+
+	now := a.now()
+
+	expiration, ok := claims.numericDate("exp")
+	if !ok || !now.Before(expiration.Add(a.clockSkew)) {
+		return ErrTokenExpired
+	}
+
+	if notBefore, ok := claims.numericDate("nbf"); ok && now.Add(a.clockSkew).Before(notBefore) {
+		return ErrTokenNotYetValid
+	}
+
+	return nil
+}
+
+// scopes returns the valid scopes granted by the scopes claim and the roles.
+func (a *jwtAuthenticator) scopes(claims jwtClaims) []models.Scope {
+	granted := []models.Scope{}
+	seen := map[models.Scope]bool{}
+
+	add := func(scope models.Scope) {
+		if scope.IsValid() && !seen[scope] {
+			seen[scope] = true
+			granted = append(granted, scope)
+		}
+	}
+
+	if a.scopesClaim != "" {
+		for _, scope := range claims.stringList(a.scopesClaim) {
+			for _, s := range strings.Fields(scope) {
+				add(models.Scope(s))
+			}
+		}
+	}
+
+	if a.rolesClaim != "" {
+		for _, role := range claims.stringList(a.rolesClaim) {
+			for _, scope := range a.roleScopes[role] {
+				add(scope)
+			}
+		}
+	}
+
+	return granted
+}
+
+func verifySignature(alg string, key crypto.PublicKey, digest []byte, signature []byte) error {
+	switch alg {
+	case "RS256":
+		publicKey, ok := key.(*rsa.PublicKey)
+		if !ok {
+			return ErrUnsupportedAlg
+		}
+
+		if rsa.VerifyPKCS1v15(publicKey, crypto.SHA256, digest, signature) != nil {
+			return ErrInvalidSignature
+		}
+	case "ES256":
+		publicKey, ok := key.(*ecdsa.PublicKey)
+		if !ok {
+			return ErrUnsupportedAlg
+		}
+
+		// JWS encodes ECDSA signatures as the fixed size r and s concatenated
+		if len(signature) != 64 {
+			return ErrInvalidSignature
+		}
+
+		r := new(big.Int).SetBytes(signature[:32])
+		s := new(big.Int).SetBytes(signature[32:])
+		if !ecdsa.Verify(publicKey, digest, r, s) {
+			return ErrInvalidSignature
+		}
+	default:
+		return ErrUnsupportedAlg
+	}
+
+	return nil
+}
+
+func decodeSegment(segment string, v interface{}) error {
+	b, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(b, v)
+}
+
+// stringList returns a claim holding either a string or an array of strings.
+func (c jwtClaims) stringList(name string) []string {
+	switch value := c[name].(type) {
+	case string:
+		return []string{value}
+	case []interface{}:
+		values := make([]string, 0, len(value))
+		for _, v := range value {
+			if s, ok := v.(string); ok {
+				values = append(values, s)
+			}
+		}
+		return values
+	default:
+		return nil
+	}
+}
+
+// numericDate returns a NumericDate claim.
+func (c jwtClaims) numericDate(name string) (time.Time, bool) {
+	value, ok := c[name].(float64)
+	if !ok {
+		return time.Time{}, false
+	}
+
+	return time.Unix(int64(value), 0), true
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,355 @@
+package authenticators
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tam-code/image-upload/config"
+	"github.com/tam-code/image-upload/src/models"
+)
+
+const (
+	testIssuer   = "https://idp.example.com/"
+	testAudience = "image-upload"
+)
+
+func TestAuthenticate(t *testing.T) {
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	otherKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+
+	server := newJWKSServer(t, map[string]crypto.PublicKey{"rsa": &rsaKey.PublicKey, "ec": &ecKey.PublicKey})
+	defer server.Close()
+
+	authenticator := NewJWTAuthenticator(config.JWTConfig{
+		JWKSURL:          server.URL,
+		Issuer:           testIssuer,
+		Audience:         testAudience,
+		ScopesClaim:      "scope",
+		TenantClaim:      "tenant",
+		RolesClaim:       "roles",
+		RoleScopes:       []config.JWTRoleScopeConfig{{Role: "admin", Scopes: []string{"keys:manage", "janitor:run"}}},
+		JWKSCacheSeconds: 3600,
+	})
+
+	validClaims := func() map[string]interface{} {
+		return map[string]interface{}{
+			"iss":    testIssuer,
+			"aud":    testAudience,
+			"sub":    "user-1",
+			"tenant": "marketing",
+			"exp":    time.Now().Add(time.Hour).Unix(),
+			"scope":  "images:read stats:read unknown:scope",
+		}
+	}
+
+	tests := []struct {
+		name            string
+		token           func() string
+		expectedError   error
+		expectedSubject string
+		expectedTenant  string
+		expectedScopes  []models.Scope
+	}{
+		{
+			name:            "valid RS256 token",
+			token:           func() string { return signRS256(rsaKey, "rsa", validClaims()) },
+			expectedSubject: "user-1",
+			expectedTenant:  "marketing",
+			expectedScopes:  []models.Scope{models.ScopeImagesRead, models.ScopeStatsRead},
+		},
+		{
+			name:            "valid ES256 token",
+			token:           func() string { return signES256(ecKey, "ec", validClaims()) },
+			expectedSubject: "user-1",
+			expectedTenant:  "marketing",
+			expectedScopes:  []models.Scope{models.ScopeImagesRead, models.ScopeStatsRead},
+		},
+		{
+			name: "scopes from an array and roles",
+			token: func() string {
+				claims := validClaims()
+				claims["aud"] = []string{"other", testAudience}
+				claims["scope"] = []string{"images:read", "images:read"}
+				claims["roles"] = []string{"admin", "viewer"}
+				return signRS256(rsaKey, "rsa", claims)
+			},
+			expectedSubject: "user-1",
+			expectedTenant:  "marketing",
+			expectedScopes:  []models.Scope{models.ScopeImagesRead, models.ScopeKeysManage, models.ScopeJanitorRun},
+		},
+		{
+			name: "invalid issuer",
+			token: func() string {
+				claims := validClaims()
+				claims["iss"] = "https://evil.example.com/"
+				return signRS256(rsaKey, "rsa", claims)
+			},
+			expectedError: ErrInvalidIssuer,
+		},
+		{
+			name: "invalid audience",
+			token: func() string {
+				claims := validClaims()
+				claims["aud"] = []string{"other"}
+				return signRS256(rsaKey, "rsa", claims)
+			},
+			expectedError: ErrInvalidAudience,
+		},
+		{
+			name: "expired token",
+			token: func() string {
+				claims := validClaims()
+				claims["exp"] = time.Now().Add(-time.Minute).Unix()
+				return signRS256(rsaKey, "rsa", claims)
+			},
+			expectedError: ErrTokenExpired,
+		},
+		{
+			name: "token not yet valid",
+			token: func() string {
+				claims := validClaims()
+				claims["nbf"] = time.Now().Add(time.Hour).Unix()
+				return signRS256(rsaKey, "rsa", claims)
+			},
+			expectedError: ErrTokenNotYetValid,
+		},
+		{
+			name: "missing subject",
+			token: func() string {
+				claims := validClaims()
+				delete(claims, "sub")
+				return signRS256(rsaKey, "rsa", claims)
+			},
+			expectedError: ErrMissingSubject,
+		},
+		{
+			name: "missing tenant",
+			token: func() string {
+				claims := validClaims()
+				delete(claims, "tenant")
+				return signRS256(rsaKey, "rsa", claims)
+			},
+			expectedError: ErrInvalidTenant,
+		},
+		{
+			name: "invalid tenant",
+			token: func() string {
+				claims := validClaims()
+				claims["tenant"] = "../marketing"
+				return signRS256(rsaKey, "rsa", claims)
+			},
+			expectedError: ErrInvalidTenant,
+		},
+		{
+			name:          "signed by another key",
+			token:         func() string { return signRS256(otherKey, "rsa", validClaims()) },
+			expectedError: ErrInvalidSignature,
+		},
+		{
+			name:          "algorithm not matching the key",
+			token:         func() string { return signES256(ecKey, "rsa", validClaims()) },
+			expectedError: ErrUnsupportedAlg,
+		},
+		{
+			name:          "unsigned token",
+			token:         func() string { return encodeToken(map[string]string{"alg": "none"}, validClaims(), nil) },
+			expectedError: ErrUnsupportedAlg,
+		},
+		{
+			name:          "unknown key",
+			token:         func() string { return signRS256(rsaKey, "unknown", validClaims()) },
+			expectedError: ErrUnknownKey,
+		},
+		{
+			name:          "malformed token",
+			token:         func() string { return "not-a-token" },
+			expectedError: ErrMalformedToken,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			principal, err := authenticator.Authenticate(tt.token())
+
+			assert.Equal(t, tt.expectedError, err)
+			if err != nil {
+				return
+			}
+
+			assert.Equal(t, tt.expectedSubject, principal.Subject)
+			assert.Equal(t, tt.expectedTenant, principal.Tenant)
+			assert.Equal(t, tt.expectedScopes, principal.Scopes)
+		})
+	}
+}
+
+func TestKeySetRotation(t *testing.T) {
+	oldKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	newKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+
+	var fetches int32
+	var rotated atomic.Bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&fetches, 1)
+		if rotated.Load() {
+			writeJWKS(t, w, map[string]crypto.PublicKey{"new": &newKey.PublicKey})
+			return
+		}
+		writeJWKS(t, w, map[string]crypto.PublicKey{"old": &oldKey.PublicKey})
+	}))
+	defer server.Close()
+
+	now := time.Now()
+	keys := newKeySet(server.URL, server.Client(), time.Hour, time.Minute)
+	keys.now = func() time.Time { return now }
+
+	_, err := keys.Key("old")
+	assert.NoError(t, err)
+	_, err = keys.Key("old")
+	assert.NoError(t, err)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&fetches), "cached keys are reused")
+
+	rotated.Store(true)
+
+	_, err = keys.Key("new")
+	assert.Equal(t, ErrUnknownKey, err, "unknown keys don't refresh more than once per minimum interval")
+	assert.Equal(t, int32(1), atomic.LoadInt32(&fetches))
+
+	now = now.Add(time.Minute)
+
+	_, err = keys.Key("new")
+	assert.NoError(t, err, "unknown keys refresh the rotated set")
+	assert.Equal(t, int32(2), atomic.LoadInt32(&fetches))
+
+	_, err = keys.Key("old")
+	assert.Equal(t, ErrUnknownKey, err, "removed keys are no longer trusted")
+}
+
+func TestKeySetSlowRefresh(t *testing.T) {
+	key, _ := rsa.GenerateKey(rand.Reader, 2048)
+
+	var slow atomic.Bool
+	fetching := make(chan struct{}, 1)
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if slow.Load() {
+			fetching <- struct{}{}
+			<-release
+		}
+		writeJWKS(t, w, map[string]crypto.PublicKey{"current": &key.PublicKey})
+	}))
+	defer server.Close()
+	defer close(release)
+
+	var mutex sync.Mutex
+	now := time.Now()
+	keys := newKeySet(server.URL, server.Client(), time.Hour, time.Minute)
+	keys.now = func() time.Time {
+		mutex.Lock()
+		defer mutex.Unlock()
+		return now
+	}
+
+	_, err := keys.Key("current")
+	assert.NoError(t, err)
+
+	slow.Store(true)
+	mutex.Lock()
+	now = now.Add(time.Hour)
+	mutex.Unlock()
+
+	// the expired keys are fetched again by the first token
+	go keys.Key("current")
+	<-fetching
+
+	done := make(chan error)
+	go func() {
+		_, err := keys.Key("current")
+		done <- err
+	}()
+
+	select {
+	case err := <-done:
+		assert.NoError(t, err, "the cached keys are served while the endpoint is slow")
+	case <-time.After(time.Second):
+		t.Fatal("the token waited for the slow endpoint")
+	}
+}
+
+func newJWKSServer(t *testing.T, keys map[string]crypto.PublicKey) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeJWKS(t, w, keys)
+	}))
+}
+
+func writeJWKS(t *testing.T, w http.ResponseWriter, keys map[string]crypto.PublicKey) {
+	set := jsonWebKeySet{}
+	for kid, key := range keys {
+		switch k := key.(type) {
+		case *rsa.PublicKey:
+			set.Keys = append(set.Keys, jsonWebKey{
+				Kid: kid, Kty: "RSA", Use: "sig",
+				N: encodeBigInt(k.N), E: encodeBigInt(big.NewInt(int64(k.E))),
+			})
+		case *ecdsa.PublicKey:
+			set.Keys = append(set.Keys, jsonWebKey{
+				Kid: kid, Kty: "EC", Use: "sig", Crv: "P-256",
+				X: encodeBigInt(k.X), Y: encodeBigInt(k.Y),
+			})
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(set); err != nil {
+		t.Error(err)
+	}
+}
+
+func signRS256(key *rsa.PrivateKey, kid string, claims map[string]interface{}) string {
+	return encodeToken(map[string]string{"alg": "RS256", "kid": kid}, claims, func(digest []byte) []byte {
+		signature, _ := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest)
+		return signature
+	})
+}
+
+func signES256(key *ecdsa.PrivateKey, kid string, claims map[string]interface{}) string {
+	return encodeToken(map[string]string{"alg": "ES256", "kid": kid}, claims, func(digest []byte) []byte {
+		r, s, _ := ecdsa.Sign(rand.Reader, key, digest)
+		signature := make([]byte, 64)
+		r.FillBytes(signature[:32])
+		s.FillBytes(signature[32:])
+		return signature
+	})
+}
+
+func encodeToken(header map[string]string, claims map[string]interface{}, sign func(digest []byte) []byte) string {
+	headerJSON, _ := json.Marshal(header)
+	claimsJSON, _ := json.Marshal(claims)
+
+	signingInput := base64.RawURLEncoding.EncodeToString(headerJSON) + "." + base64.RawURLEncoding.EncodeToString(claimsJSON)
+	if sign == nil {
+		return signingInput + "."
+	}
+
+	digest := sha256.Sum256([]byte(signingInput))
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(sign(digest[:]))
+}
+
+func encodeBigInt(i *big.Int) string {
+	return base64.RawURLEncoding.EncodeToString(i.Bytes())
+}
@@ -0,0 +1,15 @@
+package broadcasters
+
+import "github.com/tam-code/image-upload/config"
+
+type (
+	Broadcasters struct {
+		Statistics StatisticsBroadcaster
+	}
+)
+
+func NewBroadcasters(cfg config.StatisticsStreamConfig) *Broadcasters {
+	return &Broadcasters{
+		Statistics: NewStatisticsBroadcaster(cfg.HistorySize, cfg.SubscriberBuffer),
+	}
+}
@@ -0,0 +1,143 @@
+package broadcasters
+
+import (
+	"sync"
+	"time"
+
+	"github.com/tam-code/image-upload/src/models"
+)
+
+type (
+	StatisticsBroadcaster interface {
+		Publish(tenant string, deltas []models.StatisticsDelta)
+		Subscribe(tenant string, lastEventID uint64) *StatisticsSubscription
+		Unsubscribe(subscription *StatisticsSubscription)
+	}
+
+	// StatisticsSubscription receives the live statistics events of a tenant,
+	// event ids are shared by every tenant so they have gaps. Replay holds the
+	// events published after the requested Last-Event-ID, Missed is set when
+	// those events are no longer kept in history and the client must refetch
+	// the full statistics.
+	StatisticsSubscription struct {
+		Events <-chan models.StatisticsEvent
+		Replay []models.StatisticsEvent
+		Missed bool
+
+		tenant string
+		events chan models.StatisticsEvent
+	}
+
+	// statisticsBroadcaster only reaches the subscribers of this instance, the
+	// deltas of the events consumed by other instances aren't broadcast to
+	// them, so the stream is only complete with a single instance.
+	statisticsBroadcaster struct {
+		mutex            sync.Mutex
+		lastEventID      uint64
+		history          []models.StatisticsEvent
+		historySize      int
+		subscriberBuffer int
+		subscribers      map[*StatisticsSubscription]struct{}
+	}
+)
+
+func NewStatisticsBroadcaster(historySize, subscriberBuffer int) StatisticsBroadcaster {
+	return &statisticsBroadcaster{
+		historySize:      historySize,
+		subscriberBuffer: subscriberBuffer,
+		subscribers:      make(map[*StatisticsSubscription]struct{}),
+	}
+}
+
+// Publish never blocks the caller, subscribers that can't keep up are
+// disconnected and are expected to reconnect with their Last-Event-ID.
+func (b *statisticsBroadcaster) Publish(tenant string, deltas []models.StatisticsDelta) {
+	if len(deltas) == 0 {
+		return
+	}
+
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	b.lastEventID++
+	event := models.StatisticsEvent{
+		ID:     b.lastEventID,
+		Tenant: tenant,
+		Deltas: deltas,
+		Time:   time.Now(),
+	}
+
+	if b.historySize > 0 {
+		if len(b.history) == b.historySize {
+			b.history = b.history[1:]
+		}
+		b.history = append(b.history, event)
+	}
+
+	for subscription := range b.subscribers {
+		if subscription.tenant != tenant {
+			continue
+		}
+
+		select {
+		case subscription.events <- event:
+		default:
+			delete(b.subscribers, subscription)
+			close(subscription.events)
+		}
+	}
+}
+
+func (b *statisticsBroadcaster) Subscribe(tenant string, lastEventID uint64) *StatisticsSubscription {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	events := make(chan models.StatisticsEvent, b.subscriberBuffer)
+	subscription := &StatisticsSubscription{
+		Events: events,
+		tenant: tenant,
+		events: events,
+	}
+
+	if lastEventID > 0 {
+		subscription.Replay, subscription.Missed = b.eventsAfter(tenant, lastEventID)
+	}
+
+	b.subscribers[subscription] = struct{}{}
+
+	return subscription
+}
+
+func (b *statisticsBroadcaster) Unsubscribe(subscription *StatisticsSubscription) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if _, ok := b.subscribers[subscription]; ok {
+		delete(b.subscribers, subscription)
+		close(subscription.events)
+	}
+}
+
+func (b *statisticsBroadcaster) eventsAfter(tenant string, lastEventID uint64) ([]models.StatisticsEvent, bool) {
+	// the id is unknown, most likely issued before a restart
+	if lastEventID > b.lastEventID {
+		return nil, true
+	}
+
+	if lastEventID == b.lastEventID {
+		return nil, false
+	}
+
+	if len(b.history) == 0 || b.history[0].ID > lastEventID+1 {
+		return nil, true
+	}
+
+	var replay []models.StatisticsEvent
+	for _, event := range b.history {
+		if event.ID > lastEventID && event.Tenant == tenant {
+			replay = append(replay, event)
+		}
+	}
+
+	return replay, false
+}
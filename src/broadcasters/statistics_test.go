@@ -0,0 +1,108 @@
+package broadcasters
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/tam-code/image-upload/src/models"
+)
+
+func TestStatisticsBroadcasterSubscribe(t *testing.T) {
+	delta := []models.StatisticsDelta{{Type: models.ImageFormatType, Name: "JPEG", Delta: 1, Count: 1}}
+
+	tests := []struct {
+		name           string
+		published      int
+		lastEventID    uint64
+		expectedReplay []uint64
+		expectedMissed bool
+	}{
+		{
+			name:      "fresh subscription",
+			published: 3,
+		},
+		{
+			name:           "resume within history",
+			published:      3,
+			lastEventID:    1,
+			expectedReplay: []uint64{2, 3},
+		},
+		{
+			name:        "resume up to date",
+			published:   3,
+			lastEventID: 3,
+		},
+		{
+			name:           "resume older than history",
+			published:      5,
+			lastEventID:    1,
+			expectedMissed: true,
+		},
+		{
+			name:           "resume unknown event",
+			published:      1,
+			lastEventID:    10,
+			expectedMissed: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			broadcaster := NewStatisticsBroadcaster(3, 1)
+			for i := 0; i < tt.published; i++ {
+				broadcaster.Publish(models.DefaultTenant, delta)
+			}
+
+			subscription := broadcaster.Subscribe(models.DefaultTenant, tt.lastEventID)
+			defer broadcaster.Unsubscribe(subscription)
+
+			var replay []uint64
+			for _, event := range subscription.Replay {
+				replay = append(replay, event.ID)
+			}
+
+			assert.Equal(t, tt.expectedReplay, replay)
+			assert.Equal(t, tt.expectedMissed, subscription.Missed)
+		})
+	}
+}
+
+func TestStatisticsBroadcasterPublish(t *testing.T) {
+	broadcaster := NewStatisticsBroadcaster(10, 1)
+	delta := []models.StatisticsDelta{{Type: models.ImageFormatType, Name: "JPEG", Delta: 1, Count: 1}}
+
+	fast := broadcaster.Subscribe(models.DefaultTenant, 0)
+	slow := broadcaster.Subscribe(models.DefaultTenant, 0)
+	defer broadcaster.Unsubscribe(fast)
+	defer broadcaster.Unsubscribe(slow)
+
+	broadcaster.Publish(models.DefaultTenant, delta)
+	event := <-fast.Events
+	assert.Equal(t, uint64(1), event.ID)
+
+	// slow subscriber buffer is full, publishing must not block and drops it
+	broadcaster.Publish(models.DefaultTenant, delta)
+	event = <-fast.Events
+	assert.Equal(t, uint64(2), event.ID)
+
+	event, ok := <-slow.Events
+	assert.True(t, ok)
+	assert.Equal(t, uint64(1), event.ID)
+
+	_, ok = <-slow.Events
+	assert.False(t, ok)
+
+	// empty deltas are not published
+	broadcaster.Publish(models.DefaultTenant, nil)
+	assert.Equal(t, 0, len(fast.Events))
+
+	// events of other tenants are neither delivered nor replayed
+	broadcaster.Publish("marketing", delta)
+	assert.Equal(t, 0, len(fast.Events))
+
+	resumed := broadcaster.Subscribe(models.DefaultTenant, 2)
+	defer broadcaster.Unsubscribe(resumed)
+	assert.Empty(t, resumed.Replay)
+	assert.False(t, resumed.Missed)
+}
@@ -0,0 +1,77 @@
+package buses
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/tam-code/image-upload/config"
+)
+
+const (
+	KafkaBus  = "kafka"
+	MemoryBus = "memory"
+)
+
+var ErrClosed = errors.New("bus closed")
+
+type (
+	Header struct {
+		Key   string
+		Value []byte
+	}
+
+	// Message is an event of a topic, the partition, offset and time are set
+	// by the bus once published.
+	Message struct {
+		Topic     string
+		Partition int
+		Offset    int64
+		// HighWaterMark is the offset of the next message produced to the
+		// partition when the message was fetched
+		HighWaterMark int64
+		Key           []byte
+		Value         []byte
+		Headers       []Header
+		Time          time.Time
+	}
+
+	Producer interface {
+		WriteMessages(ctx context.Context, msgs ...Message) error
+		Close() error
+	}
+
+	// Consumer delivers the messages of the partitions assigned to it in its
+	// group at least once, the messages fetched and not committed are fetched
+	// again by the member the partition is assigned to after a rebalance.
+	Consumer interface {
+		FetchMessage(ctx context.Context) (Message, error)
+		CommitMessages(ctx context.Context, msgs ...Message) error
+		// Lag is how many messages of the assigned partitions weren't fetched yet
+		Lag() int64
+		Close() error
+	}
+
+	// Bus creates the producers and consumers of the topics.
+	Bus interface {
+		Producer(topic string) (Producer, error)
+		Consumer(topic string, group string) (Consumer, error)
+		// Seek moves the group of the topic to the position, the group must
+		// have no member. The end offsets of the partitions are returned, the
+		// messages before them are fetched again.
+		Seek(ctx context.Context, topic string, group string, position Position) (map[int]int64, error)
+	}
+)
+
+// NewBus creates the configured bus, Kafka or the in-process memory bus.
+func NewBus(cfg config.BusConfig, kafkaConfig config.KafkaConfig) (Bus, error) {
+	switch cfg.Type {
+	case KafkaBus:
+		return NewKafkaBus(kafkaConfig), nil
+	case MemoryBus:
+		return NewMemoryBus(cfg.Partitions), nil
+	default:
+		return nil, fmt.Errorf("unknown bus type %q", cfg.Type)
+	}
+}
@@ -0,0 +1,133 @@
+package buses
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	CloudEventsSpecVersion = "1.0"
+	// CloudEventsContentType is the content type of the messages whose body is
+	// a structured CloudEvents envelope
+	CloudEventsContentType = "application/cloudevents+json"
+	ContentTypeHeader      = "content-type"
+
+	// the ce_ headers repeat the attributes of the envelope, as the Kafka
+	// binary mode does, so they can be routed without reading the body
+	cloudEventsIDHeader          = "ce_id"
+	cloudEventsTypeHeader        = "ce_type"
+	cloudEventsSourceHeader      = "ce_source"
+	cloudEventsSpecVersionHeader = "ce_specversion"
+	cloudEventsTimeHeader        = "ce_time"
+
+	cloudEventsSource     = "/image-upload"
+	cloudEventsTypePrefix = "com.github.tam-code.image-upload."
+
+	// LegacyEventVersion is the version of the bodies without envelope
+	LegacyEventVersion = 1
+)
+
+// CloudEvent is the CloudEvents 1.0 envelope of the events, its data is the
+// payload of the version of the event named by its type.
+type CloudEvent struct {
+	SpecVersion     string          `json:"specversion"`
+	ID              string          `json:"id"`
+	Source          string          `json:"source"`
+	Type            string          `json:"type"`
+	Subject         string          `json:"subject,omitempty"`
+	Time            time.Time       `json:"time"`
+	DataContentType string          `json:"datacontenttype"`
+	Data            json.RawMessage `json:"data"`
+}
+
+// CloudEventType is the CloudEvents type of the version of the event, e.g.
+// com.github.tam-code.image-upload.image_uploaded.v2.
+func CloudEventType(eventType string, version int) string {
+	return fmt.Sprintf("%s%s.v%d", cloudEventsTypePrefix, eventType, version)
+}
+
+// NewEventMessage wraps the payload of the version of the event in a
+// CloudEvents envelope, the subject is the tenant of the event.
+func NewEventMessage(eventType string, version int, subject string, payload interface{}) (Message, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return Message{}, err
+	}
+
+	id, err := newMessageID()
+	if err != nil {
+		return Message{}, err
+	}
+
+	event := CloudEvent{
+		SpecVersion:     CloudEventsSpecVersion,
+		ID:              id,
+		Source:          cloudEventsSource,
+		Type:            CloudEventType(eventType, version),
+		Subject:         subject,
+		Time:            time.Now().UTC(),
+		DataContentType: "application/json",
+		Data:            data,
+	}
+
+	body, err := json.Marshal(event)
+	if err != nil {
+		return Message{}, err
+	}
+
+	return Message{
+		Value: body,
+		Headers: []Header{
+			{Key: EventTypeHeader, Value: []byte(eventType)},
+			{Key: ContentTypeHeader, Value: []byte(CloudEventsContentType)},
+			{Key: cloudEventsSpecVersionHeader, Value: []byte(event.SpecVersion)},
+			{Key: cloudEventsIDHeader, Value: []byte(event.ID)},
+			{Key: cloudEventsTypeHeader, Value: []byte(event.Type)},
+			{Key: cloudEventsSourceHeader, Value: []byte(event.Source)},
+			{Key: cloudEventsTimeHeader, Value: []byte(event.Time.Format(time.RFC3339Nano))},
+		},
+	}, nil
+}
+
+// ContentType returns the content type of the body of the message, the
+// version 1 bodies were published without one and are JSON.
+func ContentType(msg Message) string {
+	if contentType, ok := header(msg, ContentTypeHeader); ok {
+		return contentType
+	}
+
+	return "application/json"
+}
+
+// DecodeEvent returns the version and the payload of the body of an event,
+// the bodies that aren't a CloudEvents envelope are version 1 payloads.
+func DecodeEvent(body []byte) (int, json.RawMessage, error) {
+	if !bytes.HasPrefix(bytes.TrimSpace(body), []byte("{")) {
+		return LegacyEventVersion, body, nil
+	}
+
+	var event CloudEvent
+	if err := json.Unmarshal(body, &event); err != nil {
+		return 0, nil, err
+	}
+
+	if event.SpecVersion != CloudEventsSpecVersion {
+		return 0, nil, fmt.Errorf("unsupported CloudEvents spec version %q", event.SpecVersion)
+	}
+
+	separator := strings.LastIndex(event.Type, ".v")
+	if separator < 0 {
+		return 0, nil, fmt.Errorf("no version in the event type %q", event.Type)
+	}
+
+	version, err := strconv.Atoi(event.Type[separator+2:])
+	if err != nil {
+		return 0, nil, fmt.Errorf("no version in the event type %q", event.Type)
+	}
+
+	return version, event.Data, nil
+}
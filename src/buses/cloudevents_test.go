@@ -0,0 +1,71 @@
+package buses
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCloudEvents(t *testing.T) {
+	msg, err := NewEventMessage(ImageUploadedEvent, 2, "marketing", map[string]string{"tenant": "marketing"})
+	assert.NoError(t, err)
+
+	assert.Equal(t, ImageUploadedEvent, EventType(msg))
+	contentType, _ := header(msg, ContentTypeHeader)
+	assert.Equal(t, CloudEventsContentType, contentType)
+	eventType, _ := header(msg, cloudEventsTypeHeader)
+	assert.Equal(t, "com.github.tam-code.image-upload.image_uploaded.v2", eventType)
+
+	var envelope CloudEvent
+	assert.NoError(t, json.Unmarshal(msg.Value, &envelope))
+	assert.Equal(t, "1.0", envelope.SpecVersion)
+	assert.Equal(t, "marketing", envelope.Subject)
+	assert.NotEmpty(t, envelope.ID)
+	assert.False(t, envelope.Time.IsZero())
+
+	tests := []struct {
+		name            string
+		body            string
+		expectedVersion int
+		expectedPayload string
+		expectErr       bool
+	}{
+		{
+			name:            "envelope",
+			body:            string(msg.Value),
+			expectedVersion: 2,
+			expectedPayload: `{"tenant":"marketing"}`,
+		},
+		{
+			name:            "version 1 array of ids without envelope",
+			body:            `["first","second"]`,
+			expectedVersion: LegacyEventVersion,
+			expectedPayload: `["first","second"]`,
+		},
+		{
+			name:      "unknown spec version",
+			body:      `{"specversion":"0.3","type":"com.github.tam-code.image-upload.image_uploaded.v2","data":{}}`,
+			expectErr: true,
+		},
+		{
+			name:      "type without version",
+			body:      `{"specversion":"1.0","type":"image_uploaded","data":{}}`,
+			expectErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			version, payload, err := DecodeEvent([]byte(tt.body))
+			if tt.expectErr {
+				assert.Error(t, err)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedVersion, version)
+			assert.JSONEq(t, tt.expectedPayload, string(payload))
+		})
+	}
+}
@@ -0,0 +1,49 @@
+package buses
+
+const (
+	// EventTypeHeader names the event carried by a message, messages without
+	// it were published before deletions existed and are image uploads
+	EventTypeHeader = "event_type"
+	// MessageIDHeader identifies the messages of the idempotent Kafka
+	// producers, the consumers drop the messages whose id they recently fetched
+	MessageIDHeader = "message_id"
+	// TenantHeader names the tenant of the images of an image event, the
+	// version 1 bodies don't carry it
+	TenantHeader = "tenant"
+
+	ImageUploadedEvent = "image_uploaded"
+	ImageDeletedEvent  = "image_deleted"
+	AuditEvent         = "audit_event"
+)
+
+// EventType returns the event type of the message.
+func EventType(msg Message) string {
+	if eventType, ok := header(msg, EventTypeHeader); ok {
+		return eventType
+	}
+
+	return ImageUploadedEvent
+}
+
+// MessageID returns the id of the message, empty when it has none.
+func MessageID(msg Message) string {
+	messageID, _ := header(msg, MessageIDHeader)
+	return messageID
+}
+
+// EventTenant returns the tenant of the image event, empty for the events
+// published before the header existed.
+func EventTenant(msg Message) string {
+	tenant, _ := header(msg, TenantHeader)
+	return tenant
+}
+
+func header(msg Message, key string) (string, bool) {
+	for _, header := range msg.Headers {
+		if header.Key == key {
+			return string(header.Value), true
+		}
+	}
+
+	return "", false
+}
@@ -0,0 +1,214 @@
+package buses
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"sync"
+
+	segmentio "github.com/segmentio/kafka-go"
+
+	"github.com/tam-code/image-upload/config"
+	"github.com/tam-code/image-upload/src/kafka"
+)
+
+// recentMessageIDs is how many message ids the Kafka consumers remember to
+// drop the messages the idempotent producers retried.
+const recentMessageIDs = 1024
+
+type (
+	kafkaBus struct {
+		cfg config.KafkaConfig
+	}
+
+	kafkaProducer struct {
+		writer     *segmentio.Writer
+		idempotent bool
+	}
+
+	kafkaConsumer struct {
+		reader *segmentio.Reader
+
+		mutex       sync.Mutex
+		seenIDs     map[string]struct{}
+		seenIDsRing []string
+		next        int
+	}
+)
+
+// NewKafkaBus creates the producers and consumers of the brokers of cfg.
+func NewKafkaBus(cfg config.KafkaConfig) Bus {
+	return &kafkaBus{cfg: cfg}
+}
+
+// Producer writes to the topic, the messages of idempotent producers are
+// stamped with a message id before being written.
+func (b *kafkaBus) Producer(topic string) (Producer, error) {
+	cfg := b.cfg
+	cfg.Topic = topic
+
+	writer, err := kafka.NewKafkaWriter(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	return &kafkaProducer{writer: writer, idempotent: cfg.Producer.Idempotent}, nil
+}
+
+func (b *kafkaBus) Consumer(topic string, group string) (Consumer, error) {
+	cfg := b.cfg
+	cfg.Topic = topic
+	cfg.Group = group
+
+	reader, err := kafka.NewKafkaReader(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	return newKafkaConsumer(reader), nil
+}
+
+func (b *kafkaBus) Seek(ctx context.Context, topic string, group string, position Position) (map[int]int64, error) {
+	cfg := b.cfg
+	cfg.Topic = topic
+	cfg.Group = group
+
+	ends, err := kafka.SeekGroup(ctx, cfg, position.Time, position.Offset)
+	if errors.Is(err, kafka.ErrGroupActive) {
+		return nil, ErrGroupActive
+	}
+
+	return ends, err
+}
+
+func (p *kafkaProducer) WriteMessages(ctx context.Context, msgs ...Message) error {
+	kafkaMessages := make([]segmentio.Message, len(msgs))
+	for i, msg := range msgs {
+		if p.idempotent && MessageID(msg) == "" {
+			messageID, err := newMessageID()
+			if err != nil {
+				return err
+			}
+
+			msg.Headers = append(msg.Headers, Header{Key: MessageIDHeader, Value: []byte(messageID)})
+		}
+
+		// the writer sets the topic, so the fetched messages can be written again
+		msg.Topic = ""
+		kafkaMessages[i] = toKafkaMessage(msg)
+	}
+
+	return p.writer.WriteMessages(ctx, kafkaMessages...)
+}
+
+func (p *kafkaProducer) Close() error {
+	return p.writer.Close()
+}
+
+func newKafkaConsumer(reader *segmentio.Reader) *kafkaConsumer {
+	return &kafkaConsumer{
+		reader:      reader,
+		seenIDs:     make(map[string]struct{}, recentMessageIDs),
+		seenIDsRing: make([]string, recentMessageIDs),
+	}
+}
+
+// FetchMessage skips the messages whose id was recently fetched, their offset
+// is committed along with the next messages of the partition.
+func (c *kafkaConsumer) FetchMessage(ctx context.Context) (Message, error) {
+	for {
+		kafkaMessage, err := c.reader.FetchMessage(ctx)
+		if err != nil {
+			return Message{}, err
+		}
+
+		msg := fromKafkaMessage(kafkaMessage)
+		if !c.seen(MessageID(msg)) {
+			return msg, nil
+		}
+	}
+}
+
+func (c *kafkaConsumer) CommitMessages(ctx context.Context, msgs ...Message) error {
+	kafkaMessages := make([]segmentio.Message, len(msgs))
+	for i, msg := range msgs {
+		kafkaMessages[i] = toKafkaMessage(msg)
+	}
+
+	return c.reader.CommitMessages(ctx, kafkaMessages...)
+}
+
+func (c *kafkaConsumer) Lag() int64 {
+	return c.reader.Stats().Lag
+}
+
+func (c *kafkaConsumer) Close() error {
+	return c.reader.Close()
+}
+
+// seen tells if the message id was recently fetched and remembers it
+// otherwise, forgetting the oldest one.
+func (c *kafkaConsumer) seen(messageID string) bool {
+	if messageID == "" {
+		return false
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if _, ok := c.seenIDs[messageID]; ok {
+		return true
+	}
+
+	delete(c.seenIDs, c.seenIDsRing[c.next])
+	c.seenIDsRing[c.next] = messageID
+	c.seenIDs[messageID] = struct{}{}
+	c.next = (c.next + 1) % recentMessageIDs
+
+	return false
+}
+
+func toKafkaMessage(msg Message) segmentio.Message {
+	headers := make([]segmentio.Header, len(msg.Headers))
+	for i, header := range msg.Headers {
+		headers[i] = segmentio.Header{Key: header.Key, Value: header.Value}
+	}
+
+	return segmentio.Message{
+		Topic:     msg.Topic,
+		Partition: msg.Partition,
+		Offset:    msg.Offset,
+		Key:       msg.Key,
+		Value:     msg.Value,
+		Headers:   headers,
+		Time:      msg.Time,
+	}
+}
+
+func fromKafkaMessage(kafkaMessage segmentio.Message) Message {
+	headers := make([]Header, len(kafkaMessage.Headers))
+	for i, header := range kafkaMessage.Headers {
+		headers[i] = Header{Key: header.Key, Value: header.Value}
+	}
+
+	return Message{
+		Topic:         kafkaMessage.Topic,
+		Partition:     kafkaMessage.Partition,
+		Offset:        kafkaMessage.Offset,
+		HighWaterMark: kafkaMessage.HighWaterMark,
+		Key:           kafkaMessage.Key,
+		Value:         kafkaMessage.Value,
+		Headers:       headers,
+		Time:          kafkaMessage.Time,
+	}
+}
+
+func newMessageID() (string, error) {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(id), nil
+}
@@ -0,0 +1,41 @@
+package buses
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKafkaConsumerDropsRecentMessageIDs(t *testing.T) {
+	consumer := newKafkaConsumer(nil)
+
+	assert.False(t, consumer.seen(""), "messages without id are never dropped")
+	assert.False(t, consumer.seen(""))
+
+	assert.False(t, consumer.seen("first"))
+	assert.True(t, consumer.seen("first"), "the retried message is dropped")
+
+	for i := 0; i < recentMessageIDs; i++ {
+		consumer.seen(strconv.Itoa(i))
+	}
+	assert.False(t, consumer.seen("first"), "the oldest ids are forgotten")
+}
+
+func TestKafkaMessageConversion(t *testing.T) {
+	msg := Message{
+		Topic:     "image_uploaded",
+		Partition: 3,
+		Offset:    42,
+		Key:       []byte("tenant-a"),
+		Value:     []byte(`{"name":"a.jpg"}`),
+		Headers:   []Header{{Key: EventTypeHeader, Value: []byte(ImageDeletedEvent)}, {Key: MessageIDHeader, Value: []byte("id-1")}},
+	}
+
+	converted := fromKafkaMessage(toKafkaMessage(msg))
+
+	assert.Equal(t, msg, converted)
+	assert.Equal(t, ImageDeletedEvent, EventType(converted))
+	assert.Equal(t, "id-1", MessageID(converted))
+	assert.Equal(t, ImageUploadedEvent, EventType(Message{}), "messages without event type are image uploads")
+}
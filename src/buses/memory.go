@@ -0,0 +1,327 @@
+package buses
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"sync"
+	"time"
+)
+
+const defaultMemoryPartitions = 1
+
+type (
+	// memoryBus keeps the topics in the process, its state is guarded by a
+	// single mutex.
+	memoryBus struct {
+		partitions int
+
+		mutex  sync.Mutex
+		topics map[string]*memoryTopic
+		// changed is closed and replaced when messages are published or the
+		// groups rebalance, waking up the fetching consumers
+		changed chan struct{}
+	}
+
+	memoryTopic struct {
+		name       string
+		partitions []*memoryPartition
+		groups     map[string]*memoryGroup
+		// nextPartition spreads the messages without key over the partitions
+		nextPartition int
+	}
+
+	// memoryPartition keeps the messages some group didn't commit yet, the
+	// first one has the offset first.
+	memoryPartition struct {
+		first    int64
+		messages []Message
+	}
+
+	memoryGroup struct {
+		// committed is the offset of the next message to handle by partition
+		committed []int64
+		members   []*memoryConsumer
+	}
+
+	memoryProducer struct {
+		bus   *memoryBus
+		topic string
+	}
+
+	memoryConsumer struct {
+		bus   *memoryBus
+		topic *memoryTopic
+		group *memoryGroup
+		// positions is the offset of the next message to fetch by assigned partition
+		positions map[int]int64
+		closed    bool
+	}
+)
+
+// NewMemoryBus creates an in-process bus whose topics have the given number
+// of partitions. The messages are delivered at least once to every group,
+// and dropped once all the groups of their topic committed them, the topics
+// without consumer group drop their messages right away.
+func NewMemoryBus(partitions int) Bus {
+	if partitions <= 0 {
+		partitions = defaultMemoryPartitions
+	}
+
+	return &memoryBus{
+		partitions: partitions,
+		topics:     map[string]*memoryTopic{},
+		changed:    make(chan struct{}),
+	}
+}
+
+func (b *memoryBus) Producer(topic string) (Producer, error) {
+	return &memoryProducer{bus: b, topic: topic}, nil
+}
+
+// Consumer joins the group, the partitions of the topic are assigned again
+// among the members of the group and each member fetches its partitions from
+// the last committed offset.
+func (b *memoryBus) Consumer(topic string, group string) (Consumer, error) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	t := b.topic(topic)
+	g, ok := t.groups[group]
+	if !ok {
+		g = &memoryGroup{committed: make([]int64, len(t.partitions))}
+		for i, partition := range t.partitions {
+			g.committed[i] = partition.first
+		}
+		t.groups[group] = g
+	}
+
+	consumer := &memoryConsumer{bus: b, topic: t, group: g}
+	g.members = append(g.members, consumer)
+	b.rebalance(g)
+
+	return consumer, nil
+}
+
+// Seek sets the committed offsets of the group, the messages the groups
+// committed before are no longer retained so the group is moved to the first
+// retained message at most.
+func (b *memoryBus) Seek(ctx context.Context, topic string, group string, position Position) (map[int]int64, error) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	t := b.topic(topic)
+	g, ok := t.groups[group]
+	if !ok {
+		g = &memoryGroup{committed: make([]int64, len(t.partitions))}
+		t.groups[group] = g
+	}
+
+	if len(g.members) > 0 {
+		return nil, ErrGroupActive
+	}
+
+	ends := map[int]int64{}
+	for i, partition := range t.partitions {
+		end := partition.first + int64(len(partition.messages))
+		ends[i] = end
+
+		if position.Time.IsZero() {
+			g.committed[i] = min(max(position.Offset, partition.first), end)
+			continue
+		}
+
+		g.committed[i] = end
+		for _, msg := range partition.messages {
+			if !msg.Time.Before(position.Time) {
+				g.committed[i] = msg.Offset
+				break
+			}
+		}
+	}
+
+	return ends, nil
+}
+
+// topic returns the topic, creating it on first use.
+func (b *memoryBus) topic(name string) *memoryTopic {
+	topic, ok := b.topics[name]
+	if !ok {
+		topic = &memoryTopic{name: name, partitions: make([]*memoryPartition, b.partitions), groups: map[string]*memoryGroup{}}
+		for i := range topic.partitions {
+			topic.partitions[i] = &memoryPartition{}
+		}
+		b.topics[name] = topic
+	}
+
+	return topic
+}
+
+// rebalance assigns the partitions to the members of the group in turn.
+func (b *memoryBus) rebalance(group *memoryGroup) {
+	for i, member := range group.members {
+		member.positions = map[int]int64{}
+		for partition := i; partition < len(group.committed); partition += len(group.members) {
+			member.positions[partition] = group.committed[partition]
+		}
+	}
+
+	b.notify()
+}
+
+func (b *memoryBus) notify() {
+	close(b.changed)
+	b.changed = make(chan struct{})
+}
+
+// WriteMessages appends the messages to the partition of their key, the
+// messages without key are spread over the partitions.
+func (p *memoryProducer) WriteMessages(ctx context.Context, msgs ...Message) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	p.bus.mutex.Lock()
+	defer p.bus.mutex.Unlock()
+
+	topic := p.bus.topic(p.topic)
+	for _, msg := range msgs {
+		partitionIndex := topic.nextPartition
+		if msg.Key != nil {
+			hash := fnv.New32a()
+			hash.Write(msg.Key)
+			partitionIndex = int(hash.Sum32() % uint32(len(topic.partitions)))
+		} else {
+			topic.nextPartition = (topic.nextPartition + 1) % len(topic.partitions)
+		}
+
+		partition := topic.partitions[partitionIndex]
+		msg.Topic = topic.name
+		msg.Partition = partitionIndex
+		msg.Offset = partition.first + int64(len(partition.messages))
+		msg.Headers = append([]Header(nil), msg.Headers...)
+		if msg.Time.IsZero() {
+			msg.Time = time.Now()
+		}
+
+		if len(topic.groups) == 0 {
+			partition.first++
+			continue
+		}
+		partition.messages = append(partition.messages, msg)
+	}
+
+	p.bus.notify()
+
+	return nil
+}
+
+func (p *memoryProducer) Close() error {
+	return nil
+}
+
+// FetchMessage returns the next message of the assigned partitions, waiting
+// for one to be published.
+func (c *memoryConsumer) FetchMessage(ctx context.Context) (Message, error) {
+	for {
+		c.bus.mutex.Lock()
+		if c.closed {
+			c.bus.mutex.Unlock()
+			return Message{}, ErrClosed
+		}
+
+		for partitionIndex, position := range c.positions {
+			partition := c.topic.partitions[partitionIndex]
+			if position-partition.first < int64(len(partition.messages)) {
+				c.positions[partitionIndex]++
+				msg := partition.messages[position-partition.first]
+				msg.HighWaterMark = partition.first + int64(len(partition.messages))
+				c.bus.mutex.Unlock()
+				return msg, nil
+			}
+		}
+
+		changed := c.bus.changed
+		c.bus.mutex.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return Message{}, ctx.Err()
+		case <-changed:
+		}
+	}
+}
+
+// CommitMessages records the handled messages of the group, failing for the
+// partitions assigned to another member since the messages were fetched.
+func (c *memoryConsumer) CommitMessages(ctx context.Context, msgs ...Message) error {
+	c.bus.mutex.Lock()
+	defer c.bus.mutex.Unlock()
+
+	if c.closed {
+		return ErrClosed
+	}
+
+	for _, msg := range msgs {
+		if _, ok := c.positions[msg.Partition]; !ok || msg.Topic != c.topic.name {
+			return fmt.Errorf("partition %d of %s isn't assigned to the consumer", msg.Partition, msg.Topic)
+		}
+
+		if msg.Offset >= c.group.committed[msg.Partition] {
+			c.group.committed[msg.Partition] = msg.Offset + 1
+		}
+		c.topic.trim(msg.Partition)
+	}
+
+	return nil
+}
+
+func (c *memoryConsumer) Lag() int64 {
+	c.bus.mutex.Lock()
+	defer c.bus.mutex.Unlock()
+
+	var lag int64
+	for partitionIndex, position := range c.positions {
+		partition := c.topic.partitions[partitionIndex]
+		lag += partition.first + int64(len(partition.messages)) - position
+	}
+
+	return lag
+}
+
+// Close leaves the group, the messages fetched and not committed are fetched
+// again by the member the partition is assigned to.
+func (c *memoryConsumer) Close() error {
+	c.bus.mutex.Lock()
+	defer c.bus.mutex.Unlock()
+
+	if c.closed {
+		return nil
+	}
+	c.closed = true
+
+	for i, member := range c.group.members {
+		if member == c {
+			c.group.members = append(c.group.members[:i], c.group.members[i+1:]...)
+			break
+		}
+	}
+	c.bus.rebalance(c.group)
+
+	return nil
+}
+
+// trim drops the messages of the partition every group committed.
+func (t *memoryTopic) trim(partitionIndex int) {
+	partition := t.partitions[partitionIndex]
+
+	committed := partition.first + int64(len(partition.messages))
+	for _, group := range t.groups {
+		committed = min(committed, group.committed[partitionIndex])
+	}
+
+	if committed > partition.first {
+		partition.messages = partition.messages[committed-partition.first:]
+		partition.first = committed
+	}
+}
@@ -0,0 +1,125 @@
+package buses
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemoryBusGroups(t *testing.T) {
+	bus := NewMemoryBus(2)
+	ctx := context.Background()
+
+	statistics, err := bus.Consumer("image_uploaded", "statistics")
+	assert.NoError(t, err)
+	audit, err := bus.Consumer("image_uploaded", "audit")
+	assert.NoError(t, err)
+
+	producer, err := bus.Producer("image_uploaded")
+	assert.NoError(t, err)
+	assert.NoError(t, producer.WriteMessages(ctx,
+		Message{Key: []byte("tenant-a"), Value: []byte("1")},
+		Message{Key: []byte("tenant-a"), Value: []byte("2")},
+	))
+
+	assert.Equal(t, int64(2), statistics.Lag())
+
+	for _, consumer := range []Consumer{statistics, audit} {
+		first := fetch(t, consumer)
+		second := fetch(t, consumer)
+
+		assert.Equal(t, "1", string(first.Value), "every group gets the messages of a key in order")
+		assert.Equal(t, "2", string(second.Value))
+		assert.Equal(t, first.Partition, second.Partition)
+		assert.Equal(t, first.Offset+1, second.Offset)
+		assert.Equal(t, "image_uploaded", first.Topic)
+		assert.NoError(t, consumer.CommitMessages(ctx, first, second))
+	}
+
+	assert.Equal(t, int64(0), statistics.Lag())
+}
+
+func TestMemoryBusAtLeastOnce(t *testing.T) {
+	bus := NewMemoryBus(1)
+	ctx := context.Background()
+
+	first, err := bus.Consumer("image_uploaded", "statistics")
+	assert.NoError(t, err)
+
+	producer, _ := bus.Producer("image_uploaded")
+	assert.NoError(t, producer.WriteMessages(ctx, Message{Value: []byte("1")}, Message{Value: []byte("2")}))
+
+	committed := fetch(t, first)
+	assert.NoError(t, first.CommitMessages(ctx, committed))
+	uncommitted := fetch(t, first)
+	assert.Equal(t, "2", string(uncommitted.Value))
+
+	// the second member gets no partition until the first one leaves the group
+	second, err := bus.Consumer("image_uploaded", "statistics")
+	assert.NoError(t, err)
+	assert.NoError(t, first.Close())
+	assert.ErrorIs(t, first.CommitMessages(ctx, uncommitted), ErrClosed)
+
+	redelivered := fetch(t, second)
+	assert.Equal(t, uncommitted.Offset, redelivered.Offset, "the message fetched and not committed is fetched again")
+	assert.Equal(t, "2", string(redelivered.Value))
+	assert.NoError(t, second.CommitMessages(ctx, redelivered))
+}
+
+func TestMemoryBusRebalance(t *testing.T) {
+	bus := NewMemoryBus(4)
+	ctx := context.Background()
+
+	first, _ := bus.Consumer("image_uploaded", "statistics")
+	second, _ := bus.Consumer("image_uploaded", "statistics")
+
+	producer, _ := bus.Producer("image_uploaded")
+	for i := 0; i < 4; i++ {
+		assert.NoError(t, producer.WriteMessages(ctx, Message{Value: []byte{byte(i)}}))
+	}
+
+	partitions := map[int]bool{}
+	for _, consumer := range []Consumer{first, second} {
+		assert.Equal(t, int64(2), consumer.Lag(), "the partitions are shared by the members")
+		for i := 0; i < 2; i++ {
+			msg := fetch(t, consumer)
+			partitions[msg.Partition] = true
+			assert.NoError(t, consumer.CommitMessages(ctx, msg))
+		}
+	}
+	assert.Len(t, partitions, 4)
+
+	msg := Message{Topic: "image_uploaded", Partition: 1}
+	assert.Error(t, first.CommitMessages(ctx, msg), "partitions of another member can't be committed")
+}
+
+func TestMemoryBusFetchWaits(t *testing.T) {
+	bus := NewMemoryBus(1)
+
+	consumer, _ := bus.Consumer("image_uploaded", "statistics")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err := consumer.FetchMessage(ctx)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	producer, _ := bus.Producer("image_uploaded")
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		producer.WriteMessages(context.Background(), Message{Value: []byte("late")})
+	}()
+
+	assert.Equal(t, "late", string(fetch(t, consumer).Value))
+}
+
+func fetch(t *testing.T, consumer Consumer) Message {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	msg, err := consumer.FetchMessage(ctx)
+	assert.NoError(t, err)
+
+	return msg
+}
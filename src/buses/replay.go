@@ -0,0 +1,72 @@
+package buses
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ReplayHeader marks the messages of a replay, its value is the id of the
+// replay. The handlers with side effects outside the service skip them.
+const ReplayHeader = "replay"
+
+var ErrGroupActive = errors.New("the consumer group has members")
+
+type (
+	// Position is where a consumer group is moved to, the first message
+	// produced at or after Time, or Offset of every partition when Time is
+	// zero.
+	Position struct {
+		Time   time.Time
+		Offset int64
+	}
+
+	replayContextKey struct{}
+
+	// replayConsumer marks the messages a group fetches again after a seek.
+	replayConsumer struct {
+		Consumer
+		ends     map[int]int64
+		replayID string
+	}
+)
+
+// WithReplay returns a context whose published events are marked as a
+// replay.
+func WithReplay(ctx context.Context, replayID string) context.Context {
+	return context.WithValue(ctx, replayContextKey{}, replayID)
+}
+
+// ReplayFromContext returns the id of the replay of the context, empty when
+// the context isn't replaying.
+func ReplayFromContext(ctx context.Context) string {
+	replayID, _ := ctx.Value(replayContextKey{}).(string)
+	return replayID
+}
+
+// IsReplay tells if the message was published or fetched again by a replay.
+func IsReplay(msg Message) bool {
+	_, ok := header(msg, ReplayHeader)
+	return ok
+}
+
+// NewReplayConsumer marks as a replay the messages of the partitions before
+// their end offset at the time of the seek, the messages produced since are
+// left as they are.
+func NewReplayConsumer(c Consumer, ends map[int]int64, replayID string) Consumer {
+	return &replayConsumer{Consumer: c, ends: ends, replayID: replayID}
+}
+
+func (c *replayConsumer) FetchMessage(ctx context.Context) (Message, error) {
+	msg, err := c.Consumer.FetchMessage(ctx)
+	if err != nil {
+		return Message{}, err
+	}
+
+	if msg.Offset < c.ends[msg.Partition] && !IsReplay(msg) {
+		// the headers may be shared with the messages of the bus
+		msg.Headers = append(append([]Header(nil), msg.Headers...), Header{Key: ReplayHeader, Value: []byte(c.replayID)})
+	}
+
+	return msg, nil
+}
@@ -0,0 +1,97 @@
+package buses
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemoryBusSeek(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name            string
+		position        Position
+		expectedOffsets []int64
+	}{
+		{
+			name:            "by time",
+			position:        Position{Time: start.Add(time.Minute)},
+			expectedOffsets: []int64{1, 2},
+		},
+		{
+			name:     "after the last message",
+			position: Position{Time: start.Add(time.Hour)},
+		},
+		{
+			name:            "by offset",
+			position:        Position{Offset: 2},
+			expectedOffsets: []int64{2},
+		},
+		{
+			name:            "offset before the first retained message",
+			position:        Position{Offset: -5},
+			expectedOffsets: []int64{1, 2},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bus := NewMemoryBus(1)
+			ctx := context.Background()
+
+			consumer, _ := bus.Consumer("image_uploaded", "statistics")
+			producer, _ := bus.Producer("image_uploaded")
+			for i := 0; i < 3; i++ {
+				assert.NoError(t, producer.WriteMessages(ctx, Message{Time: start.Add(time.Duration(i) * time.Minute)}))
+			}
+			// the first message is committed by every group and dropped
+			assert.NoError(t, consumer.CommitMessages(ctx, fetch(t, consumer)))
+
+			_, err := bus.Seek(ctx, "image_uploaded", "statistics", tt.position)
+			assert.ErrorIs(t, err, ErrGroupActive)
+			assert.NoError(t, consumer.Close())
+
+			ends, err := bus.Seek(ctx, "image_uploaded", "statistics", tt.position)
+			assert.NoError(t, err)
+			assert.Equal(t, map[int]int64{0: 3}, ends)
+
+			consumer, _ = bus.Consumer("image_uploaded", "statistics")
+			var offsets []int64
+			for consumer.Lag() > 0 {
+				offsets = append(offsets, fetch(t, consumer).Offset)
+			}
+			assert.Equal(t, tt.expectedOffsets, offsets)
+		})
+	}
+}
+
+func TestReplayConsumer(t *testing.T) {
+	bus := NewMemoryBus(1)
+	ctx := context.Background()
+
+	producer, _ := bus.Producer("image_uploaded")
+	audit, _ := bus.Consumer("image_uploaded", "audit")
+	assert.NoError(t, producer.WriteMessages(ctx, Message{Value: []byte("1")}, Message{Value: []byte("2")}))
+
+	ends, err := bus.Seek(ctx, "image_uploaded", "statistics", Position{Offset: 0})
+	assert.NoError(t, err)
+
+	consumer, _ := bus.Consumer("image_uploaded", "statistics")
+	consumer = NewReplayConsumer(consumer, ends, "replay-1")
+	assert.NoError(t, producer.WriteMessages(ctx, Message{Value: []byte("3")}))
+
+	for _, expected := range []bool{true, true, false} {
+		msg := fetch(t, consumer)
+		assert.Equal(t, expected, IsReplay(msg), "message %s", msg.Value)
+	}
+
+	assert.False(t, IsReplay(fetch(t, audit)), "the messages of the other groups aren't marked")
+}
+
+func TestReplayContext(t *testing.T) {
+	assert.Equal(t, "", ReplayFromContext(context.Background()))
+	assert.Equal(t, "replay-1", ReplayFromContext(WithReplay(context.Background(), "replay-1")))
+}
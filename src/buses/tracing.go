@@ -0,0 +1,49 @@
+package buses
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel"
+)
+
+// headerCarrier reads and writes the trace context in the message headers.
+type headerCarrier struct {
+	msg *Message
+}
+
+func (c headerCarrier) Get(key string) string {
+	value, _ := header(*c.msg, key)
+	return value
+}
+
+func (c headerCarrier) Set(key string, value string) {
+	for i, header := range c.msg.Headers {
+		if header.Key == key {
+			c.msg.Headers[i].Value = []byte(value)
+			return
+		}
+	}
+
+	c.msg.Headers = append(c.msg.Headers, Header{Key: key, Value: []byte(value)})
+}
+
+func (c headerCarrier) Keys() []string {
+	keys := make([]string, 0, len(c.msg.Headers))
+	for _, header := range c.msg.Headers {
+		keys = append(keys, header.Key)
+	}
+
+	return keys
+}
+
+// InjectTraceContext adds the trace context of ctx to the message headers so
+// the consumer spans continue the trace of the producer.
+func InjectTraceContext(ctx context.Context, msg *Message) {
+	otel.GetTextMapPropagator().Inject(ctx, headerCarrier{msg})
+}
+
+// ExtractTraceContext returns ctx with the trace context of the message
+// headers, messages published without one start a new trace.
+func ExtractTraceContext(ctx context.Context, msg Message) context.Context {
+	return otel.GetTextMapPropagator().Extract(ctx, headerCarrier{&msg})
+}
@@ -0,0 +1,38 @@
+package buses
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestTraceContextPropagation(t *testing.T) {
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	defer otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator())
+
+	provider := sdktrace.NewTracerProvider()
+	ctx, span := provider.Tracer("test").Start(context.Background(), "publish image_uploaded")
+	defer span.End()
+
+	msg := Message{Headers: []Header{
+		{Key: EventTypeHeader, Value: []byte(ImageUploadedEvent)},
+		{Key: "traceparent", Value: []byte("stale")},
+	}}
+	InjectTraceContext(ctx, &msg)
+
+	assert.Len(t, msg.Headers, 2, "the trace context replaces the existing header")
+	assert.Equal(t, ImageUploadedEvent, EventType(msg))
+
+	extracted := trace.SpanContextFromContext(ExtractTraceContext(context.Background(), msg))
+	assert.True(t, extracted.IsRemote())
+	assert.Equal(t, span.SpanContext().TraceID(), extracted.TraceID())
+	assert.Equal(t, span.SpanContext().SpanID(), extracted.SpanID())
+
+	withoutTrace := trace.SpanContextFromContext(ExtractTraceContext(context.Background(), Message{}))
+	assert.False(t, withoutTrace.IsValid(), "messages without trace context start a new trace")
+}
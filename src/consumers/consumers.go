@@ -3,20 +3,36 @@ package consumers
 import (
 	"context"
 
-	"github.com/tam-code/image-upload/src/kafka"
+	"github.com/tam-code/image-upload/config"
+	"github.com/tam-code/image-upload/src/broadcasters"
+	"github.com/tam-code/image-upload/src/buses"
+	"github.com/tam-code/image-upload/src/handlers"
 	"github.com/tam-code/image-upload/src/repositories"
 )
 
 type Consumers struct {
-	imageUploadedConsumer ImageUploadedConsumer
+	imageEventsConsumer ImageEventsConsumer
 }
 
-func NewConsumers(c kafka.Consumer, repositories *repositories.Repositories) *Consumers {
+// NewConsumers registers the handlers of the image events, each one logged,
+// retried and measured.
+func NewConsumers(cfg *config.Config, c buses.Consumer, repositories *repositories.Repositories, broadcasters *broadcasters.Broadcasters) *Consumers {
+	dispatcher := handlers.NewDispatcher(cfg.Handlers, handlers.Logging, handlers.Retry(cfg.Handlers.Retry), handlers.Metrics)
+	dispatcher.Register(handlers.StatisticsHandler, buses.ImageUploadedEvent, handlers.NewImageUploadedHandler(repositories, broadcasters))
+	dispatcher.Register(handlers.StatisticsHandler, buses.ImageDeletedEvent, handlers.NewImageDeletedHandler(repositories, broadcasters))
+
+	// a handler by url, so a failing receiver is retried on its own
+	for _, url := range cfg.Handlers.Webhooks.URLs {
+		webhookHandler := handlers.NewWebhookHandler(url, cfg.Handlers.Webhooks)
+		dispatcher.Register(handlers.WebhooksHandler, buses.ImageUploadedEvent, webhookHandler)
+		dispatcher.Register(handlers.WebhooksHandler, buses.ImageDeletedEvent, webhookHandler)
+	}
+
 	return &Consumers{
-		imageUploadedConsumer: NewImageUploadedConsumer(c, repositories),
+		imageEventsConsumer: NewImageEventsConsumer(c, dispatcher),
 	}
 }
 
 func (c *Consumers) Run() {
-	go c.imageUploadedConsumer.Consume(context.Background())
+	go c.imageEventsConsumer.Consume(context.Background())
 }
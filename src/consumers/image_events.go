@@ -0,0 +1,104 @@
+package consumers
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"strconv"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/tam-code/image-upload/src/buses"
+	"github.com/tam-code/image-upload/src/handlers"
+	"github.com/tam-code/image-upload/src/loggers"
+	"github.com/tam-code/image-upload/src/metrics"
+)
+
+var tracer = otel.Tracer("github.com/tam-code/image-upload/src/consumers")
+
+type (
+	ImageEventsConsumer interface {
+		Consume(context.Context)
+	}
+
+	imageEventsConsumer struct {
+		consumer   buses.Consumer
+		dispatcher handlers.Dispatcher
+	}
+)
+
+func NewImageEventsConsumer(c buses.Consumer, dispatcher handlers.Dispatcher) ImageEventsConsumer {
+	return &imageEventsConsumer{
+		consumer:   c,
+		dispatcher: dispatcher,
+	}
+}
+
+func (c *imageEventsConsumer) Consume(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			slog.Error("consumer canceled", "error", ctx.Err())
+			os.Exit(1)
+		default:
+			// Fetch message from the bus
+			msg, err := c.consumer.FetchMessage(ctx)
+			if err != nil {
+				slog.Error("error fetching message", "error", err)
+				os.Exit(1)
+			}
+
+			// the high water mark is the offset of the next message produced
+			metrics.KafkaConsumerLag.WithLabelValues(msg.Topic, strconv.Itoa(msg.Partition)).Set(float64(max(0, msg.HighWaterMark-msg.Offset-1)))
+
+			c.handle(ctx, msg)
+
+			// Commit message, commits are cumulative so the offset of a message
+			// that failed is committed along with the next one
+			if err := c.consumer.CommitMessages(ctx, msg); err != nil {
+				metrics.KafkaCommitErrors.WithLabelValues(msg.Topic).Inc()
+				slog.Error("error committing message", "topic", msg.Topic, "partition", msg.Partition, "offset", msg.Offset, "error", err)
+			}
+		}
+	}
+}
+
+// handle dispatches the message to the handlers of its event in a consumer
+// span continuing the trace of the producer, with a logger scoped to the
+// message.
+func (c *imageEventsConsumer) handle(ctx context.Context, msg buses.Message) {
+	eventType := buses.EventType(msg)
+
+	ctx, span := tracer.Start(buses.ExtractTraceContext(ctx, msg), "process "+eventType,
+		trace.WithSpanKind(trace.SpanKindConsumer),
+		trace.WithAttributes(
+			attribute.String("messaging.system", "kafka"),
+			attribute.String("messaging.destination.name", msg.Topic),
+			attribute.Int("messaging.kafka.destination.partition", msg.Partition),
+			attribute.Int64("messaging.kafka.message.offset", msg.Offset),
+			attribute.String("event_type", eventType),
+		),
+	)
+	defer span.End()
+
+	logger := slog.Default().With(
+		slog.String("topic", msg.Topic),
+		slog.Int("partition", msg.Partition),
+		slog.Int64("offset", msg.Offset),
+		slog.String("event_type", eventType),
+		slog.String("trace_id", span.SpanContext().TraceID().String()),
+	)
+	if buses.IsReplay(msg) {
+		logger = logger.With(slog.Bool("replay", true))
+		span.SetAttributes(attribute.Bool("replay", true))
+	}
+	ctx = loggers.WithLogger(ctx, logger)
+
+	start := time.Now()
+	c.dispatcher.Dispatch(ctx, msg)
+
+	metrics.KafkaHandleDuration.WithLabelValues(eventType).Observe(time.Since(start).Seconds())
+}
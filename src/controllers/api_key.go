@@ -0,0 +1,194 @@
+package controllers
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"github.com/tam-code/image-upload/src/middleware"
+	"github.com/tam-code/image-upload/src/models"
+	"github.com/tam-code/image-upload/src/repositories"
+)
+
+const (
+	apiKeyPrefix        = "iuk_"
+	apiKeySecretBytes   = 32
+	apiKeyVisiblePrefix = len(apiKeyPrefix) + 8
+)
+
+type (
+	APIKeyController interface {
+		CreateAPIKey(w http.ResponseWriter, r *http.Request)
+		ListAPIKeys(w http.ResponseWriter, r *http.Request)
+		RevokeAPIKey(w http.ResponseWriter, r *http.Request)
+	}
+
+	apiKeyController struct {
+		apiKeyRepo repositories.APIKeyRepository
+	}
+)
+
+func NewAPIKeyController(repositories *repositories.Repositories) APIKeyController {
+	return &apiKeyController{
+		apiKeyRepo: repositories.APIKey,
+	}
+}
+
+// CreateAPIKey mints a key, its secret is only returned in this response.
+func (c *apiKeyController) CreateAPIKey(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Error parsing form, "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	name := strings.TrimSpace(r.FormValue("name"))
+	if name == "" {
+		http.Error(w, "Name is required", http.StatusBadRequest)
+		return
+	}
+
+	// scopes can be repeated or comma separated
+	var scopes []models.Scope
+	for _, value := range r.Form["scopes"] {
+		for _, scope := range strings.Split(value, ",") {
+			if scope = strings.TrimSpace(scope); scope == "" {
+				continue
+			}
+
+			if !models.Scope(scope).IsValid() {
+				http.Error(w, "Invalid scope "+scope, http.StatusBadRequest)
+				return
+			}
+			scopes = append(scopes, models.Scope(scope))
+		}
+	}
+
+	if len(scopes) == 0 {
+		http.Error(w, "At least one scope is required", http.StatusBadRequest)
+		return
+	}
+
+	// keys belong to the tenant of their creator, crossing tenants requires
+	// the tenants:manage scope
+	tenant, ok := requestTenant(w, r)
+	if !ok {
+		return
+	}
+
+	principal := middleware.PrincipalFromContext(r.Context())
+	canManageTenants := principal != nil && principal.HasScope(models.ScopeTenantsManage)
+
+	if value := r.FormValue("tenant"); value != "" && value != tenant {
+		if !models.IsValidTenant(value) {
+			http.Error(w, "Invalid tenant, it must be lowercase letters, digits, - or _", http.StatusBadRequest)
+			return
+		}
+
+		if !canManageTenants {
+			http.Error(w, "Forbidden, missing scope "+string(models.ScopeTenantsManage), http.StatusForbidden)
+			return
+		}
+		tenant = value
+	}
+
+	for _, scope := range scopes {
+		if scope == models.ScopeTenantsManage && !canManageTenants {
+			http.Error(w, "Forbidden, missing scope "+string(models.ScopeTenantsManage), http.StatusForbidden)
+			return
+		}
+	}
+
+	apiKey := models.APIKey{
+		Name:      name,
+		Tenant:    tenant,
+		Scopes:    scopes,
+		CreatedAt: time.Now(),
+	}
+
+	if expiration := r.FormValue("expiration"); expiration != "" {
+		expirationTime, err := time.Parse(time.RFC3339, expiration)
+		if err != nil {
+			http.Error(w, "Invalid expiration, it must be ISO8601 format e.g. 2007-10-09T22:50:01.23Z", http.StatusBadRequest)
+			return
+		}
+
+		if expirationTime.Before(time.Now()) {
+			http.Error(w, "Expiration must be in the future", http.StatusBadRequest)
+			return
+		}
+		apiKey.ExpiresAt = &expirationTime
+	}
+
+	secret, err := newAPIKeySecret()
+	if err != nil {
+		http.Error(w, "Error generating api key", http.StatusInternalServerError)
+		return
+	}
+	apiKey.Hash = models.HashAPIKey(secret)
+	apiKey.Prefix = secret[:apiKeyVisiblePrefix]
+
+	createdAPIKey, err := c.apiKeyRepo.CreateAPIKey(apiKey)
+	if err != nil {
+		http.Error(w, "Error creating api key", http.StatusInternalServerError)
+		return
+	}
+
+	middleware.AddAuditTargets(r.Context(), createdAPIKey.ID)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(models.MintedAPIKey{Key: secret, APIKey: *createdAPIKey})
+}
+
+func (c *apiKeyController) ListAPIKeys(w http.ResponseWriter, r *http.Request) {
+	tenant, ok := requestTenant(w, r)
+	if !ok {
+		return
+	}
+
+	apiKeys, err := c.apiKeyRepo.ListAPIKeys(tenant)
+	if err != nil {
+		http.Error(w, "Error listing api keys", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(apiKeys)
+}
+
+func (c *apiKeyController) RevokeAPIKey(w http.ResponseWriter, r *http.Request) {
+	apiKeyID := mux.Vars(r)["api_key_id"]
+	middleware.AddAuditTargets(r.Context(), apiKeyID)
+
+	tenant, ok := requestTenant(w, r)
+	if !ok {
+		return
+	}
+
+	revoked, err := c.apiKeyRepo.RevokeAPIKey(tenant, apiKeyID, time.Now())
+	if err != nil {
+		http.Error(w, "Error revoking api key", http.StatusInternalServerError)
+		return
+	}
+
+	if !revoked {
+		http.Error(w, "Invalid api key id or not found", http.StatusNotFound)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func newAPIKeySecret() (string, error) {
+	secret := make([]byte, apiKeySecretBytes)
+	if _, err := rand.Read(secret); err != nil {
+		return "", err
+	}
+
+	return apiKeyPrefix + base64.RawURLEncoding.EncodeToString(secret), nil
+}
@@ -0,0 +1,234 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	mocks "github.com/tam-code/image-upload/mocks/repositories"
+	"github.com/tam-code/image-upload/src/middleware"
+	"github.com/tam-code/image-upload/src/models"
+)
+
+func TestCreateAPIKey(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockAPIKeyRepo := mocks.NewMockAPIKeyRepository(ctrl)
+
+	controller := &apiKeyController{
+		apiKeyRepo: mockAPIKeyRepo,
+	}
+
+	tenantsManager := &models.Principal{Subject: "admin", Tenant: models.DefaultTenant, Scopes: []models.Scope{models.ScopeKeysManage, models.ScopeTenantsManage}}
+	keysManager := &models.Principal{Subject: "manager", Tenant: "marketing", Scopes: []models.Scope{models.ScopeKeysManage}}
+
+	tests := []struct {
+		name           string
+		form           url.Values
+		principal      *models.Principal
+		mockRepoFunc   func()
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:           "missing name",
+			form:           url.Values{"scopes": {"stats:read"}},
+			mockRepoFunc:   func() {},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   "Name is required",
+		},
+		{
+			name:           "missing scopes",
+			form:           url.Values{"name": {"dashboard"}},
+			mockRepoFunc:   func() {},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   "At least one scope is required",
+		},
+		{
+			name:           "invalid scope",
+			form:           url.Values{"name": {"dashboard"}, "scopes": {"stats:write"}},
+			mockRepoFunc:   func() {},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   "Invalid scope stats:write",
+		},
+		{
+			name:           "expiration in the past",
+			form:           url.Values{"name": {"dashboard"}, "scopes": {"stats:read"}, "expiration": {"2001-01-01T00:00:00Z"}},
+			mockRepoFunc:   func() {},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   "Expiration must be in the future",
+		},
+		{
+			name:           "invalid tenant",
+			form:           url.Values{"name": {"dashboard"}, "scopes": {"stats:read"}, "tenant": {"../sales"}},
+			principal:      tenantsManager,
+			mockRepoFunc:   func() {},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   "Invalid tenant",
+		},
+		{
+			name:           "other tenant without tenants scope",
+			form:           url.Values{"name": {"dashboard"}, "scopes": {"stats:read"}, "tenant": {"sales"}},
+			principal:      keysManager,
+			mockRepoFunc:   func() {},
+			expectedStatus: http.StatusForbidden,
+			expectedBody:   "missing scope tenants:manage",
+		},
+		{
+			name:           "tenants scope without tenants scope",
+			form:           url.Values{"name": {"dashboard"}, "scopes": {"tenants:manage"}},
+			principal:      keysManager,
+			mockRepoFunc:   func() {},
+			expectedStatus: http.StatusForbidden,
+			expectedBody:   "missing scope tenants:manage",
+		},
+		{
+			name:      "key of the creator tenant",
+			form:      url.Values{"name": {"dashboard"}, "scopes": {"stats:read"}},
+			principal: keysManager,
+			mockRepoFunc: func() {
+				mockAPIKeyRepo.EXPECT().CreateAPIKey(gomock.Any()).DoAndReturn(func(apiKey models.APIKey) (*models.APIKey, error) {
+					apiKey.ID = "created"
+					return &apiKey, nil
+				})
+			},
+			expectedStatus: http.StatusCreated,
+			expectedBody:   `"tenant":"marketing"`,
+		},
+		{
+			name:      "key of another tenant",
+			form:      url.Values{"name": {"dashboard"}, "scopes": {"stats:read"}, "tenant": {"sales"}},
+			principal: tenantsManager,
+			mockRepoFunc: func() {
+				mockAPIKeyRepo.EXPECT().CreateAPIKey(gomock.Any()).DoAndReturn(func(apiKey models.APIKey) (*models.APIKey, error) {
+					apiKey.ID = "created"
+					return &apiKey, nil
+				})
+			},
+			expectedStatus: http.StatusCreated,
+			expectedBody:   `"tenant":"sales"`,
+		},
+		{
+			name: "repository error",
+			form: url.Values{"name": {"dashboard"}, "scopes": {"stats:read"}},
+			mockRepoFunc: func() {
+				mockAPIKeyRepo.EXPECT().CreateAPIKey(gomock.Any()).Return(nil, errors.New("db error"))
+			},
+			expectedStatus: http.StatusInternalServerError,
+			expectedBody:   "Error creating api key",
+		},
+		{
+			name: "successful creation",
+			form: url.Values{"name": {"dashboard"}, "scopes": {"stats:read,images:read", "links:create"}, "expiration": {"2047-10-09T22:50:01Z"}},
+			mockRepoFunc: func() {
+				mockAPIKeyRepo.EXPECT().CreateAPIKey(gomock.Any()).DoAndReturn(func(apiKey models.APIKey) (*models.APIKey, error) {
+					assert.Equal(t, "dashboard", apiKey.Name)
+					assert.Equal(t, []models.Scope{models.ScopeStatsRead, models.ScopeImagesRead, models.ScopeLinksCreate}, apiKey.Scopes)
+					assert.Equal(t, time.Date(2047, 10, 9, 22, 50, 1, 0, time.UTC), *apiKey.ExpiresAt)
+					apiKey.ID = "created"
+					return &apiKey, nil
+				})
+			},
+			expectedStatus: http.StatusCreated,
+			expectedBody:   `"id":"created"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockRepoFunc()
+
+			req := httptest.NewRequest(http.MethodPost, "/api-keys", strings.NewReader(tt.form.Encode()))
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			if tt.principal != nil {
+				req = req.WithContext(middleware.WithPrincipal(req.Context(), tt.principal))
+			} else {
+				req = withTenant(req, models.DefaultTenant)
+			}
+			w := httptest.NewRecorder()
+
+			controller.CreateAPIKey(w, req)
+
+			resp := w.Result()
+			bodyBytes, _ := io.ReadAll(resp.Body)
+
+			assert.Equal(t, tt.expectedStatus, resp.StatusCode)
+			assert.Contains(t, string(bodyBytes), tt.expectedBody)
+
+			if resp.StatusCode == http.StatusCreated {
+				var minted models.MintedAPIKey
+				assert.NoError(t, json.Unmarshal(bodyBytes, &minted))
+				assert.True(t, strings.HasPrefix(minted.Key, apiKeyPrefix))
+				assert.Equal(t, minted.Key[:apiKeyVisiblePrefix], minted.Prefix)
+				assert.NotContains(t, string(bodyBytes), models.HashAPIKey(minted.Key))
+			}
+		})
+	}
+}
+
+func TestRevokeAPIKey(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockAPIKeyRepo := mocks.NewMockAPIKeyRepository(ctrl)
+
+	controller := &apiKeyController{
+		apiKeyRepo: mockAPIKeyRepo,
+	}
+
+	tests := []struct {
+		name           string
+		apiKeyID       string
+		mockRepoFunc   func()
+		expectedStatus int
+	}{
+		{
+			name:     "not found",
+			apiKeyID: "missing",
+			mockRepoFunc: func() {
+				mockAPIKeyRepo.EXPECT().RevokeAPIKey(models.DefaultTenant, "missing", gomock.Any()).Return(false, nil)
+			},
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name:     "repository error",
+			apiKeyID: "valid",
+			mockRepoFunc: func() {
+				mockAPIKeyRepo.EXPECT().RevokeAPIKey(models.DefaultTenant, "valid", gomock.Any()).Return(false, errors.New("db error"))
+			},
+			expectedStatus: http.StatusInternalServerError,
+		},
+		{
+			name:     "successful revocation",
+			apiKeyID: "valid",
+			mockRepoFunc: func() {
+				mockAPIKeyRepo.EXPECT().RevokeAPIKey(models.DefaultTenant, "valid", gomock.Any()).Return(true, nil)
+			},
+			expectedStatus: http.StatusNoContent,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockRepoFunc()
+
+			req := withTenant(httptest.NewRequest(http.MethodDelete, "/api-keys/{api_key_id}", nil), models.DefaultTenant)
+			req = mux.SetURLVars(req, map[string]string{"api_key_id": tt.apiKeyID})
+			w := httptest.NewRecorder()
+
+			controller.RevokeAPIKey(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+		})
+	}
+}
@@ -0,0 +1,101 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/tam-code/image-upload/src/middleware"
+	"github.com/tam-code/image-upload/src/models"
+	"github.com/tam-code/image-upload/src/repositories"
+)
+
+type (
+	AuditController interface {
+		ListAuditEvents(w http.ResponseWriter, r *http.Request)
+	}
+
+	auditController struct {
+		auditRepo repositories.AuditRepository
+	}
+)
+
+func NewAuditController(repositories *repositories.Repositories) AuditController {
+	return &auditController{
+		auditRepo: repositories.Audit,
+	}
+}
+
+// ListAuditEvents returns the audit events of the tenant newest first, reading
+// another tenant's events requires the tenants:manage scope.
+func (c *auditController) ListAuditEvents(w http.ResponseWriter, r *http.Request) {
+	filter, err := parseAuditFilter(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	tenant, ok := requestTenant(w, r)
+	if !ok {
+		return
+	}
+
+	filter.Tenant = tenant
+	if value := r.URL.Query().Get("tenant"); value != "" && value != filter.Tenant {
+		principal := middleware.PrincipalFromContext(r.Context())
+		if principal == nil || !principal.HasScope(models.ScopeTenantsManage) {
+			http.Error(w, "Forbidden, missing scope "+string(models.ScopeTenantsManage), http.StatusForbidden)
+			return
+		}
+		filter.Tenant = value
+	}
+
+	page, err := c.auditRepo.ListAuditEvents(*filter)
+	if err != nil {
+		if errors.Is(err, repositories.ErrInvalidCursor) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		http.Error(w, "Error listing audit events", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(page)
+}
+
+func parseAuditFilter(r *http.Request) (*models.AuditFilter, error) {
+	query := r.URL.Query()
+	filter := &models.AuditFilter{
+		Actor:    query.Get("actor"),
+		Action:   models.AuditAction(query.Get("action")),
+		TargetID: query.Get("targetID"),
+		Outcome:  models.AuditOutcome(query.Get("outcome")),
+		Cursor:   query.Get("cursor"),
+	}
+
+	var err error
+	for param, value := range map[string]*time.Time{
+		"from": &filter.From,
+		"to":   &filter.To,
+	} {
+		if query.Get(param) == "" {
+			continue
+		}
+
+		if *value, err = time.Parse(time.RFC3339, query.Get(param)); err != nil {
+			return nil, fmt.Errorf("invalid %s, it must be ISO8601 format e.g. 2007-10-09T22:50:01.23Z", param)
+		}
+	}
+
+	if query.Get("limit") != "" {
+		if filter.Limit, err = strconv.Atoi(query.Get("limit")); err != nil || filter.Limit < 0 {
+			return nil, fmt.Errorf("invalid limit, it must be a positive number")
+		}
+	}
+
+	return filter, nil
+}
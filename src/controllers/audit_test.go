@@ -0,0 +1,126 @@
+package controllers
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	mocks "github.com/tam-code/image-upload/mocks/repositories"
+	"github.com/tam-code/image-upload/src/middleware"
+	"github.com/tam-code/image-upload/src/models"
+	"github.com/tam-code/image-upload/src/repositories"
+)
+
+func TestListAuditEvents(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockAuditRepo := mocks.NewMockAuditRepository(ctrl)
+
+	controller := &auditController{
+		auditRepo: mockAuditRepo,
+	}
+
+	auditor := &models.Principal{Subject: "auditor", Tenant: "marketing", Scopes: []models.Scope{models.ScopeAuditRead}}
+	tenantsManager := &models.Principal{Subject: "admin", Tenant: models.DefaultTenant, Scopes: []models.Scope{models.ScopeAuditRead, models.ScopeTenantsManage}}
+	from, _ := time.Parse(time.RFC3339, "2024-01-02T00:00:00Z")
+
+	tests := []struct {
+		name           string
+		query          string
+		principal      *models.Principal
+		mockRepoFunc   func()
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:      "list the events of the principal tenant",
+			query:     "?actor=key-1&action=image.delete&targetID=image-1&outcome=denied&from=2024-01-02T00:00:00Z&limit=10",
+			principal: auditor,
+			mockRepoFunc: func() {
+				mockAuditRepo.EXPECT().ListAuditEvents(models.AuditFilter{
+					Tenant: "marketing", Actor: "key-1", Action: models.AuditImageDelete, TargetID: "image-1",
+					Outcome: models.AuditDenied, From: from, Limit: 10,
+				}).Return(&models.AuditPage{Events: []models.AuditEvent{{ID: "event-1", Tenant: "marketing"}}, NextCursor: "event-1"}, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `"nextCursor":"event-1"`,
+		},
+		{
+			name:           "another tenant requires tenants:manage",
+			query:          "?tenant=default",
+			principal:      auditor,
+			mockRepoFunc:   func() {},
+			expectedStatus: http.StatusForbidden,
+			expectedBody:   "Forbidden, missing scope tenants:manage",
+		},
+		{
+			name:      "list the events of another tenant",
+			query:     "?tenant=marketing",
+			principal: tenantsManager,
+			mockRepoFunc: func() {
+				mockAuditRepo.EXPECT().ListAuditEvents(models.AuditFilter{Tenant: "marketing"}).Return(&models.AuditPage{Events: []models.AuditEvent{}}, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `"events":[]`,
+		},
+		{
+			name:           "invalid from",
+			query:          "?from=yesterday",
+			principal:      auditor,
+			mockRepoFunc:   func() {},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   "invalid from",
+		},
+		{
+			name:           "invalid limit",
+			query:          "?limit=-1",
+			principal:      auditor,
+			mockRepoFunc:   func() {},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   "invalid limit",
+		},
+		{
+			name:      "invalid cursor",
+			query:     "?cursor=invalid",
+			principal: auditor,
+			mockRepoFunc: func() {
+				mockAuditRepo.EXPECT().ListAuditEvents(models.AuditFilter{Tenant: "marketing", Cursor: "invalid"}).Return(nil, repositories.ErrInvalidCursor)
+			},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   "invalid cursor",
+		},
+		{
+			name:      "repository error",
+			principal: auditor,
+			mockRepoFunc: func() {
+				mockAuditRepo.EXPECT().ListAuditEvents(gomock.Any()).Return(nil, errors.New("db error"))
+			},
+			expectedStatus: http.StatusInternalServerError,
+			expectedBody:   "Error listing audit events",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockRepoFunc()
+
+			req := httptest.NewRequest(http.MethodGet, "/audit-events"+tt.query, nil)
+			req = req.WithContext(middleware.WithPrincipal(req.Context(), tt.principal))
+			w := httptest.NewRecorder()
+
+			controller.ListAuditEvents(w, req)
+
+			resp := w.Result()
+			bodyBytes, _ := io.ReadAll(resp.Body)
+
+			assert.Equal(t, tt.expectedStatus, resp.StatusCode)
+			assert.Contains(t, string(bodyBytes), tt.expectedBody)
+		})
+	}
+}
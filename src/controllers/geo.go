@@ -0,0 +1,194 @@
+package controllers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+
+	"github.com/tam-code/image-upload/src/models"
+	"github.com/tam-code/image-upload/src/repositories"
+)
+
+const (
+	defaultGeoLimit = 100
+	maxGeoLimit     = 1000
+)
+
+type (
+	GeoController interface {
+		GetImagesInBoundingBox(w http.ResponseWriter, r *http.Request)
+		GetImagesNear(w http.ResponseWriter, r *http.Request)
+		GetImagesInPolygon(w http.ResponseWriter, r *http.Request)
+		ExportUploadLinkGeoJSON(w http.ResponseWriter, r *http.Request)
+	}
+
+	geoController struct {
+		imageRepo repositories.ImageRepository
+	}
+)
+
+func NewGeoController(repositories *repositories.Repositories) GeoController {
+	return &geoController{
+		imageRepo: repositories.Image,
+	}
+}
+
+func (c *geoController) GetImagesInBoundingBox(w http.ResponseWriter, r *http.Request) {
+	values, err := parseFloatParams(r, "minLng", "minLat", "maxLng", "maxLat")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	limit, err := parseGeoLimit(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	boundingBox := models.NewBoundingBox(values[0], values[1], values[2], values[3])
+	if err := boundingBox.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	tenant, ok := requestTenant(w, r)
+	if !ok {
+		return
+	}
+
+	images, err := c.imageRepo.GetImagesInBoundingBox(r.Context(), tenant, boundingBox, limit)
+	if err != nil {
+		http.Error(w, "Error getting images in bounding box", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(images)
+}
+
+func (c *geoController) GetImagesNear(w http.ResponseWriter, r *http.Request) {
+	values, err := parseFloatParams(r, "lng", "lat", "radius")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	limit, err := parseGeoLimit(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	point := models.NewGeoPoint(values[0], values[1])
+	if err := point.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if values[2] <= 0 {
+		http.Error(w, "radius must be a positive number of meters", http.StatusBadRequest)
+		return
+	}
+
+	tenant, ok := requestTenant(w, r)
+	if !ok {
+		return
+	}
+
+	images, err := c.imageRepo.GetImagesNear(r.Context(), tenant, point, values[2], limit)
+	if err != nil {
+		http.Error(w, "Error getting images near point", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(images)
+}
+
+func (c *geoController) GetImagesInPolygon(w http.ResponseWriter, r *http.Request) {
+	limit, err := parseGeoLimit(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var polygon models.GeoPolygon
+	if err := json.NewDecoder(r.Body).Decode(&polygon); err != nil {
+		http.Error(w, "Invalid polygon, it must be a GeoJSON polygon", http.StatusBadRequest)
+		return
+	}
+
+	if err := polygon.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	tenant, ok := requestTenant(w, r)
+	if !ok {
+		return
+	}
+
+	images, err := c.imageRepo.GetImagesWithin(r.Context(), tenant, &polygon, limit)
+	if err != nil {
+		http.Error(w, "Error getting images in polygon", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(images)
+}
+
+func (c *geoController) ExportUploadLinkGeoJSON(w http.ResponseWriter, r *http.Request) {
+	tenant, ok := requestTenant(w, r)
+	if !ok {
+		return
+	}
+
+	uploadLinkID := mux.Vars(r)["upload_link_id"]
+	images, err := c.imageRepo.GetGeotaggedImagesByUploadLinkID(r.Context(), tenant, uploadLinkID)
+	if err != nil {
+		http.Error(w, "Error getting geotagged images", http.StatusInternalServerError)
+		return
+	}
+
+	featureCollection := models.GeoFeatureCollection{
+		Type:     models.GeoJSONFeatureCollection,
+		Features: []models.GeoFeature{},
+	}
+	for _, image := range images {
+		featureCollection.Features = append(featureCollection.Features, image.GeoFeature())
+	}
+
+	w.Header().Set("Content-Type", "application/geo+json")
+	json.NewEncoder(w).Encode(featureCollection)
+}
+
+func parseFloatParams(r *http.Request, params ...string) ([]float64, error) {
+	values := make([]float64, len(params))
+	for i, param := range params {
+		value, err := strconv.ParseFloat(r.URL.Query().Get(param), 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s, it must be a number", param)
+		}
+		values[i] = value
+	}
+
+	return values, nil
+}
+
+func parseGeoLimit(r *http.Request) (int, error) {
+	if r.URL.Query().Get("limit") == "" {
+		return defaultGeoLimit, nil
+	}
+
+	limit, err := strconv.Atoi(r.URL.Query().Get("limit"))
+	if err != nil || limit <= 0 || limit > maxGeoLimit {
+		return 0, fmt.Errorf("invalid limit, it must be between 1 and %d", maxGeoLimit)
+	}
+
+	return limit, nil
+}
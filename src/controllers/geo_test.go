@@ -0,0 +1,290 @@
+package controllers
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	mocks "github.com/tam-code/image-upload/mocks/repositories"
+	"github.com/tam-code/image-upload/src/models"
+)
+
+func TestGetImagesInBoundingBox(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockImageRepo := mocks.NewMockImageRepository(ctrl)
+
+	controller := &geoController{
+		imageRepo: mockImageRepo,
+	}
+
+	tests := []struct {
+		name           string
+		query          string
+		mockRepoFunc   func()
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:           "missing coordinates",
+			query:          "minLng=1&minLat=2",
+			mockRepoFunc:   func() {},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   "invalid maxLng",
+		},
+		{
+			name:           "inverted latitudes",
+			query:          "minLng=1&minLat=10&maxLng=10&maxLat=1",
+			mockRepoFunc:   func() {},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   "minLat must be lower than maxLat",
+		},
+		{
+			name:  "box crossing the antimeridian",
+			query: "minLng=170&minLat=-20&maxLng=-170&maxLat=-10",
+			mockRepoFunc: func() {
+				mockImageRepo.EXPECT().GetImagesInBoundingBox(gomock.Any(), models.DefaultTenant, models.NewBoundingBox(170, -20, -170, -10), defaultGeoLimit).Return([]models.Image{{ID: "fiji"}}, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `"id":"fiji"`,
+		},
+		{
+			name:           "latitude out of range",
+			query:          "minLng=1&minLat=-100&maxLng=2&maxLat=2",
+			mockRepoFunc:   func() {},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   "latitude must be between -90 and 90",
+		},
+		{
+			name:  "error getting images",
+			query: "minLng=1&minLat=1&maxLng=2&maxLat=2",
+			mockRepoFunc: func() {
+				mockImageRepo.EXPECT().GetImagesInBoundingBox(gomock.Any(), models.DefaultTenant, models.NewBoundingBox(1, 1, 2, 2), defaultGeoLimit).Return(nil, errors.New("error"))
+			},
+			expectedStatus: http.StatusInternalServerError,
+			expectedBody:   "Error getting images in bounding box",
+		},
+		{
+			name:  "successful retrieval",
+			query: "minLng=1&minLat=1&maxLng=2&maxLat=2&limit=10",
+			mockRepoFunc: func() {
+				mockImageRepo.EXPECT().GetImagesInBoundingBox(gomock.Any(), models.DefaultTenant, models.NewBoundingBox(1, 1, 2, 2), 10).Return([]models.Image{{ID: "valid", Name: "test_image"}}, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `"id":"valid","name":"test_image"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockRepoFunc()
+
+			req := withTenant(httptest.NewRequest(http.MethodGet, "/images/geo/bbox?"+tt.query, nil), models.DefaultTenant)
+			w := httptest.NewRecorder()
+
+			controller.GetImagesInBoundingBox(w, req)
+
+			resp := w.Result()
+			bodyBytes, _ := io.ReadAll(resp.Body)
+			bodyString := strings.TrimSpace(string(bodyBytes))
+
+			assert.Equal(t, tt.expectedStatus, resp.StatusCode)
+			assert.Contains(t, bodyString, tt.expectedBody)
+		})
+	}
+}
+
+func TestGetImagesNear(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockImageRepo := mocks.NewMockImageRepository(ctrl)
+
+	controller := &geoController{
+		imageRepo: mockImageRepo,
+	}
+
+	tests := []struct {
+		name           string
+		query          string
+		mockRepoFunc   func()
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:           "invalid radius",
+			query:          "lng=1&lat=1&radius=0",
+			mockRepoFunc:   func() {},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   "radius must be a positive number of meters",
+		},
+		{
+			name:           "invalid limit",
+			query:          "lng=1&lat=1&radius=10&limit=5000",
+			mockRepoFunc:   func() {},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   "invalid limit",
+		},
+		{
+			name:  "successful retrieval",
+			query: "lng=13.4&lat=52.5&radius=1000",
+			mockRepoFunc: func() {
+				mockImageRepo.EXPECT().GetImagesNear(gomock.Any(), models.DefaultTenant, models.NewGeoPoint(13.4, 52.5), 1000.0, defaultGeoLimit).Return([]models.Image{{ID: "valid"}}, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `"id":"valid"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockRepoFunc()
+
+			req := withTenant(httptest.NewRequest(http.MethodGet, "/images/geo/near?"+tt.query, nil), models.DefaultTenant)
+			w := httptest.NewRecorder()
+
+			controller.GetImagesNear(w, req)
+
+			resp := w.Result()
+			bodyBytes, _ := io.ReadAll(resp.Body)
+			bodyString := strings.TrimSpace(string(bodyBytes))
+
+			assert.Equal(t, tt.expectedStatus, resp.StatusCode)
+			assert.Contains(t, bodyString, tt.expectedBody)
+		})
+	}
+}
+
+func TestGetImagesInPolygon(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockImageRepo := mocks.NewMockImageRepository(ctrl)
+
+	controller := &geoController{
+		imageRepo: mockImageRepo,
+	}
+
+	tests := []struct {
+		name           string
+		body           string
+		mockRepoFunc   func()
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:           "invalid body",
+			body:           "invalid",
+			mockRepoFunc:   func() {},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   "Invalid polygon",
+		},
+		{
+			name:           "open ring",
+			body:           `{"type":"Polygon","coordinates":[[[0,0],[1,0],[1,1],[0,1]]]}`,
+			mockRepoFunc:   func() {},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   "polygon ring must be closed",
+		},
+		{
+			name: "successful retrieval",
+			body: `{"type":"Polygon","coordinates":[[[0,0],[1,0],[1,1],[0,1],[0,0]]]}`,
+			mockRepoFunc: func() {
+				mockImageRepo.EXPECT().GetImagesWithin(gomock.Any(), models.DefaultTenant, &models.GeoPolygon{Type: models.GeoJSONPolygon, Coordinates: [][][]float64{{{0, 0}, {1, 0}, {1, 1}, {0, 1}, {0, 0}}}}, defaultGeoLimit).Return([]models.Image{{ID: "valid"}}, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `"id":"valid"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockRepoFunc()
+
+			req := withTenant(httptest.NewRequest(http.MethodPost, "/images/geo/polygon", strings.NewReader(tt.body)), models.DefaultTenant)
+			w := httptest.NewRecorder()
+
+			controller.GetImagesInPolygon(w, req)
+
+			resp := w.Result()
+			bodyBytes, _ := io.ReadAll(resp.Body)
+			bodyString := strings.TrimSpace(string(bodyBytes))
+
+			assert.Equal(t, tt.expectedStatus, resp.StatusCode)
+			assert.Contains(t, bodyString, tt.expectedBody)
+		})
+	}
+}
+
+func TestExportUploadLinkGeoJSON(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockImageRepo := mocks.NewMockImageRepository(ctrl)
+
+	controller := &geoController{
+		imageRepo: mockImageRepo,
+	}
+
+	tests := []struct {
+		name           string
+		mockRepoFunc   func()
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name: "error getting images",
+			mockRepoFunc: func() {
+				mockImageRepo.EXPECT().GetGeotaggedImagesByUploadLinkID(gomock.Any(), models.DefaultTenant, "link").Return(nil, errors.New("error"))
+			},
+			expectedStatus: http.StatusInternalServerError,
+			expectedBody:   "Error getting geotagged images",
+		},
+		{
+			name: "empty collection",
+			mockRepoFunc: func() {
+				mockImageRepo.EXPECT().GetGeotaggedImagesByUploadLinkID(gomock.Any(), models.DefaultTenant, "link").Return(nil, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"type":"FeatureCollection","features":[]}`,
+		},
+		{
+			name: "successful export",
+			mockRepoFunc: func() {
+				mockImageRepo.EXPECT().GetGeotaggedImagesByUploadLinkID(gomock.Any(), models.DefaultTenant, "link").Return([]models.Image{{
+					ID:       "valid",
+					Name:     "test_image",
+					Location: models.NewGeoPoint(13.4, 52.5),
+				}}, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `"type":"Feature","id":"valid","geometry":{"type":"Point","coordinates":[13.4,52.5]}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockRepoFunc()
+
+			req := withTenant(httptest.NewRequest(http.MethodGet, "/upload-link/link/geo", nil), models.DefaultTenant)
+			req = mux.SetURLVars(req, map[string]string{"upload_link_id": "link"})
+			w := httptest.NewRecorder()
+
+			controller.ExportUploadLinkGeoJSON(w, req)
+
+			resp := w.Result()
+			bodyBytes, _ := io.ReadAll(resp.Body)
+			bodyString := strings.TrimSpace(string(bodyBytes))
+
+			assert.Equal(t, tt.expectedStatus, resp.StatusCode)
+			assert.Contains(t, bodyString, tt.expectedBody)
+		})
+	}
+}
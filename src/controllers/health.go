@@ -0,0 +1,52 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/tam-code/image-upload/src/healthcheckers"
+	"github.com/tam-code/image-upload/src/models"
+)
+
+type (
+	HealthController interface {
+		Live(w http.ResponseWriter, r *http.Request)
+		Ready(w http.ResponseWriter, r *http.Request)
+		Status(w http.ResponseWriter, r *http.Request)
+	}
+
+	healthController struct {
+		healthChecker healthcheckers.HealthChecker
+	}
+)
+
+func NewHealthController(healthChecker healthcheckers.HealthChecker) HealthController {
+	return &healthController{
+		healthChecker: healthChecker,
+	}
+}
+
+// Live tells the process serves requests, it doesn't check the dependencies
+// so an outage of one of them doesn't get the instance restarted.
+func (c *healthController) Live(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]models.HealthStatus{"status": models.HealthUp})
+}
+
+// Ready checks the dependencies, the instance shouldn't get traffic while
+// one of them is down.
+func (c *healthController) Ready(w http.ResponseWriter, r *http.Request) {
+	report := c.healthChecker.Ready(r.Context())
+
+	w.Header().Set("Content-Type", "application/json")
+	if report.Status != models.HealthUp {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	json.NewEncoder(w).Encode(report)
+}
+
+// Status returns the diagnostics of the instance.
+func (c *healthController) Status(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(c.healthChecker.Status(r.Context()))
+}
@@ -0,0 +1,90 @@
+package controllers
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	mocksHealth "github.com/tam-code/image-upload/mocks/healthcheckers"
+	"github.com/tam-code/image-upload/src/models"
+)
+
+func TestReady(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockHealthChecker := mocksHealth.NewMockHealthChecker(ctrl)
+
+	controller := &healthController{
+		healthChecker: mockHealthChecker,
+	}
+
+	tests := []struct {
+		name           string
+		report         *models.ReadinessReport
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name: "dependencies up",
+			report: &models.ReadinessReport{Status: models.HealthUp, Checks: []models.DependencyCheck{
+				{Name: "mongo", Status: models.HealthUp},
+			}},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `"status":"up"`,
+		},
+		{
+			name: "dependency down",
+			report: &models.ReadinessReport{Status: models.HealthDown, Checks: []models.DependencyCheck{
+				{Name: "mongo", Status: models.HealthUp},
+				{Name: "kafka", Status: models.HealthDown, Error: "connection refused"},
+			}},
+			expectedStatus: http.StatusServiceUnavailable,
+			expectedBody:   `"error":"connection refused"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockHealthChecker.EXPECT().Ready(gomock.Any()).Return(tt.report)
+
+			req := httptest.NewRequest("GET", "/readyz", nil)
+			w := httptest.NewRecorder()
+
+			controller.Ready(w, req)
+
+			body, _ := io.ReadAll(w.Body)
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			assert.Contains(t, string(body), tt.expectedBody)
+		})
+	}
+}
+
+func TestStatus(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockHealthChecker := mocksHealth.NewMockHealthChecker(ctrl)
+
+	controller := &healthController{
+		healthChecker: mockHealthChecker,
+	}
+
+	mockHealthChecker.EXPECT().Status(gomock.Any()).Return(&models.StatusReport{
+		Versions:    map[string]string{"go": "go1.22.0"},
+		ConfigHash:  "abc",
+		ConsumerLag: 3,
+		Readiness:   &models.ReadinessReport{Status: models.HealthUp, Checks: []models.DependencyCheck{}},
+	})
+
+	req := httptest.NewRequest("GET", "/debug/status", nil)
+	w := httptest.NewRecorder()
+
+	controller.Status(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"versions":{"go":"go1.22.0"},"configHash":"abc","consumerLag":3,"readiness":{"status":"up","checks":[]}}`, w.Body.String())
+}
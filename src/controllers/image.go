@@ -1,68 +1,153 @@
 package controllers
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"io"
-	"log"
 	"mime"
 	"mime/multipart"
 	"net/http"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"time"
 
-	"github.com/evanoberholster/imagemeta"
 	"github.com/gorilla/mux"
-
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/tam-code/image-upload/config"
+	"github.com/tam-code/image-upload/src/loggers"
+	"github.com/tam-code/image-upload/src/metadata"
+	"github.com/tam-code/image-upload/src/metrics"
+	"github.com/tam-code/image-upload/src/middleware"
 	"github.com/tam-code/image-upload/src/models"
 	"github.com/tam-code/image-upload/src/producers"
 	"github.com/tam-code/image-upload/src/repositories"
+	"github.com/tam-code/image-upload/src/storages"
 )
 
 const (
-	uploadPath = "./upload/"
+	uploadPath = storages.UploadPath
+
+	// MaxUploadBytes is the size of an uploaded file, and of an upload
+	// request retried with an Idempotency-Key, at most
+	MaxUploadBytes = 10 << 20
+)
+
+var tracer = otel.Tracer("github.com/tam-code/image-upload/src/controllers")
+
+var (
+	errImageTooLarge    = errors.New("file size exceeds 10MB")
+	errInvalidImageType = errors.New("invalid file type")
 )
 
 type (
 	ImageController interface {
 		UploadImage(w http.ResponseWriter, r *http.Request)
 		GetImage(w http.ResponseWriter, r *http.Request)
+		ListImages(w http.ResponseWriter, r *http.Request)
+		DeleteImage(w http.ResponseWriter, r *http.Request)
+		DeleteUploadLinkImages(w http.ResponseWriter, r *http.Request)
 	}
 
 	imageController struct {
 		uploadLinkRepo        repositories.UploadLinkRepository
 		imageRepo             repositories.ImageRepository
 		imageUploadedProducer producers.ImageUploadedProducer
+		imageDeletedProducer  producers.ImageDeletedProducer
+		settings              imageSettings
+		tenantSettings        map[string]imageSettings
+		imageStorage          storages.ImageStorage
+	}
+
+	// imageSettings are the upload settings of the deployment or of a tenant
+	// that overrides them
+	imageSettings struct {
+		privacyMode          models.PrivacyMode
+		normalizeOrientation bool
+		jpegQuality          int
+	}
+
+	// stagedImage is an image of the batch whose file isn't committed yet,
+	// outcome is its index in the partial upload report
+	stagedImage struct {
+		image      *models.Image
+		stagedPath string
+		size       int64
+		outcome    int
 	}
 )
 
-func NewImageController(repositories *repositories.Repositories, producers *producers.Producers) ImageController {
+func NewImageController(repositories *repositories.Repositories, producers *producers.Producers, cfg *config.Config) ImageController {
+	tenantSettings := make(map[string]imageSettings)
+	for _, tenant := range cfg.Tenancy.Tenants {
+		tenantSettings[tenant.ID] = newImageSettings(cfg.ForTenant(tenant.ID))
+	}
+
 	return &imageController{
 		uploadLinkRepo:        repositories.UploadLink,
 		imageRepo:             repositories.Image,
 		imageUploadedProducer: producers.ImageUploaded,
+		imageDeletedProducer:  producers.ImageDeleted,
+		settings:              newImageSettings(cfg),
+		tenantSettings:        tenantSettings,
+		imageStorage:          storages.NewImageStorage(storages.UploadPath),
 	}
 }
 
+func newImageSettings(cfg *config.Config) imageSettings {
+	return imageSettings{
+		privacyMode:          models.PrivacyMode(cfg.Privacy.DefaultMode),
+		normalizeOrientation: cfg.Orientation.Normalize,
+		jpegQuality:          cfg.Orientation.JpegQuality,
+	}
+}
+
+func (c *imageController) settingsFor(tenant string) imageSettings {
+	if settings, ok := c.tenantSettings[tenant]; ok {
+		return settings
+	}
+
+	return c.settings
+}
+
 func (c *imageController) UploadImage(w http.ResponseWriter, r *http.Request) {
 
+	logger := loggers.FromContext(r.Context())
+
 	uploadLinkId := mux.Vars(r)["upload_link_id"]
-	uploadLink, err := c.uploadLinkRepo.GetUploadLinkByID(uploadLinkId)
+	uploadLink, err := c.uploadLinkRepo.GetUploadLinkByID(r.Context(), uploadLinkId)
 	if err != nil {
 		http.Error(w, "Invalid upload link or not found", http.StatusNotFound)
 		return
 	}
 
+	middleware.SetAuditTenant(r.Context(), uploadLink.Tenant)
+	middleware.AddAuditTargets(r.Context(), uploadLinkId)
+
 	if uploadLink.ExpirationTime.Before(time.Now()) {
 		http.Error(w, "Upload link expired", http.StatusForbidden)
 		return
 	}
 
-	// Parse our multipart form, 10 << 20 specifies a maximum
+	// by default the batch is all or nothing, partial stores the valid files
+	// and reports the outcome of each one
+	partial := false
+	if value := r.URL.Query().Get("partial"); value != "" {
+		if partial, err = strconv.ParseBool(value); err != nil {
+			http.Error(w, "Invalid partial, it must be true or false", http.StatusBadRequest)
+			return
+		}
+	}
+
+	// Parse our multipart form, MaxUploadBytes specifies a maximum
 	// upload of 10 MB files.
-	err = r.ParseMultipartForm(10 << 20) // 10MB
+	err = r.ParseMultipartForm(MaxUploadBytes)
 	if err != nil {
 		http.Error(w, "Error parsing form, "+err.Error(), http.StatusBadRequest)
 		return
@@ -74,43 +159,115 @@ func (c *imageController) UploadImage(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	var images []interface{}
+	// the upload link privacy mode overrides the tenant one
+	settings := c.settingsFor(uploadLink.Tenant)
+	if uploadLink.PrivacyMode != "" {
+		settings.privacyMode = uploadLink.PrivacyMode
+	}
+
+	// files stay staged until the image records are inserted, whatever
+	// wasn't committed is removed on return
+	var staged []stagedImage
+	defer func() {
+		for _, s := range staged {
+			if err := c.imageStorage.Remove(s.stagedPath); err != nil {
+				logger.Error("error removing staged image", "path", s.stagedPath, "error", err)
+			}
+		}
+	}()
+
+	var outcomes []models.ImageUploadOutcome
 	imagesMap := make(map[string]int)
 	for _, file := range files {
 		// check if file already uploaded, incase of multiple files with same name
 		_, ok := imagesMap[file.Filename]
 		if ok {
+			metrics.UploadedImages.WithLabelValues(string(models.ImageUploadDuplicate), "").Inc()
+			outcomes = append(outcomes, models.ImageUploadOutcome{Name: file.Filename, Status: models.ImageUploadDuplicate})
 			continue
 		}
 
 		imagesMap[file.Filename] = 1
 
-		image, err := c.handleFileUpload(file, uploadLinkId)
+		image, stagedPath, err := c.handleFileUpload(r.Context(), file, uploadLinkId, uploadLink.Tenant, settings)
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusBadRequest)
-			return
+			metrics.UploadedImages.WithLabelValues(string(models.ImageUploadRejected), rejectionReason(err)).Inc()
+			if !partial {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+
+			outcomes = append(outcomes, models.ImageUploadOutcome{Name: file.Filename, Status: models.ImageUploadRejected, Reason: err.Error()})
+			continue
 		}
 
-		if image != nil {
-			images = append(images, image)
+		if image == nil {
+			metrics.UploadedImages.WithLabelValues(string(models.ImageUploadDuplicate), "").Inc()
+			outcomes = append(outcomes, models.ImageUploadOutcome{Name: file.Filename, Status: models.ImageUploadDuplicate})
+			continue
+		}
+
+		staged = append(staged, stagedImage{image: image, stagedPath: stagedPath, size: file.Size, outcome: len(outcomes)})
+		outcomes = append(outcomes, models.ImageUploadOutcome{Name: file.Filename, Status: models.ImageUploadStored})
+	}
+
+	if len(staged) == 0 {
+		if partial {
+			writeImageUploadOutcomes(w, outcomes)
+			return
 		}
+
+		http.Error(w, "No images uploaded", http.StatusBadRequest)
+		return
 	}
 
-	insertedImages, err := c.imageRepo.InsertImages(images)
+	var images []interface{}
+	for _, s := range staged {
+		images = append(images, s.image)
+	}
+
+	insertedImages, err := c.imageRepo.InsertImages(r.Context(), images)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
-	if len(insertedImages) == 0 {
-		http.Error(w, "No images uploaded", http.StatusBadRequest)
+	if err = c.commitImages(r.Context(), uploadLink.Tenant, staged, insertedImages); err != nil {
+		logger.Error("error committing images", "error", err)
+		http.Error(w, "Error storing images", http.StatusInternalServerError)
 		return
 	}
 
+	committed := staged
+	staged = nil
+
+	for _, s := range committed {
+		metrics.UploadedImages.WithLabelValues(string(models.ImageUploadStored), "").Inc()
+		metrics.UploadedBytes.Add(float64(s.size))
+	}
+
+	middleware.AddAuditTargets(r.Context(), insertedImages...)
+
 	// publish images uploaded event
-	err = c.imageUploadedProducer.Publish(insertedImages)
+	event := models.ImageUploadedEvent{UploadLinkID: uploadLinkId, Tenant: uploadLink.Tenant}
+	for i, s := range committed {
+		image := *s.image
+		image.ID = insertedImages[i]
+		event.Images = append(event.Images, image)
+	}
+
+	err = c.imageUploadedProducer.Publish(r.Context(), event)
 	if err != nil {
-		log.Printf("error publishing images uploaded event: %v", err)
+		logger.Error("error publishing images uploaded event", "error", err)
+	}
+
+	if partial {
+		for i, s := range committed {
+			outcomes[s.outcome].ID = insertedImages[i]
+		}
+
+		writeImageUploadOutcomes(w, outcomes)
+		return
 	}
 
 	// return inserted images ids
@@ -118,46 +275,132 @@ func (c *imageController) UploadImage(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(insertedImages)
 }
 
-func (c *imageController) handleFileUpload(file *multipart.FileHeader, uploadLinkId string) (*models.Image, error) {
+// handleFileUpload validates the file and stages it with the metadata the
+// privacy mode allows. It returns a nil image for duplicates.
+func (c *imageController) handleFileUpload(ctx context.Context, file *multipart.FileHeader, uploadLinkId, tenant string, settings imageSettings) (*models.Image, string, error) {
+	ctx, span := tracer.Start(ctx, "process image", trace.WithAttributes(
+		attribute.String("image.name", file.Filename),
+		attribute.Int64("image.size", file.Size),
+	))
+	defer span.End()
+
 	// validate file
 	err := validateImage(file)
 	if err != nil {
-		return nil, err
+		span.SetStatus(codes.Error, err.Error())
+		return nil, "", err
 	}
 
 	// check duplicate image
-	imageExist, err := c.imageRepo.GetImageByNameAndUploadLinkID(file.Filename, uploadLinkId)
+	imageExist, err := c.imageRepo.GetImageByNameAndUploadLinkID(ctx, tenant, file.Filename, uploadLinkId)
 	if err != nil {
-		return nil, fmt.Errorf("error getting image by name: %w", err)
+		return nil, "", fmt.Errorf("error getting image by name: %w", err)
 	}
 
 	if imageExist != nil {
-		log.Printf("image already uploaded: %s", file.Filename)
-		return nil, nil
+		loggers.FromContext(ctx).Info("image already uploaded", "name", file.Filename)
+		return nil, "", nil
 	}
 
-	// upload file
-	loc, err := uploadImageSource(file, uploadLinkId)
+	// stage file
+	stagedPath, err := c.stageImageSource(file)
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
 
+	image, err := c.processStagedImage(ctx, file, uploadLinkId, tenant, settings, stagedPath)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "error processing image")
+		if removeErr := c.imageStorage.Remove(stagedPath); removeErr != nil {
+			loggers.FromContext(ctx).Error("error removing staged image", "path", stagedPath, "error", removeErr)
+		}
+		return nil, "", err
+	}
+
+	return image, stagedPath, nil
+}
+
+func (c *imageController) processStagedImage(ctx context.Context, file *multipart.FileHeader, uploadLinkId, tenant string, settings imageSettings, stagedPath string) (*models.Image, error) {
 	// create image model
 	image := models.Image{
 		Name:         file.Filename,
-		Path:         loc,
+		Tenant:       tenant,
+		Path:         c.imageStorage.Path(tenant, uploadLinkId, file.Filename),
 		UploadLinkID: uploadLinkId,
 		UploadedAt:   time.Now(),
 	}
 
-	adaptImageMetadata(&image)
+	adaptImageMetadata(ctx, &image, stagedPath)
+
+	// remove the metadata the privacy mode doesn't allow from the stored file and the record
+	err := metadata.Strip(stagedPath, settings.privacyMode)
+	if errors.Is(err, metadata.ErrStripUnsupported) {
+		loggers.FromContext(ctx).Warn("metadata not stripped", "name", file.Filename, "error", err)
+	} else if err != nil {
+		return nil, fmt.Errorf("error stripping image metadata: %w", err)
+	}
+
+	image.ApplyPrivacyMode(settings.privacyMode)
+
+	// rotate the stored pixels upright so consumers don't have to honor the orientation tag
+	if settings.normalizeOrientation {
+		normalized, err := metadata.NormalizeOrientation(stagedPath, settings.jpegQuality)
+		if errors.Is(err, metadata.ErrOrientationTooLarge) {
+			loggers.FromContext(ctx).Warn("orientation not normalized", "name", file.Filename, "error", err)
+		} else if err != nil {
+			return nil, fmt.Errorf("error normalizing image orientation: %w", err)
+		}
+
+		if normalized && image.Metadata != nil {
+			image.Metadata.Orientation = 1
+		}
+	}
 
 	return &image, nil
 }
 
+// commitImages moves the staged files to their final paths. If one fails the
+// batch is rolled back, the committed files and the inserted records are removed.
+func (c *imageController) commitImages(ctx context.Context, tenant string, staged []stagedImage, insertedImages []string) error {
+	for i, s := range staged {
+		err := c.imageStorage.Commit(s.stagedPath, s.image.Path)
+		if err == nil {
+			continue
+		}
+
+		for _, committed := range staged[:i] {
+			if removeErr := c.imageStorage.Remove(committed.image.Path); removeErr != nil {
+				loggers.FromContext(ctx).Error("error removing committed image", "path", committed.image.Path, "error", removeErr)
+			}
+		}
+
+		for _, id := range insertedImages {
+			if deleteErr := c.imageRepo.DeleteImage(ctx, tenant, id); deleteErr != nil {
+				loggers.FromContext(ctx).Error("error deleting image", "image_id", id, "error", deleteErr)
+			}
+		}
+
+		return err
+	}
+
+	return nil
+}
+
+func writeImageUploadOutcomes(w http.ResponseWriter, outcomes []models.ImageUploadOutcome) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusMultiStatus)
+	json.NewEncoder(w).Encode(outcomes)
+}
+
 func (c *imageController) GetImage(w http.ResponseWriter, r *http.Request) {
+	tenant, ok := requestTenant(w, r)
+	if !ok {
+		return
+	}
+
 	imageID := mux.Vars(r)["image_id"]
-	image, err := c.imageRepo.GetImageByID(imageID)
+	image, err := c.imageRepo.GetImageByID(r.Context(), tenant, imageID)
 	if err != nil {
 		http.Error(w, "Invalid image id or not found", http.StatusNotFound)
 		return
@@ -168,71 +411,226 @@ func (c *imageController) GetImage(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(image)
 }
 
-func uploadImageSource(file *multipart.FileHeader, uploadLinkID string) (string, error) {
-	loc := filepath.Join(uploadPath, uploadLinkID)
-	err := os.MkdirAll(loc, os.ModePerm)
+func (c *imageController) ListImages(w http.ResponseWriter, r *http.Request) {
+	filter, err := parseImageFilter(r)
 	if err != nil {
-		return loc, err
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	tenant, ok := requestTenant(w, r)
+	if !ok {
+		return
+	}
+
+	filter.Tenant = tenant
+
+	page, err := c.imageRepo.ListImages(r.Context(), *filter)
+	if err != nil {
+		if errors.Is(err, repositories.ErrInvalidCursor) || errors.Is(err, repositories.ErrInvalidSort) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		http.Error(w, "Error listing images", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(page)
+}
+
+// DeleteImage soft deletes the image, its file is removed once the purge delay elapsed.
+func (c *imageController) DeleteImage(w http.ResponseWriter, r *http.Request) {
+	tenant, ok := requestTenant(w, r)
+	if !ok {
+		return
+	}
+
+	imageID := mux.Vars(r)["image_id"]
+	middleware.AddAuditTargets(r.Context(), imageID)
+
+	image, err := c.imageRepo.SoftDeleteImage(r.Context(), tenant, imageID, time.Now())
+	if err != nil {
+		http.Error(w, "Error deleting image", http.StatusInternalServerError)
+		return
+	}
+
+	if image == nil {
+		http.Error(w, "Invalid image id or not found", http.StatusNotFound)
+		return
+	}
+
+	// publish image deleted event
+	if err = c.imageDeletedProducer.Publish(r.Context(), tenant, []string{image.ID}); err != nil {
+		loggers.FromContext(r.Context()).Error("error publishing image deleted event", "image_id", image.ID, "error", err)
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// DeleteUploadLinkImages soft deletes every image uploaded with the upload link.
+func (c *imageController) DeleteUploadLinkImages(w http.ResponseWriter, r *http.Request) {
+	tenant, ok := requestTenant(w, r)
+	if !ok {
+		return
+	}
+
+	uploadLinkID := mux.Vars(r)["upload_link_id"]
+	middleware.AddAuditTargets(r.Context(), uploadLinkID)
+
+	uploadLink, err := c.uploadLinkRepo.GetTenantUploadLinkByID(r.Context(), tenant, uploadLinkID)
+	if err != nil || uploadLink == nil {
+		http.Error(w, "Invalid upload link or not found", http.StatusNotFound)
+		return
 	}
 
-	loc = filepath.Join(loc, "/", file.Filename)
-	dst, err := os.Create(loc)
+	deletedImages, err := c.imageRepo.SoftDeleteImagesByUploadLinkID(r.Context(), tenant, uploadLinkID, time.Now())
 	if err != nil {
-		return loc, err
+		http.Error(w, "Error deleting images", http.StatusInternalServerError)
+		return
+	}
+
+	if len(deletedImages) > 0 {
+		middleware.AddAuditTargets(r.Context(), deletedImages...)
+
+		// publish images deleted event
+		if err = c.imageDeletedProducer.Publish(r.Context(), tenant, deletedImages); err != nil {
+			loggers.FromContext(r.Context()).Error("error publishing images deleted event", "upload_link_id", uploadLinkID, "error", err)
+		}
+	} else {
+		deletedImages = []string{}
 	}
-	defer dst.Close()
 
+	// return deleted images ids
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(deletedImages)
+}
+
+func parseImageFilter(r *http.Request) (*models.ImageFilter, error) {
+	query := r.URL.Query()
+	filter := &models.ImageFilter{
+		UploadLinkID: query.Get("uploadLinkID"),
+		CameraModel:  query.Get("cameraModel"),
+		ImageFormat:  query.Get("imageFormat"),
+		Sort:         query.Get("sort"),
+		Cursor:       query.Get("cursor"),
+		Make:         query.Get("make"),
+		LensModel:    query.Get("lensModel"),
+		Keyword:      query.Get("keyword"),
+	}
+
+	var err error
+	for param, value := range map[string]*time.Time{
+		"uploadedFrom": &filter.UploadedFrom,
+		"uploadedTo":   &filter.UploadedTo,
+		"capturedFrom": &filter.CapturedFrom,
+		"capturedTo":   &filter.CapturedTo,
+	} {
+		if query.Get(param) == "" {
+			continue
+		}
+
+		if *value, err = time.Parse(time.RFC3339, query.Get(param)); err != nil {
+			return nil, fmt.Errorf("invalid %s, it must be ISO8601 format e.g. 2007-10-09T22:50:01.23Z", param)
+		}
+	}
+
+	for param, value := range map[string]*int{
+		"minWidth":  &filter.MinWidth,
+		"maxWidth":  &filter.MaxWidth,
+		"minHeight": &filter.MinHeight,
+		"maxHeight": &filter.MaxHeight,
+		"minIso":    &filter.MinISO,
+		"maxIso":    &filter.MaxISO,
+		"limit":     &filter.Limit,
+	} {
+		if query.Get(param) == "" {
+			continue
+		}
+
+		if *value, err = strconv.Atoi(query.Get(param)); err != nil || *value < 0 {
+			return nil, fmt.Errorf("invalid %s, it must be a positive number", param)
+		}
+	}
+
+	for param, value := range map[string]**bool{
+		"hasGps":     &filter.HasGPS,
+		"flashFired": &filter.FlashFired,
+	} {
+		if query.Get(param) == "" {
+			continue
+		}
+
+		parsed, err := strconv.ParseBool(query.Get(param))
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s, it must be true or false", param)
+		}
+		*value = &parsed
+	}
+
+	return filter, nil
+}
+
+func (c *imageController) stageImageSource(file *multipart.FileHeader) (string, error) {
 	f, err := file.Open()
 	if err != nil {
-		return loc, err
+		return "", err
 	}
 	defer f.Close()
 
-	if _, err := io.Copy(dst, f); err != nil {
-		return loc, err
-	}
+	return c.imageStorage.Stage(f)
+}
 
-	return loc, nil
+// rejectionReason labels the error of a rejected file.
+func rejectionReason(err error) string {
+	switch {
+	case errors.Is(err, errImageTooLarge):
+		return "too_large"
+	case errors.Is(err, errInvalidImageType):
+		return "invalid_type"
+	default:
+		return "processing_error"
+	}
 }
 
 func validateImage(file *multipart.FileHeader) error {
 	// validate file size
-	if file.Size > 10<<20 {
-		return fmt.Errorf("file size exceeds 10MB")
+	if file.Size > MaxUploadBytes {
+		return errImageTooLarge
 	}
 
 	// validate file type
 	ext := filepath.Ext(file.Filename)
 	mimeType := mime.TypeByExtension(ext)
 	if mimeType == "" || !strings.HasPrefix(mimeType, "image") {
-		return fmt.Errorf("invalid file type: %s", mimeType)
+		return fmt.Errorf("%w: %s", errInvalidImageType, mimeType)
 	}
 
 	return nil
 }
 
-func adaptImageMetadata(image *models.Image) {
+func adaptImageMetadata(ctx context.Context, image *models.Image, path string) {
 	// open file
-	f, err := os.Open("./" + image.Path)
+	f, err := os.Open(path)
 	if err != nil {
-		log.Printf("error opening image: %v", err)
+		loggers.FromContext(ctx).Error("error opening image", "path", path, "error", err)
 		return
 	}
 	defer f.Close()
 
 	// decode image
-	e, err := imagemeta.Decode(f)
+	e, imageMetadata, err := metadata.Read(f)
 	if err != nil {
-		log.Printf("error decoding image: %v", err)
+		loggers.FromContext(ctx).Warn("error decoding image", "path", path, "error", err)
 	}
 
-	// update image metadata
-	image.ImageWidth = int(e.ImageWidth)
-	image.ImageHeight = int(e.ImageHeight)
+	// update image metadata, dimensions are recorded as displayed
+	image.ImageWidth, image.ImageHeight = metadata.DisplayedDimensions(int(e.ImageWidth), int(e.ImageHeight), int(e.Orientation))
 
 	if e.GPS.Latitude() != 0 && e.GPS.Longitude() != 0 {
 		image.Latitude = e.GPS.Latitude()
 		image.Longitude = e.GPS.Longitude()
+		image.Location = models.NewGeoPoint(image.Longitude, image.Latitude)
 	}
 
 	if e.Model != "" {
@@ -242,4 +640,6 @@ func adaptImageMetadata(image *models.Image) {
 	if e.ImageType.String() != "" {
 		image.ImageFormat = e.ImageType.String()
 	}
+
+	image.Metadata = imageMetadata
 }
@@ -2,6 +2,7 @@ package controllers
 
 import (
 	"bytes"
+	"context"
 	"errors"
 	"io"
 	"mime/multipart"
@@ -18,6 +19,8 @@ import (
 	mocksProducer "github.com/tam-code/image-upload/mocks/producers"
 	mocks "github.com/tam-code/image-upload/mocks/repositories"
 	"github.com/tam-code/image-upload/src/models"
+	"github.com/tam-code/image-upload/src/repositories"
+	"github.com/tam-code/image-upload/src/storages"
 )
 
 func TestUploadImage(t *testing.T) {
@@ -33,10 +36,14 @@ func TestUploadImage(t *testing.T) {
 		uploadLinkRepo:        mockUploadLinkRepo,
 		imageRepo:             mockImageRepo,
 		imageUploadedProducer: mockImageUploadedProducer,
+		imageStorage:          storages.NewImageStorage(uploadPath),
 	}
 
 	tests := []struct {
 		name           string
+		query          string
+		files          []string
+		prepare        func()
 		uploadLinkID   string
 		uploadLink     *models.UploadLink
 		mockRepoFunc   func()
@@ -48,7 +55,7 @@ func TestUploadImage(t *testing.T) {
 			name:         "invalid upload link",
 			uploadLinkID: "invalid",
 			mockRepoFunc: func() {
-				mockUploadLinkRepo.EXPECT().GetUploadLinkByID("invalid").Return(nil, errors.New("not found"))
+				mockUploadLinkRepo.EXPECT().GetUploadLinkByID(gomock.Any(), "invalid").Return(nil, errors.New("not found"))
 			},
 			expectedStatus: http.StatusNotFound,
 			expectedBody:   "Invalid upload link or not found\n",
@@ -60,7 +67,7 @@ func TestUploadImage(t *testing.T) {
 				ExpirationTime: time.Now().Add(-time.Hour),
 			},
 			mockRepoFunc: func() {
-				mockUploadLinkRepo.EXPECT().GetUploadLinkByID("expired").Return(&models.UploadLink{
+				mockUploadLinkRepo.EXPECT().GetUploadLinkByID(gomock.Any(), "expired").Return(&models.UploadLink{
 					ExpirationTime: time.Now().Add(-time.Hour),
 				}, nil)
 			},
@@ -74,7 +81,7 @@ func TestUploadImage(t *testing.T) {
 				ExpirationTime: time.Now().Add(time.Hour),
 			},
 			mockRepoFunc: func() {
-				mockUploadLinkRepo.EXPECT().GetUploadLinkByID("valid").Return(&models.UploadLink{
+				mockUploadLinkRepo.EXPECT().GetUploadLinkByID(gomock.Any(), "valid").Return(&models.UploadLink{
 					ExpirationTime: time.Now().Add(time.Hour),
 				}, nil)
 			},
@@ -89,21 +96,114 @@ func TestUploadImage(t *testing.T) {
 				ExpirationTime: time.Now().Add(time.Hour),
 			},
 			mockRepoFunc: func() {
-				mockUploadLinkRepo.EXPECT().GetUploadLinkByID("valid").Return(&models.UploadLink{
+				mockUploadLinkRepo.EXPECT().GetUploadLinkByID(gomock.Any(), "valid").Return(&models.UploadLink{
 					ExpirationTime: time.Now().Add(time.Hour),
 				}, nil)
-				mockImageRepo.EXPECT().InsertImages(gomock.Any()).Return([]string{"image1.jpg"}, nil)
-				mockImageRepo.EXPECT().GetImageByNameAndUploadLinkID(gomock.Any(), gomock.Any()).Return(nil, nil)
-				mockImageUploadedProducer.EXPECT().Publish(gomock.Any()).Return(nil)
+				mockImageRepo.EXPECT().InsertImages(gomock.Any(), gomock.Any()).Return([]string{"image1.jpg"}, nil)
+				mockImageRepo.EXPECT().GetImageByNameAndUploadLinkID(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, nil)
+				mockImageUploadedProducer.EXPECT().Publish(gomock.Any(), gomock.Any()).Return(nil)
 			},
 			formData:       map[string]string{"images": "image1.jpg"},
 			expectedStatus: http.StatusOK,
 			expectedBody:   `["image1.jpg"]`,
 		},
+		{
+			name:         "rejected file rolls back the batch",
+			uploadLinkID: "valid",
+			mockRepoFunc: func() {
+				mockUploadLinkRepo.EXPECT().GetUploadLinkByID(gomock.Any(), "valid").Return(&models.UploadLink{
+					ExpirationTime: time.Now().Add(time.Hour),
+				}, nil)
+				mockImageRepo.EXPECT().GetImageByNameAndUploadLinkID(gomock.Any(), gomock.Any(), "first.jpg", "valid").Return(nil, nil)
+			},
+			files:          []string{"first.jpg", "notes.txt"},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   "invalid file type: text/plain; charset=utf-8",
+		},
+		{
+			name:         "partial upload",
+			query:        "?partial=true",
+			uploadLinkID: "valid",
+			mockRepoFunc: func() {
+				mockUploadLinkRepo.EXPECT().GetUploadLinkByID(gomock.Any(), "valid").Return(&models.UploadLink{
+					Tenant:         "marketing",
+					ExpirationTime: time.Now().Add(time.Hour),
+				}, nil)
+				mockImageRepo.EXPECT().GetImageByNameAndUploadLinkID(gomock.Any(), "marketing", "first.jpg", "valid").Return(nil, nil)
+				mockImageRepo.EXPECT().GetImageByNameAndUploadLinkID(gomock.Any(), "marketing", "existing.jpg", "valid").Return(&models.Image{}, nil)
+				mockImageRepo.EXPECT().InsertImages(gomock.Any(), gomock.Len(1)).DoAndReturn(func(_ context.Context, images []interface{}) ([]string, error) {
+					image := images[0].(*models.Image)
+					assert.Equal(t, "marketing", image.Tenant)
+					assert.Equal(t, storages.NewImageStorage(uploadPath).Path("marketing", "valid", "first.jpg"), image.Path)
+					return []string{"id1"}, nil
+				})
+				mockImageUploadedProducer.EXPECT().Publish(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, event models.ImageUploadedEvent) error {
+					assert.Equal(t, "valid", event.UploadLinkID)
+					assert.Equal(t, "marketing", event.Tenant)
+					assert.Equal(t, []string{"id1"}, event.ImageIDs())
+					assert.Equal(t, "first.jpg", event.Images[0].Name)
+					return nil
+				})
+			},
+			files:          []string{"first.jpg", "notes.txt", "existing.jpg", "first.jpg"},
+			expectedStatus: http.StatusMultiStatus,
+			expectedBody: `[{"name":"first.jpg","status":"stored","id":"id1"},` +
+				`{"name":"notes.txt","status":"rejected","reason":"invalid file type: text/plain; charset=utf-8"},` +
+				`{"name":"existing.jpg","status":"duplicate"},{"name":"first.jpg","status":"duplicate"}]`,
+		},
+		{
+			name:         "partial upload without stored images",
+			query:        "?partial=true",
+			uploadLinkID: "valid",
+			mockRepoFunc: func() {
+				mockUploadLinkRepo.EXPECT().GetUploadLinkByID(gomock.Any(), "valid").Return(&models.UploadLink{
+					ExpirationTime: time.Now().Add(time.Hour),
+				}, nil)
+			},
+			files:          []string{"notes.txt"},
+			expectedStatus: http.StatusMultiStatus,
+			expectedBody:   `[{"name":"notes.txt","status":"rejected","reason":"invalid file type: text/plain; charset=utf-8"}]`,
+		},
+		{
+			name:         "invalid partial",
+			query:        "?partial=maybe",
+			uploadLinkID: "valid",
+			mockRepoFunc: func() {
+				mockUploadLinkRepo.EXPECT().GetUploadLinkByID(gomock.Any(), "valid").Return(&models.UploadLink{
+					ExpirationTime: time.Now().Add(time.Hour),
+				}, nil)
+			},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   "Invalid partial, it must be true or false",
+		},
+		{
+			name:         "commit failure rolls back inserted images",
+			uploadLinkID: "valid",
+			prepare: func() {
+				// a non empty directory at the final path makes the rename fail
+				os.MkdirAll(uploadPath+"valid/second.jpg/blocked", os.ModePerm)
+			},
+			mockRepoFunc: func() {
+				mockUploadLinkRepo.EXPECT().GetUploadLinkByID(gomock.Any(), "valid").Return(&models.UploadLink{
+					ExpirationTime: time.Now().Add(time.Hour),
+				}, nil)
+				mockImageRepo.EXPECT().GetImageByNameAndUploadLinkID(gomock.Any(), gomock.Any(), gomock.Any(), "valid").Return(nil, nil).Times(2)
+				mockImageRepo.EXPECT().InsertImages(gomock.Any(), gomock.Len(2)).Return([]string{"id1", "id2"}, nil)
+				mockImageRepo.EXPECT().DeleteImage(gomock.Any(), gomock.Any(), "id1").Return(nil)
+				mockImageRepo.EXPECT().DeleteImage(gomock.Any(), gomock.Any(), "id2").Return(nil)
+			},
+			files:          []string{"first.jpg", "second.jpg"},
+			expectedStatus: http.StatusInternalServerError,
+			expectedBody:   "Error storing images",
+		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
+			defer cleanUploadTestFolder()
+			if tt.prepare != nil {
+				tt.prepare()
+			}
 			tt.mockRepoFunc()
 
 			var body bytes.Buffer
@@ -112,9 +212,13 @@ func TestUploadImage(t *testing.T) {
 				filePart, _ := writer.CreateFormFile(key, val)
 				filePart.Write([]byte("Hello, World!"))
 			}
+			for _, name := range tt.files {
+				filePart, _ := writer.CreateFormFile("images", name)
+				filePart.Write([]byte("Hello, World!"))
+			}
 			writer.Close()
 
-			req := httptest.NewRequest(http.MethodPost, "/upload-image", &body)
+			req := httptest.NewRequest(http.MethodPost, "/upload-image"+tt.query, &body)
 			req.Header.Set("Content-Type", writer.FormDataContentType())
 			req = mux.SetURLVars(req, map[string]string{"upload_link_id": tt.uploadLinkID})
 			w := httptest.NewRecorder()
@@ -127,6 +231,15 @@ func TestUploadImage(t *testing.T) {
 
 			assert.Equal(t, tt.expectedStatus, resp.StatusCode)
 			assert.Contains(t, tt.expectedBody, bodyString)
+
+			// nothing is left staged and only committed batches are stored
+			storedFiles, _ := storages.NewImageStorage(uploadPath).List()
+			for _, file := range storedFiles {
+				assert.NotContains(t, file.Path, ".staging", "staged file left behind")
+				if tt.expectedStatus != http.StatusOK && tt.expectedStatus != http.StatusMultiStatus {
+					assert.Contains(t, file.Path, "blocked", "file stored by a failed batch")
+				}
+			}
 		})
 	}
 }
@@ -156,7 +269,7 @@ func TestGetImage(t *testing.T) {
 			name:    "image not found",
 			imageID: "invalid",
 			mockRepoFunc: func() {
-				mockImageRepo.EXPECT().GetImageByID("invalid").Return(nil, errors.New("not found"))
+				mockImageRepo.EXPECT().GetImageByID(gomock.Any(), "marketing", "invalid").Return(nil, errors.New("not found"))
 			},
 			expectedStatus: http.StatusNotFound,
 			expectedBody:   "Invalid image id or not found",
@@ -165,7 +278,7 @@ func TestGetImage(t *testing.T) {
 			name:    "successful retrieval",
 			imageID: "valid",
 			mockRepoFunc: func() {
-				mockImageRepo.EXPECT().GetImageByID("valid").Return(&models.Image{
+				mockImageRepo.EXPECT().GetImageByID(gomock.Any(), "marketing", "valid").Return(&models.Image{
 					ID:   "valid",
 					Name: "test_image",
 				}, nil)
@@ -179,7 +292,7 @@ func TestGetImage(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			tt.mockRepoFunc()
 
-			req := httptest.NewRequest(http.MethodGet, "/image/{image_id}", nil)
+			req := withTenant(httptest.NewRequest(http.MethodGet, "/image/{image_id}", nil), "marketing")
 			req = mux.SetURLVars(req, map[string]string{"image_id": tt.imageID})
 			w := httptest.NewRecorder()
 
@@ -194,3 +307,264 @@ func TestGetImage(t *testing.T) {
 		})
 	}
 }
+
+func TestListImages(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockImageRepo := mocks.NewMockImageRepository(ctrl)
+
+	controller := &imageController{
+		imageRepo: mockImageRepo,
+	}
+
+	hasGPS := true
+	flashFired := false
+
+	tests := []struct {
+		name           string
+		query          string
+		mockRepoFunc   func()
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:           "invalid upload time",
+			query:          "uploadedFrom=yesterday",
+			mockRepoFunc:   func() {},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   "invalid uploadedFrom",
+		},
+		{
+			name:           "invalid dimension",
+			query:          "minWidth=-1",
+			mockRepoFunc:   func() {},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   "invalid minWidth",
+		},
+		{
+			name:           "invalid gps presence",
+			query:          "hasGps=maybe",
+			mockRepoFunc:   func() {},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   "invalid hasGps",
+		},
+		{
+			name:  "invalid cursor",
+			query: "cursor=invalid",
+			mockRepoFunc: func() {
+				mockImageRepo.EXPECT().ListImages(gomock.Any(), models.ImageFilter{Tenant: "marketing", Cursor: "invalid"}).Return(nil, repositories.ErrInvalidCursor)
+			},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   "invalid cursor",
+		},
+		{
+			name:  "error listing images",
+			query: "",
+			mockRepoFunc: func() {
+				mockImageRepo.EXPECT().ListImages(gomock.Any(), models.ImageFilter{Tenant: "marketing"}).Return(nil, errors.New("error"))
+			},
+			expectedStatus: http.StatusInternalServerError,
+			expectedBody:   "Error listing images",
+		},
+		{
+			name:  "metadata filters",
+			query: "make=Canon&lensModel=RF&keyword=sunset&capturedTo=2024-01-01T00:00:00Z&minIso=100&maxIso=800&flashFired=false",
+			mockRepoFunc: func() {
+				mockImageRepo.EXPECT().ListImages(gomock.Any(), models.ImageFilter{
+					Tenant:     "marketing",
+					Make:       "Canon",
+					LensModel:  "RF",
+					Keyword:    "sunset",
+					CapturedTo: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+					MinISO:     100,
+					MaxISO:     800,
+					FlashFired: &flashFired,
+				}).Return(&models.ImagePage{}, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `"images":null`,
+		},
+		{
+			name:  "successful listing",
+			query: "uploadLinkID=link&cameraModel=Canon&imageFormat=JPEG&uploadedFrom=2024-01-01T00:00:00Z&minWidth=100&maxHeight=200&hasGps=true&sort=name&limit=1",
+			mockRepoFunc: func() {
+				mockImageRepo.EXPECT().ListImages(gomock.Any(), models.ImageFilter{
+					Tenant:       "marketing",
+					UploadLinkID: "link",
+					CameraModel:  "Canon",
+					ImageFormat:  "JPEG",
+					UploadedFrom: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+					MinWidth:     100,
+					MaxHeight:    200,
+					HasGPS:       &hasGPS,
+					Sort:         "name",
+					Limit:        1,
+				}).Return(&models.ImagePage{
+					Images:     []models.Image{{ID: "valid", Name: "test_image"}},
+					NextCursor: "next",
+				}, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `"nextCursor":"next"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockRepoFunc()
+
+			req := withTenant(httptest.NewRequest(http.MethodGet, "/images?"+tt.query, nil), "marketing")
+			w := httptest.NewRecorder()
+
+			controller.ListImages(w, req)
+
+			resp := w.Result()
+			bodyBytes, _ := io.ReadAll(resp.Body)
+			bodyString := strings.TrimSpace(string(bodyBytes))
+
+			assert.Equal(t, tt.expectedStatus, resp.StatusCode)
+			assert.Contains(t, bodyString, tt.expectedBody)
+		})
+	}
+}
+
+func TestDeleteImage(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockImageRepo := mocks.NewMockImageRepository(ctrl)
+	mockImageDeletedProducer := mocksProducer.NewMockImageDeletedProducer(ctrl)
+
+	controller := &imageController{
+		imageRepo:            mockImageRepo,
+		imageDeletedProducer: mockImageDeletedProducer,
+	}
+
+	tests := []struct {
+		name           string
+		imageID        string
+		mockRepoFunc   func()
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:    "image not found",
+			imageID: "missing",
+			mockRepoFunc: func() {
+				mockImageRepo.EXPECT().SoftDeleteImage(gomock.Any(), "marketing", "missing", gomock.Any()).Return(nil, nil)
+			},
+			expectedStatus: http.StatusNotFound,
+			expectedBody:   "Invalid image id or not found",
+		},
+		{
+			name:    "repository error",
+			imageID: "valid",
+			mockRepoFunc: func() {
+				mockImageRepo.EXPECT().SoftDeleteImage(gomock.Any(), "marketing", "valid", gomock.Any()).Return(nil, errors.New("db error"))
+			},
+			expectedStatus: http.StatusInternalServerError,
+			expectedBody:   "Error deleting image",
+		},
+		{
+			name:    "successful deletion",
+			imageID: "valid",
+			mockRepoFunc: func() {
+				mockImageRepo.EXPECT().SoftDeleteImage(gomock.Any(), "marketing", "valid", gomock.Any()).Return(&models.Image{ID: "valid"}, nil)
+				mockImageDeletedProducer.EXPECT().Publish(gomock.Any(), "marketing", []string{"valid"}).Return(nil)
+			},
+			expectedStatus: http.StatusNoContent,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockRepoFunc()
+
+			req := withTenant(httptest.NewRequest(http.MethodDelete, "/images/{image_id}", nil), "marketing")
+			req = mux.SetURLVars(req, map[string]string{"image_id": tt.imageID})
+			w := httptest.NewRecorder()
+
+			controller.DeleteImage(w, req)
+
+			resp := w.Result()
+			bodyBytes, _ := io.ReadAll(resp.Body)
+
+			assert.Equal(t, tt.expectedStatus, resp.StatusCode)
+			assert.Contains(t, string(bodyBytes), tt.expectedBody)
+		})
+	}
+}
+
+func TestDeleteUploadLinkImages(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockUploadLinkRepo := mocks.NewMockUploadLinkRepository(ctrl)
+	mockImageRepo := mocks.NewMockImageRepository(ctrl)
+	mockImageDeletedProducer := mocksProducer.NewMockImageDeletedProducer(ctrl)
+
+	controller := &imageController{
+		uploadLinkRepo:       mockUploadLinkRepo,
+		imageRepo:            mockImageRepo,
+		imageDeletedProducer: mockImageDeletedProducer,
+	}
+
+	tests := []struct {
+		name           string
+		uploadLinkID   string
+		mockRepoFunc   func()
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:         "upload link not found",
+			uploadLinkID: "invalid",
+			mockRepoFunc: func() {
+				mockUploadLinkRepo.EXPECT().GetTenantUploadLinkByID(gomock.Any(), "marketing", "invalid").Return(nil, errors.New("not found"))
+			},
+			expectedStatus: http.StatusNotFound,
+			expectedBody:   "Invalid upload link or not found",
+		},
+		{
+			name:         "no images to delete",
+			uploadLinkID: "empty",
+			mockRepoFunc: func() {
+				mockUploadLinkRepo.EXPECT().GetTenantUploadLinkByID(gomock.Any(), "marketing", "empty").Return(&models.UploadLink{ID: "empty"}, nil)
+				mockImageRepo.EXPECT().SoftDeleteImagesByUploadLinkID(gomock.Any(), "marketing", "empty", gomock.Any()).Return(nil, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   "[]",
+		},
+		{
+			name:         "successful deletion",
+			uploadLinkID: "valid",
+			mockRepoFunc: func() {
+				mockUploadLinkRepo.EXPECT().GetTenantUploadLinkByID(gomock.Any(), "marketing", "valid").Return(&models.UploadLink{ID: "valid"}, nil)
+				mockImageRepo.EXPECT().SoftDeleteImagesByUploadLinkID(gomock.Any(), "marketing", "valid", gomock.Any()).Return([]string{"first", "second"}, nil)
+				mockImageDeletedProducer.EXPECT().Publish(gomock.Any(), "marketing", []string{"first", "second"}).Return(errors.New("kafka down"))
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `["first","second"]`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockRepoFunc()
+
+			req := withTenant(httptest.NewRequest(http.MethodDelete, "/upload-link/{upload_link_id}/images", nil), "marketing")
+			req = mux.SetURLVars(req, map[string]string{"upload_link_id": tt.uploadLinkID})
+			w := httptest.NewRecorder()
+
+			controller.DeleteUploadLinkImages(w, req)
+
+			resp := w.Result()
+			bodyBytes, _ := io.ReadAll(resp.Body)
+			bodyString := strings.TrimSpace(string(bodyBytes))
+
+			assert.Equal(t, tt.expectedStatus, resp.StatusCode)
+			assert.Contains(t, bodyString, tt.expectedBody)
+		})
+	}
+}
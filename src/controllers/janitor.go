@@ -0,0 +1,47 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/tam-code/image-upload/src/janitors"
+)
+
+type (
+	JanitorController interface {
+		CollectGarbage(w http.ResponseWriter, r *http.Request)
+	}
+
+	janitorController struct {
+		garbageCollector janitors.GarbageCollector
+	}
+)
+
+func NewJanitorController(janitors *janitors.Janitors) JanitorController {
+	return &janitorController{
+		garbageCollector: janitors.GarbageCollector,
+	}
+}
+
+// CollectGarbage runs the garbage collector on demand and returns its report,
+// the dryRun query parameter overrides the configured mode.
+func (c *janitorController) CollectGarbage(w http.ResponseWriter, r *http.Request) {
+	dryRun := c.garbageCollector.DryRun()
+	if value := r.URL.Query().Get("dryRun"); value != "" {
+		var err error
+		if dryRun, err = strconv.ParseBool(value); err != nil {
+			http.Error(w, "Invalid dryRun, it must be true or false", http.StatusBadRequest)
+			return
+		}
+	}
+
+	report, err := c.garbageCollector.Collect(r.Context(), dryRun)
+	if err != nil {
+		http.Error(w, "Error collecting garbage", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
+}
@@ -0,0 +1,90 @@
+package controllers
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	mocksJanitor "github.com/tam-code/image-upload/mocks/janitors"
+	"github.com/tam-code/image-upload/src/models"
+)
+
+func TestCollectGarbage(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockGarbageCollector := mocksJanitor.NewMockGarbageCollector(ctrl)
+
+	controller := &janitorController{
+		garbageCollector: mockGarbageCollector,
+	}
+
+	tests := []struct {
+		name           string
+		query          string
+		mockFunc       func()
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:  "configured mode",
+			query: "",
+			mockFunc: func() {
+				mockGarbageCollector.EXPECT().DryRun().Return(false)
+				mockGarbageCollector.EXPECT().Collect(gomock.Any(), false).Return(&models.GarbageCollectionReport{OrphanedFiles: []string{"upload/link/a.jpg"}}, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `"dryRun":false`,
+		},
+		{
+			name:  "dry run override",
+			query: "?dryRun=true",
+			mockFunc: func() {
+				mockGarbageCollector.EXPECT().DryRun().Return(false)
+				mockGarbageCollector.EXPECT().Collect(gomock.Any(), true).Return(&models.GarbageCollectionReport{DryRun: true}, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `"dryRun":true`,
+		},
+		{
+			name:  "invalid dry run",
+			query: "?dryRun=maybe",
+			mockFunc: func() {
+				mockGarbageCollector.EXPECT().DryRun().Return(false)
+			},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   "Invalid dryRun, it must be true or false",
+		},
+		{
+			name:  "collector error",
+			query: "",
+			mockFunc: func() {
+				mockGarbageCollector.EXPECT().DryRun().Return(true)
+				mockGarbageCollector.EXPECT().Collect(gomock.Any(), true).Return(nil, errors.New("storage error"))
+			},
+			expectedStatus: http.StatusInternalServerError,
+			expectedBody:   "Error collecting garbage",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockFunc()
+
+			req := httptest.NewRequest(http.MethodPost, "/janitor/garbage-collection"+tt.query, nil)
+			w := httptest.NewRecorder()
+
+			controller.CollectGarbage(w, req)
+
+			resp := w.Result()
+			bodyBytes, _ := io.ReadAll(resp.Body)
+
+			assert.Equal(t, tt.expectedStatus, resp.StatusCode)
+			assert.Contains(t, string(bodyBytes), tt.expectedBody)
+		})
+	}
+}
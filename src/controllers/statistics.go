@@ -2,19 +2,30 @@ package controllers
 
 import (
 	"encoding/json"
+	"fmt"
 	"net/http"
+	"strconv"
+	"time"
 
+	"github.com/tam-code/image-upload/src/broadcasters"
 	"github.com/tam-code/image-upload/src/models"
 	"github.com/tam-code/image-upload/src/repositories"
 )
 
+const (
+	defaultHeartbeatInterval = 15 * time.Second
+)
+
 type (
 	StatisticsController interface {
 		GetStatistics(w http.ResponseWriter, r *http.Request)
+		StreamStatistics(w http.ResponseWriter, r *http.Request)
 	}
 
 	statisticsController struct {
-		statisticsRepository repositories.StatisticsRepository
+		statisticsRepository  repositories.StatisticsRepository
+		statisticsBroadcaster broadcasters.StatisticsBroadcaster
+		heartbeatInterval     time.Duration
 	}
 
 	statistics struct {
@@ -24,26 +35,33 @@ type (
 	}
 )
 
-func NewStatisticsController(repositories *repositories.Repositories) StatisticsController {
+func NewStatisticsController(repositories *repositories.Repositories, broadcasters *broadcasters.Broadcasters, heartbeatInterval time.Duration) StatisticsController {
 	return &statisticsController{
-		statisticsRepository: repositories.Statistics,
+		statisticsRepository:  repositories.Statistics,
+		statisticsBroadcaster: broadcasters.Statistics,
+		heartbeatInterval:     heartbeatInterval,
 	}
 }
 
 func (c *statisticsController) GetStatistics(w http.ResponseWriter, r *http.Request) {
-	mostPopularImageFormat, err := c.statisticsRepository.GetStatisticsSortedByCount(models.ImageFormatType, 1)
+	tenant, ok := requestTenant(w, r)
+	if !ok {
+		return
+	}
+
+	mostPopularImageFormat, err := c.statisticsRepository.GetStatisticsSortedByCount(r.Context(), tenant, models.ImageFormatType, 1)
 	if err != nil {
 		http.Error(w, "Error getting most popular image format", http.StatusInternalServerError)
 		return
 	}
 
-	mostPopularCameraModels, err := c.statisticsRepository.GetStatisticsSortedByCount(models.CameraModelType, 10)
+	mostPopularCameraModels, err := c.statisticsRepository.GetStatisticsSortedByCount(r.Context(), tenant, models.CameraModelType, 10)
 	if err != nil {
 		http.Error(w, "Error getting most popular camera models", http.StatusInternalServerError)
 		return
 	}
 
-	uploadFrequencyPerDay, err := c.statisticsRepository.GetStatisticsFrequency(models.DateFrequencyType, 30)
+	uploadFrequencyPerDay, err := c.statisticsRepository.GetStatisticsFrequency(r.Context(), tenant, models.DateFrequencyType, 30)
 	if err != nil {
 		http.Error(w, "Error getting upload frequency per day", http.StatusInternalServerError)
 		return
@@ -58,3 +76,81 @@ func (c *statisticsController) GetStatistics(w http.ResponseWriter, r *http.Requ
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(statistics)
 }
+
+func (c *statisticsController) StreamStatistics(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+
+	var lastEventID uint64
+	if header := r.Header.Get("Last-Event-ID"); header != "" {
+		id, err := strconv.ParseUint(header, 10, 64)
+		if err != nil {
+			http.Error(w, "Invalid Last-Event-ID", http.StatusBadRequest)
+			return
+		}
+		lastEventID = id
+	}
+
+	tenant, ok := requestTenant(w, r)
+	if !ok {
+		return
+	}
+
+	subscription := c.statisticsBroadcaster.Subscribe(tenant, lastEventID)
+	defer c.statisticsBroadcaster.Unsubscribe(subscription)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+
+	// tell the client its position is lost so it refetches the full statistics
+	if subscription.Missed {
+		writeServerSentEvent(w, "", "reset", map[string]time.Time{"time": time.Now()})
+	}
+
+	for _, event := range subscription.Replay {
+		writeServerSentEvent(w, strconv.FormatUint(event.ID, 10), "statistics", event)
+	}
+	flusher.Flush()
+
+	heartbeatInterval := c.heartbeatInterval
+	if heartbeatInterval <= 0 {
+		heartbeatInterval = defaultHeartbeatInterval
+	}
+
+	heartbeat := time.NewTicker(heartbeatInterval)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case event, ok := <-subscription.Events:
+			// subscription dropped for being too slow, client reconnects with Last-Event-ID
+			if !ok {
+				return
+			}
+			writeServerSentEvent(w, strconv.FormatUint(event.ID, 10), "statistics", event)
+			flusher.Flush()
+		case now := <-heartbeat.C:
+			writeServerSentEvent(w, "", "heartbeat", map[string]time.Time{"time": now})
+			flusher.Flush()
+		}
+	}
+}
+
+func writeServerSentEvent(w http.ResponseWriter, id, event string, data interface{}) {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return
+	}
+
+	if id != "" {
+		fmt.Fprintf(w, "id: %s\n", id)
+	}
+	fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, payload)
+}
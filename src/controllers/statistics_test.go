@@ -1,16 +1,19 @@
 package controllers
 
 import (
+	"context"
 	"errors"
 	"io"
 	"net/http"
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/assert"
 	mocks "github.com/tam-code/image-upload/mocks/repositories"
+	"github.com/tam-code/image-upload/src/broadcasters"
 	"github.com/tam-code/image-upload/src/models"
 )
 
@@ -33,7 +36,7 @@ func TestGetStatistics(t *testing.T) {
 		{
 			name: "error getting most popular image format",
 			mockRepoFunc: func() {
-				mockStatisticsRepo.EXPECT().GetStatisticsSortedByCount(models.ImageFormatType, 1).Return(nil, errors.New("error"))
+				mockStatisticsRepo.EXPECT().GetStatisticsSortedByCount(gomock.Any(), models.DefaultTenant, models.ImageFormatType, 1).Return(nil, errors.New("error"))
 			},
 			expectedStatus: http.StatusInternalServerError,
 			expectedBody:   "Error getting most popular image format",
@@ -41,8 +44,8 @@ func TestGetStatistics(t *testing.T) {
 		{
 			name: "error getting most popular camera models",
 			mockRepoFunc: func() {
-				mockStatisticsRepo.EXPECT().GetStatisticsSortedByCount(models.ImageFormatType, 1).Return([]models.Statistics{{Name: "JPEG", Count: 100}}, nil)
-				mockStatisticsRepo.EXPECT().GetStatisticsSortedByCount(models.CameraModelType, 10).Return(nil, errors.New("error"))
+				mockStatisticsRepo.EXPECT().GetStatisticsSortedByCount(gomock.Any(), models.DefaultTenant, models.ImageFormatType, 1).Return([]models.Statistics{{Name: "JPEG", Count: 100}}, nil)
+				mockStatisticsRepo.EXPECT().GetStatisticsSortedByCount(gomock.Any(), models.DefaultTenant, models.CameraModelType, 10).Return(nil, errors.New("error"))
 			},
 			expectedStatus: http.StatusInternalServerError,
 			expectedBody:   "Error getting most popular camera models",
@@ -50,9 +53,9 @@ func TestGetStatistics(t *testing.T) {
 		{
 			name: "error getting upload frequency per day",
 			mockRepoFunc: func() {
-				mockStatisticsRepo.EXPECT().GetStatisticsSortedByCount(models.ImageFormatType, 1).Return([]models.Statistics{{Name: "JPEG", Count: 100}}, nil)
-				mockStatisticsRepo.EXPECT().GetStatisticsSortedByCount(models.CameraModelType, 10).Return([]models.Statistics{{Name: "Canon", Count: 50}}, nil)
-				mockStatisticsRepo.EXPECT().GetStatisticsFrequency(models.DateFrequencyType, 30).Return(nil, errors.New("error"))
+				mockStatisticsRepo.EXPECT().GetStatisticsSortedByCount(gomock.Any(), models.DefaultTenant, models.ImageFormatType, 1).Return([]models.Statistics{{Name: "JPEG", Count: 100}}, nil)
+				mockStatisticsRepo.EXPECT().GetStatisticsSortedByCount(gomock.Any(), models.DefaultTenant, models.CameraModelType, 10).Return([]models.Statistics{{Name: "Canon", Count: 50}}, nil)
+				mockStatisticsRepo.EXPECT().GetStatisticsFrequency(gomock.Any(), models.DefaultTenant, models.DateFrequencyType, 30).Return(nil, errors.New("error"))
 			},
 			expectedStatus: http.StatusInternalServerError,
 			expectedBody:   "Error getting upload frequency per day",
@@ -60,9 +63,9 @@ func TestGetStatistics(t *testing.T) {
 		{
 			name: "successful retrieval",
 			mockRepoFunc: func() {
-				mockStatisticsRepo.EXPECT().GetStatisticsSortedByCount(models.ImageFormatType, 1).Return([]models.Statistics{{Name: "JPEG", Count: 100}}, nil)
-				mockStatisticsRepo.EXPECT().GetStatisticsSortedByCount(models.CameraModelType, 10).Return([]models.Statistics{{Name: "Canon", Count: 50}}, nil)
-				mockStatisticsRepo.EXPECT().GetStatisticsFrequency(models.DateFrequencyType, 30).Return([]models.Statistics{{Name: "2023-10-01", Count: 10}}, nil)
+				mockStatisticsRepo.EXPECT().GetStatisticsSortedByCount(gomock.Any(), models.DefaultTenant, models.ImageFormatType, 1).Return([]models.Statistics{{Name: "JPEG", Count: 100}}, nil)
+				mockStatisticsRepo.EXPECT().GetStatisticsSortedByCount(gomock.Any(), models.DefaultTenant, models.CameraModelType, 10).Return([]models.Statistics{{Name: "Canon", Count: 50}}, nil)
+				mockStatisticsRepo.EXPECT().GetStatisticsFrequency(gomock.Any(), models.DefaultTenant, models.DateFrequencyType, 30).Return([]models.Statistics{{Name: "2023-10-01", Count: 10}}, nil)
 			},
 			expectedStatus: http.StatusOK,
 			expectedBody:   `{"mostPopularImageFormat":[{"name":"JPEG","count":100}],"mostPopularCameraModels":[{"name":"Canon","count":50}],"uploadFrequencyPerDay":[{"name":"2023-10-01","count":10}]}`,
@@ -73,7 +76,7 @@ func TestGetStatistics(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			tt.mockRepoFunc()
 
-			req := httptest.NewRequest(http.MethodGet, "/statistics", nil)
+			req := withTenant(httptest.NewRequest(http.MethodGet, "/statistics", nil), models.DefaultTenant)
 			w := httptest.NewRecorder()
 
 			controller.GetStatistics(w, req)
@@ -87,3 +90,80 @@ func TestGetStatistics(t *testing.T) {
 		})
 	}
 }
+
+func TestStreamStatistics(t *testing.T) {
+	broadcaster := broadcasters.NewStatisticsBroadcaster(10, 10)
+	broadcaster.Publish(models.DefaultTenant, []models.StatisticsDelta{{Type: models.ImageFormatType, Name: "JPEG", Delta: 1, Count: 1}})
+	broadcaster.Publish(models.DefaultTenant, []models.StatisticsDelta{{Type: models.ImageFormatType, Name: "JPEG", Delta: 2, Count: 3}})
+
+	controller := &statisticsController{
+		statisticsBroadcaster: broadcaster,
+		heartbeatInterval:     10 * time.Millisecond,
+	}
+
+	tests := []struct {
+		name           string
+		lastEventID    string
+		publish        []models.StatisticsDelta
+		expectedStatus int
+		expectedBody   []string
+	}{
+		{
+			name:           "invalid last event id",
+			lastEventID:    "invalid",
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   []string{"Invalid Last-Event-ID"},
+		},
+		{
+			name:           "live event and heartbeat",
+			publish:        []models.StatisticsDelta{{Type: models.CameraModelType, Name: "Canon", Delta: 1, Count: 1}},
+			expectedStatus: http.StatusOK,
+			expectedBody:   []string{"event: heartbeat", "id: 3\nevent: statistics", `"name":"Canon","delta":1,"count":1`},
+		},
+		{
+			name:           "resume with last event id",
+			lastEventID:    "1",
+			expectedStatus: http.StatusOK,
+			expectedBody:   []string{"id: 2\nevent: statistics", `"name":"JPEG","delta":2,"count":3`},
+		},
+		{
+			name:           "unknown last event id",
+			lastEventID:    "100",
+			expectedStatus: http.StatusOK,
+			expectedBody:   []string{"event: reset"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+			defer cancel()
+
+			req := withTenant(httptest.NewRequest(http.MethodGet, "/statistics/stream", nil).WithContext(ctx), models.DefaultTenant)
+			if tt.lastEventID != "" {
+				req.Header.Set("Last-Event-ID", tt.lastEventID)
+			}
+			w := httptest.NewRecorder()
+
+			done := make(chan struct{})
+			go func() {
+				controller.StreamStatistics(w, req)
+				close(done)
+			}()
+
+			if tt.publish != nil {
+				time.Sleep(5 * time.Millisecond)
+				broadcaster.Publish(models.DefaultTenant, tt.publish)
+			}
+			<-done
+
+			resp := w.Result()
+			bodyBytes, _ := io.ReadAll(resp.Body)
+
+			assert.Equal(t, tt.expectedStatus, resp.StatusCode)
+			for _, expected := range tt.expectedBody {
+				assert.Contains(t, string(bodyBytes), expected)
+			}
+		})
+	}
+}
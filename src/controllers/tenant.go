@@ -0,0 +1,21 @@
+package controllers
+
+import (
+	"net/http"
+
+	"github.com/tam-code/image-upload/src/middleware"
+)
+
+// requestTenant returns the tenant of the authenticated principal, the
+// records of a request never leave this tenant. The authenticators give every
+// principal one, the default tenant when the deployment has no tenants, so a
+// request without tenant is rejected rather than served from another one.
+func requestTenant(w http.ResponseWriter, r *http.Request) (string, bool) {
+	principal := middleware.PrincipalFromContext(r.Context())
+	if principal == nil || principal.Tenant == "" {
+		http.Error(w, "Forbidden, the caller has no tenant", http.StatusForbidden)
+		return "", false
+	}
+
+	return principal.Tenant, true
+}
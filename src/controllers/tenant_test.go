@@ -0,0 +1,58 @@
+package controllers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/tam-code/image-upload/src/middleware"
+	"github.com/tam-code/image-upload/src/models"
+)
+
+// withTenant authenticates the request as a principal of the tenant.
+func withTenant(r *http.Request, tenant string) *http.Request {
+	return r.WithContext(middleware.WithPrincipal(r.Context(), &models.Principal{Subject: "test", Tenant: tenant}))
+}
+
+func TestRequestTenant(t *testing.T) {
+	tests := []struct {
+		name           string
+		principal      *models.Principal
+		expectedTenant string
+		expectedOK     bool
+	}{
+		{
+			name: "no principal",
+		},
+		{
+			name:      "principal without tenant",
+			principal: &models.Principal{Subject: "key"},
+		},
+		{
+			name:           "principal tenant",
+			principal:      &models.Principal{Subject: "key", Tenant: "marketing"},
+			expectedTenant: "marketing",
+			expectedOK:     true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/images", nil)
+			if tt.principal != nil {
+				req = req.WithContext(middleware.WithPrincipal(req.Context(), tt.principal))
+			}
+			w := httptest.NewRecorder()
+
+			tenant, ok := requestTenant(w, req)
+
+			assert.Equal(t, tt.expectedTenant, tenant)
+			assert.Equal(t, tt.expectedOK, ok)
+			if !ok {
+				assert.Equal(t, http.StatusForbidden, w.Code)
+			}
+		})
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"net/http"
 	"time"
 
+	"github.com/tam-code/image-upload/src/middleware"
 	"github.com/tam-code/image-upload/src/models"
 	"github.com/tam-code/image-upload/src/repositories"
 )
@@ -46,15 +47,30 @@ func (c *uploadLinkController) CreateUploadLink(w http.ResponseWriter, r *http.R
 		return
 	}
 
+	tenant, ok := requestTenant(w, r)
+	if !ok {
+		return
+	}
+
+	privacyMode := models.PrivacyMode(r.FormValue("privacyMode"))
+	if privacyMode != "" && !privacyMode.IsValid() {
+		http.Error(w, "Invalid privacy mode, it must be one of keep, strip_gps or strip_all", http.StatusBadRequest)
+		return
+	}
+
 	// create upload link
-	uploadLink, err := c.uploadLinkRepo.CreateUploadLink(models.UploadLink{
+	uploadLink, err := c.uploadLinkRepo.CreateUploadLink(r.Context(), models.UploadLink{
+		Tenant:         tenant,
 		ExpirationTime: expirationTime,
+		PrivacyMode:    privacyMode,
 	})
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
+	middleware.AddAuditTargets(r.Context(), uploadLink.ID)
+
 	// return upload link
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(r.Host + c.uploadLinkPath + "/" + uploadLink.ID)
@@ -2,6 +2,7 @@ package controllers
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"net/http"
@@ -54,11 +55,30 @@ func TestCreateUploadLink(t *testing.T) {
 			expectedStatus: http.StatusBadRequest,
 			expectedBody:   "Expiration must be in the future",
 		},
+		{
+			name:           "invalid privacy mode",
+			expiration:     "2106-01-02T15:04:05.999Z&privacyMode=blur",
+			mockRepoFunc:   func() {},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   "Invalid privacy mode",
+		},
+		{
+			name:       "successful creation with privacy mode",
+			expiration: "2106-01-02T15:04:05.999Z&privacyMode=strip_gps",
+			mockRepoFunc: func() {
+				mockRepo.EXPECT().CreateUploadLink(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, uploadLink models.UploadLink) (*models.UploadLink, error) {
+					assert.Equal(t, models.PrivacyStripGPS, uploadLink.PrivacyMode)
+					return &models.UploadLink{ID: "tested-link"}, nil
+				})
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   "tested-link",
+		},
 		{
 			name:       "successful creation",
 			expiration: "2106-01-02T15:04:05.999Z",
 			mockRepoFunc: func() {
-				mockRepo.EXPECT().CreateUploadLink(gomock.Any()).Return(&models.UploadLink{ID: "tested-link"}, nil)
+				mockRepo.EXPECT().CreateUploadLink(gomock.Any(), gomock.Any()).Return(&models.UploadLink{ID: "tested-link"}, nil)
 			},
 			expectedStatus: http.StatusOK,
 			expectedBody:   "tested-link",
@@ -67,7 +87,7 @@ func TestCreateUploadLink(t *testing.T) {
 			name:       "unsuccessful creation",
 			expiration: "2106-01-02T15:04:05.999Z",
 			mockRepoFunc: func() {
-				mockRepo.EXPECT().CreateUploadLink(gomock.Any()).Return(nil, errors.New("error"))
+				mockRepo.EXPECT().CreateUploadLink(gomock.Any(), gomock.Any()).Return(nil, errors.New("error"))
 			},
 			expectedStatus: http.StatusInternalServerError,
 			expectedBody:   "error",
@@ -79,7 +99,7 @@ func TestCreateUploadLink(t *testing.T) {
 			tt.mockRepoFunc()
 
 			reqBody := bytes.NewBufferString("expiration=" + tt.expiration)
-			req := httptest.NewRequest(http.MethodPost, "/upload-link", reqBody)
+			req := withTenant(httptest.NewRequest(http.MethodPost, "/upload-link", reqBody), models.DefaultTenant)
 			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
 			w := httptest.NewRecorder()
 
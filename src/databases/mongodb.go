@@ -6,12 +6,13 @@ import (
 
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.opentelemetry.io/contrib/instrumentation/go.mongodb.org/mongo-driver/mongo/otelmongo"
 
 	"github.com/tam-code/image-upload/config"
 )
 
 func NewMongoDB(cfg config.MongoDBConfig) (*mongo.Database, error) {
-	mongoClient, err := mongo.Connect(context.Background(), options.Client().ApplyURI(cfg.MongoURI()))
+	mongoClient, err := mongo.Connect(context.Background(), options.Client().ApplyURI(cfg.MongoURI()).SetMonitor(combineMonitors(otelmongo.NewMonitor(), newCommandMonitor())))
 	if err != nil {
 		return nil, fmt.Errorf("error connecting to MongoDB: %w", err)
 	}
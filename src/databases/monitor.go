@@ -0,0 +1,74 @@
+package databases
+
+import (
+	"context"
+	"sync"
+
+	"go.mongodb.org/mongo-driver/event"
+
+	"github.com/tam-code/image-upload/src/metrics"
+)
+
+// newCommandMonitor observes the duration of the commands the repositories
+// run. The collection is only part of the started event, it is kept by
+// request id until the command completes.
+func newCommandMonitor() *event.CommandMonitor {
+	var collections sync.Map
+
+	finished := func(requestID int64, operation, outcome string, seconds float64) {
+		collection, ok := collections.LoadAndDelete(requestID)
+		if !ok {
+			return
+		}
+
+		metrics.MongoOperationDuration.WithLabelValues(collection.(string), operation, outcome).Observe(seconds)
+	}
+
+	return &event.CommandMonitor{
+		Started: func(_ context.Context, evt *event.CommandStartedEvent) {
+			// commands like ping or endSessions don't target a collection
+			field := evt.CommandName
+			if field == "getMore" {
+				field = "collection"
+			}
+
+			if collection, ok := evt.Command.Lookup(field).StringValueOK(); ok {
+				collections.Store(evt.RequestID, collection)
+			}
+		},
+		Succeeded: func(_ context.Context, evt *event.CommandSucceededEvent) {
+			finished(evt.RequestID, evt.CommandName, "success", evt.Duration.Seconds())
+		},
+		Failed: func(_ context.Context, evt *event.CommandFailedEvent) {
+			finished(evt.RequestID, evt.CommandName, "failure", evt.Duration.Seconds())
+		},
+	}
+}
+
+// combineMonitors notifies every monitor of the commands, the client only
+// accepts one.
+func combineMonitors(monitors ...*event.CommandMonitor) *event.CommandMonitor {
+	return &event.CommandMonitor{
+		Started: func(ctx context.Context, evt *event.CommandStartedEvent) {
+			for _, monitor := range monitors {
+				if monitor.Started != nil {
+					monitor.Started(ctx, evt)
+				}
+			}
+		},
+		Succeeded: func(ctx context.Context, evt *event.CommandSucceededEvent) {
+			for _, monitor := range monitors {
+				if monitor.Succeeded != nil {
+					monitor.Succeeded(ctx, evt)
+				}
+			}
+		},
+		Failed: func(ctx context.Context, evt *event.CommandFailedEvent) {
+			for _, monitor := range monitors {
+				if monitor.Failed != nil {
+					monitor.Failed(ctx, evt)
+				}
+			}
+		},
+	}
+}
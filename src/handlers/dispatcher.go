@@ -0,0 +1,208 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"time"
+
+	"github.com/tam-code/image-upload/config"
+	"github.com/tam-code/image-upload/src/buses"
+	"github.com/tam-code/image-upload/src/loggers"
+	"github.com/tam-code/image-upload/src/metrics"
+)
+
+const (
+	// StatisticsHandler counts the uploaded and deleted images in the statistics.
+	StatisticsHandler = "statistics"
+	// WebhooksHandler posts the image events to the configured URLs.
+	WebhooksHandler = "webhooks"
+)
+
+type (
+	// EventHandler handles the events of the types it is registered for, an
+	// error doesn't stop the other handlers of the event.
+	EventHandler interface {
+		Handle(ctx context.Context, msg buses.Message) error
+	}
+
+	EventHandlerFunc func(ctx context.Context, msg buses.Message) error
+
+	// EventMiddleware wraps the handler registered with the name.
+	EventMiddleware func(name string, next EventHandler) EventHandler
+
+	// Dispatcher runs the handlers registered for the event type of the
+	// messages, in their registration order.
+	Dispatcher interface {
+		Register(name string, eventType string, handler EventHandler)
+		Dispatch(ctx context.Context, msg buses.Message)
+	}
+
+	registeredHandler struct {
+		name    string
+		handler EventHandler
+	}
+
+	dispatcher struct {
+		disabled    []string
+		middlewares []EventMiddleware
+		handlers    map[string][]registeredHandler
+	}
+
+	// permanentError fails every attempt the same way, e.g. a malformed
+	// event, so it isn't retried.
+	permanentError struct {
+		err error
+	}
+)
+
+func (e *permanentError) Error() string {
+	return e.err.Error()
+}
+
+func (e *permanentError) Unwrap() error {
+	return e.err
+}
+
+// permanent marks the error as one that Retry doesn't attempt again.
+func permanent(err error) error {
+	return &permanentError{err: err}
+}
+
+// isPermanent reports whether the error, or one it wraps, is permanent.
+func isPermanent(err error) bool {
+	var permanentErr *permanentError
+	return errors.As(err, &permanentErr)
+}
+
+func (f EventHandlerFunc) Handle(ctx context.Context, msg buses.Message) error {
+	return f(ctx, msg)
+}
+
+// NewDispatcher wraps the handlers with the middlewares, the first one being
+// the outermost.
+func NewDispatcher(cfg config.HandlersConfig, middlewares ...EventMiddleware) Dispatcher {
+	return &dispatcher{
+		disabled:    cfg.Disabled,
+		middlewares: middlewares,
+		handlers:    map[string][]registeredHandler{},
+	}
+}
+
+// Register adds the handler of the event type, unless the handlers with the
+// name are disabled. A name may be registered for several event types.
+func (d *dispatcher) Register(name string, eventType string, handler EventHandler) {
+	if slices.Contains(d.disabled, name) {
+		slog.Info("event handler disabled", "handler", name, "event_type", eventType)
+		return
+	}
+
+	for i := len(d.middlewares) - 1; i >= 0; i-- {
+		handler = d.middlewares[i](name, handler)
+	}
+
+	d.handlers[eventType] = append(d.handlers[eventType], registeredHandler{name: name, handler: handler})
+}
+
+func (d *dispatcher) Dispatch(ctx context.Context, msg buses.Message) {
+	eventType := buses.EventType(msg)
+
+	handlers := d.handlers[eventType]
+	if len(handlers) == 0 {
+		loggers.FromContext(ctx).Debug("no handler for the event type")
+		return
+	}
+
+	for _, registered := range handlers {
+		if err := handle(ctx, registered.handler, msg); err != nil {
+			loggers.FromContext(ctx).Error("error handling event", "handler", registered.name, "error", err)
+		}
+	}
+}
+
+// handle turns the panics of the handler into errors, so they don't stop the
+// other handlers.
+func handle(ctx context.Context, handler EventHandler, msg buses.Message) (err error) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			err = fmt.Errorf("handler panicked: %v", recovered)
+		}
+	}()
+
+	return handler.Handle(ctx, msg)
+}
+
+// Logging scopes the logger of the context to the handler and logs the
+// duration of the handled events.
+func Logging(name string, next EventHandler) EventHandler {
+	return EventHandlerFunc(func(ctx context.Context, msg buses.Message) error {
+		logger := loggers.FromContext(ctx).With(slog.String("handler", name))
+		ctx = loggers.WithLogger(ctx, logger)
+
+		start := time.Now()
+		err := next.Handle(ctx, msg)
+		if err == nil {
+			logger.Debug("event handled", slog.Duration("duration", time.Since(start)))
+		}
+
+		return err
+	})
+}
+
+// Metrics measures the attempts of the handler by outcome.
+func Metrics(name string, next EventHandler) EventHandler {
+	return EventHandlerFunc(func(ctx context.Context, msg buses.Message) error {
+		start := time.Now()
+		err := handle(ctx, next, msg)
+
+		outcome := "success"
+		if err != nil {
+			outcome = "failure"
+		}
+		metrics.EventHandlerDuration.WithLabelValues(name, buses.EventType(msg), outcome).Observe(time.Since(start).Seconds())
+
+		return err
+	})
+}
+
+// Retry attempts the handler again after a backoff doubling every attempt,
+// the last error is returned once the attempts are exhausted. The permanent
+// errors are returned at once.
+func Retry(cfg config.HandlerRetryConfig) EventMiddleware {
+	return func(name string, next EventHandler) EventHandler {
+		return EventHandlerFunc(func(ctx context.Context, msg buses.Message) error {
+			backoff := time.Duration(cfg.BackoffMilliseconds) * time.Millisecond
+
+			var err error
+			for attempt := 1; ; attempt++ {
+				if err = handle(ctx, next, msg); err == nil || isPermanent(err) || attempt >= cfg.MaxAttempts {
+					return err
+				}
+
+				loggers.FromContext(ctx).Warn("retrying event handler", "attempt", attempt, "error", err)
+
+				select {
+				case <-ctx.Done():
+					return err
+				case <-time.After(backoff):
+				}
+				backoff *= 2
+			}
+		})
+	}
+}
+
+// SkipReplays wraps the handlers with side effects outside the service, such
+// as notifications, which must not run again for the replayed events.
+func SkipReplays(next EventHandler) EventHandler {
+	return EventHandlerFunc(func(ctx context.Context, msg buses.Message) error {
+		if buses.IsReplay(msg) {
+			loggers.FromContext(ctx).Debug("replayed event skipped")
+			return nil
+		}
+
+		return next.Handle(ctx, msg)
+	})
+}
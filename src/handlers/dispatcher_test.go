@@ -0,0 +1,175 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/tam-code/image-upload/config"
+	"github.com/tam-code/image-upload/src/buses"
+)
+
+func TestDispatcher(t *testing.T) {
+	tests := []struct {
+		name          string
+		disabled      []string
+		eventType     string
+		expectedCalls []string
+	}{
+		{
+			name:          "handlers of the event type run in their registration order",
+			eventType:     buses.ImageUploadedEvent,
+			expectedCalls: []string{"statistics", "failing", "panicking", "webhooks"},
+		},
+		{
+			name:          "handlers of another event type",
+			eventType:     buses.ImageDeletedEvent,
+			expectedCalls: []string{"statistics:deleted"},
+		},
+		{
+			name:          "disabled handlers are skipped",
+			disabled:      []string{"statistics", "failing"},
+			eventType:     buses.ImageUploadedEvent,
+			expectedCalls: []string{"panicking", "webhooks"},
+		},
+		{
+			name:      "event type without handler",
+			eventType: buses.AuditEvent,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls []string
+			record := func(call string, err error) EventHandler {
+				return EventHandlerFunc(func(ctx context.Context, msg buses.Message) error {
+					calls = append(calls, call)
+					return err
+				})
+			}
+
+			dispatcher := NewDispatcher(config.HandlersConfig{Disabled: tt.disabled})
+			dispatcher.Register("statistics", buses.ImageUploadedEvent, record("statistics", nil))
+			dispatcher.Register("statistics", buses.ImageDeletedEvent, record("statistics:deleted", nil))
+			dispatcher.Register("failing", buses.ImageUploadedEvent, record("failing", errors.New("mongo down")))
+			dispatcher.Register("panicking", buses.ImageUploadedEvent, EventHandlerFunc(func(ctx context.Context, msg buses.Message) error {
+				calls = append(calls, "panicking")
+				panic("nil map")
+			}))
+			dispatcher.Register("webhooks", buses.ImageUploadedEvent, record("webhooks", nil))
+
+			msg := buses.Message{Headers: []buses.Header{{Key: buses.EventTypeHeader, Value: []byte(tt.eventType)}}}
+			dispatcher.Dispatch(context.Background(), msg)
+
+			assert.Equal(t, tt.expectedCalls, calls)
+		})
+	}
+}
+
+func TestRetry(t *testing.T) {
+	tests := []struct {
+		name             string
+		failures         int
+		permanent        bool
+		expectedAttempts int
+		expectErr        bool
+	}{
+		{
+			name:             "success is not retried",
+			expectedAttempts: 1,
+		},
+		{
+			name:             "failure is retried until success",
+			failures:         2,
+			expectedAttempts: 3,
+		},
+		{
+			name:             "attempts are bounded",
+			failures:         5,
+			expectedAttempts: 3,
+			expectErr:        true,
+		},
+		{
+			name:             "permanent failure is not retried",
+			failures:         5,
+			permanent:        true,
+			expectedAttempts: 1,
+			expectErr:        true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			attempts := 0
+			handler := Retry(config.HandlerRetryConfig{MaxAttempts: 3, BackoffMilliseconds: 1})("statistics", EventHandlerFunc(func(ctx context.Context, msg buses.Message) error {
+				attempts++
+				if attempts <= tt.failures && tt.permanent {
+					return permanent(errors.New("malformed event"))
+				}
+				if attempts <= tt.failures {
+					return errors.New("mongo down")
+				}
+				return nil
+			}))
+
+			err := handler.Handle(context.Background(), buses.Message{})
+
+			assert.Equal(t, tt.expectedAttempts, attempts)
+			assert.Equal(t, tt.expectErr, err != nil)
+		})
+	}
+}
+
+func TestMiddlewaresOrder(t *testing.T) {
+	var calls []string
+	middleware := func(name string) EventMiddleware {
+		return func(handler string, next EventHandler) EventHandler {
+			return EventHandlerFunc(func(ctx context.Context, msg buses.Message) error {
+				calls = append(calls, name+":"+handler)
+				return next.Handle(ctx, msg)
+			})
+		}
+	}
+
+	dispatcher := NewDispatcher(config.HandlersConfig{}, middleware("outer"), middleware("inner"))
+	dispatcher.Register("statistics", buses.ImageUploadedEvent, EventHandlerFunc(func(ctx context.Context, msg buses.Message) error {
+		calls = append(calls, "handler")
+		return nil
+	}))
+
+	dispatcher.Dispatch(context.Background(), buses.Message{})
+
+	assert.Equal(t, []string{"outer:statistics", "inner:statistics", "handler"}, calls)
+}
+
+func TestSkipReplays(t *testing.T) {
+	tests := []struct {
+		name           string
+		headers        []buses.Header
+		expectedCalled bool
+	}{
+		{
+			name:           "event",
+			expectedCalled: true,
+		},
+		{
+			name:    "replayed event",
+			headers: []buses.Header{{Key: buses.ReplayHeader, Value: []byte("replay-1")}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			called := false
+			handler := SkipReplays(EventHandlerFunc(func(ctx context.Context, msg buses.Message) error {
+				called = true
+				return nil
+			}))
+
+			assert.NoError(t, handler.Handle(context.Background(), buses.Message{Headers: tt.headers}))
+			assert.Equal(t, tt.expectedCalled, called)
+		})
+	}
+}
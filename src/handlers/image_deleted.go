@@ -0,0 +1,66 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/tam-code/image-upload/src/broadcasters"
+	"github.com/tam-code/image-upload/src/buses"
+	"github.com/tam-code/image-upload/src/loggers"
+	"github.com/tam-code/image-upload/src/models"
+	"github.com/tam-code/image-upload/src/repositories"
+)
+
+type (
+	ImageDeletedHandler interface {
+		Handle(ctx context.Context, msg buses.Message) error
+	}
+
+	imageDeletedHandler struct {
+		imageRepository       repositories.ImageRepository
+		statisticsRepository  repositories.StatisticsRepository
+		statisticsBroadcaster broadcasters.StatisticsBroadcaster
+	}
+)
+
+func NewImageDeletedHandler(repositories *repositories.Repositories, broadcasters *broadcasters.Broadcasters) ImageDeletedHandler {
+	return &imageDeletedHandler{
+		imageRepository:       repositories.Image,
+		statisticsRepository:  repositories.Statistics,
+		statisticsBroadcaster: broadcasters.Statistics,
+	}
+}
+
+// Handle decrements the statistics the deleted images were counted in, the
+// soft deleted records are still readable by id until they are purged. The
+// images that aren't counted, e.g. already decremented by a replay of the
+// event, are left out.
+func (h *imageDeletedHandler) Handle(ctx context.Context, msg buses.Message) error {
+	version, payload, err := buses.DecodeEvent(msg.Value)
+	if err != nil {
+		return permanent(fmt.Errorf("error decoding event: %w", err))
+	}
+
+	if version != models.ImageDeletedEventVersion {
+		return permanent(fmt.Errorf("unsupported event version %d", version))
+	}
+
+	var images []string
+	if err := json.Unmarshal(payload, &images); err != nil {
+		return permanent(fmt.Errorf("error unmarshalling message: %w", err))
+	}
+
+	tenant := legacyEventTenant(msg)
+
+	imagesObjects, err := h.imageRepository.GetImagesByIDs(ctx, tenant, images)
+	if err != nil {
+		return fmt.Errorf("error getting images by ids: %w", err)
+	}
+
+	if len(imagesObjects) < len(images) {
+		loggers.FromContext(ctx).Warn("deleted images were purged before their statistics were decremented", "purged", len(images)-len(imagesObjects))
+	}
+
+	return countMarkedImages(ctx, h.imageRepository, h.statisticsRepository, h.statisticsBroadcaster, tenant, imagesObjects, false)
+}
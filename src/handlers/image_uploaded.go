@@ -1,144 +1,71 @@
 package handlers
 
 import (
+	"context"
 	"encoding/json"
-	"log"
+	"fmt"
 
+	"github.com/tam-code/image-upload/src/broadcasters"
+	"github.com/tam-code/image-upload/src/buses"
 	"github.com/tam-code/image-upload/src/models"
 	"github.com/tam-code/image-upload/src/repositories"
 )
 
 type (
 	ImageUploadedHandler interface {
-		Handle([]byte)
+		Handle(ctx context.Context, msg buses.Message) error
 	}
 
 	imageUploadedHandler struct {
-		imageRepository      repositories.ImageRepository
-		statisticsRepository repositories.StatisticsRepository
+		imageRepository       repositories.ImageRepository
+		statisticsRepository  repositories.StatisticsRepository
+		statisticsBroadcaster broadcasters.StatisticsBroadcaster
 	}
 )
 
-func NewImageUploadedHandler(repositories *repositories.Repositories) ImageUploadedHandler {
+func NewImageUploadedHandler(repositories *repositories.Repositories, broadcasters *broadcasters.Broadcasters) ImageUploadedHandler {
 	return &imageUploadedHandler{
-		imageRepository:      repositories.Image,
-		statisticsRepository: repositories.Statistics,
+		imageRepository:       repositories.Image,
+		statisticsRepository:  repositories.Statistics,
+		statisticsBroadcaster: broadcasters.Statistics,
 	}
 }
 
-func (h *imageUploadedHandler) Handle(message []byte) {
-	var images []string
-	if err := json.Unmarshal(message, &images); err != nil {
-		log.Fatalf("error unmarshalling message: %v", err)
-	}
-
-	// Do something with images
-	imagesObjects, err := h.imageRepository.GetImagesByIDs(images)
+// Handle counts the uploaded images in the statistics, the version 1 events
+// only carry the image ids so the images of their tenant are read from the
+// database. The images already counted, e.g. by a replay of the event, are
+// left out.
+func (h *imageUploadedHandler) Handle(ctx context.Context, msg buses.Message) error {
+	version, payload, err := buses.DecodeEvent(msg.Value)
 	if err != nil {
-		log.Fatalf("error getting images by ids: %v", err)
-	}
-
-	imageFormats := make(map[string]int)
-	cameraModels := make(map[string]int)
-	uploadedImagesPerDay := make(map[string]int)
-	for _, image := range imagesObjects {
-		if image.ImageFormat != "" {
-			imageFormats[image.ImageFormat]++
-		}
-
-		if image.CameraModel != "" {
-			cameraModels[image.CameraModel]++
-		}
-
-		uploadedImagesPerDay[image.UploadedAt.Format("2006-01-02")]++
+		return permanent(fmt.Errorf("error decoding event: %w", err))
 	}
 
-	h.updateCameraModelsCount(cameraModels)
-	h.updateImageFormatsCount(imageFormats)
-	h.updateImagesDailyUploadedCount(uploadedImagesPerDay)
-
-}
-
-func (h *imageUploadedHandler) updateCameraModelsCount(cameraModels map[string]int) {
-	// Do something with camera models
-	for model, count := range cameraModels {
-		cameraModel, err := h.statisticsRepository.GetStatistics(models.CameraModelType, model)
-		if err != nil {
-			log.Printf("error getting camera model by name: %v", err)
-		}
-
-		if cameraModel == nil {
-			cameraModel = &models.Statistics{
-				Type:  models.CameraModelType,
-				Name:  model,
-				Count: count,
-			}
-
-			if err = h.statisticsRepository.InsertStatistics(cameraModel); err != nil {
-				log.Printf("error creating camera model: %v", err)
-			}
-			continue
+	var tenant string
+	var imagesObjects []models.Image
+	switch version {
+	case buses.LegacyEventVersion:
+		var images []string
+		if err := json.Unmarshal(payload, &images); err != nil {
+			return permanent(fmt.Errorf("error unmarshalling message: %w", err))
 		}
 
-		cameraModel.Count += count
-		if err := h.statisticsRepository.UpdateStatistics(cameraModel); err != nil {
-			log.Printf("error updating camera model: %v", err)
-		}
-	}
-}
+		tenant = legacyEventTenant(msg)
 
-func (h *imageUploadedHandler) updateImageFormatsCount(imageFormats map[string]int) {
-	// Do something with image formats
-	for format, count := range imageFormats {
-		imageFormat, err := h.statisticsRepository.GetStatistics(models.ImageFormatType, format)
-		if err != nil {
-			log.Printf("error getting image format by id: %v", err)
+		if imagesObjects, err = h.imageRepository.GetImagesByIDs(ctx, tenant, images); err != nil {
+			return fmt.Errorf("error getting images by ids: %w", err)
 		}
-
-		if imageFormat == nil {
-			imageFormat = &models.Statistics{
-				Type:  models.ImageFormatType,
-				Name:  format,
-				Count: count,
-			}
-
-			if err = h.statisticsRepository.InsertStatistics(imageFormat); err != nil {
-				log.Printf("error creating image format: %v", err)
-			}
-			continue
+	case models.ImageUploadedEventVersion:
+		var event models.ImageUploadedEvent
+		if err := json.Unmarshal(payload, &event); err != nil {
+			return permanent(fmt.Errorf("error unmarshalling message: %w", err))
 		}
 
-		imageFormat.Count += count
-		if err := h.statisticsRepository.UpdateStatistics(imageFormat); err != nil {
-			log.Printf("error updating image format: %v", err)
-		}
+		tenant = event.Tenant
+		imagesObjects = event.Images
+	default:
+		return permanent(fmt.Errorf("unsupported event version %d", version))
 	}
-}
 
-func (h *imageUploadedHandler) updateImagesDailyUploadedCount(uploadedImagesPerDay map[string]int) {
-	// Do something with images daily uploaded
-	for day, count := range uploadedImagesPerDay {
-		imagesDailyUploaded, err := h.statisticsRepository.GetStatistics(models.DateFrequencyType, day)
-		if err != nil {
-			log.Printf("error getting images daily uploaded by day: %v", err)
-		}
-
-		if imagesDailyUploaded == nil {
-			imagesDailyUploaded = &models.Statistics{
-				Type:  models.DateFrequencyType,
-				Name:  day,
-				Count: count,
-			}
-
-			if err = h.statisticsRepository.InsertStatistics(imagesDailyUploaded); err != nil {
-				log.Printf("error creating images daily uploaded: %v", err)
-			}
-			continue
-		}
-
-		imagesDailyUploaded.Count += count
-		if err := h.statisticsRepository.UpdateStatistics(imagesDailyUploaded); err != nil {
-			log.Printf("error updating images daily uploaded: %v", err)
-		}
-	}
+	return countMarkedImages(ctx, h.imageRepository, h.statisticsRepository, h.statisticsBroadcaster, tenant, imagesObjects, true)
 }
@@ -0,0 +1,172 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/tam-code/image-upload/src/broadcasters"
+	"github.com/tam-code/image-upload/src/buses"
+	"github.com/tam-code/image-upload/src/loggers"
+	"github.com/tam-code/image-upload/src/models"
+	"github.com/tam-code/image-upload/src/repositories"
+)
+
+var tracer = otel.Tracer("github.com/tam-code/image-upload/src/handlers")
+
+// legacyEventTenant returns the tenant of the version 1 event, the default
+// tenant for the events published before they carried one, the tenant their
+// images were backfilled with.
+func legacyEventTenant(msg buses.Message) string {
+	if tenant := buses.EventTenant(msg); tenant != "" {
+		return tenant
+	}
+
+	return models.DefaultTenant
+}
+
+// countMarkedImages marks the images of the tenant counted, or not counted,
+// and applies only the images whose mark changed to the statistics, so
+// handling an event again leaves them unchanged. When the statistics can't be
+// applied the marks are restored and the error returned, the retries apply
+// the images again.
+func countMarkedImages(ctx context.Context, imageRepository repositories.ImageRepository, statisticsRepository repositories.StatisticsRepository, statisticsBroadcaster broadcasters.StatisticsBroadcaster, tenant string, images []models.Image, counted bool) error {
+	ids := make([]string, len(images))
+	for i, image := range images {
+		ids[i] = image.ID
+	}
+
+	// the images marked before an error are applied, they won't be again
+	marked, markErr := imageRepository.MarkImagesCounted(ctx, tenant, ids, counted)
+
+	var markedImages []models.Image
+	for _, image := range images {
+		if slices.Contains(marked, image.ID) {
+			markedImages = append(markedImages, image)
+		}
+	}
+
+	if len(markedImages) < len(images) {
+		loggers.FromContext(ctx).Debug("images already applied to the statistics", "images", len(images)-len(markedImages))
+	}
+
+	sign := 1
+	if !counted {
+		sign = -1
+	}
+	if len(markedImages) > 0 {
+		if err := applyImagesStatistics(ctx, statisticsRepository, statisticsBroadcaster, tenant, markedImages, sign); err != nil {
+			if restoreErr := imageRepository.RestoreImagesCounted(ctx, tenant, marked, !counted); restoreErr != nil {
+				err = errors.Join(err, restoreErr)
+			}
+			return errors.Join(err, markErr)
+		}
+	}
+
+	if markErr != nil {
+		return fmt.Errorf("error marking images counted: %w", markErr)
+	}
+
+	return nil
+}
+
+// applyImagesStatistics counts the images in the statistics of the tenant,
+// every image adds sign to its buckets, and pushes the applied deltas to the
+// live statistics subscribers of the tenant. The deltas applied before an
+// error are reverted, so the images can be applied again.
+func applyImagesStatistics(ctx context.Context, statisticsRepository repositories.StatisticsRepository, statisticsBroadcaster broadcasters.StatisticsBroadcaster, tenant string, images []models.Image, sign int) error {
+	ctx, span := tracer.Start(ctx, "apply images statistics", trace.WithAttributes(
+		attribute.Int("images", len(images)),
+		attribute.Int("sign", sign),
+	))
+	defer span.End()
+
+	counts := countImagesStatistics(images, sign)
+
+	var deltas []models.StatisticsDelta
+	for _, statisticsType := range []models.StatisticsType{models.CameraModelType, models.ImageFormatType, models.DateFrequencyType} {
+		applied, err := updateStatisticsCounts(ctx, statisticsRepository, tenant, statisticsType, counts[statisticsType])
+		deltas = append(deltas, applied...)
+		if err != nil {
+			revertStatisticsDeltas(ctx, statisticsRepository, tenant, deltas)
+			return err
+		}
+	}
+
+	statisticsBroadcaster.Publish(tenant, deltas)
+
+	return nil
+}
+
+// revertStatisticsDeltas takes the applied deltas back out of the tenant
+// statistics. The deltas it can't revert are logged, a replay resetting the
+// statistics rebuilds them.
+func revertStatisticsDeltas(ctx context.Context, statisticsRepository repositories.StatisticsRepository, tenant string, deltas []models.StatisticsDelta) {
+	for _, delta := range deltas {
+		if _, err := statisticsRepository.IncrementStatistics(ctx, tenant, delta.Type, delta.Name, -delta.Delta); err != nil {
+			loggers.FromContext(ctx).Error("error reverting statistics", "type", delta.Type, "name", delta.Name, "delta", delta.Delta, "error", err)
+		}
+	}
+}
+
+// countImagesStatistics groups the images by statistics bucket, every image
+// adds sign to the count of its buckets.
+func countImagesStatistics(images []models.Image, sign int) map[models.StatisticsType]map[string]int {
+	counts := map[models.StatisticsType]map[string]int{
+		models.CameraModelType:   {},
+		models.ImageFormatType:   {},
+		models.DateFrequencyType: {},
+	}
+
+	for _, image := range images {
+		if image.ImageFormat != "" {
+			counts[models.ImageFormatType][image.ImageFormat] += sign
+		}
+
+		if image.CameraModel != "" {
+			counts[models.CameraModelType][image.CameraModel] += sign
+		}
+
+		counts[models.DateFrequencyType][image.UploadedAt.Format("2006-01-02")] += sign
+	}
+
+	return counts
+}
+
+// updateStatisticsCounts applies the counts to the tenant statistics of the
+// type and returns the applied deltas, with the ones applied before an error.
+// Missing buckets are created on increments only and counts never go below
+// zero.
+func updateStatisticsCounts(ctx context.Context, statisticsRepository repositories.StatisticsRepository, tenant string, statisticsType models.StatisticsType, counts map[string]int) ([]models.StatisticsDelta, error) {
+	var deltas []models.StatisticsDelta
+	for name, count := range counts {
+		if count == 0 {
+			continue
+		}
+
+		previous, err := statisticsRepository.IncrementStatistics(ctx, tenant, statisticsType, name, count)
+		if err != nil {
+			return deltas, fmt.Errorf("error updating %s statistics %q: %w", statisticsType, name, err)
+		}
+
+		// the decrements are clamped to the previous count
+		applied := max(count, -previous)
+		if applied == 0 {
+			continue
+		}
+
+		deltas = append(deltas, models.StatisticsDelta{
+			Type:  statisticsType,
+			Name:  name,
+			Delta: applied,
+			Count: previous + applied,
+		})
+	}
+
+	return deltas, nil
+}
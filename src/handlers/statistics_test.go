@@ -0,0 +1,181 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+
+	mocks "github.com/tam-code/image-upload/mocks/repositories"
+	"github.com/tam-code/image-upload/src/broadcasters"
+	"github.com/tam-code/image-upload/src/buses"
+	"github.com/tam-code/image-upload/src/models"
+)
+
+func TestUpdateStatisticsCounts(t *testing.T) {
+	tests := []struct {
+		name           string
+		count          int
+		previous       int
+		err            error
+		expectedDeltas []models.StatisticsDelta
+		expectedErr    bool
+	}{
+		{
+			name:           "increment",
+			count:          2,
+			previous:       3,
+			expectedDeltas: []models.StatisticsDelta{{Type: models.ImageFormatType, Name: "jpeg", Delta: 2, Count: 5}},
+		},
+		{
+			name:           "decrement clamped to zero",
+			count:          -3,
+			previous:       1,
+			expectedDeltas: []models.StatisticsDelta{{Type: models.ImageFormatType, Name: "jpeg", Delta: -1, Count: 0}},
+		},
+		{
+			name:  "decrement of a missing bucket",
+			count: -1,
+		},
+		{
+			name:        "error",
+			count:       1,
+			err:         errors.New("mongo down"),
+			expectedErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			statisticsRepository := mocks.NewMockStatisticsRepository(ctrl)
+			statisticsRepository.EXPECT().IncrementStatistics(gomock.Any(), models.DefaultTenant, models.ImageFormatType, "jpeg", tt.count).Return(tt.previous, tt.err)
+
+			deltas, err := updateStatisticsCounts(context.Background(), statisticsRepository, models.DefaultTenant, models.ImageFormatType, map[string]int{"jpeg": tt.count})
+
+			assert.Equal(t, tt.expectedErr, err != nil)
+			assert.Equal(t, tt.expectedDeltas, deltas)
+		})
+	}
+}
+
+func TestImageUploadedHandlerReplay(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	imageRepository := mocks.NewMockImageRepository(ctrl)
+	statisticsRepository := mocks.NewMockStatisticsRepository(ctrl)
+	handler := &imageUploadedHandler{
+		imageRepository:       imageRepository,
+		statisticsRepository:  statisticsRepository,
+		statisticsBroadcaster: broadcasters.NewStatisticsBroadcaster(10, 10),
+	}
+
+	uploadedAt := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	msg, err := buses.NewEventMessage(buses.ImageUploadedEvent, models.ImageUploadedEventVersion, "marketing", models.ImageUploadedEvent{
+		UploadLinkID: "link",
+		Tenant:       "marketing",
+		Images:       []models.Image{{ID: "image", Tenant: "marketing", ImageFormat: "jpeg", CameraModel: "X100V", UploadedAt: uploadedAt}},
+	})
+	assert.NoError(t, err)
+
+	replay := msg
+	replay.Headers = append(append([]buses.Header(nil), msg.Headers...), buses.Header{Key: buses.ReplayHeader, Value: []byte("replay-1")})
+
+	// the image is counted when the event is first handled, the replay finds
+	// it already marked and leaves the statistics unchanged
+	gomock.InOrder(
+		imageRepository.EXPECT().MarkImagesCounted(gomock.Any(), "marketing", []string{"image"}, true).Return([]string{"image"}, nil),
+		statisticsRepository.EXPECT().IncrementStatistics(gomock.Any(), "marketing", models.CameraModelType, "X100V", 1).Return(0, nil),
+		statisticsRepository.EXPECT().IncrementStatistics(gomock.Any(), "marketing", models.ImageFormatType, "jpeg", 1).Return(0, nil),
+		statisticsRepository.EXPECT().IncrementStatistics(gomock.Any(), "marketing", models.DateFrequencyType, "2024-01-01", 1).Return(0, nil),
+		imageRepository.EXPECT().MarkImagesCounted(gomock.Any(), "marketing", []string{"image"}, true).Return(nil, nil),
+	)
+
+	assert.NoError(t, handler.Handle(context.Background(), msg))
+	assert.NoError(t, handler.Handle(context.Background(), replay))
+}
+
+func TestImageUploadedHandlerStatisticsFailure(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	imageRepository := mocks.NewMockImageRepository(ctrl)
+	statisticsRepository := mocks.NewMockStatisticsRepository(ctrl)
+	handler := &imageUploadedHandler{
+		imageRepository:       imageRepository,
+		statisticsRepository:  statisticsRepository,
+		statisticsBroadcaster: broadcasters.NewStatisticsBroadcaster(10, 10),
+	}
+
+	msg, err := buses.NewEventMessage(buses.ImageUploadedEvent, models.ImageUploadedEventVersion, "marketing", models.ImageUploadedEvent{
+		UploadLinkID: "link",
+		Tenant:       "marketing",
+		Images:       []models.Image{{ID: "image", Tenant: "marketing", ImageFormat: "jpeg", CameraModel: "X100V", UploadedAt: time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)}},
+	})
+	assert.NoError(t, err)
+
+	// the applied camera model is reverted and the mark restored, so the
+	// retry counts the image again
+	gomock.InOrder(
+		imageRepository.EXPECT().MarkImagesCounted(gomock.Any(), "marketing", []string{"image"}, true).Return([]string{"image"}, nil),
+		statisticsRepository.EXPECT().IncrementStatistics(gomock.Any(), "marketing", models.CameraModelType, "X100V", 1).Return(0, nil),
+		statisticsRepository.EXPECT().IncrementStatistics(gomock.Any(), "marketing", models.ImageFormatType, "jpeg", 1).Return(0, errors.New("mongo down")),
+		statisticsRepository.EXPECT().IncrementStatistics(gomock.Any(), "marketing", models.CameraModelType, "X100V", -1).Return(1, nil),
+		imageRepository.EXPECT().RestoreImagesCounted(gomock.Any(), "marketing", []string{"image"}, false).Return(nil),
+	)
+
+	assert.Error(t, handler.Handle(context.Background(), msg))
+}
+
+func TestImageDeletedHandlerLegacyEvent(t *testing.T) {
+	tests := []struct {
+		name           string
+		headers        []buses.Header
+		expectedTenant string
+	}{
+		{
+			name:           "event with tenant",
+			headers:        []buses.Header{{Key: buses.TenantHeader, Value: []byte("marketing")}},
+			expectedTenant: "marketing",
+		},
+		{
+			name:           "event published before the tenants",
+			expectedTenant: models.DefaultTenant,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			imageRepository := mocks.NewMockImageRepository(ctrl)
+			handler := &imageDeletedHandler{
+				imageRepository:       imageRepository,
+				statisticsRepository:  mocks.NewMockStatisticsRepository(ctrl),
+				statisticsBroadcaster: broadcasters.NewStatisticsBroadcaster(10, 10),
+			}
+
+			image := models.Image{ID: "image", Tenant: tt.expectedTenant}
+			imageRepository.EXPECT().GetImagesByIDs(gomock.Any(), tt.expectedTenant, []string{"image"}).Return([]models.Image{image}, nil)
+			imageRepository.EXPECT().MarkImagesCounted(gomock.Any(), tt.expectedTenant, []string{"image"}, false).Return(nil, nil)
+
+			assert.NoError(t, handler.Handle(context.Background(), buses.Message{Value: []byte(`["image"]`), Headers: tt.headers}))
+		})
+	}
+}
+
+func TestImageDeletedHandlerMalformedEvent(t *testing.T) {
+	handler := &imageDeletedHandler{}
+
+	err := handler.Handle(context.Background(), buses.Message{Value: []byte(`{"images":`)})
+
+	assert.Error(t, err)
+	assert.True(t, isPermanent(err))
+}
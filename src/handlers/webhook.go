@@ -0,0 +1,79 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/tam-code/image-upload/config"
+	"github.com/tam-code/image-upload/src/buses"
+)
+
+const (
+	WebhookEventTypeHeader = "X-Image-Upload-Event"
+	WebhookTenantHeader    = "X-Image-Upload-Tenant"
+	// WebhookSignatureHeader is sha256= followed by the hex HMAC-SHA256 of
+	// the body keyed with the webhooks secret
+	WebhookSignatureHeader = "X-Image-Upload-Signature"
+)
+
+type webhookHandler struct {
+	url    string
+	secret []byte
+	client *http.Client
+}
+
+// NewWebhookHandler posts the events to the url as they were published, the
+// CloudEvents envelope or the version 1 body. The receiver is outside the
+// service, so the replayed events aren't posted again.
+func NewWebhookHandler(url string, cfg config.WebhooksConfig) EventHandler {
+	return SkipReplays(&webhookHandler{
+		url:    url,
+		secret: []byte(cfg.Secret),
+		client: &http.Client{Timeout: time.Duration(cfg.TimeoutMilliseconds) * time.Millisecond},
+	})
+}
+
+// Handle fails on the responses other than 2xx, so the event is posted again
+// by the retries.
+func (h *webhookHandler) Handle(ctx context.Context, msg buses.Message) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, h.url, bytes.NewReader(msg.Value))
+	if err != nil {
+		return fmt.Errorf("error creating webhook request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", buses.ContentType(msg))
+	req.Header.Set(WebhookEventTypeHeader, buses.EventType(msg))
+	req.Header.Set(WebhookTenantHeader, buses.EventTenant(msg))
+	if len(h.secret) > 0 {
+		req.Header.Set(WebhookSignatureHeader, "sha256="+webhookSignature(h.secret, msg.Value))
+	}
+
+	resp, err := h.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("error posting webhook: %w", err)
+	}
+	defer resp.Body.Close()
+
+	// drained so the connection is reused
+	io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook %s answered %s", h.url, resp.Status)
+	}
+
+	return nil
+}
+
+func webhookSignature(secret []byte, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(body)
+
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,87 @@
+package handlers
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/tam-code/image-upload/config"
+	"github.com/tam-code/image-upload/src/buses"
+)
+
+func TestWebhookHandler(t *testing.T) {
+	body := []byte(`["6524"]`)
+	event := []buses.Header{
+		{Key: buses.EventTypeHeader, Value: []byte(buses.ImageDeletedEvent)},
+		{Key: buses.TenantHeader, Value: []byte("marketing")},
+	}
+
+	tests := []struct {
+		name           string
+		secret         string
+		headers        []buses.Header
+		status         int
+		expectedPosted bool
+		expectedErr    bool
+	}{
+		{
+			name:           "signed event",
+			secret:         "secret",
+			headers:        event,
+			status:         http.StatusNoContent,
+			expectedPosted: true,
+		},
+		{
+			name:           "unsigned event",
+			headers:        event,
+			status:         http.StatusOK,
+			expectedPosted: true,
+		},
+		{
+			name:           "receiver failure",
+			headers:        event,
+			status:         http.StatusServiceUnavailable,
+			expectedPosted: true,
+			expectedErr:    true,
+		},
+		{
+			name:    "replayed event",
+			headers: append([]buses.Header{{Key: buses.ReplayHeader, Value: []byte("replay-1")}}, event...),
+			status:  http.StatusOK,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			posted := false
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				posted = true
+
+				received, _ := io.ReadAll(r.Body)
+				assert.Equal(t, body, received)
+				assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+				assert.Equal(t, buses.ImageDeletedEvent, r.Header.Get(WebhookEventTypeHeader))
+				assert.Equal(t, "marketing", r.Header.Get(WebhookTenantHeader))
+
+				if tt.secret == "" {
+					assert.Empty(t, r.Header.Get(WebhookSignatureHeader))
+				} else {
+					assert.Equal(t, "sha256="+webhookSignature([]byte(tt.secret), body), r.Header.Get(WebhookSignatureHeader))
+				}
+
+				w.WriteHeader(tt.status)
+			}))
+			defer server.Close()
+
+			handler := NewWebhookHandler(server.URL, config.WebhooksConfig{Secret: tt.secret, TimeoutMilliseconds: 1000})
+			err := handler.Handle(context.Background(), buses.Message{Value: body, Headers: tt.headers})
+
+			assert.Equal(t, tt.expectedErr, err != nil)
+			assert.Equal(t, tt.expectedPosted, posted)
+		})
+	}
+}
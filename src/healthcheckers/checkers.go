@@ -0,0 +1,95 @@
+package healthcheckers
+
+import (
+	"context"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/readpref"
+
+	"github.com/tam-code/image-upload/config"
+	"github.com/tam-code/image-upload/src/kafka"
+	"github.com/tam-code/image-upload/src/storages"
+)
+
+type (
+	// Checker tells if a dependency of the service is usable.
+	Checker interface {
+		Name() string
+		Check(ctx context.Context) error
+	}
+
+	mongoChecker struct {
+		mongoDB *mongo.Database
+	}
+
+	kafkaBrokersChecker struct {
+		cfg config.KafkaConfig
+	}
+
+	consumerGroupChecker struct {
+		cfg config.KafkaConfig
+	}
+
+	storageChecker struct {
+		imageStorage storages.ImageStorage
+	}
+)
+
+// NewMongoChecker pings the primary of the MongoDB deployment.
+func NewMongoChecker(mongoDB *mongo.Database) Checker {
+	return &mongoChecker{mongoDB: mongoDB}
+}
+
+func (c *mongoChecker) Name() string {
+	return "mongo"
+}
+
+func (c *mongoChecker) Check(ctx context.Context) error {
+	return c.mongoDB.Client().Ping(ctx, readpref.Primary())
+}
+
+// NewKafkaBrokersChecker fetches the metadata of the topic from the brokers.
+func NewKafkaBrokersChecker(cfg config.KafkaConfig) Checker {
+	return &kafkaBrokersChecker{cfg: cfg}
+}
+
+func (c *kafkaBrokersChecker) Name() string {
+	return "kafka"
+}
+
+func (c *kafkaBrokersChecker) Check(ctx context.Context) error {
+	return kafka.CheckBrokers(ctx, c.cfg)
+}
+
+// NewConsumerGroupChecker checks the consumer of the instance joined its group.
+func NewConsumerGroupChecker(cfg config.KafkaConfig) Checker {
+	return &consumerGroupChecker{cfg: cfg}
+}
+
+func (c *consumerGroupChecker) Name() string {
+	return "kafka_consumer_group"
+}
+
+func (c *consumerGroupChecker) Check(ctx context.Context) error {
+	return kafka.CheckGroupMembership(ctx, c.cfg)
+}
+
+// NewStorageChecker stages and removes an empty file to check the storage is
+// writable, a file left behind is collected as an orphan.
+func NewStorageChecker(imageStorage storages.ImageStorage) Checker {
+	return &storageChecker{imageStorage: imageStorage}
+}
+
+func (c *storageChecker) Name() string {
+	return "storage"
+}
+
+func (c *storageChecker) Check(ctx context.Context) error {
+	path, err := c.imageStorage.Stage(strings.NewReader(""))
+	if err != nil {
+		return err
+	}
+
+	return c.imageStorage.Remove(path)
+}
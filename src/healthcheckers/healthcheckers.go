@@ -0,0 +1,164 @@
+package healthcheckers
+
+import (
+	"context"
+	"runtime"
+	"runtime/debug"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"github.com/tam-code/image-upload/config"
+	"github.com/tam-code/image-upload/src/buses"
+	"github.com/tam-code/image-upload/src/models"
+	"github.com/tam-code/image-upload/src/storages"
+)
+
+const defaultCheckTimeout = 2 * time.Second
+
+// dependencyModules are the modules whose version the status reports.
+var dependencyModules = map[string]string{
+	"github.com/segmentio/kafka-go": "kafka-go",
+	"go.mongodb.org/mongo-driver":   "mongo-driver",
+}
+
+type (
+	HealthChecker interface {
+		Ready(ctx context.Context) *models.ReadinessReport
+		Status(ctx context.Context) *models.StatusReport
+	}
+
+	healthChecker struct {
+		checkers   []Checker
+		timeout    time.Duration
+		mongoDB    *mongo.Database
+		consumer   buses.Consumer
+		configHash string
+		versions   map[string]string
+	}
+)
+
+// NewHealthChecker checks MongoDB, the image storage and, with the Kafka bus,
+// the brokers and the membership of the consumer of the instance in its group.
+func NewHealthChecker(cfg *config.Config, mongoDB *mongo.Database, consumer buses.Consumer) HealthChecker {
+	timeout := time.Duration(cfg.Health.CheckTimeoutMilliseconds) * time.Millisecond
+	if timeout <= 0 {
+		timeout = defaultCheckTimeout
+	}
+
+	checkers := []Checker{
+		NewMongoChecker(mongoDB),
+		NewStorageChecker(storages.NewImageStorage(storages.UploadPath)),
+	}
+	if cfg.Bus.Type == buses.KafkaBus {
+		checkers = append(checkers, NewKafkaBrokersChecker(cfg.Kafka), NewConsumerGroupChecker(cfg.Kafka))
+	}
+
+	return &healthChecker{
+		checkers:   checkers,
+		timeout:    timeout,
+		mongoDB:    mongoDB,
+		consumer:   consumer,
+		configHash: cfg.Hash(),
+		versions:   buildVersions(),
+	}
+}
+
+// Ready runs the dependency checks concurrently, each bounded by the timeout.
+func (c *healthChecker) Ready(ctx context.Context) *models.ReadinessReport {
+	report := &models.ReadinessReport{
+		Status: models.HealthUp,
+		Checks: make([]models.DependencyCheck, len(c.checkers)),
+	}
+
+	var wg sync.WaitGroup
+	for i, checker := range c.checkers {
+		wg.Add(1)
+		go func(i int, checker Checker) {
+			defer wg.Done()
+			report.Checks[i] = c.check(ctx, checker)
+		}(i, checker)
+	}
+	wg.Wait()
+
+	for _, check := range report.Checks {
+		if check.Status != models.HealthUp {
+			report.Status = models.HealthDown
+		}
+	}
+
+	return report
+}
+
+func (c *healthChecker) check(ctx context.Context, checker Checker) models.DependencyCheck {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	start := time.Now()
+	err := checker.Check(ctx)
+
+	check := models.DependencyCheck{
+		Name:       checker.Name(),
+		Status:     models.HealthUp,
+		DurationMs: time.Since(start).Milliseconds(),
+	}
+	if err != nil {
+		check.Status = models.HealthDown
+		check.Error = err.Error()
+	}
+
+	return check
+}
+
+// Status reports the versions, the configuration hash, the consumer lag and
+// the readiness of the instance.
+func (c *healthChecker) Status(ctx context.Context) *models.StatusReport {
+	versions := make(map[string]string, len(c.versions)+1)
+	for name, version := range c.versions {
+		versions[name] = version
+	}
+
+	if c.mongoDB != nil {
+		var buildInfo struct {
+			Version string `bson:"version"`
+		}
+		if err := c.mongoDB.RunCommand(ctx, bson.D{{Key: "buildInfo", Value: 1}}).Decode(&buildInfo); err == nil {
+			versions["mongo"] = buildInfo.Version
+		}
+	}
+
+	return &models.StatusReport{
+		Versions:    versions,
+		ConfigHash:  c.configHash,
+		ConsumerLag: c.consumer.Lag(),
+		Readiness:   c.Ready(ctx),
+	}
+}
+
+// buildVersions reads the versions of the service and its main dependencies
+// from the build information of the binary.
+func buildVersions() map[string]string {
+	versions := map[string]string{"go": runtime.Version()}
+
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return versions
+	}
+
+	versions["service"] = info.Main.Version
+	for _, setting := range info.Settings {
+		if setting.Key == "vcs.revision" {
+			versions["revision"] = setting.Value
+		}
+	}
+
+	for _, dependency := range info.Deps {
+		if name, ok := dependencyModules[dependency.Path]; ok {
+			versions[name] = dependency.Version
+		}
+	}
+
+	return versions
+}
@@ -0,0 +1,69 @@
+package healthcheckers
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tam-code/image-upload/src/models"
+)
+
+type checkerFunc struct {
+	name  string
+	check func(ctx context.Context) error
+}
+
+func (c checkerFunc) Name() string                    { return c.name }
+func (c checkerFunc) Check(ctx context.Context) error { return c.check(ctx) }
+
+func TestReady(t *testing.T) {
+	up := checkerFunc{name: "mongo", check: func(ctx context.Context) error { return nil }}
+	down := checkerFunc{name: "kafka", check: func(ctx context.Context) error { return errors.New("connection refused") }}
+	hanging := checkerFunc{name: "storage", check: func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}}
+
+	tests := []struct {
+		name           string
+		checkers       []Checker
+		expectedStatus models.HealthStatus
+		expectedErrors []string
+	}{
+		{
+			name:           "every dependency up",
+			checkers:       []Checker{up},
+			expectedStatus: models.HealthUp,
+			expectedErrors: []string{""},
+		},
+		{
+			name:           "a dependency down",
+			checkers:       []Checker{up, down},
+			expectedStatus: models.HealthDown,
+			expectedErrors: []string{"", "connection refused"},
+		},
+		{
+			name:           "a check timing out",
+			checkers:       []Checker{hanging, up},
+			expectedStatus: models.HealthDown,
+			expectedErrors: []string{context.DeadlineExceeded.Error(), ""},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checker := &healthChecker{checkers: tt.checkers, timeout: 10 * time.Millisecond}
+
+			report := checker.Ready(context.Background())
+
+			assert.Equal(t, tt.expectedStatus, report.Status)
+			assert.Len(t, report.Checks, len(tt.checkers))
+			for i, check := range report.Checks {
+				assert.Equal(t, tt.checkers[i].Name(), check.Name)
+				assert.Equal(t, tt.expectedErrors[i], check.Error)
+			}
+		})
+	}
+}
@@ -0,0 +1,210 @@
+package janitors
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/tam-code/image-upload/src/loggers"
+	"github.com/tam-code/image-upload/src/models"
+	"github.com/tam-code/image-upload/src/producers"
+	"github.com/tam-code/image-upload/src/repositories"
+	"github.com/tam-code/image-upload/src/storages"
+)
+
+const (
+	defaultGarbageCollectionInterval = time.Hour
+)
+
+type (
+	GarbageCollector interface {
+		Run(ctx context.Context)
+		Collect(ctx context.Context, dryRun bool) (*models.GarbageCollectionReport, error)
+		DryRun() bool
+	}
+
+	garbageCollector struct {
+		uploadLinkRepository repositories.UploadLinkRepository
+		imageRepository      repositories.ImageRepository
+		imageDeletedProducer producers.ImageDeletedProducer
+		imageStorage         storages.ImageStorage
+		interval             time.Duration
+		expiredLinkRetention time.Duration
+		orphanGrace          time.Duration
+		dryRun               bool
+
+		// runs are serialized, the schedule and on demand runs would race otherwise
+		mu sync.Mutex
+	}
+)
+
+func NewGarbageCollector(repositories *repositories.Repositories, producers *producers.Producers, imageStorage storages.ImageStorage, interval, expiredLinkRetention, orphanGrace time.Duration, dryRun bool) GarbageCollector {
+	if interval <= 0 {
+		interval = defaultGarbageCollectionInterval
+	}
+
+	return &garbageCollector{
+		uploadLinkRepository: repositories.UploadLink,
+		imageRepository:      repositories.Image,
+		imageDeletedProducer: producers.ImageDeleted,
+		imageStorage:         imageStorage,
+		interval:             interval,
+		expiredLinkRetention: expiredLinkRetention,
+		orphanGrace:          orphanGrace,
+		dryRun:               dryRun,
+	}
+}
+
+// Run collects garbage every interval until ctx is done, with the configured dry run mode.
+func (g *garbageCollector) Run(ctx context.Context) {
+	ticker := time.NewTicker(g.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := g.Collect(ctx, g.dryRun); err != nil {
+				loggers.FromContext(ctx).Error("error collecting garbage", "error", err)
+			}
+		}
+	}
+}
+
+func (g *garbageCollector) DryRun() bool {
+	return g.dryRun
+}
+
+// Collect deletes the upload links expired for longer than the retention with
+// their images, then the stored files no image references. On a dry run
+// nothing is deleted and the report lists what would have been.
+func (g *garbageCollector) Collect(ctx context.Context, dryRun bool) (*models.GarbageCollectionReport, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	report := &models.GarbageCollectionReport{
+		DryRun:             dryRun,
+		StartedAt:          time.Now(),
+		ExpiredUploadLinks: []string{},
+		OrphanedFiles:      []string{},
+	}
+
+	if g.expiredLinkRetention > 0 {
+		if err := g.collectExpiredUploadLinks(ctx, report); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := g.collectOrphanedFiles(ctx, report); err != nil {
+		return nil, err
+	}
+
+	report.FinishedAt = time.Now()
+
+	loggers.FromContext(ctx).Info("garbage collected",
+		"dry_run", report.DryRun,
+		"expired_upload_links", len(report.ExpiredUploadLinks),
+		"deleted_images", report.DeletedImages,
+		"orphaned_files", len(report.OrphanedFiles),
+		"reclaimed_bytes", report.ReclaimedBytes,
+		"errors", len(report.Errors),
+	)
+
+	return report, nil
+}
+
+// collectExpiredUploadLinks soft deletes the images of the expired upload links
+// so the statistics follow and the purger removes their files, the links
+// themselves are deleted right away.
+func (g *garbageCollector) collectExpiredUploadLinks(ctx context.Context, report *models.GarbageCollectionReport) error {
+	uploadLinks, err := g.uploadLinkRepository.GetUploadLinksExpiredBefore(ctx, report.StartedAt.Add(-g.expiredLinkRetention))
+	if err != nil {
+		return err
+	}
+
+	for _, uploadLink := range uploadLinks {
+		if report.DryRun {
+			count, err := g.imageRepository.CountImagesByUploadLinkID(ctx, uploadLink.Tenant, uploadLink.ID)
+			if err != nil {
+				report.Errors = append(report.Errors, err.Error())
+				continue
+			}
+
+			report.ExpiredUploadLinks = append(report.ExpiredUploadLinks, uploadLink.ID)
+			report.DeletedImages += count
+			continue
+		}
+
+		deletedImages, err := g.imageRepository.SoftDeleteImagesByUploadLinkID(ctx, uploadLink.Tenant, uploadLink.ID, report.StartedAt)
+		if err != nil {
+			report.Errors = append(report.Errors, err.Error())
+			continue
+		}
+
+		if len(deletedImages) > 0 {
+			if err = g.imageDeletedProducer.Publish(ctx, uploadLink.Tenant, deletedImages); err != nil {
+				loggers.FromContext(ctx).Error("error publishing images deleted event", "upload_link_id", uploadLink.ID, "error", err)
+			}
+		}
+		report.DeletedImages += len(deletedImages)
+
+		if err = g.uploadLinkRepository.DeleteUploadLink(ctx, uploadLink.Tenant, uploadLink.ID); err != nil {
+			report.Errors = append(report.Errors, err.Error())
+			continue
+		}
+
+		report.ExpiredUploadLinks = append(report.ExpiredUploadLinks, uploadLink.ID)
+	}
+
+	return nil
+}
+
+// collectOrphanedFiles removes the stored files no image record points to,
+// files younger than the grace period may belong to an upload in progress.
+func (g *garbageCollector) collectOrphanedFiles(ctx context.Context, report *models.GarbageCollectionReport) error {
+	files, err := g.imageStorage.List()
+	if err != nil {
+		return fmt.Errorf("error listing stored files: %w", err)
+	}
+
+	referencedPaths := make(map[string]map[string]bool)
+	for _, file := range files {
+		if file.ModTime.After(report.StartedAt.Add(-g.orphanGrace)) {
+			continue
+		}
+
+		paths, ok := referencedPaths[file.UploadLinkID]
+		if !ok {
+			imagePaths, err := g.imageRepository.GetImagePathsByUploadLinkID(ctx, file.UploadLinkID)
+			if err != nil {
+				report.Errors = append(report.Errors, err.Error())
+				continue
+			}
+
+			paths = make(map[string]bool)
+			for _, path := range imagePaths {
+				paths[filepath.Clean(path)] = true
+			}
+			referencedPaths[file.UploadLinkID] = paths
+		}
+
+		if paths[file.Path] {
+			continue
+		}
+
+		if !report.DryRun {
+			if err = g.imageStorage.Remove(file.Path); err != nil {
+				report.Errors = append(report.Errors, err.Error())
+				continue
+			}
+		}
+
+		report.OrphanedFiles = append(report.OrphanedFiles, file.Path)
+		report.ReclaimedBytes += file.Size
+	}
+
+	return nil
+}
@@ -0,0 +1,102 @@
+package janitors
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	mocksProducer "github.com/tam-code/image-upload/mocks/producers"
+	mocks "github.com/tam-code/image-upload/mocks/repositories"
+	"github.com/tam-code/image-upload/src/models"
+	"github.com/tam-code/image-upload/src/storages"
+)
+
+func TestCollect(t *testing.T) {
+	tests := []struct {
+		name                 string
+		dryRun               bool
+		mockFunc             func(uploadLinkRepo *mocks.MockUploadLinkRepository, imageRepo *mocks.MockImageRepository, producer *mocksProducer.MockImageDeletedProducer)
+		expectedDeletedFiles bool
+	}{
+		{
+			name: "delete expired upload links and orphaned files",
+			mockFunc: func(uploadLinkRepo *mocks.MockUploadLinkRepository, imageRepo *mocks.MockImageRepository, producer *mocksProducer.MockImageDeletedProducer) {
+				uploadLinkRepo.EXPECT().GetUploadLinksExpiredBefore(gomock.Any(), gomock.Any()).Return([]models.UploadLink{{ID: "expired", Tenant: "marketing"}}, nil)
+				imageRepo.EXPECT().SoftDeleteImagesByUploadLinkID(gomock.Any(), "marketing", "expired", gomock.Any()).Return([]string{"first", "second"}, nil)
+				producer.EXPECT().Publish(gomock.Any(), "marketing", []string{"first", "second"}).Return(nil)
+				uploadLinkRepo.EXPECT().DeleteUploadLink(gomock.Any(), "marketing", "expired").Return(nil)
+			},
+			expectedDeletedFiles: true,
+		},
+		{
+			name:   "dry run",
+			dryRun: true,
+			mockFunc: func(uploadLinkRepo *mocks.MockUploadLinkRepository, imageRepo *mocks.MockImageRepository, producer *mocksProducer.MockImageDeletedProducer) {
+				uploadLinkRepo.EXPECT().GetUploadLinksExpiredBefore(gomock.Any(), gomock.Any()).Return([]models.UploadLink{{ID: "expired", Tenant: "marketing"}}, nil)
+				imageRepo.EXPECT().CountImagesByUploadLinkID(gomock.Any(), "marketing", "expired").Return(2, nil)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockUploadLinkRepo := mocks.NewMockUploadLinkRepository(ctrl)
+			mockImageRepo := mocks.NewMockImageRepository(ctrl)
+			mockImageDeletedProducer := mocksProducer.NewMockImageDeletedProducer(ctrl)
+
+			root := t.TempDir()
+			old := time.Now().Add(-2 * time.Hour)
+			writeFile := func(uploadLinkID, name string, modTime time.Time) string {
+				assert.NoError(t, os.MkdirAll(filepath.Join(root, uploadLinkID), os.ModePerm))
+				path := filepath.Join(root, uploadLinkID, name)
+				assert.NoError(t, os.WriteFile(path, []byte("image"), 0o644))
+				assert.NoError(t, os.Chtimes(path, modTime, modTime))
+				return path
+			}
+
+			referenced := writeFile("link", "referenced.jpg", old)
+			orphaned := writeFile("link", "orphaned.jpg", old)
+			recent := writeFile("link", "recent.jpg", time.Now())
+			unknownLink := writeFile("unknown", "orphaned.jpg", old)
+
+			tt.mockFunc(mockUploadLinkRepo, mockImageRepo, mockImageDeletedProducer)
+			mockImageRepo.EXPECT().GetImagePathsByUploadLinkID(gomock.Any(), "link").Return([]string{referenced, recent}, nil)
+			mockImageRepo.EXPECT().GetImagePathsByUploadLinkID(gomock.Any(), "unknown").Return(nil, nil)
+
+			collector := &garbageCollector{
+				uploadLinkRepository: mockUploadLinkRepo,
+				imageRepository:      mockImageRepo,
+				imageDeletedProducer: mockImageDeletedProducer,
+				imageStorage:         storages.NewImageStorage(root),
+				expiredLinkRetention: 24 * time.Hour,
+				orphanGrace:          time.Hour,
+			}
+
+			report, err := collector.Collect(context.Background(), tt.dryRun)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.dryRun, report.DryRun)
+			assert.Equal(t, []string{"expired"}, report.ExpiredUploadLinks)
+			assert.Equal(t, 2, report.DeletedImages)
+			assert.ElementsMatch(t, []string{orphaned, unknownLink}, report.OrphanedFiles)
+			assert.Equal(t, int64(10), report.ReclaimedBytes)
+			assert.Empty(t, report.Errors)
+
+			assert.FileExists(t, referenced)
+			assert.FileExists(t, recent)
+			if tt.expectedDeletedFiles {
+				assert.NoFileExists(t, orphaned)
+				assert.NoFileExists(t, unknownLink)
+			} else {
+				assert.FileExists(t, orphaned)
+				assert.FileExists(t, unknownLink)
+			}
+		})
+	}
+}
@@ -0,0 +1,111 @@
+package janitors
+
+import (
+	"context"
+	"time"
+
+	"github.com/tam-code/image-upload/src/loggers"
+	"github.com/tam-code/image-upload/src/repositories"
+	"github.com/tam-code/image-upload/src/storages"
+)
+
+const (
+	defaultPurgeInterval  = 5 * time.Minute
+	defaultPurgeBatchSize = 100
+)
+
+type (
+	ImagePurger interface {
+		Run(ctx context.Context)
+		Purge(ctx context.Context) (int, error)
+	}
+
+	imagePurger struct {
+		imageRepository repositories.ImageRepository
+		imageStorage    storages.ImageStorage
+		delay           time.Duration
+		interval        time.Duration
+		batchSize       int
+	}
+)
+
+func NewImagePurger(repositories *repositories.Repositories, imageStorage storages.ImageStorage, delay, interval time.Duration, batchSize int) ImagePurger {
+	if interval <= 0 {
+		interval = defaultPurgeInterval
+	}
+
+	if batchSize <= 0 {
+		batchSize = defaultPurgeBatchSize
+	}
+
+	return &imagePurger{
+		imageRepository: repositories.Image,
+		imageStorage:    imageStorage,
+		delay:           delay,
+		interval:        interval,
+		batchSize:       batchSize,
+	}
+}
+
+// Run purges the images deleted for longer than the delay every interval until ctx is done.
+func (p *imagePurger) Run(ctx context.Context) {
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			// keep purging while full batches come back
+			for {
+				purged, err := p.Purge(ctx)
+				if err != nil {
+					loggers.FromContext(ctx).Error("error purging deleted images", "error", err)
+				}
+
+				if err != nil || purged < p.batchSize {
+					break
+				}
+			}
+		}
+	}
+}
+
+// Purge removes the files and records of one batch of images whose purge delay
+// elapsed and returns how many were purged.
+func (p *imagePurger) Purge(ctx context.Context) (int, error) {
+	logger := loggers.FromContext(ctx)
+
+	images, err := p.imageRepository.GetImagesDeletedBefore(ctx, time.Now().Add(-p.delay), p.batchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	purged := 0
+	for _, image := range images {
+		// an image uploaded again under the same name after the deletion shares the file
+		liveImage, err := p.imageRepository.GetImageByNameAndUploadLinkID(ctx, image.Tenant, image.Name, image.UploadLinkID)
+		if err != nil {
+			logger.Error("error checking image file is unused", "image_id", image.ID, "error", err)
+			continue
+		}
+
+		// the stored file is the only blob kept for an image
+		if liveImage == nil || liveImage.Path != image.Path {
+			if err = p.imageStorage.Remove(image.Path); err != nil {
+				logger.Error("error removing image file", "image_id", image.ID, "error", err)
+				continue
+			}
+		}
+
+		if err = p.imageRepository.DeleteImage(ctx, image.Tenant, image.ID); err != nil {
+			logger.Error("error purging image", "image_id", image.ID, "error", err)
+			continue
+		}
+
+		purged++
+	}
+
+	return purged, nil
+}
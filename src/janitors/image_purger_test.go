@@ -0,0 +1,84 @@
+package janitors
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	mocks "github.com/tam-code/image-upload/mocks/repositories"
+	"github.com/tam-code/image-upload/src/models"
+	"github.com/tam-code/image-upload/src/storages"
+)
+
+func TestPurge(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockImageRepo := mocks.NewMockImageRepository(ctrl)
+
+	dir := t.TempDir()
+	purger := &imagePurger{
+		imageRepository: mockImageRepo,
+		imageStorage:    storages.NewImageStorage(dir),
+		delay:           time.Hour,
+		batchSize:       10,
+	}
+
+	writeFile := func(name string) string {
+		path := filepath.Join(dir, name)
+		assert.NoError(t, os.WriteFile(path, []byte("image"), 0o644))
+		return path
+	}
+
+	deletedPath := writeFile("deleted.jpg")
+	reuploadedPath := writeFile("reuploaded.jpg")
+	failingPath := writeFile("failing.jpg")
+
+	mockImageRepo.EXPECT().GetImagesDeletedBefore(gomock.Any(), gomock.Any(), 10).DoAndReturn(func(_ context.Context, before time.Time, limit int) ([]models.Image, error) {
+		assert.WithinDuration(t, time.Now().Add(-time.Hour), before, time.Second)
+		return []models.Image{
+			{ID: "deleted", Name: "deleted.jpg", Tenant: "marketing", UploadLinkID: "link", Path: deletedPath},
+			{ID: "reuploaded", Name: "reuploaded.jpg", Tenant: "marketing", UploadLinkID: "link", Path: reuploadedPath},
+			{ID: "missing", Name: "missing.jpg", Tenant: "marketing", UploadLinkID: "link", Path: filepath.Join(dir, "missing.jpg")},
+			{ID: "failing", Name: "failing.jpg", Tenant: "marketing", UploadLinkID: "link", Path: failingPath},
+		}, nil
+	})
+
+	mockImageRepo.EXPECT().GetImageByNameAndUploadLinkID(gomock.Any(), "marketing", "deleted.jpg", "link").Return(nil, nil)
+	mockImageRepo.EXPECT().DeleteImage(gomock.Any(), "marketing", "deleted").Return(nil)
+
+	mockImageRepo.EXPECT().GetImageByNameAndUploadLinkID(gomock.Any(), "marketing", "reuploaded.jpg", "link").Return(&models.Image{ID: "new", Path: reuploadedPath}, nil)
+	mockImageRepo.EXPECT().DeleteImage(gomock.Any(), "marketing", "reuploaded").Return(nil)
+
+	mockImageRepo.EXPECT().GetImageByNameAndUploadLinkID(gomock.Any(), "marketing", "missing.jpg", "link").Return(nil, nil)
+	mockImageRepo.EXPECT().DeleteImage(gomock.Any(), "marketing", "missing").Return(nil)
+
+	mockImageRepo.EXPECT().GetImageByNameAndUploadLinkID(gomock.Any(), "marketing", "failing.jpg", "link").Return(nil, errors.New("db error"))
+
+	purged, err := purger.Purge(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 3, purged)
+
+	assert.NoFileExists(t, deletedPath)
+	assert.FileExists(t, reuploadedPath)
+	assert.FileExists(t, failingPath)
+}
+
+func TestPurgeRepositoryError(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockImageRepo := mocks.NewMockImageRepository(ctrl)
+	mockImageRepo.EXPECT().GetImagesDeletedBefore(gomock.Any(), gomock.Any(), 10).Return(nil, errors.New("db error"))
+
+	purger := &imagePurger{imageRepository: mockImageRepo, batchSize: 10}
+
+	purged, err := purger.Purge(context.Background())
+	assert.Error(t, err)
+	assert.Equal(t, 0, purged)
+}
@@ -0,0 +1,45 @@
+package janitors
+
+import (
+	"context"
+	"time"
+
+	"github.com/tam-code/image-upload/config"
+	"github.com/tam-code/image-upload/src/producers"
+	"github.com/tam-code/image-upload/src/repositories"
+	"github.com/tam-code/image-upload/src/storages"
+)
+
+type Janitors struct {
+	ImagePurger      ImagePurger
+	GarbageCollector GarbageCollector
+
+	garbageCollectorEnabled bool
+}
+
+func NewJanitors(cfg *config.Config, repositories *repositories.Repositories, producers *producers.Producers) *Janitors {
+	imageStorage := storages.NewImageStorage(storages.UploadPath)
+
+	return &Janitors{
+		ImagePurger: NewImagePurger(repositories, imageStorage,
+			time.Duration(cfg.Deletion.PurgeDelaySeconds)*time.Second,
+			time.Duration(cfg.Deletion.PurgeIntervalSeconds)*time.Second,
+			cfg.Deletion.PurgeBatchSize,
+		),
+		GarbageCollector: NewGarbageCollector(repositories, producers, imageStorage,
+			time.Duration(cfg.GarbageCollector.IntervalSeconds)*time.Second,
+			time.Duration(cfg.GarbageCollector.ExpiredLinkRetentionSeconds)*time.Second,
+			time.Duration(cfg.GarbageCollector.OrphanGraceSeconds)*time.Second,
+			cfg.GarbageCollector.DryRun,
+		),
+		garbageCollectorEnabled: cfg.GarbageCollector.Enabled,
+	}
+}
+
+func (j *Janitors) Run() {
+	go j.ImagePurger.Run(context.Background())
+
+	if j.garbageCollectorEnabled {
+		go j.GarbageCollector.Run(context.Background())
+	}
+}
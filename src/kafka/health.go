@@ -0,0 +1,112 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	segmentio "github.com/segmentio/kafka-go"
+
+	"github.com/tam-code/image-upload/config"
+)
+
+const defaultDialerTimeout = 10 * time.Second
+
+// ClientID identifies the connections of this instance, so its membership
+// of the consumer group can be told apart from the other instances.
+var ClientID = newClientID()
+
+var ErrNotGroupMember = errors.New("not a member of the consumer group")
+
+// CheckBrokers fetches the metadata of the topic from the brokers.
+func CheckBrokers(ctx context.Context, cfg config.KafkaConfig) error {
+	client, transport, err := newClient(cfg)
+	if err != nil {
+		return err
+	}
+	defer transport.CloseIdleConnections()
+
+	metadata, err := client.Metadata(ctx, &segmentio.MetadataRequest{Topics: []string{cfg.Topic}})
+	if err != nil {
+		return err
+	}
+
+	for _, topic := range metadata.Topics {
+		if topic.Name == cfg.Topic && topic.Error != nil {
+			return fmt.Errorf("topic %s: %w", cfg.Topic, topic.Error)
+		}
+	}
+
+	return nil
+}
+
+// CheckGroupMembership tells if the consumer of this instance joined the
+// consumer group, it may have no partition assigned when the group has more
+// members than the topic has partitions.
+func CheckGroupMembership(ctx context.Context, cfg config.KafkaConfig) error {
+	client, transport, err := newClient(cfg)
+	if err != nil {
+		return err
+	}
+	defer transport.CloseIdleConnections()
+
+	groups, err := client.DescribeGroups(ctx, &segmentio.DescribeGroupsRequest{GroupIDs: []string{cfg.Group}})
+	if err != nil {
+		return err
+	}
+
+	for _, group := range groups.Groups {
+		if group.GroupID != cfg.Group {
+			continue
+		}
+
+		if group.Error != nil {
+			return fmt.Errorf("group %s: %w", cfg.Group, group.Error)
+		}
+
+		for _, member := range group.Members {
+			if member.ClientID == ClientID {
+				return nil
+			}
+		}
+	}
+
+	return ErrNotGroupMember
+}
+
+// newClient returns the transport of the client too, its connections are
+// closed once the request is done.
+func newClient(cfg config.KafkaConfig) (*segmentio.Client, *segmentio.Transport, error) {
+	transport, err := newTransport(cfg)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	client := &segmentio.Client{
+		Addr:      segmentio.TCP(strings.Split(cfg.Brokers, ",")...),
+		Timeout:   dialerTimeout(cfg),
+		Transport: transport,
+	}
+
+	return client, transport, nil
+}
+
+func dialerTimeout(cfg config.KafkaConfig) time.Duration {
+	if cfg.DialerTimeoutMilliseconds <= 0 {
+		return defaultDialerTimeout
+	}
+
+	return time.Duration(cfg.DialerTimeoutMilliseconds) * time.Millisecond
+}
+
+func newClientID() string {
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "unknown"
+	}
+
+	return fmt.Sprintf("image-upload-%s-%d", hostname, os.Getpid())
+}
@@ -8,20 +8,49 @@ import (
 	"github.com/tam-code/image-upload/config"
 )
 
-type Message = segmentio.Message
-type Header = segmentio.Header
+// NewKafkaWriter builds the writer of the topic, with the producer tuning of
+// the configuration. The idempotent writers require the acks of all the
+// replicas, kafka-go has no idempotent producer so the retried batches are
+// dropped by the consumers of the bus instead.
+func NewKafkaWriter(cfg config.KafkaConfig) (*segmentio.Writer, error) {
+	transport, err := newTransport(cfg)
+	if err != nil {
+		return nil, err
+	}
 
-func NewKafkaWriter(cfg config.KafkaConfig) *segmentio.Writer {
-	writerConfig := segmentio.WriterConfig{
-		Brokers:  strings.Split(cfg.Brokers, ","),
-		Topic:    cfg.Topic,
-		Balancer: &segmentio.LeastBytes{},
+	requiredAcks := segmentio.RequireAll
+	if cfg.Producer.RequiredAcks != "" && !cfg.Producer.Idempotent {
+		if err := requiredAcks.UnmarshalText([]byte(cfg.Producer.RequiredAcks)); err != nil {
+			return nil, err
+		}
 	}
 
-	return segmentio.NewWriter(writerConfig)
+	var compression segmentio.Compression
+	if cfg.Producer.Compression != "" {
+		if err := compression.UnmarshalText([]byte(cfg.Producer.Compression)); err != nil {
+			return nil, err
+		}
+	}
+
+	return &segmentio.Writer{
+		Addr:         segmentio.TCP(strings.Split(cfg.Brokers, ",")...),
+		Topic:        cfg.Topic,
+		Balancer:     &segmentio.LeastBytes{},
+		Transport:    transport,
+		RequiredAcks: requiredAcks,
+		Compression:  compression,
+		BatchSize:    cfg.Producer.BatchSize,
+		BatchTimeout: time.Duration(cfg.Producer.BatchTimeoutMilliseconds) * time.Millisecond,
+		MaxAttempts:  cfg.Producer.MaxAttempts,
+	}, nil
 }
 
-func NewKafkaReader(cfg config.KafkaConfig) *segmentio.Reader {
+func NewKafkaReader(cfg config.KafkaConfig) (*segmentio.Reader, error) {
+	dialer, err := newDialer(cfg)
+	if err != nil {
+		return nil, err
+	}
+
 	readerConfig := segmentio.ReaderConfig{
 		Brokers:  strings.Split(cfg.Brokers, ","),
 		GroupID:  cfg.Group,
@@ -29,7 +58,8 @@ func NewKafkaReader(cfg config.KafkaConfig) *segmentio.Reader {
 		MinBytes: 10e3, // 10KB
 		MaxBytes: 10e6, // 10MB
 		MaxWait:  time.Duration(cfg.MaxWaitTimeoutMilliseconds) * time.Millisecond,
+		Dialer:   dialer,
 	}
 
-	return segmentio.NewReader(readerConfig)
+	return segmentio.NewReader(readerConfig), nil
 }
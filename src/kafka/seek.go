@@ -0,0 +1,182 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	segmentio "github.com/segmentio/kafka-go"
+
+	"github.com/tam-code/image-upload/config"
+)
+
+var ErrGroupActive = errors.New("the consumer group has members")
+
+// SeekGroup commits the offsets of the first messages of the partitions of
+// the topic produced at or after the time, or the offset when the time is
+// zero, for the consumer group. The offsets are clamped to the retained
+// messages, and the end offsets of the partitions are returned. Kafka only
+// takes the commit when the group has no member, ErrGroupActive is returned
+// otherwise.
+func SeekGroup(ctx context.Context, cfg config.KafkaConfig, at time.Time, offset int64) (map[int]int64, error) {
+	client, transport, err := newClient(cfg)
+	if err != nil {
+		return nil, err
+	}
+	defer transport.CloseIdleConnections()
+
+	metadata, err := client.Metadata(ctx, &segmentio.MetadataRequest{Topics: []string{cfg.Topic}})
+	if err != nil {
+		return nil, err
+	}
+
+	var partitions []int
+	for _, topic := range metadata.Topics {
+		if topic.Name != cfg.Topic {
+			continue
+		}
+
+		if topic.Error != nil {
+			return nil, fmt.Errorf("topic %s: %w", cfg.Topic, topic.Error)
+		}
+
+		for _, partition := range topic.Partitions {
+			partitions = append(partitions, partition.ID)
+		}
+	}
+
+	// a partition may only be asked one offset by request
+	first, err := listOffsets(ctx, client, cfg.Topic, partitions, segmentio.FirstOffsetOf, firstOffset)
+	if err != nil {
+		return nil, err
+	}
+
+	ends, err := listOffsets(ctx, client, cfg.Topic, partitions, segmentio.LastOffsetOf, lastOffset)
+	if err != nil {
+		return nil, err
+	}
+
+	targets := map[int]int64{}
+	if at.IsZero() {
+		for _, partition := range partitions {
+			targets[partition] = offset
+		}
+	} else {
+		targets, err = listOffsets(ctx, client, cfg.Topic, partitions, func(partition int) segmentio.OffsetRequest {
+			return segmentio.TimeOffsetOf(partition, at)
+		}, timeOffset)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	commits := make([]segmentio.OffsetCommit, 0, len(partitions))
+	for _, partition := range partitions {
+		target := targets[partition]
+		// no message was produced after the time
+		if target < 0 {
+			target = ends[partition]
+		}
+
+		commits = append(commits, segmentio.OffsetCommit{Partition: partition, Offset: min(max(target, first[partition]), ends[partition])})
+	}
+
+	// the generation -1 commits the offsets of a group without member
+	response, err := client.OffsetCommit(ctx, &segmentio.OffsetCommitRequest{
+		GroupID:      cfg.Group,
+		GenerationID: -1,
+		Topics:       map[string][]segmentio.OffsetCommit{cfg.Topic: commits},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, partition := range response.Topics[cfg.Topic] {
+		if partition.Error == nil {
+			continue
+		}
+
+		if errors.Is(partition.Error, segmentio.UnknownMemberId) || errors.Is(partition.Error, segmentio.IllegalGeneration) || errors.Is(partition.Error, segmentio.RebalanceInProgress) {
+			return nil, fmt.Errorf("group %s: %w", cfg.Group, ErrGroupActive)
+		}
+
+		return nil, fmt.Errorf("partition %d of %s: %w", partition.Partition, cfg.Topic, partition.Error)
+	}
+
+	return ends, nil
+}
+
+// listOffsets sends the request of every partition and returns the offset
+// read from the answer of each one.
+func listOffsets(ctx context.Context, client *segmentio.Client, topic string, partitions []int, request func(partition int) segmentio.OffsetRequest, read func(segmentio.PartitionOffsets) int64) (map[int]int64, error) {
+	requests := make([]segmentio.OffsetRequest, len(partitions))
+	for i, partition := range partitions {
+		requests[i] = request(partition)
+	}
+
+	response, err := client.ListOffsets(ctx, &segmentio.ListOffsetsRequest{Topics: map[string][]segmentio.OffsetRequest{topic: requests}})
+	if err != nil {
+		return nil, err
+	}
+
+	offsets := map[int]int64{}
+	for _, partition := range response.Topics[topic] {
+		if partition.Error != nil {
+			return nil, fmt.Errorf("partition %d of %s: %w", partition.Partition, topic, partition.Error)
+		}
+
+		offsets[partition.Partition] = read(partition)
+	}
+
+	return offsets, nil
+}
+
+// firstOffset reads the answer of a segmentio.FirstOffsetOf request. kafka-go
+// files the offset by the timestamp of the answer, not of the request: the
+// brokers answer it with the timestamp -1, so it lands in LastOffset, and the
+// FirstOffset initialized to 0 is only set by the brokers echoing the
+// timestamp of the request. The brokers answering with the timestamp of the
+// message put it among the offsets by time.
+func firstOffset(partition segmentio.PartitionOffsets) int64 {
+	if offset, ok := anyTimeOffset(partition); ok {
+		return offset
+	}
+
+	if partition.LastOffset >= 0 {
+		return partition.LastOffset
+	}
+
+	return partition.FirstOffset
+}
+
+// lastOffset reads the answer of a segmentio.LastOffsetOf request, filed like
+// the first offsets.
+func lastOffset(partition segmentio.PartitionOffsets) int64 {
+	if offset, ok := anyTimeOffset(partition); ok {
+		return offset
+	}
+
+	return partition.LastOffset
+}
+
+// timeOffset reads the answer of a segmentio.TimeOffsetOf request, -1 when
+// no message was produced after the time. The brokers answer the offset -1
+// with the timestamp -1, it lands in LastOffset, initialized to -1 too.
+func timeOffset(partition segmentio.PartitionOffsets) int64 {
+	if offset, ok := anyTimeOffset(partition); ok {
+		return offset
+	}
+
+	return partition.LastOffset
+}
+
+// anyTimeOffset returns the offset among the offsets by time, a partition
+// answers a single request.
+func anyTimeOffset(partition segmentio.PartitionOffsets) (int64, bool) {
+	for offset := range partition.Offsets {
+		return offset, true
+	}
+
+	return 0, false
+}
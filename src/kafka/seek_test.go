@@ -0,0 +1,62 @@
+package kafka
+
+import (
+	"testing"
+	"time"
+
+	segmentio "github.com/segmentio/kafka-go"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadOffsets(t *testing.T) {
+	// the partitions as kafka-go returns them for the answers of the brokers
+	tests := []struct {
+		name      string
+		read      func(segmentio.PartitionOffsets) int64
+		partition segmentio.PartitionOffsets
+		expected  int64
+	}{
+		{
+			name:      "first offset answered without timestamp",
+			read:      firstOffset,
+			partition: segmentio.PartitionOffsets{FirstOffset: 0, LastOffset: 42, Offsets: map[int64]time.Time{}},
+			expected:  42,
+		},
+		{
+			name:      "first offset answered with the timestamp of the request",
+			read:      firstOffset,
+			partition: segmentio.PartitionOffsets{FirstOffset: 42, LastOffset: -1, Offsets: map[int64]time.Time{}},
+			expected:  42,
+		},
+		{
+			name:      "first offset answered with the timestamp of the message",
+			read:      firstOffset,
+			partition: segmentio.PartitionOffsets{FirstOffset: 0, LastOffset: -1, Offsets: map[int64]time.Time{42: time.Now()}},
+			expected:  42,
+		},
+		{
+			name:      "last offset",
+			read:      lastOffset,
+			partition: segmentio.PartitionOffsets{FirstOffset: -1, LastOffset: 100, Offsets: map[int64]time.Time{}},
+			expected:  100,
+		},
+		{
+			name:      "time offset",
+			read:      timeOffset,
+			partition: segmentio.PartitionOffsets{FirstOffset: -1, LastOffset: -1, Offsets: map[int64]time.Time{57: time.Now()}},
+			expected:  57,
+		},
+		{
+			name:      "no message after the time",
+			read:      timeOffset,
+			partition: segmentio.PartitionOffsets{FirstOffset: -1, LastOffset: -1, Offsets: map[int64]time.Time{}},
+			expected:  -1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, tt.read(tt.partition))
+		})
+	}
+}
@@ -0,0 +1,120 @@
+package kafka
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+
+	segmentio "github.com/segmentio/kafka-go"
+	"github.com/segmentio/kafka-go/sasl"
+	"github.com/segmentio/kafka-go/sasl/plain"
+	"github.com/segmentio/kafka-go/sasl/scram"
+
+	"github.com/tam-code/image-upload/config"
+)
+
+const (
+	SASLPlain       = "plain"
+	SASLScramSHA256 = "scram-sha-256"
+	SASLScramSHA512 = "scram-sha-512"
+)
+
+// newDialer builds the dialer of the readers, with the TLS, SASL and timeout
+// settings of the brokers.
+func newDialer(cfg config.KafkaConfig) (*segmentio.Dialer, error) {
+	tlsConfig, mechanism, err := newSecurity(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	return &segmentio.Dialer{
+		// the client id tells the group membership of this instance apart
+		ClientID:      ClientID,
+		Timeout:       dialerTimeout(cfg),
+		DualStack:     true,
+		TLS:           tlsConfig,
+		SASLMechanism: mechanism,
+	}, nil
+}
+
+// newTransport builds the transport of the writers and the admin client, with
+// the same settings as newDialer.
+func newTransport(cfg config.KafkaConfig) (*segmentio.Transport, error) {
+	tlsConfig, mechanism, err := newSecurity(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	return &segmentio.Transport{
+		ClientID:    ClientID,
+		DialTimeout: dialerTimeout(cfg),
+		TLS:         tlsConfig,
+		SASL:        mechanism,
+	}, nil
+}
+
+func newSecurity(cfg config.KafkaConfig) (*tls.Config, sasl.Mechanism, error) {
+	tlsConfig, err := newTLSConfig(cfg)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	mechanism, err := newSASLMechanism(cfg.SASL)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return tlsConfig, mechanism, nil
+}
+
+// newTLSConfig returns nil when TLS is disabled.
+func newTLSConfig(cfg config.KafkaConfig) (*tls.Config, error) {
+	if !cfg.WithTls {
+		return nil, nil
+	}
+
+	tlsConfig := &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		InsecureSkipVerify: cfg.TLS.InsecureSkipVerify,
+	}
+
+	if cfg.TLS.CAFile != "" {
+		caPEM, err := os.ReadFile(cfg.TLS.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("error reading the kafka CA file: %w", err)
+		}
+
+		tlsConfig.RootCAs = x509.NewCertPool()
+		if !tlsConfig.RootCAs.AppendCertsFromPEM(caPEM) {
+			return nil, fmt.Errorf("no certificate found in the kafka CA file %s", cfg.TLS.CAFile)
+		}
+	}
+
+	if cfg.TLS.CertFile != "" {
+		certificate, err := tls.LoadX509KeyPair(cfg.TLS.CertFile, cfg.TLS.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("error loading the kafka client certificate: %w", err)
+		}
+
+		tlsConfig.Certificates = []tls.Certificate{certificate}
+	}
+
+	return tlsConfig, nil
+}
+
+// newSASLMechanism returns nil when SASL is disabled.
+func newSASLMechanism(cfg config.KafkaSASLConfig) (sasl.Mechanism, error) {
+	switch cfg.Mechanism {
+	case "":
+		return nil, nil
+	case SASLPlain:
+		return plain.Mechanism{Username: cfg.Username, Password: cfg.Password}, nil
+	case SASLScramSHA256:
+		return scram.Mechanism(scram.SHA256, cfg.Username, cfg.Password)
+	case SASLScramSHA512:
+		return scram.Mechanism(scram.SHA512, cfg.Username, cfg.Password)
+	default:
+		return nil, fmt.Errorf("unknown kafka SASL mechanism %q", cfg.Mechanism)
+	}
+}
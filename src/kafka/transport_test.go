@@ -0,0 +1,165 @@
+package kafka
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	segmentio "github.com/segmentio/kafka-go"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/tam-code/image-upload/config"
+)
+
+func TestNewDialer(t *testing.T) {
+	certFile, keyFile := writeCertificate(t)
+	emptyFile := filepath.Join(t.TempDir(), "empty.pem")
+	assert.NoError(t, os.WriteFile(emptyFile, nil, 0600))
+
+	tests := []struct {
+		name              string
+		cfg               config.KafkaConfig
+		expectTLS         bool
+		expectCertificate bool
+		expectMechanism   string
+		expectErr         bool
+	}{
+		{
+			name: "plaintext",
+			cfg:  config.KafkaConfig{Brokers: "kafka:9092"},
+		},
+		{
+			name: "tls with CA and client certificate",
+			cfg: config.KafkaConfig{
+				WithTls: true,
+				TLS:     config.KafkaTLSConfig{CAFile: certFile, CertFile: certFile, KeyFile: keyFile},
+			},
+			expectTLS:         true,
+			expectCertificate: true,
+		},
+		{
+			name:      "tls with a CA file without certificate",
+			cfg:       config.KafkaConfig{WithTls: true, TLS: config.KafkaTLSConfig{CAFile: emptyFile}},
+			expectErr: true,
+		},
+		{
+			name:      "tls with a missing client certificate",
+			cfg:       config.KafkaConfig{WithTls: true, TLS: config.KafkaTLSConfig{CertFile: "missing.pem", KeyFile: keyFile}},
+			expectErr: true,
+		},
+		{
+			name:            "sasl plain",
+			cfg:             config.KafkaConfig{SASL: config.KafkaSASLConfig{Mechanism: SASLPlain, Username: "user", Password: "secret"}},
+			expectMechanism: "PLAIN",
+		},
+		{
+			name:            "sasl scram over tls",
+			cfg:             config.KafkaConfig{WithTls: true, SASL: config.KafkaSASLConfig{Mechanism: SASLScramSHA512, Username: "user", Password: "secret"}},
+			expectTLS:       true,
+			expectMechanism: "SCRAM-SHA-512",
+		},
+		{
+			name:      "unknown sasl mechanism",
+			cfg:       config.KafkaConfig{SASL: config.KafkaSASLConfig{Mechanism: "gssapi"}},
+			expectErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.cfg.DialerTimeoutMilliseconds = 1500
+
+			dialer, err := newDialer(tt.cfg)
+			if tt.expectErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+
+			assert.Equal(t, 1500*time.Millisecond, dialer.Timeout)
+			assert.Equal(t, tt.expectTLS, dialer.TLS != nil)
+			if tt.expectTLS {
+				assert.Equal(t, tt.expectCertificate, len(dialer.TLS.Certificates) == 1)
+			}
+
+			if tt.expectMechanism == "" {
+				assert.Nil(t, dialer.SASLMechanism)
+			} else {
+				assert.Equal(t, tt.expectMechanism, dialer.SASLMechanism.Name())
+			}
+		})
+	}
+}
+
+func TestNewKafkaWriter(t *testing.T) {
+	tests := []struct {
+		name              string
+		producer          config.KafkaProducerConfig
+		expectAcks        segmentio.RequiredAcks
+		expectCompression segmentio.Compression
+	}{
+		{
+			name:       "defaults to the acks of all the replicas",
+			expectAcks: segmentio.RequireAll,
+		},
+		{
+			name:              "tuned producer",
+			producer:          config.KafkaProducerConfig{RequiredAcks: "one", Compression: "zstd", BatchSize: 50, BatchTimeoutMilliseconds: 5},
+			expectAcks:        segmentio.RequireOne,
+			expectCompression: segmentio.Zstd,
+		},
+		{
+			name:       "idempotent producer requires the acks of all the replicas",
+			producer:   config.KafkaProducerConfig{RequiredAcks: "none", Idempotent: true},
+			expectAcks: segmentio.RequireAll,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			writer, err := NewKafkaWriter(config.KafkaConfig{Brokers: "kafka-1:9092,kafka-2:9092", Topic: "image_uploaded", Producer: tt.producer})
+			assert.NoError(t, err)
+
+			assert.Equal(t, "kafka-1:9092,kafka-2:9092", writer.Addr.String())
+			assert.Equal(t, tt.expectAcks, writer.RequiredAcks)
+			assert.Equal(t, tt.expectCompression, writer.Compression)
+			assert.Equal(t, tt.producer.BatchSize, writer.BatchSize)
+			assert.Equal(t, time.Duration(tt.producer.BatchTimeoutMilliseconds)*time.Millisecond, writer.BatchTimeout)
+		})
+	}
+}
+
+func writeCertificate(t *testing.T) (string, string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "kafka"},
+		NotBefore:             time.Now(),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}
+	certificate, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	assert.NoError(t, err)
+
+	privateKey, err := x509.MarshalECPrivateKey(key)
+	assert.NoError(t, err)
+
+	dir := t.TempDir()
+	certFile := filepath.Join(dir, "cert.pem")
+	keyFile := filepath.Join(dir, "key.pem")
+	assert.NoError(t, os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certificate}), 0600))
+	assert.NoError(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: privateKey}), 0600))
+
+	return certFile, keyFile
+}
@@ -0,0 +1,52 @@
+package loggers
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+
+	"github.com/tam-code/image-upload/config"
+)
+
+const (
+	JSONFormat = "json"
+	TextFormat = "text"
+)
+
+type contextKey struct{}
+
+// NewLogger builds the logger of the service, writing to stderr with the
+// configured level and format.
+func NewLogger(cfg config.LoggingConfig) (*slog.Logger, error) {
+	var level slog.Level
+	if cfg.Level != "" {
+		if err := level.UnmarshalText([]byte(cfg.Level)); err != nil {
+			return nil, fmt.Errorf("invalid logging level %q, it must be debug, info, warn or error", cfg.Level)
+		}
+	}
+
+	options := &slog.HandlerOptions{Level: level}
+	switch cfg.Format {
+	case JSONFormat, "":
+		return slog.New(slog.NewJSONHandler(os.Stderr, options)), nil
+	case TextFormat:
+		return slog.New(slog.NewTextHandler(os.Stderr, options)), nil
+	default:
+		return nil, fmt.Errorf("invalid logging format %q, it must be json or text", cfg.Format)
+	}
+}
+
+// WithLogger returns a copy of ctx carrying the logger.
+func WithLogger(ctx context.Context, logger *slog.Logger) context.Context {
+	return context.WithValue(ctx, contextKey{}, logger)
+}
+
+// FromContext returns the logger of ctx, the default logger when it has none.
+func FromContext(ctx context.Context) *slog.Logger {
+	if logger, ok := ctx.Value(contextKey{}).(*slog.Logger); ok {
+		return logger
+	}
+
+	return slog.Default()
+}
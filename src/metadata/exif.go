@@ -0,0 +1,220 @@
+package metadata
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+)
+
+const (
+	tagImageWidth  = 0x0100
+	tagImageHeight = 0x0101
+	tagOrientation = 0x0112
+	tagExifIFD     = 0x8769
+	tagPixelX      = 0xa002
+	tagPixelY      = 0xa003
+	tagGPSIFD      = 0x8825
+)
+
+// tiffTypeSizes are the byte sizes of the TIFF field types, indexed by type.
+var tiffTypeSizes = [...]uint32{0, 1, 1, 2, 4, 8, 1, 1, 2, 4, 8, 4, 8}
+
+// tiff edits an EXIF TIFF structure in place, offsets are relative to the
+// start of the TIFF header.
+type tiff struct {
+	data  []byte
+	order binary.ByteOrder
+}
+
+type tiffField struct {
+	// position of the 12 bytes entry
+	position uint32
+	tag      uint16
+	kind     uint16
+	count    uint32
+	value    uint32
+}
+
+func newTIFF(data []byte) (*tiff, error) {
+	if len(data) < 8 {
+		return nil, fmt.Errorf("tiff header too short")
+	}
+
+	t := &tiff{data: data}
+	switch string(data[:2]) {
+	case "II":
+		t.order = binary.LittleEndian
+	case "MM":
+		t.order = binary.BigEndian
+	default:
+		return nil, fmt.Errorf("invalid tiff byte order")
+	}
+
+	return t, nil
+}
+
+func (t *tiff) ifd0() uint32 {
+	return t.order.Uint32(t.data[4:8])
+}
+
+func (t *tiff) fields(offset uint32) ([]tiffField, error) {
+	if uint64(offset)+2 > uint64(len(t.data)) {
+		return nil, fmt.Errorf("ifd offset %d out of range", offset)
+	}
+
+	count := uint32(t.order.Uint16(t.data[offset:]))
+	if uint64(offset)+2+uint64(count)*12+4 > uint64(len(t.data)) {
+		return nil, fmt.Errorf("ifd at %d out of range", offset)
+	}
+
+	fields := make([]tiffField, count)
+	for i := uint32(0); i < count; i++ {
+		position := offset + 2 + i*12
+		fields[i] = tiffField{
+			position: position,
+			tag:      t.order.Uint16(t.data[position:]),
+			kind:     t.order.Uint16(t.data[position+2:]),
+			count:    t.order.Uint32(t.data[position+4:]),
+			value:    t.order.Uint32(t.data[position+8:]),
+		}
+	}
+
+	return fields, nil
+}
+
+func (t *tiff) find(offset uint32, tag uint16) (*tiffField, error) {
+	fields, err := t.fields(offset)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, field := range fields {
+		if field.tag == tag {
+			return &field, nil
+		}
+	}
+
+	return nil, nil
+}
+
+// size is the byte size of the field value, values up to 4 bytes are stored
+// inline in the entry.
+func (f tiffField) size() uint32 {
+	if int(f.kind) >= len(tiffTypeSizes) {
+		return 0
+	}
+
+	return tiffTypeSizes[f.kind] * f.count
+}
+
+// removeGPS zeroes the GPS IFD with its values and drops its pointer from IFD0.
+func (t *tiff) removeGPS() error {
+	pointer, err := t.find(t.ifd0(), tagGPSIFD)
+	if err != nil || pointer == nil {
+		return err
+	}
+
+	fields, err := t.fields(pointer.value)
+	if err != nil {
+		return err
+	}
+
+	for _, field := range fields {
+		if size := field.size(); size > 4 && uint64(field.value)+uint64(size) <= uint64(len(t.data)) {
+			zero(t.data[field.value : field.value+size])
+		}
+	}
+	zero(t.data[pointer.value : pointer.value+2+uint32(len(fields))*12+4])
+	t.removeField(t.ifd0(), pointer)
+
+	return nil
+}
+
+// removeField shifts the following entries and the next IFD offset over the
+// removed entry, the freed trailing bytes are zeroed.
+func (t *tiff) removeField(offset uint32, field *tiffField) {
+	count := uint32(t.order.Uint16(t.data[offset:]))
+	end := offset + 2 + count*12 + 4
+
+	copy(t.data[field.position:end-12], t.data[field.position+12:end])
+	zero(t.data[end-12 : end])
+	t.order.PutUint16(t.data[offset:], uint16(count-1))
+}
+
+func (t *tiff) orientation() int {
+	field, err := t.find(t.ifd0(), tagOrientation)
+	if err != nil || field == nil {
+		return 0
+	}
+
+	return int(t.order.Uint16(t.data[field.position+8:]))
+}
+
+func (t *tiff) setOrientation(orientation int) error {
+	field, err := t.find(t.ifd0(), tagOrientation)
+	if err != nil || field == nil {
+		return err
+	}
+
+	t.order.PutUint16(t.data[field.position+8:], uint16(orientation))
+
+	return nil
+}
+
+// swapDimensions swaps the recorded width and height, in IFD0 and in the Exif IFD.
+func (t *tiff) swapDimensions() error {
+	if err := t.swapFields(t.ifd0(), tagImageWidth, tagImageHeight); err != nil {
+		return err
+	}
+
+	pointer, err := t.find(t.ifd0(), tagExifIFD)
+	if err != nil || pointer == nil {
+		return err
+	}
+
+	return t.swapFields(pointer.value, tagPixelX, tagPixelY)
+}
+
+// swapFields swaps the type, count and value of two entries of the IFD.
+func (t *tiff) swapFields(offset uint32, tagA, tagB uint16) error {
+	fieldA, err := t.find(offset, tagA)
+	if err != nil {
+		return err
+	}
+
+	fieldB, err := t.find(offset, tagB)
+	if err != nil || fieldA == nil || fieldB == nil {
+		return err
+	}
+
+	a := t.data[fieldA.position+2 : fieldA.position+12]
+	b := t.data[fieldB.position+2 : fieldB.position+12]
+	for i := range a {
+		a[i], b[i] = b[i], a[i]
+	}
+
+	return nil
+}
+
+// newOrientationExif returns a minimal APP1 Exif payload holding only the orientation.
+func newOrientationExif(orientation int) []byte {
+	var buf bytes.Buffer
+	buf.Write(exifPrefix)
+	buf.WriteString("MM\x00\x2a")
+	binary.Write(&buf, binary.BigEndian, uint32(8))
+	binary.Write(&buf, binary.BigEndian, uint16(1))
+	binary.Write(&buf, binary.BigEndian, uint16(tagOrientation))
+	binary.Write(&buf, binary.BigEndian, uint16(3))
+	binary.Write(&buf, binary.BigEndian, uint32(1))
+	binary.Write(&buf, binary.BigEndian, uint16(orientation))
+	binary.Write(&buf, binary.BigEndian, uint16(0))
+	binary.Write(&buf, binary.BigEndian, uint32(0))
+
+	return buf.Bytes()
+}
+
+func zero(data []byte) {
+	for i := range data {
+		data[i] = 0
+	}
+}
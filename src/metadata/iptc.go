@@ -0,0 +1,90 @@
+package metadata
+
+import (
+	"bytes"
+	"encoding/binary"
+)
+
+const (
+	photoshopResourceIPTC = 0x0404
+
+	iptcTagMarker         = 0x1c
+	iptcApplicationRecord = 2
+	iptcObjectName        = 5
+	iptcKeywords          = 25
+	iptcCaption           = 120
+)
+
+type iptc struct {
+	Title       string
+	Keywords    []string
+	Description string
+}
+
+// parseIPTC reads the IPTC-IIM application record from a Photoshop APP13
+// segment, unknown resources and datasets are skipped.
+func parseIPTC(data []byte) iptc {
+	var result iptc
+
+	data = bytes.TrimPrefix(data, photoshopPrefix)
+	for len(data) >= 12 && bytes.HasPrefix(data, []byte("8BIM")) {
+		resourceID := binary.BigEndian.Uint16(data[4:6])
+
+		// pascal string name padded to an even length
+		nameLength := int(data[6]) + 1
+		if nameLength%2 != 0 {
+			nameLength++
+		}
+
+		offset := 6 + nameLength
+		if len(data) < offset+4 {
+			return result
+		}
+
+		size := int(binary.BigEndian.Uint32(data[offset : offset+4]))
+		offset += 4
+		if size < 0 || len(data) < offset+size {
+			return result
+		}
+
+		if resourceID == photoshopResourceIPTC {
+			parseIPTCRecords(data[offset:offset+size], &result)
+		}
+
+		if size%2 != 0 {
+			size++
+		}
+		if len(data) < offset+size {
+			return result
+		}
+		data = data[offset+size:]
+	}
+
+	return result
+}
+
+func parseIPTCRecords(data []byte, result *iptc) {
+	for len(data) >= 5 && data[0] == iptcTagMarker {
+		record, dataset := data[1], data[2]
+		size := int(binary.BigEndian.Uint16(data[3:5]))
+		if len(data) < 5+size {
+			return
+		}
+
+		value := string(data[5 : 5+size])
+		data = data[5+size:]
+
+		if record != iptcApplicationRecord {
+			continue
+		}
+
+		switch dataset {
+		case iptcObjectName:
+			result.Title = value
+		case iptcKeywords:
+			result.Keywords = append(result.Keywords, value)
+		case iptcCaption:
+			result.Description = value
+		}
+	}
+}
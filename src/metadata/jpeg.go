@@ -0,0 +1,115 @@
+package metadata
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+const (
+	markerSOI   = 0xd8
+	markerSOS   = 0xda
+	markerEOI   = 0xd9
+	markerAPP1  = 0xe1
+	markerAPP13 = 0xed
+)
+
+var (
+	ErrNotJPEG = errors.New("not a jpeg image")
+
+	exifPrefix = []byte("Exif\x00\x00")
+	xmpPrefix  = []byte("http://ns.adobe.com/xap/1.0/\x00")
+	// the extended XMP segments carry the chunks of the packet too large for
+	// the main segment, after the GUID of the packet, its full length and the
+	// offset of the chunk
+	xmpExtensionPrefix = []byte("http://ns.adobe.com/xmp/extension/\x00")
+	photoshopPrefix    = []byte("Photoshop 3.0\x00")
+)
+
+// jpegSegment is a marker segment found before the start of scan, Data
+// excludes the marker and the length bytes.
+type jpegSegment struct {
+	Marker byte
+	Data   []byte
+}
+
+func (s jpegSegment) isExif() bool {
+	return s.Marker == markerAPP1 && bytes.HasPrefix(s.Data, exifPrefix)
+}
+
+func (s jpegSegment) isXMP() bool {
+	return s.Marker == markerAPP1 && bytes.HasPrefix(s.Data, xmpPrefix)
+}
+
+func (s jpegSegment) isExtendedXMP() bool {
+	return s.Marker == markerAPP1 && bytes.HasPrefix(s.Data, xmpExtensionPrefix)
+}
+
+func (s jpegSegment) isPhotoshop() bool {
+	return s.Marker == markerAPP13 && bytes.HasPrefix(s.Data, photoshopPrefix)
+}
+
+// readJPEGSegments reads the segments up to the start of scan marker, the
+// reader is left right after the SOS marker so the entropy coded data can be
+// copied untouched.
+func readJPEGSegments(r *bufio.Reader) ([]jpegSegment, error) {
+	var soi [2]byte
+	if _, err := io.ReadFull(r, soi[:]); err != nil || soi[0] != 0xff || soi[1] != markerSOI {
+		return nil, ErrNotJPEG
+	}
+
+	var segments []jpegSegment
+	for {
+		marker, err := readMarker(r)
+		if err != nil {
+			return nil, err
+		}
+
+		if marker == markerSOS {
+			return segments, nil
+		}
+
+		if marker == markerEOI {
+			return nil, fmt.Errorf("jpeg ended before start of scan")
+		}
+
+		var length uint16
+		if err := binary.Read(r, binary.BigEndian, &length); err != nil {
+			return nil, err
+		}
+
+		if length < 2 {
+			return nil, fmt.Errorf("invalid jpeg segment length %d", length)
+		}
+
+		data := make([]byte, length-2)
+		if _, err := io.ReadFull(r, data); err != nil {
+			return nil, err
+		}
+
+		segments = append(segments, jpegSegment{Marker: marker, Data: data})
+	}
+}
+
+func readMarker(r *bufio.Reader) (byte, error) {
+	b, err := r.ReadByte()
+	if err != nil {
+		return 0, err
+	}
+
+	if b != 0xff {
+		return 0, fmt.Errorf("invalid jpeg marker prefix 0x%x", b)
+	}
+
+	// skip fill bytes
+	for b == 0xff {
+		if b, err = r.ReadByte(); err != nil {
+			return 0, err
+		}
+	}
+
+	return b, nil
+}
@@ -0,0 +1,107 @@
+package metadata
+
+import (
+	"bufio"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/evanoberholster/imagemeta"
+	"github.com/evanoberholster/imagemeta/exif2"
+
+	"github.com/tam-code/image-upload/src/models"
+)
+
+// Read decodes the EXIF of the image and, for JPEG images, the XMP and IPTC
+// descriptive fields. The returned metadata is filled as far as decoding got
+// even when an error is returned.
+func Read(r io.ReadSeeker) (exif2.Exif, *models.ImageMetadata, error) {
+	e, err := imagemeta.Decode(r)
+	metadata := newImageMetadata(e)
+
+	if _, seekErr := r.Seek(0, io.SeekStart); seekErr != nil {
+		return e, metadata, seekErr
+	}
+
+	segments, jpegErr := readJPEGSegments(bufio.NewReader(r))
+	if jpegErr == nil {
+		addDescriptiveMetadata(metadata, segments)
+	}
+
+	return e, metadata, err
+}
+
+func newImageMetadata(e exif2.Exif) *models.ImageMetadata {
+	metadata := &models.ImageMetadata{
+		Make:         strings.TrimSpace(e.Make),
+		LensModel:    strings.TrimSpace(e.LensModel),
+		FocalLength:  float64(e.FocalLength),
+		Aperture:     float64(e.FNumber),
+		ExposureTime: float64(e.ExposureTime),
+		ISO:          int(e.ISOSpeed),
+		FlashFired:   e.Flash.Fired(),
+		Orientation:  int(e.Orientation),
+		Software:     strings.TrimSpace(e.Software),
+		Artist:       strings.TrimSpace(e.Artist),
+		Copyright:    strings.TrimSpace(e.Copyright),
+		Description:  strings.TrimSpace(e.ImageDescription),
+	}
+
+	if metadata.ISO == 0 {
+		metadata.ISO = int(e.ISO)
+	}
+
+	if e.Flash != 0 {
+		metadata.Flash = e.Flash.String()
+	}
+
+	if capturedAt := e.DateTimeOriginal(); !capturedAt.IsZero() && capturedAt.Year() > 1 {
+		// keep the offset the camera recorded, mongo stores the time in UTC
+		if capturedAt.Location() != time.UTC {
+			metadata.CaptureOffset, _ = capturedAt.Zone()
+		}
+		capturedAt = capturedAt.UTC()
+		metadata.CapturedAt = &capturedAt
+	}
+
+	return metadata
+}
+
+// addDescriptiveMetadata prefers XMP over IPTC for the title and the
+// description, keywords from both are merged.
+func addDescriptiveMetadata(metadata *models.ImageMetadata, segments []jpegSegment) {
+	var iptcData iptc
+	var xmpData xmpDublinCore
+	for _, segment := range segments {
+		switch {
+		case segment.isXMP():
+			xmpData = parseXMP(segment.Data)
+		case segment.isPhotoshop():
+			iptcData = parseIPTC(segment.Data)
+		}
+	}
+
+	metadata.Title = firstNonEmpty(xmpData.Title, iptcData.Title)
+	metadata.Description = firstNonEmpty(xmpData.Description, iptcData.Description, metadata.Description)
+
+	seen := make(map[string]bool)
+	for _, keyword := range append(xmpData.Keywords, iptcData.Keywords...) {
+		keyword = strings.TrimSpace(keyword)
+		if keyword == "" || seen[keyword] {
+			continue
+		}
+
+		seen[keyword] = true
+		metadata.Keywords = append(metadata.Keywords, keyword)
+	}
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if value = strings.TrimSpace(value); value != "" {
+			return value
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,283 @@
+package metadata
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	tiffASCII    = 2
+	tiffShort    = 3
+	tiffLong     = 4
+	tiffRational = 5
+)
+
+type tiffEntry struct {
+	Tag   uint16
+	Type  uint16
+	Count uint32
+	Data  []byte
+}
+
+func asciiEntry(tag uint16, value string) tiffEntry {
+	return tiffEntry{Tag: tag, Type: tiffASCII, Count: uint32(len(value) + 1), Data: append([]byte(value), 0)}
+}
+
+func shortEntry(tag uint16, value uint16) tiffEntry {
+	data := make([]byte, 4)
+	binary.LittleEndian.PutUint16(data, value)
+	return tiffEntry{Tag: tag, Type: tiffShort, Count: 1, Data: data}
+}
+
+func rationalEntry(tag uint16, values ...uint32) tiffEntry {
+	data := make([]byte, 4*len(values))
+	for i, value := range values {
+		binary.LittleEndian.PutUint32(data[i*4:], value)
+	}
+	return tiffEntry{Tag: tag, Type: tiffRational, Count: uint32(len(values) / 2), Data: data}
+}
+
+func ifdSize(entries []tiffEntry) int {
+	size := 2 + 12*len(entries) + 4
+	for _, entry := range entries {
+		if len(entry.Data) > 4 {
+			size += len(entry.Data) + len(entry.Data)%2
+		}
+	}
+	return size
+}
+
+func writeIFD(buf *bytes.Buffer, start int, entries []tiffEntry) {
+	dataOffset := start + 2 + 12*len(entries) + 4
+	var data bytes.Buffer
+
+	binary.Write(buf, binary.LittleEndian, uint16(len(entries)))
+	for _, entry := range entries {
+		binary.Write(buf, binary.LittleEndian, entry.Tag)
+		binary.Write(buf, binary.LittleEndian, entry.Type)
+		binary.Write(buf, binary.LittleEndian, entry.Count)
+		if len(entry.Data) > 4 {
+			binary.Write(buf, binary.LittleEndian, uint32(dataOffset+data.Len()))
+			data.Write(entry.Data)
+			if len(entry.Data)%2 != 0 {
+				data.WriteByte(0)
+			}
+			continue
+		}
+
+		value := make([]byte, 4)
+		copy(value, entry.Data)
+		buf.Write(value)
+	}
+	binary.Write(buf, binary.LittleEndian, uint32(0))
+	buf.Write(data.Bytes())
+}
+
+// buildExif returns an APP1 Exif payload with the given IFD0, Exif and GPS entries.
+func buildExif(ifd0, exifIFD, gpsIFD []tiffEntry) []byte {
+	pointer := func(tag uint16) tiffEntry {
+		return tiffEntry{Tag: tag, Type: tiffLong, Count: 1, Data: make([]byte, 4)}
+	}
+
+	entries := append([]tiffEntry{}, ifd0...)
+	if exifIFD != nil {
+		entries = append(entries, pointer(0x8769))
+	}
+	if gpsIFD != nil {
+		entries = append(entries, pointer(0x8825))
+	}
+
+	exifStart := 8 + ifdSize(entries)
+	gpsStart := exifStart
+	if exifIFD != nil {
+		gpsStart += ifdSize(exifIFD)
+	}
+
+	for i := range entries {
+		switch entries[i].Tag {
+		case 0x8769:
+			binary.LittleEndian.PutUint32(entries[i].Data, uint32(exifStart))
+		case 0x8825:
+			binary.LittleEndian.PutUint32(entries[i].Data, uint32(gpsStart))
+		}
+	}
+
+	var tiff bytes.Buffer
+	tiff.WriteString("II*\x00")
+	binary.Write(&tiff, binary.LittleEndian, uint32(8))
+	writeIFD(&tiff, 8, entries)
+	if exifIFD != nil {
+		writeIFD(&tiff, exifStart, exifIFD)
+	}
+	if gpsIFD != nil {
+		writeIFD(&tiff, gpsStart, gpsIFD)
+	}
+
+	return append(append([]byte{}, exifPrefix...), tiff.Bytes()...)
+}
+
+// buildJPEG encodes a width x height image and inserts the given segments after SOI.
+func buildJPEG(t *testing.T, width, height int, segments ...jpegSegment) []byte {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for x := 0; x < width; x++ {
+		for y := 0; y < height; y++ {
+			img.Set(x, y, color.RGBA{R: uint8(x * 10), G: uint8(y * 10), B: 100, A: 255})
+		}
+	}
+
+	var encoded bytes.Buffer
+	if err := jpeg.Encode(&encoded, img, &jpeg.Options{Quality: 90}); err != nil {
+		t.Fatal(err)
+	}
+
+	var result bytes.Buffer
+	result.Write(encoded.Bytes()[:2])
+	for _, segment := range segments {
+		result.Write([]byte{0xff, segment.Marker})
+		binary.Write(&result, binary.BigEndian, uint16(len(segment.Data)+2))
+		result.Write(segment.Data)
+	}
+	result.Write(encoded.Bytes()[2:])
+
+	return result.Bytes()
+}
+
+func buildXMP(title, description string, keywords ...string) []byte {
+	var subjects string
+	for _, keyword := range keywords {
+		subjects += "<rdf:li>" + keyword + "</rdf:li>"
+	}
+
+	packet := `<x:xmpmeta xmlns:x="adobe:ns:meta/"><rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#">` +
+		`<rdf:Description rdf:about="" xmlns:dc="http://purl.org/dc/elements/1.1/">` +
+		`<dc:title><rdf:Alt><rdf:li xml:lang="x-default">` + title + `</rdf:li></rdf:Alt></dc:title>` +
+		`<dc:description><rdf:Alt><rdf:li xml:lang="x-default">` + description + `</rdf:li></rdf:Alt></dc:description>` +
+		`<dc:subject><rdf:Bag>` + subjects + `</rdf:Bag></dc:subject>` +
+		`</rdf:Description></rdf:RDF></x:xmpmeta>`
+
+	return append(append([]byte{}, xmpPrefix...), packet...)
+}
+
+func buildIPTC(title string, keywords ...string) []byte {
+	var records bytes.Buffer
+	writeRecord := func(dataset byte, value string) {
+		records.Write([]byte{iptcTagMarker, iptcApplicationRecord, dataset})
+		binary.Write(&records, binary.BigEndian, uint16(len(value)))
+		records.WriteString(value)
+	}
+
+	writeRecord(iptcObjectName, title)
+	for _, keyword := range keywords {
+		writeRecord(iptcKeywords, keyword)
+	}
+
+	var data bytes.Buffer
+	data.Write(photoshopPrefix)
+	data.WriteString("8BIM")
+	binary.Write(&data, binary.BigEndian, uint16(photoshopResourceIPTC))
+	data.Write([]byte{0, 0})
+	binary.Write(&data, binary.BigEndian, uint32(records.Len()))
+	data.Write(records.Bytes())
+	if records.Len()%2 != 0 {
+		data.WriteByte(0)
+	}
+
+	return data.Bytes()
+}
+
+func TestRead(t *testing.T) {
+	exif := buildExif(
+		[]tiffEntry{
+			asciiEntry(0x010f, "Canon"),
+			asciiEntry(0x0110, "Canon EOS R5"),
+			shortEntry(0x0112, 6),
+			asciiEntry(0x0131, "Firmware 1.0"),
+			asciiEntry(0x013b, "Jane Doe"),
+			asciiEntry(0x8298, "Jane Doe 2024"),
+		},
+		[]tiffEntry{
+			rationalEntry(0x829a, 1, 250),
+			rationalEntry(0x829d, 28, 10),
+			shortEntry(0x8827, 400),
+			asciiEntry(0x9003, "2024:05:01 10:00:00"),
+			asciiEntry(0x9011, "+02:00"),
+			shortEntry(0x9209, 1),
+			rationalEntry(0x920a, 50, 1),
+			asciiEntry(0xa434, "RF 50mm F1.8 STM"),
+		},
+		nil,
+	)
+
+	tests := []struct {
+		name     string
+		image    []byte
+		validate func(t *testing.T, e, title, description string, keywords []string)
+	}{
+		{
+			name: "xmp takes precedence over iptc",
+			image: buildJPEG(t, 8, 8,
+				jpegSegment{Marker: markerAPP1, Data: exif},
+				jpegSegment{Marker: markerAPP1, Data: buildXMP("Sunset", "A sunset over the sea", "sea", "sunset")},
+				jpegSegment{Marker: markerAPP13, Data: buildIPTC("Old title", "sunset", "beach")},
+			),
+			validate: func(t *testing.T, model, title, description string, keywords []string) {
+				assert.Equal(t, "Canon EOS R5", model)
+				assert.Equal(t, "Sunset", title)
+				assert.Equal(t, "A sunset over the sea", description)
+				assert.Equal(t, []string{"sea", "sunset", "beach"}, keywords)
+			},
+		},
+		{
+			name: "iptc only",
+			image: buildJPEG(t, 8, 8,
+				jpegSegment{Marker: markerAPP13, Data: buildIPTC("Harbour", "boat")},
+			),
+			validate: func(t *testing.T, model, title, description string, keywords []string) {
+				assert.Equal(t, "", model)
+				assert.Equal(t, "Harbour", title)
+				assert.Equal(t, "", description)
+				assert.Equal(t, []string{"boat"}, keywords)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e, metadata, _ := Read(bytes.NewReader(tt.image))
+			tt.validate(t, e.Model, metadata.Title, metadata.Description, metadata.Keywords)
+		})
+	}
+
+	e, metadata, err := Read(bytes.NewReader(tests[0].image))
+	assert.NoError(t, err)
+	assert.Equal(t, "Canon EOS R5", e.Model)
+	assert.Equal(t, "Canon", metadata.Make)
+	assert.Equal(t, "RF 50mm F1.8 STM", metadata.LensModel)
+	assert.Equal(t, 50.0, metadata.FocalLength)
+	assert.InDelta(t, 2.8, metadata.Aperture, 0.01)
+	assert.InDelta(t, 0.004, metadata.ExposureTime, 0.0001)
+	assert.Equal(t, 400, metadata.ISO)
+	assert.True(t, metadata.FlashFired)
+	assert.Equal(t, 6, metadata.Orientation)
+	assert.Equal(t, "Firmware 1.0", metadata.Software)
+	assert.Equal(t, "Jane Doe", metadata.Artist)
+	assert.Equal(t, "Jane Doe 2024", metadata.Copyright)
+	assert.Equal(t, "+02:00", metadata.CaptureOffset)
+	if assert.NotNil(t, metadata.CapturedAt) {
+		assert.Equal(t, time.Date(2024, 5, 1, 8, 0, 0, 0, time.UTC), *metadata.CapturedAt)
+	}
+}
+
+func TestReadNotJPEG(t *testing.T) {
+	_, metadata, err := Read(bytes.NewReader([]byte("Hello, World!")))
+	assert.Error(t, err)
+	assert.NotNil(t, metadata)
+	assert.Nil(t, metadata.Keywords)
+}
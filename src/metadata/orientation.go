@@ -0,0 +1,195 @@
+package metadata
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"image"
+	"image/draw"
+	"image/jpeg"
+	"os"
+	"path/filepath"
+)
+
+const (
+	orientationNormal = 1
+
+	// maxOrientationPixels bounds the images rotated, the decoded pixels and
+	// their rotation are held in memory at once
+	maxOrientationPixels = 40_000_000
+)
+
+// ErrOrientationTooLarge is returned for the images with more pixels than
+// are rotated, they are left untouched with their EXIF orientation.
+var ErrOrientationTooLarge = errors.New("image too large to normalize its orientation")
+
+// DisplayedDimensions returns the width and height of the image as displayed
+// once its EXIF orientation is applied, orientations 5 to 8 swap the axes.
+func DisplayedDimensions(width, height, orientation int) (int, int) {
+	if swapsAxes(orientation) {
+		return height, width
+	}
+
+	return width, height
+}
+
+func swapsAxes(orientation int) bool {
+	return orientation >= 5 && orientation <= 8
+}
+
+// NormalizeOrientation rotates the pixels of the JPEG at path so the image is
+// upright and resets its EXIF orientation to 1. The pixels have to be
+// re-encoded with the given quality, every other segment is kept as is.
+// It reports whether the file was rewritten, images that are already upright
+// or aren't JPEG are left untouched, and the ones over the pixel budget too,
+// with ErrOrientationTooLarge.
+func NormalizeOrientation(path string, quality int) (bool, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return false, err
+	}
+
+	segments, err := readJPEGSegments(bufio.NewReader(bytes.NewReader(data)))
+	if err != nil {
+		return false, nil
+	}
+
+	orientation := 0
+	for _, segment := range segments {
+		if !segment.isExif() {
+			continue
+		}
+
+		t, err := newTIFF(segment.Data[len(exifPrefix):])
+		if err != nil {
+			continue
+		}
+
+		if orientation = t.orientation(); orientation > orientationNormal && orientation <= 8 {
+			if err = t.setOrientation(orientationNormal); err != nil {
+				return false, err
+			}
+
+			if swapsAxes(orientation) {
+				if err = t.swapDimensions(); err != nil {
+					return false, err
+				}
+			}
+		}
+	}
+
+	if orientation <= orientationNormal || orientation > 8 {
+		return false, nil
+	}
+
+	// the dimensions are checked before the pixels are decoded
+	config, err := jpeg.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return false, err
+	}
+
+	if config.Width*config.Height > maxOrientationPixels {
+		return false, ErrOrientationTooLarge
+	}
+
+	img, err := jpeg.Decode(bytes.NewReader(data))
+	if err != nil {
+		return false, err
+	}
+
+	if quality <= 0 {
+		quality = jpeg.DefaultQuality
+	}
+
+	var encoded bytes.Buffer
+	if err = jpeg.Encode(&encoded, orient(img, orientation), &jpeg.Options{Quality: quality}); err != nil {
+		return false, err
+	}
+
+	encodedReader := bufio.NewReader(&encoded)
+	encodedSegments, err := readJPEGSegments(encodedReader)
+	if err != nil {
+		return false, err
+	}
+
+	// keep the original application segments with the reset orientation and
+	// take the tables and frame header of the new encoding
+	var result bytes.Buffer
+	result.Write([]byte{0xff, markerSOI})
+	for _, segment := range segments {
+		if isApplicationSegment(segment) {
+			writeJPEGSegment(&result, segment.Marker, segment.Data)
+		}
+	}
+	for _, segment := range encodedSegments {
+		if !isApplicationSegment(segment) {
+			writeJPEGSegment(&result, segment.Marker, segment.Data)
+		}
+	}
+	result.Write([]byte{0xff, markerSOS})
+	if _, err = encodedReader.WriteTo(&result); err != nil {
+		return false, err
+	}
+
+	dst, err := os.CreateTemp(filepath.Dir(path), ".orient-*")
+	if err != nil {
+		return false, err
+	}
+	defer os.Remove(dst.Name())
+
+	_, err = dst.Write(result.Bytes())
+	if closeErr := dst.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return false, err
+	}
+
+	return true, os.Rename(dst.Name(), path)
+}
+
+func isApplicationSegment(segment jpegSegment) bool {
+	return (segment.Marker >= markerAPP0 && segment.Marker <= markerAPP15) || segment.Marker == markerCOM
+}
+
+// orient applies the EXIF orientation transform to the image. The rows of
+// the image are converted one at a time and moved to their displayed place,
+// so the rotation is the only full copy of the pixels.
+func orient(img image.Image, orientation int) *image.RGBA {
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	dstWidth, dstHeight := DisplayedDimensions(width, height, orientation)
+	dst := image.NewRGBA(image.Rect(0, 0, dstWidth, dstHeight))
+
+	row := image.NewRGBA(image.Rect(0, 0, width, 1))
+	for sy := 0; sy < height; sy++ {
+		draw.Draw(row, row.Bounds(), img, image.Pt(bounds.Min.X, bounds.Min.Y+sy), draw.Src)
+
+		for sx := 0; sx < width; sx++ {
+			// place the source pixel (sx, sy) is displayed at
+			var dx, dy int
+			switch orientation {
+			case 2:
+				dx, dy = width-1-sx, sy
+			case 3:
+				dx, dy = width-1-sx, height-1-sy
+			case 4:
+				dx, dy = sx, height-1-sy
+			case 5:
+				dx, dy = sy, sx
+			case 6:
+				dx, dy = height-1-sy, sx
+			case 7:
+				dx, dy = height-1-sy, width-1-sx
+			case 8:
+				dx, dy = sy, width-1-sx
+			default:
+				dx, dy = sx, sy
+			}
+
+			copy(dst.Pix[dst.PixOffset(dx, dy):dst.PixOffset(dx, dy)+4], row.Pix[sx*4:sx*4+4])
+		}
+	}
+
+	return dst
+}
@@ -0,0 +1,150 @@
+package metadata
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDisplayedDimensions(t *testing.T) {
+	for orientation := 0; orientation <= 9; orientation++ {
+		width, height := DisplayedDimensions(4000, 3000, orientation)
+		if orientation >= 5 && orientation <= 8 {
+			assert.Equal(t, []int{3000, 4000}, []int{width, height}, "orientation %d", orientation)
+			continue
+		}
+		assert.Equal(t, []int{4000, 3000}, []int{width, height}, "orientation %d", orientation)
+	}
+}
+
+func TestOrient(t *testing.T) {
+	// 3x2 source, each pixel red channel is its index
+	// 0 1 2
+	// 3 4 5
+	src := image.NewRGBA(image.Rect(0, 0, 3, 2))
+	for i := 0; i < 6; i++ {
+		src.Set(i%3, i/3, color.RGBA{R: uint8(i), A: 255})
+	}
+
+	tests := []struct {
+		orientation int
+		expected    [][]uint8
+	}{
+		{orientation: 1, expected: [][]uint8{{0, 1, 2}, {3, 4, 5}}},
+		{orientation: 2, expected: [][]uint8{{2, 1, 0}, {5, 4, 3}}},
+		{orientation: 3, expected: [][]uint8{{5, 4, 3}, {2, 1, 0}}},
+		{orientation: 4, expected: [][]uint8{{3, 4, 5}, {0, 1, 2}}},
+		{orientation: 5, expected: [][]uint8{{0, 3}, {1, 4}, {2, 5}}},
+		{orientation: 6, expected: [][]uint8{{3, 0}, {4, 1}, {5, 2}}},
+		{orientation: 7, expected: [][]uint8{{5, 2}, {4, 1}, {3, 0}}},
+		{orientation: 8, expected: [][]uint8{{2, 5}, {1, 4}, {0, 3}}},
+	}
+
+	for _, tt := range tests {
+		dst := orient(src, tt.orientation)
+
+		var actual [][]uint8
+		for y := 0; y < dst.Bounds().Dy(); y++ {
+			var row []uint8
+			for x := 0; x < dst.Bounds().Dx(); x++ {
+				row = append(row, dst.RGBAAt(x, y).R)
+			}
+			actual = append(actual, row)
+		}
+
+		assert.Equal(t, tt.expected, actual, "orientation %d", tt.orientation)
+	}
+}
+
+func TestNormalizeOrientation(t *testing.T) {
+	longEntry := func(tag uint16, value uint32) tiffEntry {
+		data := make([]byte, 4)
+		binary.LittleEndian.PutUint32(data, value)
+		return tiffEntry{Tag: tag, Type: tiffLong, Count: 1, Data: data}
+	}
+
+	tests := []struct {
+		name               string
+		orientation        uint16
+		expectedNormalized bool
+		expectedWidth      int
+		expectedHeight     int
+	}{
+		{name: "rotate 90", orientation: 6, expectedNormalized: true, expectedWidth: 8, expectedHeight: 16},
+		{name: "rotate 270", orientation: 8, expectedNormalized: true, expectedWidth: 8, expectedHeight: 16},
+		{name: "rotate 180", orientation: 3, expectedNormalized: true, expectedWidth: 16, expectedHeight: 8},
+		{name: "already upright", orientation: 1, expectedWidth: 16, expectedHeight: 8},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			original := buildJPEG(t, 16, 8,
+				jpegSegment{Marker: markerAPP1, Data: buildExif(
+					[]tiffEntry{asciiEntry(0x0110, "Pixel 8"), shortEntry(tagOrientation, tt.orientation)},
+					[]tiffEntry{longEntry(tagPixelX, 16), longEntry(tagPixelY, 8)},
+					nil,
+				)},
+			)
+
+			path := filepath.Join(t.TempDir(), "image.jpg")
+			assert.NoError(t, os.WriteFile(path, original, 0o644))
+
+			normalized, err := NormalizeOrientation(path, 90)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedNormalized, normalized)
+
+			data, err := os.ReadFile(path)
+			assert.NoError(t, err)
+
+			img, err := jpeg.Decode(bytes.NewReader(data))
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedWidth, img.Bounds().Dx())
+			assert.Equal(t, tt.expectedHeight, img.Bounds().Dy())
+
+			e, metadata, err := Read(bytes.NewReader(data))
+			assert.NoError(t, err)
+			assert.Equal(t, "Pixel 8", e.Model)
+			assert.Equal(t, 1, metadata.Orientation)
+			assert.Equal(t, tt.expectedWidth, int(e.ImageWidth))
+			assert.Equal(t, tt.expectedHeight, int(e.ImageHeight))
+		})
+	}
+
+	t.Run("over the pixel budget", func(t *testing.T) {
+		original := buildJPEG(t, 16, 8,
+			jpegSegment{Marker: markerAPP1, Data: buildExif([]tiffEntry{shortEntry(tagOrientation, 6)}, nil, nil)},
+		)
+
+		// claim 10000x10000 pixels in the frame header
+		sof := bytes.Index(original, []byte{0xff, 0xc0})
+		binary.BigEndian.PutUint16(original[sof+5:], 10000)
+		binary.BigEndian.PutUint16(original[sof+7:], 10000)
+
+		path := filepath.Join(t.TempDir(), "image.jpg")
+		assert.NoError(t, os.WriteFile(path, original, 0o644))
+
+		normalized, err := NormalizeOrientation(path, 90)
+		assert.ErrorIs(t, err, ErrOrientationTooLarge)
+		assert.False(t, normalized)
+
+		unchanged, err := os.ReadFile(path)
+		assert.NoError(t, err)
+		assert.Equal(t, original, unchanged)
+	})
+
+	t.Run("not a jpeg", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "image.png")
+		assert.NoError(t, os.WriteFile(path, pngSignature, 0o644))
+
+		normalized, err := NormalizeOrientation(path, 90)
+		assert.NoError(t, err)
+		assert.False(t, normalized)
+	})
+}
@@ -0,0 +1,356 @@
+package metadata
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/tam-code/image-upload/src/models"
+)
+
+const (
+	markerAPP0  = 0xe0
+	markerAPP2  = 0xe2
+	markerAPP14 = 0xee
+	markerAPP15 = 0xef
+	markerCOM   = 0xfe
+
+	// pngMaxChunkLength is the largest chunk length the PNG specification allows
+	pngMaxChunkLength = 1<<31 - 1
+)
+
+var (
+	ErrStripUnsupported = errors.New("metadata stripping is not supported for this format")
+
+	pngSignature = []byte("\x89PNG\r\n\x1a\n")
+	xmpGPSTags   = [][]byte{[]byte("GPSLatitude"), []byte("GPSLongitude")}
+)
+
+// Strip rewrites the image at path without the metadata removed by the
+// privacy mode. JPEG and PNG are rewritten segment by segment so the pixel
+// data is never recompressed, other formats return ErrStripUnsupported.
+func Strip(path string, mode models.PrivacyMode) error {
+	if mode == "" || mode == models.PrivacyKeep {
+		return nil
+	}
+
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	r := bufio.NewReader(src)
+	header, _ := r.Peek(len(pngSignature))
+
+	var strip func(*bufio.Reader, io.Writer, models.PrivacyMode) error
+	switch {
+	case len(header) >= 2 && header[0] == 0xff && header[1] == markerSOI:
+		strip = stripJPEG
+	case bytes.Equal(header, pngSignature):
+		strip = stripPNG
+	default:
+		return ErrStripUnsupported
+	}
+
+	dst, err := os.CreateTemp(filepath.Dir(path), ".strip-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(dst.Name())
+
+	w := bufio.NewWriter(dst)
+	if err = strip(r, w, mode); err == nil {
+		err = w.Flush()
+	}
+	if closeErr := dst.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(dst.Name(), path)
+}
+
+func stripJPEG(r *bufio.Reader, w io.Writer, mode models.PrivacyMode) error {
+	segments, err := readJPEGSegments(r)
+	if err != nil {
+		return err
+	}
+
+	if _, err = w.Write([]byte{0xff, markerSOI}); err != nil {
+		return err
+	}
+
+	xmp := xmpGPS(segments)
+
+	for _, segment := range segments {
+		data, keep := stripJPEGSegment(segment, mode, xmp)
+		if !keep {
+			continue
+		}
+
+		if err = writeJPEGSegment(w, segment.Marker, data); err != nil {
+			return err
+		}
+	}
+
+	if _, err = w.Write([]byte{0xff, markerSOS}); err != nil {
+		return err
+	}
+
+	_, err = io.Copy(w, r)
+	return err
+}
+
+// stripJPEGSegment returns the data to write for the segment and false when
+// the segment must be dropped.
+func stripJPEGSegment(segment jpegSegment, mode models.PrivacyMode, xmp xmpGPSPackets) ([]byte, bool) {
+	if mode == models.PrivacyStripGPS {
+		switch {
+		case segment.isExif():
+			t, err := newTIFF(segment.Data[len(exifPrefix):])
+			if err != nil || t.removeGPS() != nil {
+				return nil, false
+			}
+			return segment.Data, true
+		case segment.isXMP():
+			return segment.Data, !hasXMPGPS(segment.Data)
+		case segment.isExtendedXMP():
+			// the extension is useless without the main packet it extends
+			return segment.Data, !xmp.main && !xmp.extended
+		default:
+			return segment.Data, true
+		}
+	}
+
+	switch {
+	case segment.isExif():
+		// keep the orientation so the image still displays upright
+		t, err := newTIFF(segment.Data[len(exifPrefix):])
+		if err != nil {
+			return nil, false
+		}
+		if orientation := t.orientation(); orientation > 1 {
+			return newOrientationExif(orientation), true
+		}
+		return nil, false
+	case segment.Marker == markerAPP0, segment.Marker == markerAPP2, segment.Marker == markerAPP14:
+		// JFIF, ICC profile and Adobe color transform are needed to render the image
+		return segment.Data, true
+	case segment.Marker >= markerAPP0 && segment.Marker <= markerAPP15, segment.Marker == markerCOM:
+		return nil, false
+	default:
+		return segment.Data, true
+	}
+}
+
+func writeJPEGSegment(w io.Writer, marker byte, data []byte) error {
+	if len(data)+2 > 0xffff {
+		return fmt.Errorf("jpeg segment too large")
+	}
+
+	if _, err := w.Write([]byte{0xff, marker}); err != nil {
+		return err
+	}
+
+	if err := binary.Write(w, binary.BigEndian, uint16(len(data)+2)); err != nil {
+		return err
+	}
+
+	_, err := w.Write(data)
+	return err
+}
+
+// stripPNG copies the chunks kept by the privacy mode. The metadata chunks are
+// read to be inspected, the others are streamed, so the lengths of a forged
+// file never size an allocation.
+func stripPNG(r *bufio.Reader, w io.Writer, mode models.PrivacyMode) error {
+	signature := make([]byte, len(pngSignature))
+	if _, err := io.ReadFull(r, signature); err != nil {
+		return err
+	}
+
+	if _, err := w.Write(signature); err != nil {
+		return err
+	}
+
+	for {
+		header := make([]byte, 8)
+		if _, err := io.ReadFull(r, header); err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+
+		length, chunkType := binary.BigEndian.Uint32(header[:4]), string(header[4:])
+		if length > pngMaxChunkLength {
+			return fmt.Errorf("png chunk %q too large", chunkType)
+		}
+
+		if isPNGMetadataChunk(chunkType) {
+			if err := stripPNGMetadataChunk(r, w, header, mode); err != nil {
+				return err
+			}
+		} else {
+			dst := w
+			if keep, _ := stripPNGChunk(chunkType, nil, mode); keep {
+				if _, err := w.Write(header); err != nil {
+					return err
+				}
+			} else {
+				dst = io.Discard
+			}
+
+			// the data and the CRC
+			if _, err := io.CopyN(dst, r, int64(length)+4); err != nil {
+				return unexpectedEOF(err)
+			}
+		}
+
+		if chunkType == "IEND" {
+			return nil
+		}
+	}
+}
+
+// stripPNGMetadataChunk reads the data of the metadata chunk, growing with
+// the data actually read, and writes it back when it is kept, with a new CRC
+// when its data changed.
+func stripPNGMetadataChunk(r io.Reader, w io.Writer, header []byte, mode models.PrivacyMode) error {
+	length := binary.BigEndian.Uint32(header[:4])
+
+	var chunk bytes.Buffer
+	if _, err := io.CopyN(&chunk, r, int64(length)+4); err != nil {
+		return unexpectedEOF(err)
+	}
+
+	data, checksum := chunk.Bytes()[:length], chunk.Bytes()[length:]
+	keep, modified := stripPNGChunk(string(header[4:]), data, mode)
+	if !keep {
+		return nil
+	}
+
+	if modified {
+		crc := crc32.NewIEEE()
+		crc.Write(header[4:])
+		crc.Write(data)
+		binary.BigEndian.PutUint32(checksum, crc.Sum32())
+	}
+
+	if _, err := w.Write(header); err != nil {
+		return err
+	}
+
+	_, err := w.Write(chunk.Bytes())
+	return err
+}
+
+// isPNGMetadataChunk tells the chunks whose data stripPNGChunk inspects.
+func isPNGMetadataChunk(chunkType string) bool {
+	switch chunkType {
+	case "eXIf", "tEXt", "zTXt", "iTXt":
+		return true
+	default:
+		return false
+	}
+}
+
+func unexpectedEOF(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
+
+// stripPNGChunk edits the chunk data in place, it returns whether the chunk
+// is kept and whether its data changed.
+func stripPNGChunk(chunkType string, data []byte, mode models.PrivacyMode) (bool, bool) {
+	switch chunkType {
+	case "eXIf":
+		if mode == models.PrivacyStripAll {
+			return false, false
+		}
+		t, err := newTIFF(data)
+		if err != nil || t.removeGPS() != nil {
+			return false, false
+		}
+		return true, true
+	case "tEXt", "zTXt", "iTXt":
+		if mode == models.PrivacyStripAll {
+			return false, false
+		}
+		return !hasXMPGPS(data), false
+	case "tIME":
+		return mode != models.PrivacyStripAll, false
+	default:
+		return true, false
+	}
+}
+
+// xmpGPSPackets tells which XMP packets of a JPEG have GPS tags, a main
+// packet, which is dropped along with the extension, or the extended one
+// split over several segments.
+type xmpGPSPackets struct {
+	main     bool
+	extended bool
+}
+
+func xmpGPS(segments []jpegSegment) xmpGPSPackets {
+	var tags xmpGPSPackets
+
+	type chunk struct {
+		offset uint32
+		data   []byte
+	}
+	chunks := map[string][]chunk{}
+
+	for _, segment := range segments {
+		switch {
+		case segment.isXMP():
+			tags.main = tags.main || hasXMPGPS(segment.Data)
+		case segment.isExtendedXMP():
+			// GUID, full length and offset
+			header := segment.Data[len(xmpExtensionPrefix):]
+			if len(header) < 40 {
+				tags.extended = true
+				continue
+			}
+
+			guid := string(header[:32])
+			chunks[guid] = append(chunks[guid], chunk{offset: binary.BigEndian.Uint32(header[36:40]), data: header[40:]})
+		}
+	}
+
+	// a tag may be split between two chunks, so the chunks are joined first
+	for _, packetChunks := range chunks {
+		sort.Slice(packetChunks, func(i, j int) bool { return packetChunks[i].offset < packetChunks[j].offset })
+
+		var packet []byte
+		for _, chunk := range packetChunks {
+			packet = append(packet, chunk.data...)
+		}
+		tags.extended = tags.extended || hasXMPGPS(packet)
+	}
+
+	return tags
+}
+
+func hasXMPGPS(data []byte) bool {
+	for _, tag := range xmpGPSTags {
+		if bytes.Contains(data, tag) {
+			return true
+		}
+	}
+
+	return false
+}
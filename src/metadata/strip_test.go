@@ -0,0 +1,273 @@
+package metadata
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/tam-code/image-upload/src/models"
+)
+
+func gpsIFD() []tiffEntry {
+	return []tiffEntry{
+		asciiEntry(0x0001, "N"),
+		rationalEntry(0x0002, 52, 1, 30, 1, 0, 1),
+		asciiEntry(0x0003, "E"),
+		rationalEntry(0x0004, 13, 1, 24, 1, 0, 1),
+	}
+}
+
+func scanData(t *testing.T, data []byte) []byte {
+	index := bytes.Index(data, []byte{0xff, markerSOS})
+	if index < 0 {
+		t.Fatal("start of scan not found")
+	}
+	return data[index:]
+}
+
+func TestStripJPEG(t *testing.T) {
+	original := buildJPEG(t, 16, 8,
+		jpegSegment{Marker: markerAPP1, Data: buildExif(
+			[]tiffEntry{asciiEntry(0x010f, "Canon"), asciiEntry(0x0110, "Canon EOS R5"), shortEntry(0x0112, 6)},
+			[]tiffEntry{shortEntry(0x8827, 400)},
+			gpsIFD(),
+		)},
+		jpegSegment{Marker: markerAPP1, Data: buildXMP("Sunset", "", "sea")},
+		jpegSegment{Marker: markerAPP1, Data: append(append([]byte{}, xmpPrefix...), "<exif:GPSLatitude>52,30N</exif:GPSLatitude>"...)},
+		jpegSegment{Marker: markerAPP13, Data: buildIPTC("Harbour", "boat")},
+		jpegSegment{Marker: markerCOM, Data: []byte("comment")},
+	)
+
+	tests := []struct {
+		name     string
+		mode     models.PrivacyMode
+		validate func(t *testing.T, stripped []byte)
+	}{
+		{
+			name: "keep",
+			mode: models.PrivacyKeep,
+			validate: func(t *testing.T, stripped []byte) {
+				assert.Equal(t, original, stripped)
+			},
+		},
+		{
+			name: "strip gps",
+			mode: models.PrivacyStripGPS,
+			validate: func(t *testing.T, stripped []byte) {
+				e, metadata, err := Read(bytes.NewReader(stripped))
+				assert.NoError(t, err)
+				assert.Equal(t, 0.0, e.GPS.Latitude())
+				assert.Equal(t, 0.0, e.GPS.Longitude())
+				assert.Equal(t, "Canon EOS R5", e.Model)
+				assert.Equal(t, 400, metadata.ISO)
+				assert.Equal(t, 6, metadata.Orientation)
+				assert.Equal(t, "Sunset", metadata.Title)
+				assert.Equal(t, []string{"sea", "boat"}, metadata.Keywords)
+				assert.NotContains(t, string(stripped), "GPSLatitude")
+				assert.Contains(t, string(stripped), "comment")
+			},
+		},
+		{
+			name: "strip all",
+			mode: models.PrivacyStripAll,
+			validate: func(t *testing.T, stripped []byte) {
+				e, metadata, _ := Read(bytes.NewReader(stripped))
+				assert.Equal(t, 0.0, e.GPS.Latitude())
+				assert.Equal(t, "", e.Model)
+				assert.Equal(t, 6, metadata.Orientation)
+				assert.Equal(t, "", metadata.Title)
+				assert.Nil(t, metadata.Keywords)
+				assert.NotContains(t, string(stripped), "comment")
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "image.jpg")
+			assert.NoError(t, os.WriteFile(path, original, 0o644))
+
+			assert.NoError(t, Strip(path, tt.mode))
+
+			stripped, err := os.ReadFile(path)
+			assert.NoError(t, err)
+
+			// pixel data is copied, not recompressed
+			assert.Equal(t, scanData(t, original), scanData(t, stripped))
+			_, err = jpeg.Decode(bytes.NewReader(stripped))
+			assert.NoError(t, err)
+
+			tt.validate(t, stripped)
+		})
+	}
+}
+
+// buildExtendedXMP splits the packet over extended XMP segments of chunkSize
+// bytes, written in reverse order as their offset tells where they belong.
+func buildExtendedXMP(guid string, packet string, chunkSize int) []jpegSegment {
+	var segments []jpegSegment
+	for offset := 0; offset < len(packet); offset += chunkSize {
+		var data bytes.Buffer
+		data.Write(xmpExtensionPrefix)
+		data.WriteString(guid)
+		binary.Write(&data, binary.BigEndian, uint32(len(packet)))
+		binary.Write(&data, binary.BigEndian, uint32(offset))
+		data.WriteString(packet[offset:min(offset+chunkSize, len(packet))])
+
+		segments = append([]jpegSegment{{Marker: markerAPP1, Data: data.Bytes()}}, segments...)
+	}
+
+	return segments
+}
+
+func TestStripJPEGExtendedXMP(t *testing.T) {
+	const guid = "2B9FA9A1A0E2E0D7A4B1C7A2F0C1D3E4"
+
+	tests := []struct {
+		name              string
+		main              []byte
+		extension         string
+		expectedMain      bool
+		expectedExtension bool
+	}{
+		{
+			name:              "gps tag split between the chunks of the extension",
+			main:              buildXMP("Sunset", ""),
+			extension:         "<x:xmpmeta><exif:GPSLatitude>52,30N</exif:GPSLatitude></x:xmpmeta>",
+			expectedMain:      true,
+			expectedExtension: false,
+		},
+		{
+			name:              "gps tag in the main packet",
+			main:              append(append([]byte{}, xmpPrefix...), "<exif:GPSLongitude>13,24E</exif:GPSLongitude>"...),
+			extension:         "<x:xmpmeta><photoshop:History>cropped</photoshop:History></x:xmpmeta>",
+			expectedMain:      false,
+			expectedExtension: false,
+		},
+		{
+			name:              "no gps tag",
+			main:              buildXMP("Sunset", ""),
+			extension:         "<x:xmpmeta><photoshop:History>cropped</photoshop:History></x:xmpmeta>",
+			expectedMain:      true,
+			expectedExtension: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// the chunks split GPSLatitude in the middle
+			segments := append([]jpegSegment{{Marker: markerAPP1, Data: tt.main}}, buildExtendedXMP(guid, tt.extension, 20)...)
+			original := buildJPEG(t, 8, 8, segments...)
+
+			path := filepath.Join(t.TempDir(), "image.jpg")
+			assert.NoError(t, os.WriteFile(path, original, 0o644))
+
+			assert.NoError(t, Strip(path, models.PrivacyStripGPS))
+
+			stripped, err := os.ReadFile(path)
+			assert.NoError(t, err)
+
+			assert.Equal(t, tt.expectedMain, bytes.Contains(stripped, tt.main))
+			assert.Equal(t, tt.expectedExtension, bytes.Contains(stripped, xmpExtensionPrefix))
+			assert.NotContains(t, string(stripped), "GPS")
+		})
+	}
+}
+
+func TestStripPNG(t *testing.T) {
+	var encoded bytes.Buffer
+	assert.NoError(t, png.Encode(&encoded, image.NewRGBA(image.Rect(0, 0, 4, 4))))
+
+	chunk := func(chunkType string, data []byte) []byte {
+		var buf bytes.Buffer
+		binary.Write(&buf, binary.BigEndian, uint32(len(data)))
+		buf.WriteString(chunkType)
+		buf.Write(data)
+		binary.Write(&buf, binary.BigEndian, crc32.ChecksumIEEE(append([]byte(chunkType), data...)))
+		return buf.Bytes()
+	}
+
+	// insert the metadata chunks right after IHDR
+	ihdrEnd := len(pngSignature) + 4 + 4 + 13 + 4
+	exif := buildExif([]tiffEntry{asciiEntry(0x010f, "Canon")}, nil, gpsIFD())
+	original := append(append([]byte{}, encoded.Bytes()[:ihdrEnd]...), chunk("eXIf", exif[len(exifPrefix):])...)
+	original = append(original, chunk("tEXt", []byte("Author\x00Jane Doe"))...)
+	original = append(original, encoded.Bytes()[ihdrEnd:]...)
+
+	tests := []struct {
+		name          string
+		mode          models.PrivacyMode
+		expectExif    bool
+		expectText    bool
+		expectGPSData bool
+	}{
+		{name: "keep", mode: models.PrivacyKeep, expectExif: true, expectText: true, expectGPSData: true},
+		{name: "strip gps", mode: models.PrivacyStripGPS, expectExif: true, expectText: true},
+		{name: "strip all", mode: models.PrivacyStripAll},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "image.png")
+			assert.NoError(t, os.WriteFile(path, original, 0o644))
+
+			assert.NoError(t, Strip(path, tt.mode))
+
+			stripped, err := os.ReadFile(path)
+			assert.NoError(t, err)
+
+			_, err = png.Decode(bytes.NewReader(stripped))
+			assert.NoError(t, err)
+
+			assert.Equal(t, tt.expectExif, bytes.Contains(stripped, []byte("eXIf")))
+			assert.Equal(t, tt.expectText, bytes.Contains(stripped, []byte("Jane Doe")))
+			assert.Equal(t, tt.expectGPSData, bytes.Contains(stripped, []byte("N\x00")))
+		})
+	}
+}
+
+func TestStripPNGForgedLength(t *testing.T) {
+	tests := []struct {
+		name      string
+		length    uint32
+		chunkType string
+	}{
+		{name: "length over the specification", length: 0xfffffff0, chunkType: "IDAT"},
+		{name: "metadata chunk longer than the file", length: 1<<31 - 1, chunkType: "tEXt"},
+		{name: "data chunk longer than the file", length: 1<<31 - 1, chunkType: "IDAT"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			forged := append([]byte{}, pngSignature...)
+			forged = binary.BigEndian.AppendUint32(forged, tt.length)
+			forged = append(forged, tt.chunkType+"data"...)
+
+			path := filepath.Join(t.TempDir(), "image.png")
+			assert.NoError(t, os.WriteFile(path, forged, 0o644))
+
+			assert.Error(t, Strip(path, models.PrivacyStripAll))
+
+			// the image is left as it was
+			unchanged, err := os.ReadFile(path)
+			assert.NoError(t, err)
+			assert.Equal(t, forged, unchanged)
+		})
+	}
+}
+
+func TestStripUnsupported(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "image.gif")
+	assert.NoError(t, os.WriteFile(path, []byte("GIF89a"), 0o644))
+
+	assert.ErrorIs(t, Strip(path, models.PrivacyStripAll), ErrStripUnsupported)
+	assert.NoError(t, Strip(path, models.PrivacyKeep))
+}
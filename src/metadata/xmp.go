@@ -0,0 +1,39 @@
+package metadata
+
+import (
+	"bytes"
+	"encoding/xml"
+)
+
+// xmpPacket maps the Dublin Core properties of an x:xmpmeta packet, elements
+// are matched by local name so any namespace prefix works.
+type xmpPacket struct {
+	Descriptions []struct {
+		Title       []string `xml:"title>Alt>li"`
+		Description []string `xml:"description>Alt>li"`
+		Subject     []string `xml:"subject>Bag>li"`
+	} `xml:"RDF>Description"`
+}
+
+type xmpDublinCore struct {
+	Title       string
+	Description string
+	Keywords    []string
+}
+
+func parseXMP(data []byte) xmpDublinCore {
+	var result xmpDublinCore
+
+	var packet xmpPacket
+	if err := xml.Unmarshal(bytes.TrimPrefix(data, xmpPrefix), &packet); err != nil {
+		return result
+	}
+
+	for _, description := range packet.Descriptions {
+		result.Title = firstNonEmpty(append([]string{result.Title}, description.Title...)...)
+		result.Description = firstNonEmpty(append([]string{result.Description}, description.Description...)...)
+		result.Keywords = append(result.Keywords, description.Subject...)
+	}
+
+	return result
+}
@@ -0,0 +1,99 @@
+package metrics
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "image_upload"
+
+// Registry holds the metrics of the service along with the Go runtime and
+// process ones.
+var Registry = prometheus.NewRegistry()
+
+var factory = promauto.With(Registry)
+
+var (
+	HTTPRequestDuration = factory.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "request_duration_seconds",
+		Help:      "Duration of the HTTP requests by route template, method and status code.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"route", "method", "code"})
+
+	UploadedBytes = factory.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "uploads",
+		Name:      "stored_bytes_total",
+		Help:      "Bytes of the uploaded images stored.",
+	})
+
+	UploadedImages = factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "uploads",
+		Name:      "images_total",
+		Help:      "Uploaded images by status (stored, duplicate or rejected) and rejection reason.",
+	}, []string{"status", "reason"})
+
+	KafkaPublishErrors = factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "kafka",
+		Name:      "publish_errors_total",
+		Help:      "Events that couldn't be published by event type.",
+	}, []string{"event_type"})
+
+	KafkaConsumerLag = factory.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "kafka",
+		Name:      "consumer_lag_messages",
+		Help:      "Messages of the partition not consumed yet, as of the last fetched message.",
+	}, []string{"topic", "partition"})
+
+	KafkaHandleDuration = factory.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "kafka",
+		Name:      "handle_duration_seconds",
+		Help:      "Duration of the handling of the consumed events by event type.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"event_type"})
+
+	KafkaCommitErrors = factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "kafka",
+		Name:      "commit_errors_total",
+		Help:      "Consumed messages whose offset couldn't be committed by topic.",
+	}, []string{"topic"})
+
+	EventHandlerDuration = factory.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "events",
+		Name:      "handler_duration_seconds",
+		Help:      "Duration of the attempts of the event handlers by handler, event type and outcome (success or failure).",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"handler", "event_type", "outcome"})
+
+	MongoOperationDuration = factory.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "mongo",
+		Name:      "operation_duration_seconds",
+		Help:      "Duration of the MongoDB commands by collection, operation and outcome (success or failure).",
+		Buckets:   []float64{.001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5},
+	}, []string{"collection", "operation", "outcome"})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+}
+
+// Handler serves the metrics in the Prometheus exposition format.
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{Registry: Registry})
+}
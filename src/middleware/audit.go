@@ -0,0 +1,122 @@
+package middleware
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/tam-code/image-upload/src/loggers"
+	"github.com/tam-code/image-upload/src/models"
+	"github.com/tam-code/image-upload/src/producers"
+	"github.com/tam-code/image-upload/src/repositories"
+)
+
+const (
+	ForwardedForHeader = "X-Forwarded-For"
+
+	auditEventContextKey contextKey = "audit_event"
+)
+
+type statusRecorder struct {
+	http.ResponseWriter
+	statusCode int
+}
+
+func (r *statusRecorder) WriteHeader(statusCode int) {
+	r.statusCode = statusCode
+	r.ResponseWriter.WriteHeader(statusCode)
+}
+
+// Flush keeps streaming responses working behind the recorder.
+func (r *statusRecorder) Flush() {
+	if flusher, ok := r.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Audit records the action with its actor, targets and outcome once the
+// request completed, it wraps the authentication and the scope check so
+// unauthenticated and denied requests are recorded too, the authenticated
+// principal becomes the actor with WithPrincipal. Handlers add the records they acted on with AddAuditTargets. The event
+// is exported to the producer when it isn't nil, a failure to store or export
+// it doesn't fail the request.
+func Audit(repository repositories.AuditRepository, producer producers.AuditEventProducer, trustForwardedFor bool, action models.AuditAction) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			event := &models.AuditEvent{
+				Tenant:    models.DefaultTenant,
+				Actor:     models.AnonymousActor,
+				Action:    action,
+				TargetIDs: []string{},
+				ClientIP:  clientIP(r, trustForwardedFor),
+				UserAgent: r.UserAgent(),
+			}
+
+			setAuditPrincipal(event, PrincipalFromContext(r.Context()))
+
+			recorder := &statusRecorder{ResponseWriter: w, statusCode: http.StatusOK}
+			next.ServeHTTP(recorder, r.WithContext(context.WithValue(r.Context(), auditEventContextKey, event)))
+
+			event.StatusCode = recorder.statusCode
+			event.Outcome = models.NewAuditOutcome(recorder.statusCode)
+			event.Time = time.Now()
+
+			if err := repository.InsertAuditEvent(event); err != nil {
+				loggers.FromContext(r.Context()).Error("error recording audit event", "action", event.Action, "error", err)
+			}
+
+			if producer != nil {
+				if err := producer.Publish(r.Context(), *event); err != nil {
+					loggers.FromContext(r.Context()).Error("error exporting audit event", "action", event.Action, "error", err)
+				}
+			}
+		})
+	}
+}
+
+// AddAuditTargets adds the ids of the records the audited request acted on.
+func AddAuditTargets(ctx context.Context, ids ...string) {
+	if event, ok := ctx.Value(auditEventContextKey).(*models.AuditEvent); ok {
+		event.TargetIDs = append(event.TargetIDs, ids...)
+	}
+}
+
+// SetAuditTenant sets the tenant of an audited request that isn't
+// authenticated, like the uploads to a tenant's upload link.
+func SetAuditTenant(ctx context.Context, tenant string) {
+	if event, ok := ctx.Value(auditEventContextKey).(*models.AuditEvent); ok {
+		event.Tenant = tenant
+	}
+}
+
+// setAuditPrincipal makes the principal the actor of the event, the event is
+// left anonymous without principal.
+func setAuditPrincipal(event *models.AuditEvent, principal *models.Principal) {
+	if principal == nil {
+		return
+	}
+
+	event.Actor = principal.Subject
+	if principal.Tenant != "" {
+		event.Tenant = principal.Tenant
+	}
+}
+
+// clientIP returns the address of the client, the first X-Forwarded-For entry
+// is only trusted behind a proxy setting it.
+func clientIP(r *http.Request, trustForwardedFor bool) string {
+	if trustForwardedFor {
+		if forwardedFor := r.Header.Get(ForwardedForHeader); forwardedFor != "" {
+			return strings.TrimSpace(strings.Split(forwardedFor, ",")[0])
+		}
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+
+	return host
+}
@@ -0,0 +1,147 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	producerMocks "github.com/tam-code/image-upload/mocks/producers"
+	mocks "github.com/tam-code/image-upload/mocks/repositories"
+	"github.com/tam-code/image-upload/src/models"
+	"github.com/tam-code/image-upload/src/producers"
+)
+
+func TestAudit(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockAuditRepo := mocks.NewMockAuditRepository(ctrl)
+	mockAuditProducer := producerMocks.NewMockAuditEventProducer(ctrl)
+
+	tests := []struct {
+		name              string
+		principal         *models.Principal
+		forwardedFor      string
+		trustForwardedFor bool
+		handler           http.HandlerFunc
+		insertErr         error
+		export            bool
+		expectedEvent     models.AuditEvent
+	}{
+		{
+			name:      "successful action records its targets",
+			principal: &models.Principal{Subject: "key-1", Tenant: "marketing", Scopes: []models.Scope{models.ScopeImagesDelete}},
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				AddAuditTargets(r.Context(), "image-1")
+				w.WriteHeader(http.StatusNoContent)
+			},
+			expectedEvent: models.AuditEvent{
+				Tenant: "marketing", Actor: "key-1", Action: models.AuditImageDelete, TargetIDs: []string{"image-1"},
+				ClientIP: "192.0.2.1", UserAgent: "test-agent", Outcome: models.AuditSuccess, StatusCode: http.StatusNoContent,
+			},
+		},
+		{
+			name:      "denied action",
+			principal: &models.Principal{Subject: "key-1", Tenant: models.DefaultTenant},
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				http.Error(w, "Forbidden", http.StatusForbidden)
+			},
+			expectedEvent: models.AuditEvent{
+				Tenant: models.DefaultTenant, Actor: "key-1", Action: models.AuditImageDelete, TargetIDs: []string{},
+				ClientIP: "192.0.2.1", UserAgent: "test-agent", Outcome: models.AuditDenied, StatusCode: http.StatusForbidden,
+			},
+		},
+		{
+			name: "unauthenticated attempt",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				ValidateAPIKey(nil)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})).ServeHTTP(w, r)
+			},
+			expectedEvent: models.AuditEvent{
+				Tenant: models.DefaultTenant, Actor: models.AnonymousActor, Action: models.AuditImageDelete, TargetIDs: []string{},
+				ClientIP: "192.0.2.1", UserAgent: "test-agent", Outcome: models.AuditDenied, StatusCode: http.StatusUnauthorized,
+			},
+		},
+		{
+			name: "principal authenticated after the audit started",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				WithPrincipal(r.Context(), &models.Principal{Subject: "key-2", Tenant: "marketing"})
+				w.WriteHeader(http.StatusNoContent)
+			},
+			expectedEvent: models.AuditEvent{
+				Tenant: "marketing", Actor: "key-2", Action: models.AuditImageDelete, TargetIDs: []string{},
+				ClientIP: "192.0.2.1", UserAgent: "test-agent", Outcome: models.AuditSuccess, StatusCode: http.StatusNoContent,
+			},
+		},
+		{
+			name:              "anonymous action with the tenant set by the handler and a trusted proxy",
+			forwardedFor:      "203.0.113.7, 10.0.0.1",
+			trustForwardedFor: true,
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				SetAuditTenant(r.Context(), "marketing")
+				http.Error(w, "Bad request", http.StatusBadRequest)
+			},
+			expectedEvent: models.AuditEvent{
+				Tenant: "marketing", Actor: models.AnonymousActor, Action: models.AuditImageDelete, TargetIDs: []string{},
+				ClientIP: "203.0.113.7", UserAgent: "test-agent", Outcome: models.AuditFailure, StatusCode: http.StatusBadRequest,
+			},
+		},
+		{
+			name:         "untrusted forwarded for is ignored",
+			forwardedFor: "203.0.113.7",
+			handler:      func(w http.ResponseWriter, r *http.Request) {},
+			expectedEvent: models.AuditEvent{
+				Tenant: models.DefaultTenant, Actor: models.AnonymousActor, Action: models.AuditImageDelete, TargetIDs: []string{},
+				ClientIP: "192.0.2.1", UserAgent: "test-agent", Outcome: models.AuditSuccess, StatusCode: http.StatusOK,
+			},
+		},
+		{
+			name:      "exported even if storing failed",
+			handler:   func(w http.ResponseWriter, r *http.Request) {},
+			insertErr: errors.New("db error"),
+			export:    true,
+			expectedEvent: models.AuditEvent{
+				Tenant: models.DefaultTenant, Actor: models.AnonymousActor, Action: models.AuditImageDelete, TargetIDs: []string{},
+				ClientIP: "192.0.2.1", UserAgent: "test-agent", Outcome: models.AuditSuccess, StatusCode: http.StatusOK,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var recorded *models.AuditEvent
+			mockAuditRepo.EXPECT().InsertAuditEvent(gomock.Any()).DoAndReturn(func(event *models.AuditEvent) error {
+				recorded = event
+				return tt.insertErr
+			})
+
+			var producer producers.AuditEventProducer
+			if tt.export {
+				mockAuditProducer.EXPECT().Publish(gomock.Any(), gomock.Any()).Return(nil)
+				producer = mockAuditProducer
+			}
+
+			handler := Audit(mockAuditRepo, producer, tt.trustForwardedFor, models.AuditImageDelete)(tt.handler)
+
+			req := httptest.NewRequest("DELETE", "/images/image-1", nil)
+			req.RemoteAddr = "192.0.2.1:1234"
+			req.Header.Set("User-Agent", "test-agent")
+			if tt.forwardedFor != "" {
+				req.Header.Set(ForwardedForHeader, tt.forwardedFor)
+			}
+			if tt.principal != nil {
+				req = req.WithContext(WithPrincipal(req.Context(), tt.principal))
+			}
+
+			handler.ServeHTTP(httptest.NewRecorder(), req)
+
+			if assert.NotNil(t, recorded) {
+				assert.False(t, recorded.Time.IsZero())
+				recorded.Time = tt.expectedEvent.Time
+				assert.Equal(t, tt.expectedEvent, *recorded)
+			}
+		})
+	}
+}
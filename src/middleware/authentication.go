@@ -1,27 +1,136 @@
 package middleware
 
 import (
-	"log"
+	"context"
 	"net/http"
+	"strings"
+	"time"
+
+	"github.com/tam-code/image-upload/src/authenticators"
+	"github.com/tam-code/image-upload/src/loggers"
+	"github.com/tam-code/image-upload/src/models"
+	"github.com/tam-code/image-upload/src/repositories"
+)
+
+const (
+	SecretTokenHeader   = "X-Secret-Token"
+	AuthorizationHeader = "Authorization"
+
+	bearerPrefix = "Bearer "
+
+	// last use is recorded at most once per interval, keeping most
+	// authentications free of writes
+	apiKeyTouchInterval = time.Minute
 )
 
-var tokenUsers map[string]string = map[string]string{
-	"00000000": "user0",
-	"aaaaaaaa": "userA",
-	"05f717e5": "randomUser",
+type contextKey string
+
+const principalContextKey contextKey = "principal"
+
+// WithPrincipal adds the authenticated caller to the context, and makes it
+// the actor of the audit event of the request.
+func WithPrincipal(ctx context.Context, principal *models.Principal) context.Context {
+	if event, ok := ctx.Value(auditEventContextKey).(*models.AuditEvent); ok {
+		setAuditPrincipal(event, principal)
+	}
+
+	return context.WithValue(ctx, principalContextKey, principal)
+}
+
+// PrincipalFromContext returns the authenticated caller, nil when the request
+// wasn't authenticated.
+func PrincipalFromContext(ctx context.Context) *models.Principal {
+	principal, _ := ctx.Value(principalContextKey).(*models.Principal)
+	return principal
+}
+
+// ValidateBearerToken authenticates the bearer token of the Authorization
+// header and adds its principal to the request context. Requests without a
+// bearer token are left to the next authentication middleware.
+func ValidateBearerToken(authenticator authenticators.JWTAuthenticator) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			authorization := r.Header.Get(AuthorizationHeader)
+			if len(authorization) < len(bearerPrefix) || !strings.EqualFold(authorization[:len(bearerPrefix)], bearerPrefix) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			principal, err := authenticator.Authenticate(strings.TrimSpace(authorization[len(bearerPrefix):]))
+			if err != nil {
+				loggers.FromContext(r.Context()).Info("error authenticating bearer token", "error", err)
+				w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+				http.Error(w, "Unauthorized", http.StatusUnauthorized)
+				return
+			}
+
+			loggers.FromContext(r.Context()).Debug("authenticated bearer token", "subject", principal.Subject, "tenant", principal.Tenant)
+
+			next.ServeHTTP(w, r.WithContext(WithPrincipal(r.Context(), principal)))
+		})
+	}
+}
+
+// ValidateAPIKey authenticates the X-Secret-Token header against the stored
+// API keys and adds the key as principal to the request context. Requests
+// already authenticated by a bearer token are passed through.
+func ValidateAPIKey(repository repositories.APIKeyRepository) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if PrincipalFromContext(r.Context()) != nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			secretToken := r.Header.Get(SecretTokenHeader)
+			if secretToken == "" {
+				http.Error(w, "Unauthorized", http.StatusUnauthorized)
+				return
+			}
+
+			apiKey, err := repository.GetAPIKeyByHash(models.HashAPIKey(secretToken))
+			if err != nil {
+				loggers.FromContext(r.Context()).Error("error getting api key", "error", err)
+				http.Error(w, "Error authenticating request", http.StatusInternalServerError)
+				return
+			}
+
+			now := time.Now()
+			if apiKey == nil || !apiKey.IsActive(now) {
+				http.Error(w, "Unauthorized", http.StatusUnauthorized)
+				return
+			}
+
+			if apiKey.LastUsedAt == nil || now.Sub(*apiKey.LastUsedAt) >= apiKeyTouchInterval {
+				if err = repository.TouchAPIKey(apiKey.ID, now); err != nil {
+					loggers.FromContext(r.Context()).Error("error recording api key use", "api_key_id", apiKey.ID, "error", err)
+				}
+			}
+
+			loggers.FromContext(r.Context()).Debug("authenticated api key", "api_key_id", apiKey.ID, "name", apiKey.Name, "tenant", apiKey.Tenant)
+
+			principal := &models.Principal{Subject: apiKey.ID, Tenant: apiKey.Tenant, Scopes: apiKey.Scopes}
+			next.ServeHTTP(w, r.WithContext(WithPrincipal(r.Context(), principal)))
+		})
+	}
 }
 
-func ValidateSecretToken(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		secretToken := r.Header.Get("X-Secret-Token")
-		user, ok := tokenUsers[secretToken]
-		if !ok {
-			http.Error(w, "Unauthorized", http.StatusUnauthorized)
-			return
-		}
+// RequireScope rejects the requests whose principal wasn't granted the scope.
+func RequireScope(scope models.Scope) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			principal := PrincipalFromContext(r.Context())
+			if principal == nil {
+				http.Error(w, "Unauthorized", http.StatusUnauthorized)
+				return
+			}
 
-		log.Printf("Authenticated user %s\n", user)
+			if !principal.HasScope(scope) {
+				http.Error(w, "Forbidden, missing scope "+string(scope), http.StatusForbidden)
+				return
+			}
 
-		next.ServeHTTP(w, r)
-	})
+			next.ServeHTTP(w, r)
+		})
+	}
 }
@@ -0,0 +1,225 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	authenticatorMocks "github.com/tam-code/image-upload/mocks/authenticators"
+	mocks "github.com/tam-code/image-upload/mocks/repositories"
+	"github.com/tam-code/image-upload/src/authenticators"
+	"github.com/tam-code/image-upload/src/models"
+)
+
+func TestValidateAPIKey(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockAPIKeyRepo := mocks.NewMockAPIKeyRepository(ctrl)
+
+	recently := time.Now().Add(-time.Second)
+	longAgo := time.Now().Add(-time.Hour)
+	expired := time.Now().Add(-time.Minute)
+
+	tests := []struct {
+		name            string
+		token           string
+		mockRepoFunc    func()
+		expectedStatus  int
+		expectedSubject string
+	}{
+		{
+			name:           "missing token",
+			mockRepoFunc:   func() {},
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:  "unknown token",
+			token: "unknown",
+			mockRepoFunc: func() {
+				mockAPIKeyRepo.EXPECT().GetAPIKeyByHash(models.HashAPIKey("unknown")).Return(nil, nil)
+			},
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:  "revoked key",
+			token: "revoked",
+			mockRepoFunc: func() {
+				mockAPIKeyRepo.EXPECT().GetAPIKeyByHash(models.HashAPIKey("revoked")).Return(&models.APIKey{ID: "revoked", RevokedAt: &recently}, nil)
+			},
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:  "expired key",
+			token: "expired",
+			mockRepoFunc: func() {
+				mockAPIKeyRepo.EXPECT().GetAPIKeyByHash(models.HashAPIKey("expired")).Return(&models.APIKey{ID: "expired", ExpiresAt: &expired}, nil)
+			},
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:  "repository error",
+			token: "valid",
+			mockRepoFunc: func() {
+				mockAPIKeyRepo.EXPECT().GetAPIKeyByHash(models.HashAPIKey("valid")).Return(nil, errors.New("db error"))
+			},
+			expectedStatus: http.StatusInternalServerError,
+		},
+		{
+			name:  "valid key records its use",
+			token: "valid",
+			mockRepoFunc: func() {
+				mockAPIKeyRepo.EXPECT().GetAPIKeyByHash(models.HashAPIKey("valid")).Return(&models.APIKey{ID: "valid", LastUsedAt: &longAgo}, nil)
+				mockAPIKeyRepo.EXPECT().TouchAPIKey("valid", gomock.Any()).Return(nil)
+			},
+			expectedStatus:  http.StatusOK,
+			expectedSubject: "valid",
+		},
+		{
+			name:  "recently used key",
+			token: "recent",
+			mockRepoFunc: func() {
+				mockAPIKeyRepo.EXPECT().GetAPIKeyByHash(models.HashAPIKey("recent")).Return(&models.APIKey{ID: "recent", LastUsedAt: &recently}, nil)
+			},
+			expectedStatus:  http.StatusOK,
+			expectedSubject: "recent",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockRepoFunc()
+
+			var subject string
+			handler := ValidateAPIKey(mockAPIKeyRepo)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				subject = PrincipalFromContext(r.Context()).Subject
+			}))
+
+			req := httptest.NewRequest(http.MethodGet, "/statistics", nil)
+			if tt.token != "" {
+				req.Header.Set(SecretTokenHeader, tt.token)
+			}
+			w := httptest.NewRecorder()
+
+			handler.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			assert.Equal(t, tt.expectedSubject, subject)
+		})
+	}
+}
+
+func TestValidateBearerToken(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockAuthenticator := authenticatorMocks.NewMockJWTAuthenticator(ctrl)
+	mockAPIKeyRepo := mocks.NewMockAPIKeyRepository(ctrl)
+
+	recently := time.Now()
+
+	tests := []struct {
+		name            string
+		headers         map[string]string
+		mockFunc        func()
+		expectedStatus  int
+		expectedSubject string
+	}{
+		{
+			name:    "valid bearer token",
+			headers: map[string]string{AuthorizationHeader: "Bearer valid-token"},
+			mockFunc: func() {
+				mockAuthenticator.EXPECT().Authenticate("valid-token").Return(&models.Principal{Subject: "user-1"}, nil)
+			},
+			expectedStatus:  http.StatusOK,
+			expectedSubject: "user-1",
+		},
+		{
+			name:    "invalid bearer token",
+			headers: map[string]string{AuthorizationHeader: "bearer expired-token", SecretTokenHeader: "valid"},
+			mockFunc: func() {
+				mockAuthenticator.EXPECT().Authenticate("expired-token").Return(nil, authenticators.ErrTokenExpired)
+			},
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:    "falls back to the api key",
+			headers: map[string]string{AuthorizationHeader: "Basic dXNlcjpwYXNz", SecretTokenHeader: "valid"},
+			mockFunc: func() {
+				mockAPIKeyRepo.EXPECT().GetAPIKeyByHash(models.HashAPIKey("valid")).Return(&models.APIKey{ID: "key-1", LastUsedAt: &recently}, nil)
+			},
+			expectedStatus:  http.StatusOK,
+			expectedSubject: "key-1",
+		},
+		{
+			name:           "no credentials",
+			mockFunc:       func() {},
+			expectedStatus: http.StatusUnauthorized,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockFunc()
+
+			var subject string
+			handler := ValidateBearerToken(mockAuthenticator)(ValidateAPIKey(mockAPIKeyRepo)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				subject = PrincipalFromContext(r.Context()).Subject
+			})))
+
+			req := httptest.NewRequest(http.MethodGet, "/statistics", nil)
+			for key, value := range tt.headers {
+				req.Header.Set(key, value)
+			}
+			w := httptest.NewRecorder()
+
+			handler.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			assert.Equal(t, tt.expectedSubject, subject)
+		})
+	}
+}
+
+func TestRequireScope(t *testing.T) {
+	tests := []struct {
+		name           string
+		principal      *models.Principal
+		expectedStatus int
+	}{
+		{
+			name:           "unauthenticated",
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "missing scope",
+			principal:      &models.Principal{Subject: "key", Scopes: []models.Scope{models.ScopeImagesRead}},
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			name:           "granted scope",
+			principal:      &models.Principal{Subject: "key", Scopes: []models.Scope{models.ScopeImagesRead, models.ScopeStatsRead}},
+			expectedStatus: http.StatusOK,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := RequireScope(models.ScopeStatsRead)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+			req := httptest.NewRequest(http.MethodGet, "/statistics", nil)
+			if tt.principal != nil {
+				req = req.WithContext(WithPrincipal(req.Context(), tt.principal))
+			}
+			w := httptest.NewRecorder()
+
+			handler.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+		})
+	}
+}
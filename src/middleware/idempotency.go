@@ -0,0 +1,217 @@
+package middleware
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"hash"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/tam-code/image-upload/src/loggers"
+	"github.com/tam-code/image-upload/src/models"
+	"github.com/tam-code/image-upload/src/repositories"
+)
+
+const (
+	IdempotencyKeyHeader     = "Idempotency-Key"
+	IdempotentReplayedHeader = "Idempotent-Replayed"
+	maxIdempotencyKeyLength  = 255
+	// maxIdempotentResponseBytes bounds the stored responses, the larger
+	// ones aren't stored
+	maxIdempotentResponseBytes = 1 << 20
+)
+
+// responseRecorder keeps the response body up to maxIdempotentResponseBytes,
+// truncated tells it was larger.
+type responseRecorder struct {
+	http.ResponseWriter
+	statusCode int
+	body       bytes.Buffer
+	truncated  bool
+}
+
+func (r *responseRecorder) WriteHeader(statusCode int) {
+	r.statusCode = statusCode
+	r.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (r *responseRecorder) Write(data []byte) (int, error) {
+	if r.body.Len()+len(data) > maxIdempotentResponseBytes {
+		r.truncated = true
+	} else if !r.truncated {
+		r.body.Write(data)
+	}
+	return r.ResponseWriter.Write(data)
+}
+
+// Idempotency replays the stored response of a completed request to retries
+// sending the same Idempotency-Key to the same route. Reusing a key with a
+// different request is rejected, server errors aren't stored so they can be
+// retried. Requests without the header are passed through, the others are
+// read up to maxRequestBytes to be fingerprinted.
+func Idempotency(repository repositories.IdempotencyRepository, ttl, lockTimeout time.Duration, maxRequestBytes int64) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := r.Header.Get(IdempotencyKeyHeader)
+			if key == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if len(key) > maxIdempotencyKeyLength {
+				http.Error(w, "Idempotency-Key must be at most 255 characters", http.StatusBadRequest)
+				return
+			}
+
+			// the body is hashed as it is read and kept once for the handler
+			var body bytes.Buffer
+			bodyReader := io.TeeReader(http.MaxBytesReader(w, r.Body, maxRequestBytes), &body)
+
+			requestHash, err := requestFingerprint(r, bodyReader)
+			if err == nil {
+				_, err = io.Copy(io.Discard, bodyReader)
+			}
+			var maxBytesErr *http.MaxBytesError
+			if errors.As(err, &maxBytesErr) {
+				http.Error(w, "Request body too large", http.StatusRequestEntityTooLarge)
+				return
+			}
+			if err != nil {
+				http.Error(w, "Error reading request body, "+err.Error(), http.StatusBadRequest)
+				return
+			}
+			r.Body = io.NopCloser(&body)
+
+			// keys of different tenants never collide, public routes are
+			// already scoped by the upload link in their path
+			scope := r.Method + " " + r.URL.Path
+			if principal := PrincipalFromContext(r.Context()); principal != nil {
+				scope = principal.Tenant + " " + scope
+			}
+
+			now := time.Now()
+			record := &models.IdempotencyRecord{
+				Key:         key,
+				Scope:       scope,
+				RequestHash: requestHash,
+				CreatedAt:   now,
+				ExpiresAt:   now.Add(ttl),
+			}
+
+			existing, err := repository.ReserveIdempotencyKey(record, now.Add(-lockTimeout))
+			if err != nil {
+				loggers.FromContext(r.Context()).Error("error reserving idempotency key", "error", err)
+				http.Error(w, "Error checking Idempotency-Key", http.StatusInternalServerError)
+				return
+			}
+
+			if existing != nil {
+				switch {
+				case existing.RequestHash != requestHash:
+					http.Error(w, "Idempotency-Key already used with a different request", http.StatusUnprocessableEntity)
+				case !existing.Completed:
+					http.Error(w, "A request with this Idempotency-Key is in progress", http.StatusConflict)
+				default:
+					if existing.ContentType != "" {
+						w.Header().Set("Content-Type", existing.ContentType)
+					}
+					w.Header().Set(IdempotentReplayedHeader, "true")
+					w.WriteHeader(existing.StatusCode)
+					w.Write(existing.Body)
+				}
+				return
+			}
+
+			recorder := &responseRecorder{ResponseWriter: w, statusCode: http.StatusOK}
+			next.ServeHTTP(recorder, r)
+
+			if recorder.statusCode >= http.StatusInternalServerError || recorder.truncated {
+				if err = repository.ReleaseIdempotencyKey(record.Key, record.Scope); err != nil {
+					loggers.FromContext(r.Context()).Error("error releasing idempotency key", "error", err)
+				}
+				return
+			}
+
+			record.StatusCode = recorder.statusCode
+			record.ContentType = recorder.Header().Get("Content-Type")
+			record.Body = recorder.body.Bytes()
+			if err = repository.CompleteIdempotencyKey(record); err != nil {
+				loggers.FromContext(r.Context()).Error("error completing idempotency key", "error", err)
+			}
+		})
+	}
+}
+
+// requestFingerprint hashes the request content as it reads the body,
+// ignoring what a client may change between retries of the same request: the
+// multipart boundary and the order of urlencoded fields.
+func requestFingerprint(r *http.Request, body io.Reader) (string, error) {
+	h := sha256.New()
+	writeHashField(h, []byte(r.Method))
+	writeHashField(h, []byte(r.URL.Path))
+	writeHashField(h, []byte(r.URL.Query().Encode()))
+
+	mediaType, params, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	switch {
+	case strings.HasPrefix(mediaType, "multipart/"):
+		reader := multipart.NewReader(body, params["boundary"])
+		for {
+			part, err := reader.NextPart()
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			if err != nil {
+				return "", err
+			}
+
+			writeHashField(h, []byte(part.FormName()))
+			writeHashField(h, []byte(part.FileName()))
+			if err = writeHashContent(h, part); err != nil {
+				return "", err
+			}
+		}
+	case mediaType == "application/x-www-form-urlencoded":
+		content, err := io.ReadAll(body)
+		if err != nil {
+			return "", err
+		}
+
+		values, err := url.ParseQuery(string(content))
+		if err != nil {
+			return "", err
+		}
+		writeHashField(h, []byte(values.Encode()))
+	default:
+		if err := writeHashContent(h, body); err != nil {
+			return "", err
+		}
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// writeHashContent hashes the content on its own and writes its hash as a
+// field, so the content is never held in memory.
+func writeHashContent(h hash.Hash, content io.Reader) error {
+	contentHash := sha256.New()
+	if _, err := io.Copy(contentHash, content); err != nil {
+		return err
+	}
+
+	writeHashField(h, contentHash.Sum(nil))
+	return nil
+}
+
+// writeHashField length prefixes the field so concatenations can't collide.
+func writeHashField(h hash.Hash, data []byte) {
+	binary.Write(h, binary.BigEndian, uint64(len(data)))
+	h.Write(data)
+}
@@ -0,0 +1,222 @@
+package middleware
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	mocks "github.com/tam-code/image-upload/mocks/repositories"
+	"github.com/tam-code/image-upload/src/models"
+)
+
+func TestIdempotency(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockIdempotencyRepo := mocks.NewMockIdempotencyRepository(ctrl)
+
+	body := "expiration=2030-01-01T00:00:00Z"
+	request := httptest.NewRequest(http.MethodPost, "/upload-link", strings.NewReader(body))
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	requestHash, _ := requestFingerprint(request, strings.NewReader(body))
+
+	tests := []struct {
+		name             string
+		key              string
+		handlerStatus    int
+		handlerBody      string
+		maxRequestBytes  int64
+		mockRepoFunc     func()
+		expectedStatus   int
+		expectedBody     string
+		expectedCalls    int
+		expectedReplayed bool
+	}{
+		{
+			name:           "without key",
+			handlerStatus:  http.StatusOK,
+			mockRepoFunc:   func() {},
+			expectedStatus: http.StatusOK,
+			expectedBody:   "created",
+			expectedCalls:  1,
+		},
+		{
+			name:          "first request is stored",
+			key:           "key",
+			handlerStatus: http.StatusOK,
+			mockRepoFunc: func() {
+				mockIdempotencyRepo.EXPECT().ReserveIdempotencyKey(gomock.Any(), gomock.Any()).DoAndReturn(
+					func(record *models.IdempotencyRecord, lockedBefore time.Time) (*models.IdempotencyRecord, error) {
+						assert.Equal(t, "POST /upload-link", record.Scope)
+						assert.Equal(t, requestHash, record.RequestHash)
+						assert.WithinDuration(t, time.Now().Add(time.Hour), record.ExpiresAt, time.Second)
+						assert.WithinDuration(t, time.Now().Add(-time.Minute), lockedBefore, time.Second)
+						return nil, nil
+					})
+				mockIdempotencyRepo.EXPECT().CompleteIdempotencyKey(gomock.Any()).DoAndReturn(func(record *models.IdempotencyRecord) error {
+					assert.Equal(t, http.StatusOK, record.StatusCode)
+					assert.Equal(t, "application/json", record.ContentType)
+					assert.Equal(t, "created", string(record.Body))
+					return nil
+				})
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   "created",
+			expectedCalls:  1,
+		},
+		{
+			name:          "server errors are released",
+			key:           "key",
+			handlerStatus: http.StatusInternalServerError,
+			mockRepoFunc: func() {
+				mockIdempotencyRepo.EXPECT().ReserveIdempotencyKey(gomock.Any(), gomock.Any()).Return(nil, nil)
+				mockIdempotencyRepo.EXPECT().ReleaseIdempotencyKey("key", "POST /upload-link").Return(nil)
+			},
+			expectedStatus: http.StatusInternalServerError,
+			expectedBody:   "created",
+			expectedCalls:  1,
+		},
+		{
+			name: "completed request is replayed",
+			key:  "key",
+			mockRepoFunc: func() {
+				mockIdempotencyRepo.EXPECT().ReserveIdempotencyKey(gomock.Any(), gomock.Any()).Return(&models.IdempotencyRecord{
+					RequestHash: requestHash,
+					Completed:   true,
+					StatusCode:  http.StatusCreated,
+					ContentType: "application/json",
+					Body:        []byte("stored"),
+				}, nil)
+			},
+			expectedStatus:   http.StatusCreated,
+			expectedBody:     "stored",
+			expectedReplayed: true,
+		},
+		{
+			name: "key reused with a different request",
+			key:  "key",
+			mockRepoFunc: func() {
+				mockIdempotencyRepo.EXPECT().ReserveIdempotencyKey(gomock.Any(), gomock.Any()).Return(&models.IdempotencyRecord{
+					RequestHash: "other",
+					Completed:   true,
+				}, nil)
+			},
+			expectedStatus: http.StatusUnprocessableEntity,
+			expectedBody:   "Idempotency-Key already used with a different request",
+		},
+		{
+			name: "request in progress",
+			key:  "key",
+			mockRepoFunc: func() {
+				mockIdempotencyRepo.EXPECT().ReserveIdempotencyKey(gomock.Any(), gomock.Any()).Return(&models.IdempotencyRecord{
+					RequestHash: requestHash,
+				}, nil)
+			},
+			expectedStatus: http.StatusConflict,
+			expectedBody:   "A request with this Idempotency-Key is in progress",
+		},
+		{
+			name: "repository error",
+			key:  "key",
+			mockRepoFunc: func() {
+				mockIdempotencyRepo.EXPECT().ReserveIdempotencyKey(gomock.Any(), gomock.Any()).Return(nil, errors.New("db error"))
+			},
+			expectedStatus: http.StatusInternalServerError,
+			expectedBody:   "Error checking Idempotency-Key",
+		},
+		{
+			name:           "key too long",
+			key:            strings.Repeat("k", 256),
+			mockRepoFunc:   func() {},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   "Idempotency-Key must be at most 255 characters",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockRepoFunc()
+
+			maxRequestBytes := tt.maxRequestBytes
+			if maxRequestBytes == 0 {
+				maxRequestBytes = 1 << 20
+			}
+			handlerBody := tt.handlerBody
+			if handlerBody == "" {
+				handlerBody = "created"
+			}
+
+			calls := 0
+			handler := Idempotency(mockIdempotencyRepo, time.Hour, time.Minute, maxRequestBytes)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				calls++
+
+				// the handler still reads the whole body
+				readBody, _ := io.ReadAll(r.Body)
+				assert.Equal(t, body, string(readBody))
+
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(tt.handlerStatus)
+				w.Write([]byte(handlerBody))
+			}))
+
+			req := httptest.NewRequest(http.MethodPost, "/upload-link", strings.NewReader(body))
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			if tt.key != "" {
+				req.Header.Set(IdempotencyKeyHeader, tt.key)
+			}
+			w := httptest.NewRecorder()
+
+			handler.ServeHTTP(w, req)
+
+			resp := w.Result()
+			bodyBytes, _ := io.ReadAll(resp.Body)
+
+			assert.Equal(t, tt.expectedStatus, resp.StatusCode)
+			assert.Contains(t, string(bodyBytes), tt.expectedBody)
+			assert.Equal(t, tt.expectedCalls, calls)
+			assert.Equal(t, tt.expectedReplayed, resp.Header.Get(IdempotentReplayedHeader) == "true")
+		})
+	}
+}
+
+func TestRequestFingerprint(t *testing.T) {
+	multipartRequest := func(boundary, content string) (*http.Request, io.Reader) {
+		var body bytes.Buffer
+		writer := multipart.NewWriter(&body)
+		writer.SetBoundary(boundary)
+		part, _ := writer.CreateFormFile("images", "a.jpg")
+		part.Write([]byte(content))
+		writer.Close()
+
+		req := httptest.NewRequest(http.MethodPost, "/images/link", bytes.NewReader(body.Bytes()))
+		req.Header.Set("Content-Type", writer.FormDataContentType())
+		return req, bytes.NewReader(body.Bytes())
+	}
+
+	fingerprint := func(req *http.Request, body io.Reader) string {
+		hash, err := requestFingerprint(req, body)
+		assert.NoError(t, err)
+		return hash
+	}
+
+	first := fingerprint(multipartRequest("first-boundary", "image"))
+	assert.Equal(t, first, fingerprint(multipartRequest("second-boundary", "image")))
+	assert.NotEqual(t, first, fingerprint(multipartRequest("first-boundary", "other image")))
+
+	form := func(body string) string {
+		req := httptest.NewRequest(http.MethodPost, "/upload-link", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		return fingerprint(req, strings.NewReader(body))
+	}
+
+	assert.Equal(t, form("expiration=x&privacyMode=keep"), form("privacyMode=keep&expiration=x"))
+	assert.NotEqual(t, form("expiration=x"), form("expiration=y"))
+}
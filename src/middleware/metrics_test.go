@@ -0,0 +1,37 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/tam-code/image-upload/src/metrics"
+)
+
+func TestMetrics(t *testing.T) {
+	router := mux.NewRouter()
+	router.Use(Metrics)
+	router.HandleFunc("/images/{image_id}", func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "Invalid image id or not found", http.StatusNotFound)
+	}).Methods("GET")
+
+	observer := metrics.HTTPRequestDuration.WithLabelValues("/images/{image_id}", "GET", "404")
+	before := sampleCount(t, observer)
+
+	for _, id := range []string{"first", "second"} {
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/images/"+id, nil))
+	}
+
+	assert.Equal(t, before+2, sampleCount(t, observer), "requests are labelled by route template, not path")
+}
+
+func sampleCount(t *testing.T, observer prometheus.Observer) uint64 {
+	var m dto.Metric
+	assert.NoError(t, observer.(prometheus.Metric).Write(&m))
+
+	return m.GetHistogram().GetSampleCount()
+}
@@ -0,0 +1,100 @@
+package middleware
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"github.com/tam-code/image-upload/src/loggers"
+	"github.com/tam-code/image-upload/src/models"
+	"github.com/tam-code/image-upload/src/ratelimiters"
+)
+
+const (
+	RateLimitLimitHeader     = "RateLimit-Limit"
+	RateLimitRemainingHeader = "RateLimit-Remaining"
+	RateLimitResetHeader     = "RateLimit-Reset"
+	RetryAfterHeader         = "Retry-After"
+)
+
+// RateLimit takes a token from the bucket of each key of the request and
+// rejects it once one of them is empty. Keys the request doesn't have, like
+// the upload link of a route without one, are skipped. The headers describe
+// the most exhausted bucket. Requests are let through when the store fails.
+func RateLimit(store ratelimiters.Store, limit models.RateLimit, keys []models.RateLimitKey, trustForwardedFor bool) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var tightest *models.RateLimitResult
+			for _, key := range keys {
+				value := rateLimitKeyValue(r, key, trustForwardedFor)
+				if value == "" {
+					continue
+				}
+
+				result, err := store.Take(string(key)+":"+value, limit)
+				if err != nil {
+					loggers.FromContext(r.Context()).Error("error taking rate limit token", "key", key, "error", err)
+					continue
+				}
+
+				if tightest == nil || isTighter(result, tightest) {
+					tightest = result
+				}
+			}
+
+			if tightest == nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			w.Header().Set(RateLimitLimitHeader, strconv.Itoa(tightest.Limit))
+			w.Header().Set(RateLimitRemainingHeader, strconv.Itoa(tightest.Remaining))
+			w.Header().Set(RateLimitResetHeader, strconv.Itoa(seconds(tightest.Reset)))
+
+			if !tightest.Allowed {
+				w.Header().Set(RetryAfterHeader, strconv.Itoa(max(1, seconds(tightest.RetryAfter))))
+				http.Error(w, "Too many requests", http.StatusTooManyRequests)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func rateLimitKeyValue(r *http.Request, key models.RateLimitKey, trustForwardedFor bool) string {
+	switch key {
+	case models.RateLimitByAPIKey:
+		// keys are counted per tenant as subjects of different identity
+		// providers may collide
+		if principal := PrincipalFromContext(r.Context()); principal != nil {
+			return principal.Tenant + "/" + principal.Subject
+		}
+	case models.RateLimitByClientIP:
+		return clientIP(r, trustForwardedFor)
+	case models.RateLimitByUploadLink:
+		return mux.Vars(r)["upload_link_id"]
+	}
+
+	return ""
+}
+
+// isTighter tells if the bucket of a denies the request or is closer to it than b.
+func isTighter(a, b *models.RateLimitResult) bool {
+	if a.Allowed != b.Allowed {
+		return !a.Allowed
+	}
+
+	if !a.Allowed {
+		return a.RetryAfter > b.RetryAfter
+	}
+
+	return a.Remaining < b.Remaining
+}
+
+func seconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
@@ -0,0 +1,90 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	ratelimiterMocks "github.com/tam-code/image-upload/mocks/ratelimiters"
+	"github.com/tam-code/image-upload/src/models"
+)
+
+func TestRateLimit(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockStore := ratelimiterMocks.NewMockStore(ctrl)
+
+	limit := models.RateLimit{Burst: 10, RefillRate: 1}
+	keys := []models.RateLimitKey{models.RateLimitByClientIP, models.RateLimitByUploadLink, models.RateLimitByAPIKey}
+
+	tests := []struct {
+		name              string
+		principal         *models.Principal
+		mockStoreFunc     func()
+		expectedStatus    int
+		expectedRemaining string
+		expectedReset     string
+		expectedRetry     string
+	}{
+		{
+			name: "allowed reports the most exhausted bucket",
+			mockStoreFunc: func() {
+				mockStore.EXPECT().Take("clientIP:192.0.2.1", limit).Return(&models.RateLimitResult{Allowed: true, Limit: 10, Remaining: 8, Reset: 2 * time.Second}, nil)
+				mockStore.EXPECT().Take("uploadLink:link-1", limit).Return(&models.RateLimitResult{Allowed: true, Limit: 10, Remaining: 3, Reset: 6500 * time.Millisecond}, nil)
+			},
+			expectedStatus:    http.StatusOK,
+			expectedRemaining: "3",
+			expectedReset:     "7",
+		},
+		{
+			name:      "denied by one of the buckets",
+			principal: &models.Principal{Subject: "key-1", Tenant: "marketing"},
+			mockStoreFunc: func() {
+				mockStore.EXPECT().Take("clientIP:192.0.2.1", limit).Return(&models.RateLimitResult{Allowed: true, Limit: 10, Remaining: 8, Reset: 2 * time.Second}, nil)
+				mockStore.EXPECT().Take("uploadLink:link-1", limit).Return(&models.RateLimitResult{Allowed: false, Limit: 10, Remaining: 0, Reset: 10 * time.Second, RetryAfter: 200 * time.Millisecond}, nil)
+				mockStore.EXPECT().Take("apiKey:marketing/key-1", limit).Return(&models.RateLimitResult{Allowed: true, Limit: 10, Remaining: 5, Reset: 5 * time.Second}, nil)
+			},
+			expectedStatus:    http.StatusTooManyRequests,
+			expectedRemaining: "0",
+			expectedReset:     "10",
+			expectedRetry:     "1",
+		},
+		{
+			name: "store errors let the request through",
+			mockStoreFunc: func() {
+				mockStore.EXPECT().Take(gomock.Any(), limit).Return(nil, errors.New("db error")).Times(2)
+			},
+			expectedStatus: http.StatusOK,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockStoreFunc()
+
+			router := mux.NewRouter()
+			router.Use(RateLimit(mockStore, limit, keys, false))
+			router.HandleFunc("/images/{upload_link_id}", func(w http.ResponseWriter, r *http.Request) {})
+
+			req := httptest.NewRequest("POST", "/images/link-1", nil)
+			req.RemoteAddr = "192.0.2.1:1234"
+			if tt.principal != nil {
+				req = req.WithContext(WithPrincipal(req.Context(), tt.principal))
+			}
+			w := httptest.NewRecorder()
+
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			assert.Equal(t, tt.expectedRemaining, w.Header().Get(RateLimitRemainingHeader))
+			assert.Equal(t, tt.expectedReset, w.Header().Get(RateLimitResetHeader))
+			assert.Equal(t, tt.expectedRetry, w.Header().Get(RetryAfterHeader))
+		})
+	}
+}
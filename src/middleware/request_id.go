@@ -0,0 +1,75 @@
+package middleware
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"log/slog"
+	"net/http"
+	"regexp"
+	"time"
+
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/tam-code/image-upload/src/loggers"
+)
+
+const (
+	RequestIDHeader = "X-Request-ID"
+
+	requestIDContextKey contextKey = "request_id"
+)
+
+// validRequestID bounds the incoming ids so callers can't inject arbitrary
+// content in the logs and response headers.
+var validRequestID = regexp.MustCompile(`^[A-Za-z0-9._:-]{1,128}$`)
+
+// RequestID keeps the X-Request-ID of the caller, or generates one, echoes it
+// in the response and scopes a logger carrying it, and the trace id when the
+// request is traced, to the request context. The completed requests are
+// logged with their status and duration.
+func RequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+
+		requestID := r.Header.Get(RequestIDHeader)
+		if !validRequestID.MatchString(requestID) {
+			requestID = newRequestID()
+		}
+		w.Header().Set(RequestIDHeader, requestID)
+
+		logger := loggers.FromContext(r.Context()).With(slog.String("request_id", requestID))
+		if spanContext := trace.SpanContextFromContext(r.Context()); spanContext.HasTraceID() {
+			logger = logger.With(slog.String("trace_id", spanContext.TraceID().String()))
+		}
+
+		ctx := context.WithValue(r.Context(), requestIDContextKey, requestID)
+		ctx = loggers.WithLogger(ctx, logger)
+
+		recorder := &statusRecorder{ResponseWriter: w, statusCode: http.StatusOK}
+		next.ServeHTTP(recorder, r.WithContext(ctx))
+
+		logger.Info("request completed",
+			slog.String("method", r.Method),
+			slog.String("path", r.URL.Path),
+			slog.Int("status", recorder.statusCode),
+			slog.Duration("duration", time.Since(start)),
+		)
+	})
+}
+
+// RequestIDFromContext returns the id of the request, empty when the request
+// didn't go through RequestID.
+func RequestIDFromContext(ctx context.Context) string {
+	requestID, _ := ctx.Value(requestIDContextKey).(string)
+	return requestID
+}
+
+func newRequestID() string {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return ""
+	}
+
+	return hex.EncodeToString(id)
+}
@@ -0,0 +1,77 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/tam-code/image-upload/src/loggers"
+)
+
+func TestRequestID(t *testing.T) {
+	tests := []struct {
+		name              string
+		incomingRequestID string
+		expectGenerated   bool
+	}{
+		{
+			name:              "caller request id is kept",
+			incomingRequestID: "req-42",
+		},
+		{
+			name:            "missing request id is generated",
+			expectGenerated: true,
+		},
+		{
+			name:              "invalid request id is replaced",
+			incomingRequestID: "bad id\nwith newline",
+			expectGenerated:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var logs bytes.Buffer
+			logger := slog.New(slog.NewJSONHandler(&logs, nil))
+
+			var contextRequestID string
+			handler := RequestID(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				contextRequestID = RequestIDFromContext(r.Context())
+				loggers.FromContext(r.Context()).Info("handled")
+				w.WriteHeader(http.StatusCreated)
+			}))
+
+			req := httptest.NewRequest("GET", "/api/v1/images/1", nil)
+			req = req.WithContext(loggers.WithLogger(req.Context(), logger))
+			if tt.incomingRequestID != "" {
+				req.Header.Set(RequestIDHeader, tt.incomingRequestID)
+			}
+			w := httptest.NewRecorder()
+
+			handler.ServeHTTP(w, req)
+
+			requestID := w.Header().Get(RequestIDHeader)
+			if tt.expectGenerated {
+				assert.Len(t, requestID, 32)
+			} else {
+				assert.Equal(t, tt.incomingRequestID, requestID)
+			}
+			assert.Equal(t, requestID, contextRequestID)
+
+			var handled, completed map[string]interface{}
+			decoder := json.NewDecoder(&logs)
+			assert.NoError(t, decoder.Decode(&handled))
+			assert.NoError(t, decoder.Decode(&completed))
+
+			assert.Equal(t, requestID, handled["request_id"])
+			assert.Equal(t, "request completed", completed["msg"])
+			assert.Equal(t, requestID, completed["request_id"])
+			assert.Equal(t, float64(http.StatusCreated), completed["status"])
+		})
+	}
+}
@@ -0,0 +1,104 @@
+package models
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"time"
+)
+
+type Scope string
+
+const (
+	ScopeLinksCreate  Scope = "links:create"
+	ScopeImagesRead   Scope = "images:read"
+	ScopeImagesDelete Scope = "images:delete"
+	ScopeStatsRead    Scope = "stats:read"
+	ScopeJanitorRun   Scope = "janitor:run"
+	ScopeKeysManage   Scope = "keys:manage"
+	ScopeAuditRead    Scope = "audit:read"
+	// ScopeStatusRead allows reading the diagnostics of the instance, they aren't scoped to a tenant
+	ScopeStatusRead Scope = "status:read"
+	// ScopeTenantsManage allows minting API keys for other tenants
+	ScopeTenantsManage Scope = "tenants:manage"
+)
+
+// BootstrapAPIKeyName names the admin key provisioned from the configuration.
+const BootstrapAPIKeyName = "bootstrap admin"
+
+// AllScopes lists every scope, the bootstrap admin key is granted all of them.
+var AllScopes = []Scope{
+	ScopeLinksCreate,
+	ScopeImagesRead,
+	ScopeImagesDelete,
+	ScopeStatsRead,
+	ScopeJanitorRun,
+	ScopeKeysManage,
+	ScopeAuditRead,
+	ScopeStatusRead,
+	ScopeTenantsManage,
+}
+
+func (s Scope) IsValid() bool {
+	for _, scope := range AllScopes {
+		if s == scope {
+			return true
+		}
+	}
+
+	return false
+}
+
+// APIKey is stored with the hash of its secret only, the secret is returned
+// once when the key is minted.
+type APIKey struct {
+	ID         string     `json:"id" bson:"-"`
+	Name       string     `json:"name" bson:"name"`
+	Tenant     string     `json:"tenant" bson:"tenant"`
+	Prefix     string     `json:"prefix" bson:"prefix"`
+	Hash       string     `json:"-" bson:"hash"`
+	Scopes     []Scope    `json:"scopes" bson:"scopes"`
+	CreatedAt  time.Time  `json:"createdAt" bson:"created_at"`
+	ExpiresAt  *time.Time `json:"expiresAt,omitempty" bson:"expires_at,omitempty"`
+	LastUsedAt *time.Time `json:"lastUsedAt,omitempty" bson:"last_used_at,omitempty"`
+	RevokedAt  *time.Time `json:"revokedAt,omitempty" bson:"revoked_at,omitempty"`
+	// Bootstrap marks the key provisioned from the configuration on startup
+	Bootstrap bool `json:"bootstrap,omitempty" bson:"bootstrap,omitempty"`
+}
+
+// MintedAPIKey is the response to a key creation, the only time Key is visible.
+type MintedAPIKey struct {
+	Key string `json:"key"`
+	APIKey
+}
+
+// Principal is the authenticated caller of a request.
+type Principal struct {
+	Subject string
+	Tenant  string
+	Scopes  []Scope
+}
+
+// HashAPIKey returns the stored form of an API key secret, the secrets are
+// random enough for a plain SHA-256 to be safe.
+func HashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+func (k *APIKey) IsActive(now time.Time) bool {
+	if k.RevokedAt != nil {
+		return false
+	}
+
+	return k.ExpiresAt == nil || k.ExpiresAt.After(now)
+}
+
+func (p *Principal) HasScope(scope Scope) bool {
+	for _, s := range p.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+
+	return false
+}
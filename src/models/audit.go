@@ -0,0 +1,75 @@
+package models
+
+import "time"
+
+type AuditAction string
+
+const (
+	AuditUploadLinkCreate       AuditAction = "upload_link.create"
+	AuditImagesUpload           AuditAction = "images.upload"
+	AuditImageDelete            AuditAction = "image.delete"
+	AuditUploadLinkImagesDelete AuditAction = "upload_link.images.delete"
+	AuditAPIKeyCreate           AuditAction = "api_key.create"
+	AuditAPIKeyRevoke           AuditAction = "api_key.revoke"
+	AuditGarbageCollection      AuditAction = "janitor.garbage_collection"
+)
+
+type AuditOutcome string
+
+const (
+	AuditSuccess AuditOutcome = "success"
+	// AuditDenied is an action the actor wasn't authorized to perform
+	AuditDenied  AuditOutcome = "denied"
+	AuditFailure AuditOutcome = "failure"
+)
+
+// AnonymousActor performs the actions of public routes.
+const AnonymousActor = "anonymous"
+
+// AuditEvent records who performed an action on which records, events are
+// never updated once stored.
+type AuditEvent struct {
+	ID         string       `json:"id" bson:"-"`
+	Tenant     string       `json:"tenant" bson:"tenant"`
+	Actor      string       `json:"actor" bson:"actor"`
+	Action     AuditAction  `json:"action" bson:"action"`
+	TargetIDs  []string     `json:"targetIDs" bson:"target_ids"`
+	ClientIP   string       `json:"clientIP" bson:"client_ip"`
+	UserAgent  string       `json:"userAgent" bson:"user_agent"`
+	Outcome    AuditOutcome `json:"outcome" bson:"outcome"`
+	StatusCode int          `json:"statusCode" bson:"status_code"`
+	Time       time.Time    `json:"time" bson:"time"`
+}
+
+// AuditFilter narrows an audit events listing, zero values are ignored but
+// Tenant which is required.
+type AuditFilter struct {
+	Tenant   string
+	Actor    string
+	Action   AuditAction
+	TargetID string
+	Outcome  AuditOutcome
+	From     time.Time
+	To       time.Time
+	Cursor   string
+	Limit    int
+}
+
+// AuditPage is a page of audit events, newest first. NextCursor is empty on
+// the last page.
+type AuditPage struct {
+	Events     []AuditEvent `json:"events"`
+	NextCursor string       `json:"nextCursor,omitempty"`
+}
+
+// NewAuditOutcome classifies the response status of an audited request.
+func NewAuditOutcome(statusCode int) AuditOutcome {
+	switch {
+	case statusCode == 401 || statusCode == 403:
+		return AuditDenied
+	case statusCode >= 400:
+		return AuditFailure
+	default:
+		return AuditSuccess
+	}
+}
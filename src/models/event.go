@@ -0,0 +1,28 @@
+package models
+
+const (
+	// ImageUploadedEventVersion is the latest version of the image_uploaded payload
+	ImageUploadedEventVersion = 2
+	// ImageDeletedEventVersion is the latest version of the image_deleted
+	// payload, the JSON array of the image ids
+	ImageDeletedEventVersion = 1
+)
+
+// ImageUploadedEvent is the version 2 payload of image_uploaded, the images
+// carry their id so the consumers don't read them back from the database.
+// The version 1 payload is the JSON array of the image ids.
+type ImageUploadedEvent struct {
+	UploadLinkID string  `json:"uploadLinkID"`
+	Tenant       string  `json:"tenant"`
+	Images       []Image `json:"images"`
+}
+
+// ImageIDs returns the ids of the images of the event.
+func (e ImageUploadedEvent) ImageIDs() []string {
+	ids := make([]string, len(e.Images))
+	for i, image := range e.Images {
+		ids[i] = image.ID
+	}
+
+	return ids
+}
@@ -0,0 +1,123 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// eventPayloads are the payload types of the versions of the events, every
+// version is registered in testdata/schemas once published.
+var eventPayloads = map[string]interface{}{
+	"image_uploaded.v1": []string{},
+	fmt.Sprintf("image_uploaded.v%d", ImageUploadedEventVersion): ImageUploadedEvent{},
+	fmt.Sprintf("image_deleted.v%d", ImageDeletedEventVersion):   []string{},
+}
+
+// TestEventSchemasCompatibility checks the payloads against the registered
+// schemas like a schema registry would: the fields of a registered version
+// can't be removed or change type, so the consumers built against it keep
+// reading the events, new fields are fine as the consumers ignore them.
+func TestEventSchemasCompatibility(t *testing.T) {
+	registered, err := filepath.Glob("testdata/schemas/*.json")
+	assert.NoError(t, err)
+	assert.Len(t, registered, len(eventPayloads), "every version of the events is registered")
+
+	for _, path := range registered {
+		name := strings.TrimSuffix(filepath.Base(path), ".json")
+		t.Run(name, func(t *testing.T) {
+			payload, ok := eventPayloads[name]
+			if !assert.True(t, ok, "registered versions are never removed") {
+				return
+			}
+
+			content, err := os.ReadFile(path)
+			assert.NoError(t, err)
+
+			var registeredSchema map[string]interface{}
+			assert.NoError(t, json.Unmarshal(content, &registeredSchema))
+
+			var currentSchema map[string]interface{}
+			currentContent, _ := json.Marshal(schemaOf(reflect.TypeOf(payload)))
+			json.Unmarshal(currentContent, &currentSchema)
+
+			for _, incompatibility := range incompatibilities(name, registeredSchema, currentSchema) {
+				t.Error(incompatibility)
+			}
+		})
+	}
+}
+
+// incompatibilities lists the fields of the registered schema missing or
+// of another type in the current one.
+func incompatibilities(path string, registered map[string]interface{}, current map[string]interface{}) []string {
+	if registered["type"] != current["type"] {
+		return []string{fmt.Sprintf("%s is %v, it was %v", path, current["type"], registered["type"])}
+	}
+
+	var errs []string
+	if items, ok := registered["items"].(map[string]interface{}); ok {
+		errs = append(errs, incompatibilities(path+"[]", items, current["items"].(map[string]interface{}))...)
+	}
+
+	registeredProperties, _ := registered["properties"].(map[string]interface{})
+	currentProperties, _ := current["properties"].(map[string]interface{})
+	for name, property := range registeredProperties {
+		currentProperty, ok := currentProperties[name].(map[string]interface{})
+		if !ok {
+			errs = append(errs, fmt.Sprintf("%s.%s was removed", path, name))
+			continue
+		}
+
+		errs = append(errs, incompatibilities(path+"."+name, property.(map[string]interface{}), currentProperty)...)
+	}
+
+	return errs
+}
+
+// schemaOf derives the JSON schema of the encoding of the type, limited to
+// the types and the properties.
+func schemaOf(t reflect.Type) map[string]interface{} {
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	switch {
+	case t == reflect.TypeOf(time.Time{}):
+		return map[string]interface{}{"type": "string", "format": "date-time"}
+	case t.Kind() == reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case t.Kind() == reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case t.Kind() >= reflect.Int && t.Kind() <= reflect.Uint64:
+		return map[string]interface{}{"type": "integer"}
+	case t.Kind() == reflect.Float32 || t.Kind() == reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case t.Kind() == reflect.Slice || t.Kind() == reflect.Array:
+		return map[string]interface{}{"type": "array", "items": schemaOf(t.Elem())}
+	case t.Kind() == reflect.Struct:
+		properties := map[string]interface{}{}
+		for i := 0; i < t.NumField(); i++ {
+			name := strings.Split(t.Field(i).Tag.Get("json"), ",")[0]
+			if name == "-" || !t.Field(i).IsExported() {
+				continue
+			}
+			if name == "" {
+				name = t.Field(i).Name
+			}
+
+			properties[name] = schemaOf(t.Field(i).Type)
+		}
+
+		return map[string]interface{}{"type": "object", "properties": properties}
+	default:
+		return map[string]interface{}{}
+	}
+}
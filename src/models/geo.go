@@ -0,0 +1,142 @@
+package models
+
+import "fmt"
+
+const (
+	GeoJSONPoint             = "Point"
+	GeoJSONPolygon           = "Polygon"
+	GeoJSONFeature           = "Feature"
+	GeoJSONFeatureCollection = "FeatureCollection"
+)
+
+// GeoPoint is a GeoJSON point, coordinates are [longitude, latitude].
+type GeoPoint struct {
+	Type        string    `json:"type" bson:"type"`
+	Coordinates []float64 `json:"coordinates" bson:"coordinates"`
+}
+
+// GeoPolygon is a GeoJSON polygon made of closed linear rings of [longitude, latitude] positions.
+type GeoPolygon struct {
+	Type        string        `json:"type" bson:"type"`
+	Coordinates [][][]float64 `json:"coordinates" bson:"coordinates"`
+}
+
+// BoundingBox is a rectangle of longitudes and latitudes, its edges follow
+// the meridians and parallels. It crosses the antimeridian when
+// MinLongitude is greater than MaxLongitude.
+type BoundingBox struct {
+	MinLongitude float64
+	MinLatitude  float64
+	MaxLongitude float64
+	MaxLatitude  float64
+}
+
+type GeoFeature struct {
+	Type       string                 `json:"type"`
+	ID         string                 `json:"id"`
+	Geometry   GeoPoint               `json:"geometry"`
+	Properties map[string]interface{} `json:"properties"`
+}
+
+type GeoFeatureCollection struct {
+	Type     string       `json:"type"`
+	Features []GeoFeature `json:"features"`
+}
+
+func NewGeoPoint(longitude, latitude float64) *GeoPoint {
+	return &GeoPoint{
+		Type:        GeoJSONPoint,
+		Coordinates: []float64{longitude, latitude},
+	}
+}
+
+// NewBoundingBox returns the box between the south-west and north-east corners.
+func NewBoundingBox(minLongitude, minLatitude, maxLongitude, maxLatitude float64) *BoundingBox {
+	return &BoundingBox{
+		MinLongitude: minLongitude,
+		MinLatitude:  minLatitude,
+		MaxLongitude: maxLongitude,
+		MaxLatitude:  maxLatitude,
+	}
+}
+
+// CrossesAntimeridian tells if the box spans the 180th meridian, from
+// MinLongitude eastward to MaxLongitude.
+func (b *BoundingBox) CrossesAntimeridian() bool {
+	return b.MinLongitude > b.MaxLongitude
+}
+
+func (b *BoundingBox) Validate() error {
+	for _, position := range [][]float64{{b.MinLongitude, b.MinLatitude}, {b.MaxLongitude, b.MaxLatitude}} {
+		if err := validatePosition(position); err != nil {
+			return err
+		}
+	}
+
+	if b.MinLatitude >= b.MaxLatitude {
+		return fmt.Errorf("minLat must be lower than maxLat")
+	}
+
+	if b.MinLongitude == b.MaxLongitude {
+		return fmt.Errorf("minLng and maxLng must differ")
+	}
+
+	return nil
+}
+
+func (p *GeoPoint) Longitude() float64 {
+	return p.Coordinates[0]
+}
+
+func (p *GeoPoint) Latitude() float64 {
+	return p.Coordinates[1]
+}
+
+func (p *GeoPoint) Validate() error {
+	if p.Type != GeoJSONPoint || len(p.Coordinates) != 2 {
+		return fmt.Errorf("point must have type %s and [longitude, latitude] coordinates", GeoJSONPoint)
+	}
+
+	return validatePosition(p.Coordinates)
+}
+
+func (p *GeoPolygon) Validate() error {
+	if p.Type != GeoJSONPolygon || len(p.Coordinates) == 0 {
+		return fmt.Errorf("polygon must have type %s and at least one ring", GeoJSONPolygon)
+	}
+
+	for _, ring := range p.Coordinates {
+		if len(ring) < 4 {
+			return fmt.Errorf("polygon ring must have at least 4 positions")
+		}
+
+		for _, position := range ring {
+			if len(position) != 2 {
+				return fmt.Errorf("polygon position must be [longitude, latitude]")
+			}
+
+			if err := validatePosition(position); err != nil {
+				return err
+			}
+		}
+
+		first, last := ring[0], ring[len(ring)-1]
+		if first[0] != last[0] || first[1] != last[1] {
+			return fmt.Errorf("polygon ring must be closed")
+		}
+	}
+
+	return nil
+}
+
+func validatePosition(position []float64) error {
+	if position[0] < -180 || position[0] > 180 {
+		return fmt.Errorf("longitude must be between -180 and 180")
+	}
+
+	if position[1] < -90 || position[1] > 90 {
+		return fmt.Errorf("latitude must be between -90 and 90")
+	}
+
+	return nil
+}
@@ -0,0 +1,34 @@
+package models
+
+// HealthStatus is whether the service or one of its dependencies is usable.
+type HealthStatus string
+
+const (
+	HealthUp   HealthStatus = "up"
+	HealthDown HealthStatus = "down"
+)
+
+// DependencyCheck is the outcome of checking a dependency.
+type DependencyCheck struct {
+	Name       string       `json:"name"`
+	Status     HealthStatus `json:"status"`
+	Error      string       `json:"error,omitempty"`
+	DurationMs int64        `json:"durationMs"`
+}
+
+// ReadinessReport is up when every dependency check is.
+type ReadinessReport struct {
+	Status HealthStatus      `json:"status"`
+	Checks []DependencyCheck `json:"checks"`
+}
+
+// StatusReport describes the running instance for troubleshooting.
+type StatusReport struct {
+	// Versions of the service, its runtime and dependencies, by name
+	Versions map[string]string `json:"versions"`
+	// ConfigHash fingerprints the configuration without its secrets
+	ConfigHash string `json:"configHash"`
+	// ConsumerLag is how many messages of the assigned partitions weren't consumed yet
+	ConsumerLag int64            `json:"consumerLag"`
+	Readiness   *ReadinessReport `json:"readiness"`
+}
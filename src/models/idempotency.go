@@ -0,0 +1,17 @@
+package models
+
+import "time"
+
+// IdempotencyRecord reserves an Idempotency-Key for a route and, once the
+// request completed, keeps its response to replay it to retries.
+type IdempotencyRecord struct {
+	Key         string    `bson:"key"`
+	Scope       string    `bson:"scope"`
+	RequestHash string    `bson:"request_hash"`
+	Completed   bool      `bson:"completed"`
+	StatusCode  int       `bson:"status_code,omitempty"`
+	ContentType string    `bson:"content_type,omitempty"`
+	Body        []byte    `bson:"body,omitempty"`
+	CreatedAt   time.Time `bson:"created_at"`
+	ExpiresAt   time.Time `bson:"expires_at"`
+}
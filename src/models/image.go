@@ -3,15 +3,107 @@ package models
 import "time"
 
 type Image struct {
-	ID           string    `json:"id" bson:"-"`
-	Name         string    `json:"name" bson:"name"`
-	UploadLinkID string    `json:"uploadLinkID" bson:"upload_link_id"`
-	CameraModel  string    `json:"cameraModel" bson:"camera_model"`
-	ImageFormat  string    `json:"imageFormat" bson:"image_format"`
-	ImageWidth   int       `json:"imageWidth" bson:"image_width"`
-	ImageHeight  int       `json:"imageHeight" bson:"image_height"`
-	Path         string    `json:"path" bson:"path"`
-	Latitude     float64   `json:"latitude" bson:"latitude"`
-	Longitude    float64   `json:"longitude" bson:"longitude"`
-	UploadedAt   time.Time `json:"uploadTime" bson:"upload_time"`
+	ID           string         `json:"id" bson:"-"`
+	Name         string         `json:"name" bson:"name"`
+	UploadLinkID string         `json:"uploadLinkID" bson:"upload_link_id"`
+	Tenant       string         `json:"tenant" bson:"tenant"`
+	CameraModel  string         `json:"cameraModel" bson:"camera_model"`
+	ImageFormat  string         `json:"imageFormat" bson:"image_format"`
+	ImageWidth   int            `json:"imageWidth" bson:"image_width"`
+	ImageHeight  int            `json:"imageHeight" bson:"image_height"`
+	Path         string         `json:"path" bson:"path"`
+	Latitude     float64        `json:"latitude" bson:"latitude"`
+	Longitude    float64        `json:"longitude" bson:"longitude"`
+	Location     *GeoPoint      `json:"location,omitempty" bson:"location,omitempty"`
+	Metadata     *ImageMetadata `json:"metadata,omitempty" bson:"metadata,omitempty"`
+	UploadedAt   time.Time      `json:"uploadTime" bson:"upload_time"`
+	DeletedAt    *time.Time     `json:"deletedAt,omitempty" bson:"deleted_at,omitempty"`
+	// StatisticsCounted tells if the image is counted in the statistics, so
+	// the events handled again don't count it twice
+	StatisticsCounted bool `json:"-" bson:"statistics_counted"`
+}
+
+type ImageUploadStatus string
+
+const (
+	ImageUploadStored    ImageUploadStatus = "stored"
+	ImageUploadDuplicate ImageUploadStatus = "duplicate"
+	ImageUploadRejected  ImageUploadStatus = "rejected"
+)
+
+// ImageUploadOutcome reports what happened to one file of a partial upload batch.
+type ImageUploadOutcome struct {
+	Name   string            `json:"name"`
+	Status ImageUploadStatus `json:"status"`
+	ID     string            `json:"id,omitempty"`
+	Reason string            `json:"reason,omitempty"`
+}
+
+// ImageMetadata holds the EXIF, IPTC and XMP fields extracted on upload.
+type ImageMetadata struct {
+	CapturedAt    *time.Time `json:"capturedAt,omitempty" bson:"captured_at,omitempty"`
+	CaptureOffset string     `json:"captureOffset,omitempty" bson:"capture_offset,omitempty"`
+	Make          string     `json:"make,omitempty" bson:"make,omitempty"`
+	LensModel     string     `json:"lensModel,omitempty" bson:"lens_model,omitempty"`
+	FocalLength   float64    `json:"focalLength,omitempty" bson:"focal_length,omitempty"`
+	Aperture      float64    `json:"aperture,omitempty" bson:"aperture,omitempty"`
+	ExposureTime  float64    `json:"exposureTime,omitempty" bson:"exposure_time,omitempty"`
+	ISO           int        `json:"iso,omitempty" bson:"iso,omitempty"`
+	Flash         string     `json:"flash,omitempty" bson:"flash,omitempty"`
+	FlashFired    bool       `json:"flashFired" bson:"flash_fired"`
+	Orientation   int        `json:"orientation,omitempty" bson:"orientation,omitempty"`
+	Software      string     `json:"software,omitempty" bson:"software,omitempty"`
+	Artist        string     `json:"artist,omitempty" bson:"artist,omitempty"`
+	Copyright     string     `json:"copyright,omitempty" bson:"copyright,omitempty"`
+	Title         string     `json:"title,omitempty" bson:"title,omitempty"`
+	Keywords      []string   `json:"keywords,omitempty" bson:"keywords,omitempty"`
+	Description   string     `json:"description,omitempty" bson:"description,omitempty"`
+}
+
+// ImageFilter narrows an images listing, zero values are ignored but Tenant
+// which is required.
+type ImageFilter struct {
+	Tenant       string
+	UploadLinkID string
+	CameraModel  string
+	ImageFormat  string
+	UploadedFrom time.Time
+	UploadedTo   time.Time
+	MinWidth     int
+	MaxWidth     int
+	MinHeight    int
+	MaxHeight    int
+	HasGPS       *bool
+	Make         string
+	LensModel    string
+	Keyword      string
+	CapturedFrom time.Time
+	CapturedTo   time.Time
+	MinISO       int
+	MaxISO       int
+	FlashFired   *bool
+	Sort         string
+	Cursor       string
+	Limit        int
+}
+
+// ImagePage is a page of an images listing, NextCursor is empty on the last page.
+type ImagePage struct {
+	Images     []Image `json:"images"`
+	NextCursor string  `json:"nextCursor,omitempty"`
+}
+
+func (i *Image) GeoFeature() GeoFeature {
+	return GeoFeature{
+		Type:     GeoJSONFeature,
+		ID:       i.ID,
+		Geometry: *i.Location,
+		Properties: map[string]interface{}{
+			"name":         i.Name,
+			"uploadLinkID": i.UploadLinkID,
+			"cameraModel":  i.CameraModel,
+			"imageFormat":  i.ImageFormat,
+			"uploadTime":   i.UploadedAt,
+		},
+	}
 }
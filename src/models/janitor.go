@@ -0,0 +1,16 @@
+package models
+
+import "time"
+
+// GarbageCollectionReport describes what a garbage collection run deleted, or
+// would have deleted on a dry run.
+type GarbageCollectionReport struct {
+	DryRun             bool      `json:"dryRun"`
+	StartedAt          time.Time `json:"startedAt"`
+	FinishedAt         time.Time `json:"finishedAt"`
+	ExpiredUploadLinks []string  `json:"expiredUploadLinks"`
+	DeletedImages      int       `json:"deletedImages"`
+	OrphanedFiles      []string  `json:"orphanedFiles"`
+	ReclaimedBytes     int64     `json:"reclaimedBytes"`
+	Errors             []string  `json:"errors,omitempty"`
+}
@@ -0,0 +1,35 @@
+package models
+
+// PrivacyMode controls which metadata is kept in stored files and in the images collection.
+type PrivacyMode string
+
+const (
+	PrivacyKeep     PrivacyMode = "keep"
+	PrivacyStripGPS PrivacyMode = "strip_gps"
+	PrivacyStripAll PrivacyMode = "strip_all"
+)
+
+func (m PrivacyMode) IsValid() bool {
+	switch m {
+	case PrivacyKeep, PrivacyStripGPS, PrivacyStripAll:
+		return true
+	}
+
+	return false
+}
+
+// ApplyPrivacyMode drops the extracted metadata the mode doesn't allow to keep.
+func (i *Image) ApplyPrivacyMode(mode PrivacyMode) {
+	switch mode {
+	case PrivacyStripGPS:
+		i.Latitude = 0
+		i.Longitude = 0
+		i.Location = nil
+	case PrivacyStripAll:
+		i.Latitude = 0
+		i.Longitude = 0
+		i.Location = nil
+		i.CameraModel = ""
+		i.Metadata = nil
+	}
+}
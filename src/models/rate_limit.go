@@ -0,0 +1,75 @@
+package models
+
+import (
+	"math"
+	"time"
+)
+
+// RateLimitKey is what the requests of a rate limited route group are counted by.
+type RateLimitKey string
+
+const (
+	RateLimitByAPIKey     RateLimitKey = "apiKey"
+	RateLimitByClientIP   RateLimitKey = "clientIP"
+	RateLimitByUploadLink RateLimitKey = "uploadLink"
+)
+
+func (k RateLimitKey) IsValid() bool {
+	switch k {
+	case RateLimitByAPIKey, RateLimitByClientIP, RateLimitByUploadLink:
+		return true
+	default:
+		return false
+	}
+}
+
+// RateLimit is a token bucket holding up to Burst tokens, refilled at
+// RefillRate tokens per second. Each request takes a token.
+type RateLimit struct {
+	Burst      int
+	RefillRate float64
+}
+
+// RateLimitResult is the state of a bucket once a request took its token.
+type RateLimitResult struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+	// Reset is how long until the bucket is full again
+	Reset time.Duration
+	// RetryAfter is how long until a denied request can take a token
+	RetryAfter time.Duration
+}
+
+// Refill returns the tokens of a bucket elapsed after it held tokens.
+func (l RateLimit) Refill(tokens float64, elapsed time.Duration) float64 {
+	if elapsed < 0 {
+		elapsed = 0
+	}
+
+	return math.Min(float64(l.Burst), tokens+elapsed.Seconds()*l.RefillRate)
+}
+
+// Result describes a bucket left with tokens after a request was allowed or not.
+func (l RateLimit) Result(tokens float64, allowed bool) *RateLimitResult {
+	result := &RateLimitResult{
+		Allowed:   allowed,
+		Limit:     l.Burst,
+		Remaining: int(math.Floor(tokens)),
+		Reset:     l.duration(float64(l.Burst) - tokens),
+	}
+
+	if !allowed {
+		result.RetryAfter = l.duration(1 - tokens)
+	}
+
+	return result
+}
+
+func (l RateLimit) duration(tokens float64) time.Duration {
+	if tokens <= 0 || l.RefillRate <= 0 {
+		return 0
+	}
+
+	return time.Duration(tokens / l.RefillRate * float64(time.Second))
+}
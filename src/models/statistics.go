@@ -1,5 +1,7 @@
 package models
 
+import "time"
+
 type StatisticsType string
 
 const (
@@ -9,8 +11,27 @@ const (
 )
 
 type Statistics struct {
-	ID    string         `json:"-" bson:"-"`
-	Type  StatisticsType `json:"-" bson:"type"`
-	Name  string         `json:"name" bson:"name"`
-	Count int            `json:"count" bson:"count"`
+	ID     string         `json:"-" bson:"-"`
+	Tenant string         `json:"-" bson:"tenant"`
+	Type   StatisticsType `json:"-" bson:"type"`
+	Name   string         `json:"name" bson:"name"`
+	Count  int            `json:"count" bson:"count"`
+}
+
+// StatisticsDelta describes a single counter change applied by a handler,
+// Count is the counter value after the change.
+type StatisticsDelta struct {
+	Type  StatisticsType `json:"type"`
+	Name  string         `json:"name"`
+	Delta int            `json:"delta"`
+	Count int            `json:"count"`
+}
+
+// StatisticsEvent groups the deltas applied to the statistics of a tenant
+// while handling one message.
+type StatisticsEvent struct {
+	ID     uint64            `json:"id"`
+	Tenant string            `json:"-"`
+	Deltas []StatisticsDelta `json:"deltas"`
+	Time   time.Time         `json:"time"`
 }
@@ -0,0 +1,14 @@
+package models
+
+import "regexp"
+
+// DefaultTenant owns the records created before tenants were introduced and
+// the principals that don't carry a tenant.
+const DefaultTenant = "default"
+
+// tenants name storage directories, so they are restricted to safe path segments
+var tenantPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,62}$`)
+
+func IsValidTenant(tenant string) bool {
+	return tenantPattern.MatchString(tenant)
+}
@@ -3,6 +3,8 @@ package models
 import "time"
 
 type UploadLink struct {
-	ID             string    `json:"id" bson:"-"`
-	ExpirationTime time.Time `json:"expirationTime" bson:"expiration_time"`
+	ID             string      `json:"id" bson:"-"`
+	Tenant         string      `json:"tenant" bson:"tenant"`
+	ExpirationTime time.Time   `json:"expirationTime" bson:"expiration_time"`
+	PrivacyMode    PrivacyMode `json:"privacyMode,omitempty" bson:"privacy_mode,omitempty"`
 }
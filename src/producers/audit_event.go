@@ -0,0 +1,40 @@
+package producers
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/tam-code/image-upload/src/buses"
+	"github.com/tam-code/image-upload/src/models"
+)
+
+type (
+	AuditEventProducer interface {
+		Publish(ctx context.Context, event models.AuditEvent) error
+	}
+
+	auditEventProducer struct {
+		producer buses.Producer
+	}
+)
+
+func NewAuditEventProducer(p buses.Producer) AuditEventProducer {
+	return &auditEventProducer{p}
+}
+
+// Publish exports the event to the audit topic, events of a tenant share a
+// partition so they keep their order.
+func (p *auditEventProducer) Publish(ctx context.Context, event models.AuditEvent) error {
+	jsonEvent, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	message := buses.Message{
+		Key:     []byte(event.Tenant),
+		Value:   jsonEvent,
+		Headers: []buses.Header{{Key: buses.EventTypeHeader, Value: []byte(buses.AuditEvent)}},
+	}
+
+	return writeMessage(ctx, p.producer, buses.AuditEvent, message)
+}
@@ -0,0 +1,53 @@
+package producers
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/tam-code/image-upload/src/buses"
+	"github.com/tam-code/image-upload/src/models"
+)
+
+type (
+	ImageDeletedProducer interface {
+		Publish(ctx context.Context, tenant string, images []string) error
+	}
+
+	imageDeletedProducer struct {
+		producer buses.Producer
+		version  int
+	}
+)
+
+// NewImageDeletedProducer wraps the payload in a CloudEvents envelope from
+// the version 2 of the events on.
+func NewImageDeletedProducer(p buses.Producer, version int) ImageDeletedProducer {
+	return &imageDeletedProducer{producer: p, version: version}
+}
+
+// Publish publishes the ids of the deleted images of the tenant, the tenant
+// is carried by a header since the payload is the bare ids.
+func (p *imageDeletedProducer) Publish(ctx context.Context, tenant string, images []string) error {
+	var message buses.Message
+	if p.version == buses.LegacyEventVersion {
+		jsonImage, err := json.Marshal(images)
+		if err != nil {
+			return err
+		}
+
+		message = buses.Message{
+			Value:   jsonImage,
+			Headers: []buses.Header{{Key: buses.EventTypeHeader, Value: []byte(buses.ImageDeletedEvent)}},
+		}
+	} else {
+		var err error
+		message, err = buses.NewEventMessage(buses.ImageDeletedEvent, models.ImageDeletedEventVersion, tenant, images)
+		if err != nil {
+			return err
+		}
+	}
+
+	message.Headers = append(message.Headers, buses.Header{Key: buses.TenantHeader, Value: []byte(tenant)})
+
+	return writeMessage(ctx, p.producer, buses.ImageDeletedEvent, message)
+}
@@ -4,32 +4,49 @@ import (
 	"context"
 	"encoding/json"
 
-	"github.com/tam-code/image-upload/src/kafka"
+	"github.com/tam-code/image-upload/src/buses"
+	"github.com/tam-code/image-upload/src/models"
 )
 
 type (
 	ImageUploadedProducer interface {
-		Publish(images []string) error
+		Publish(ctx context.Context, event models.ImageUploadedEvent) error
 	}
 
 	imageUploadedProducer struct {
-		producer kafka.Producer
+		producer buses.Producer
+		version  int
 	}
 )
 
-func NewImageUploadedProducer(p kafka.Producer) ImageUploadedProducer {
-	return &imageUploadedProducer{p}
+// NewImageUploadedProducer publishes the version 1 payload, the image ids
+// without envelope, until the consumers are able to read the version 2 one.
+func NewImageUploadedProducer(p buses.Producer, version int) ImageUploadedProducer {
+	return &imageUploadedProducer{producer: p, version: version}
 }
 
-func (p *imageUploadedProducer) Publish(images []string) error {
-	jsonImage, err := json.Marshal(images)
-	if err != nil {
-		return err
+func (p *imageUploadedProducer) Publish(ctx context.Context, event models.ImageUploadedEvent) error {
+	var message buses.Message
+	if p.version == buses.LegacyEventVersion {
+		jsonImage, err := json.Marshal(event.ImageIDs())
+		if err != nil {
+			return err
+		}
+
+		message = buses.Message{
+			Value:   jsonImage,
+			Headers: []buses.Header{{Key: buses.EventTypeHeader, Value: []byte(buses.ImageUploadedEvent)}},
+		}
+	} else {
+		var err error
+		message, err = buses.NewEventMessage(buses.ImageUploadedEvent, models.ImageUploadedEventVersion, event.Tenant, event)
+		if err != nil {
+			return err
+		}
 	}
 
-	message := kafka.Message{
-		Value: jsonImage,
-	}
+	// the version 1 consumers read the images of this tenant back
+	message.Headers = append(message.Headers, buses.Header{Key: buses.TenantHeader, Value: []byte(event.Tenant)})
 
-	return p.producer.WriteMessages(context.Background(), message)
+	return writeMessage(ctx, p.producer, buses.ImageUploadedEvent, message)
 }
@@ -1,15 +1,66 @@
 package producers
 
-import "github.com/tam-code/image-upload/src/kafka"
+import (
+	"context"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/tam-code/image-upload/config"
+	"github.com/tam-code/image-upload/src/buses"
+	"github.com/tam-code/image-upload/src/metrics"
+)
+
+var tracer = otel.Tracer("github.com/tam-code/image-upload/src/producers")
 
 type (
 	Producers struct {
 		ImageUploaded ImageUploadedProducer
+		ImageDeleted  ImageDeletedProducer
+		// AuditEvent is nil when audit events aren't exported
+		AuditEvent AuditEventProducer
 	}
 )
 
-func NewProducers(writer kafka.Producer) *Producers {
-	return &Producers{
-		ImageUploaded: NewImageUploadedProducer(writer),
+// NewProducers creates the producers of the events topic, auditWriter writes
+// to the audit topic and is nil when the export is disabled.
+func NewProducers(cfg config.EventsConfig, writer buses.Producer, auditWriter buses.Producer) *Producers {
+	producers := &Producers{
+		ImageUploaded: NewImageUploadedProducer(writer, cfg.Version),
+		ImageDeleted:  NewImageDeletedProducer(writer, cfg.Version),
 	}
+
+	if auditWriter != nil {
+		producers.AuditEvent = NewAuditEventProducer(auditWriter)
+	}
+
+	return producers
+}
+
+// writeMessage writes the message of an event in a producer span whose trace
+// context is carried by the message headers, counting the failures. The
+// messages published by a replay are marked with its id.
+func writeMessage(ctx context.Context, producer buses.Producer, eventType string, message buses.Message) error {
+	ctx, span := tracer.Start(ctx, "publish "+eventType, trace.WithSpanKind(trace.SpanKindProducer),
+		trace.WithAttributes(attribute.String("messaging.system", "kafka"), attribute.String("event_type", eventType)),
+	)
+	defer span.End()
+
+	if replayID := buses.ReplayFromContext(ctx); replayID != "" {
+		message.Headers = append(message.Headers, buses.Header{Key: buses.ReplayHeader, Value: []byte(replayID)})
+		span.SetAttributes(attribute.String("replay", replayID))
+	}
+
+	buses.InjectTraceContext(ctx, &message)
+
+	if err := producer.WriteMessages(ctx, message); err != nil {
+		metrics.KafkaPublishErrors.WithLabelValues(eventType).Inc()
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "error publishing event")
+		return err
+	}
+
+	return nil
 }
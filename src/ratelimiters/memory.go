@@ -0,0 +1,74 @@
+package ratelimiters
+
+import (
+	"sync"
+	"time"
+
+	"github.com/tam-code/image-upload/src/models"
+)
+
+// memorySweepInterval is how often the buckets refilled since their last use
+// are dropped, a missing bucket is full.
+const memorySweepInterval = time.Minute
+
+type (
+	memoryStore struct {
+		mu        sync.Mutex
+		buckets   map[string]*memoryBucket
+		lastSweep time.Time
+		now       func() time.Time
+	}
+
+	memoryBucket struct {
+		tokens    float64
+		updatedAt time.Time
+		fullAt    time.Time
+	}
+)
+
+func NewMemoryStore() Store {
+	return &memoryStore{
+		buckets: map[string]*memoryBucket{},
+		now:     time.Now,
+	}
+}
+
+func (s *memoryStore) Take(key string, limit models.RateLimit) (*models.RateLimitResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	s.sweep(now)
+
+	bucket, found := s.buckets[key]
+	if !found {
+		bucket = &memoryBucket{tokens: float64(limit.Burst), updatedAt: now}
+		s.buckets[key] = bucket
+	}
+
+	bucket.tokens = limit.Refill(bucket.tokens, now.Sub(bucket.updatedAt))
+	bucket.updatedAt = now
+
+	allowed := bucket.tokens >= 1
+	if allowed {
+		bucket.tokens--
+	}
+
+	result := limit.Result(bucket.tokens, allowed)
+	bucket.fullAt = now.Add(result.Reset)
+
+	return result, nil
+}
+
+func (s *memoryStore) sweep(now time.Time) {
+	if now.Sub(s.lastSweep) < memorySweepInterval {
+		return
+	}
+
+	for key, bucket := range s.buckets {
+		if !bucket.fullAt.After(now) {
+			delete(s.buckets, key)
+		}
+	}
+	s.lastSweep = now
+}
@@ -0,0 +1,44 @@
+package ratelimiters
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tam-code/image-upload/src/models"
+)
+
+func TestMemoryStoreTake(t *testing.T) {
+	now := time.Now()
+	store := NewMemoryStore().(*memoryStore)
+	store.now = func() time.Time { return now }
+
+	// 2 requests, refilled at a request per second
+	limit := models.RateLimit{Burst: 2, RefillRate: 1}
+
+	result, _ := store.Take("clientIP:192.0.2.1", limit)
+	assert.Equal(t, &models.RateLimitResult{Allowed: true, Limit: 2, Remaining: 1, Reset: time.Second}, result)
+
+	result, _ = store.Take("clientIP:192.0.2.1", limit)
+	assert.Equal(t, &models.RateLimitResult{Allowed: true, Limit: 2, Remaining: 0, Reset: 2 * time.Second}, result)
+
+	result, _ = store.Take("clientIP:192.0.2.1", limit)
+	assert.Equal(t, &models.RateLimitResult{Allowed: false, Limit: 2, Remaining: 0, Reset: 2 * time.Second, RetryAfter: time.Second}, result)
+
+	result, _ = store.Take("clientIP:192.0.2.2", limit)
+	assert.True(t, result.Allowed, "keys have their own bucket")
+
+	now = now.Add(500 * time.Millisecond)
+	result, _ = store.Take("clientIP:192.0.2.1", limit)
+	assert.False(t, result.Allowed)
+	assert.Equal(t, 500*time.Millisecond, result.RetryAfter, "the bucket refills while denied")
+
+	now = now.Add(500 * time.Millisecond)
+	result, _ = store.Take("clientIP:192.0.2.1", limit)
+	assert.True(t, result.Allowed)
+
+	now = now.Add(time.Hour)
+	result, _ = store.Take("clientIP:192.0.2.1", limit)
+	assert.Equal(t, 1, result.Remaining, "the bucket never holds more than its burst")
+	assert.Len(t, store.buckets, 1, "full buckets are swept")
+}
@@ -0,0 +1,24 @@
+package ratelimiters
+
+import (
+	"time"
+
+	"github.com/tam-code/image-upload/src/models"
+	"github.com/tam-code/image-upload/src/repositories"
+)
+
+type mongoStore struct {
+	repository repositories.RateLimitRepository
+	now        func() time.Time
+}
+
+func NewMongoStore(repository repositories.RateLimitRepository) Store {
+	return &mongoStore{
+		repository: repository,
+		now:        time.Now,
+	}
+}
+
+func (s *mongoStore) Take(key string, limit models.RateLimit) (*models.RateLimitResult, error) {
+	return s.repository.TakeRateLimitToken(key, limit, s.now())
+}
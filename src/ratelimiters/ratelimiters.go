@@ -0,0 +1,31 @@
+package ratelimiters
+
+import (
+	"fmt"
+
+	"github.com/tam-code/image-upload/src/models"
+	"github.com/tam-code/image-upload/src/repositories"
+)
+
+const (
+	// MemoryStore keeps the buckets in the instance, for a single instance deployment
+	MemoryStore = "memory"
+	// MongoStore shares the buckets between the instances
+	MongoStore = "mongo"
+)
+
+type Store interface {
+	// Take takes a token from the bucket of the key.
+	Take(key string, limit models.RateLimit) (*models.RateLimitResult, error)
+}
+
+func NewStore(store string, repositories *repositories.Repositories) (Store, error) {
+	switch store {
+	case MemoryStore:
+		return NewMemoryStore(), nil
+	case MongoStore:
+		return NewMongoStore(repositories.RateLimit), nil
+	default:
+		return nil, fmt.Errorf("invalid rate limit store %q, it must be memory or mongo", store)
+	}
+}
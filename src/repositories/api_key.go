@@ -0,0 +1,179 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/tam-code/image-upload/src/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type (
+	APIKeyRepository interface {
+		CreateAPIKey(models.APIKey) (*models.APIKey, error)
+		EnsureAPIKey(models.APIKey) error
+		GetAPIKeyByHash(string) (*models.APIKey, error)
+		ListAPIKeys(string) ([]models.APIKey, error)
+		RevokeAPIKey(string, string, time.Time) (bool, error)
+		RevokeBootstrapAPIKeys(string, time.Time) (int, error)
+		TouchAPIKey(string, time.Time) error
+		EnsureIndexes() error
+	}
+
+	apiKeyRepository struct {
+		mongoCollection *mongo.Collection
+	}
+
+	// apiKeyDocument decodes the object id that models.APIKey doesn't map
+	apiKeyDocument struct {
+		ObjectID      primitive.ObjectID `bson:"_id"`
+		models.APIKey `bson:",inline"`
+	}
+)
+
+func newAPIKeyRepository(mongoDB mongo.Database) APIKeyRepository {
+	return &apiKeyRepository{
+		mongoCollection: mongoDB.Collection("api_keys"),
+	}
+}
+
+func (r *apiKeyRepository) CreateAPIKey(apiKey models.APIKey) (*models.APIKey, error) {
+	insertedData, err := r.mongoCollection.InsertOne(context.Background(), apiKey)
+	if err != nil {
+		return nil, fmt.Errorf("error inserting api key: %w", err)
+	}
+
+	apiKey.ID = insertedData.InsertedID.(primitive.ObjectID).Hex()
+
+	return &apiKey, nil
+}
+
+// EnsureAPIKey creates the key, or restores its scopes and unrevokes it when
+// a key with the same hash exists.
+func (r *apiKeyRepository) EnsureAPIKey(apiKey models.APIKey) error {
+	_, err := r.mongoCollection.UpdateOne(context.Background(),
+		bson.M{"hash": apiKey.Hash},
+		bson.M{
+			"$set":         bson.M{"name": apiKey.Name, "tenant": apiKey.Tenant, "prefix": apiKey.Prefix, "scopes": apiKey.Scopes, "bootstrap": apiKey.Bootstrap},
+			"$unset":       bson.M{"revoked_at": "", "expires_at": ""},
+			"$setOnInsert": bson.M{"created_at": apiKey.CreatedAt},
+		},
+		options.Update().SetUpsert(true),
+	)
+	if err != nil {
+		return fmt.Errorf("error ensuring api key: %w", err)
+	}
+
+	return nil
+}
+
+func (r *apiKeyRepository) GetAPIKeyByHash(hash string) (*models.APIKey, error) {
+	var document apiKeyDocument
+	err := r.mongoCollection.FindOne(context.Background(), bson.M{"hash": hash}).Decode(&document)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("error getting api key by hash: %w", err)
+	}
+
+	document.APIKey.ID = document.ObjectID.Hex()
+
+	return &document.APIKey, nil
+}
+
+func (r *apiKeyRepository) ListAPIKeys(tenant string) ([]models.APIKey, error) {
+	cursor, err := r.mongoCollection.Find(context.Background(), bson.M{"tenant": tenant}, options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}}))
+	if err != nil {
+		return nil, fmt.Errorf("error listing api keys: %w", err)
+	}
+
+	var documents []apiKeyDocument
+	if err = cursor.All(context.Background(), &documents); err != nil {
+		return nil, fmt.Errorf("error listing api keys: %w", err)
+	}
+
+	apiKeys := []models.APIKey{}
+	for _, document := range documents {
+		document.APIKey.ID = document.ObjectID.Hex()
+		apiKeys = append(apiKeys, document.APIKey)
+	}
+
+	return apiKeys, nil
+}
+
+// RevokeAPIKey reports whether an active key of the tenant with the id was revoked.
+func (r *apiKeyRepository) RevokeAPIKey(tenant, id string, revokedAt time.Time) (bool, error) {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return false, nil
+	}
+
+	result, err := r.mongoCollection.UpdateOne(context.Background(),
+		bson.M{"_id": objectID, "tenant": tenant, "revoked_at": bson.M{"$exists": false}},
+		bson.M{"$set": bson.M{"revoked_at": revokedAt}},
+	)
+	if err != nil {
+		return false, fmt.Errorf("error revoking api key: %w", err)
+	}
+
+	return result.MatchedCount > 0, nil
+}
+
+// RevokeBootstrapAPIKeys revokes the active bootstrap keys but the one with
+// the hash, so a bootstrap key no longer configured stops working. It
+// returns how many keys were revoked.
+func (r *apiKeyRepository) RevokeBootstrapAPIKeys(keepHash string, revokedAt time.Time) (int, error) {
+	result, err := r.mongoCollection.UpdateMany(context.Background(),
+		bson.M{"bootstrap": true, "hash": bson.M{"$ne": keepHash}, "revoked_at": bson.M{"$exists": false}},
+		bson.M{"$set": bson.M{"revoked_at": revokedAt}},
+	)
+	if err != nil {
+		return 0, fmt.Errorf("error revoking bootstrap api keys: %w", err)
+	}
+
+	return int(result.ModifiedCount), nil
+}
+
+func (r *apiKeyRepository) TouchAPIKey(id string, usedAt time.Time) error {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return fmt.Errorf("error converting id to object id: %w", err)
+	}
+
+	_, err = r.mongoCollection.UpdateOne(context.Background(), bson.M{"_id": objectID}, bson.M{"$set": bson.M{"last_used_at": usedAt}})
+	if err != nil {
+		return fmt.Errorf("error updating api key last use: %w", err)
+	}
+
+	return nil
+}
+
+func (r *apiKeyRepository) EnsureIndexes() error {
+	if err := backfillTenant(r.mongoCollection); err != nil {
+		return err
+	}
+
+	// the bootstrap keys were only told apart by their name before being marked
+	_, err := r.mongoCollection.UpdateMany(context.Background(),
+		bson.M{"name": models.BootstrapAPIKeyName, "bootstrap": bson.M{"$exists": false}},
+		bson.M{"$set": bson.M{"bootstrap": true}},
+	)
+	if err != nil {
+		return fmt.Errorf("error backfilling bootstrap api keys: %w", err)
+	}
+
+	_, err = r.mongoCollection.Indexes().CreateMany(context.Background(), []mongo.IndexModel{
+		{Keys: bson.D{{Key: "hash", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "tenant", Value: 1}, {Key: "created_at", Value: -1}}},
+	})
+	if err != nil {
+		return fmt.Errorf("error creating api keys indexes: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,146 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/tam-code/image-upload/src/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	defaultAuditEventsLimit = 50
+	maxAuditEventsLimit     = 200
+)
+
+type (
+	// AuditRepository is append only, audit events can't be updated or deleted.
+	AuditRepository interface {
+		InsertAuditEvent(event *models.AuditEvent) error
+		ListAuditEvents(filter models.AuditFilter) (*models.AuditPage, error)
+		EnsureIndexes() error
+	}
+
+	auditRepository struct {
+		mongoCollection *mongo.Collection
+	}
+
+	// auditEventDocument decodes the object id that models.AuditEvent doesn't map
+	auditEventDocument struct {
+		ObjectID          primitive.ObjectID `bson:"_id"`
+		models.AuditEvent `bson:",inline"`
+	}
+)
+
+func newAuditRepository(mongoDB mongo.Database) AuditRepository {
+	return &auditRepository{
+		mongoCollection: mongoDB.Collection("audit_events"),
+	}
+}
+
+func (r *auditRepository) InsertAuditEvent(event *models.AuditEvent) error {
+	insertedData, err := r.mongoCollection.InsertOne(context.Background(), event)
+	if err != nil {
+		return fmt.Errorf("error inserting audit event: %w", err)
+	}
+
+	event.ID = insertedData.InsertedID.(primitive.ObjectID).Hex()
+
+	return nil
+}
+
+// ListAuditEvents returns the events newest first, the cursor is the id of
+// the last event of the previous page.
+func (r *auditRepository) ListAuditEvents(filter models.AuditFilter) (*models.AuditPage, error) {
+	if filter.Tenant == "" {
+		return nil, ErrMissingTenant
+	}
+
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = defaultAuditEventsLimit
+	}
+	if limit > maxAuditEventsLimit {
+		limit = maxAuditEventsLimit
+	}
+
+	query := bson.D{{Key: "tenant", Value: filter.Tenant}}
+	if filter.Actor != "" {
+		query = append(query, bson.E{Key: "actor", Value: filter.Actor})
+	}
+
+	if filter.Action != "" {
+		query = append(query, bson.E{Key: "action", Value: filter.Action})
+	}
+
+	if filter.TargetID != "" {
+		query = append(query, bson.E{Key: "target_ids", Value: filter.TargetID})
+	}
+
+	if filter.Outcome != "" {
+		query = append(query, bson.E{Key: "outcome", Value: filter.Outcome})
+	}
+
+	eventTime := bson.M{}
+	if !filter.From.IsZero() {
+		eventTime["$gte"] = filter.From
+	}
+	if !filter.To.IsZero() {
+		eventTime["$lte"] = filter.To
+	}
+	if len(eventTime) > 0 {
+		query = append(query, bson.E{Key: "time", Value: eventTime})
+	}
+
+	if filter.Cursor != "" {
+		cursorID, err := primitive.ObjectIDFromHex(filter.Cursor)
+		if err != nil {
+			return nil, ErrInvalidCursor
+		}
+		query = append(query, bson.E{Key: "_id", Value: bson.M{"$lt": cursorID}})
+	}
+
+	findOptions := options.Find().
+		SetSort(bson.D{{Key: "_id", Value: -1}}).
+		SetLimit(int64(limit + 1))
+
+	cursor, err := r.mongoCollection.Find(context.Background(), query, findOptions)
+	if err != nil {
+		return nil, fmt.Errorf("error listing audit events: %w", err)
+	}
+
+	var documents []auditEventDocument
+	if err = cursor.All(context.Background(), &documents); err != nil {
+		return nil, fmt.Errorf("error listing audit events: %w", err)
+	}
+
+	page := &models.AuditPage{Events: []models.AuditEvent{}}
+	for _, document := range documents {
+		document.AuditEvent.ID = document.ObjectID.Hex()
+		page.Events = append(page.Events, document.AuditEvent)
+	}
+
+	if len(page.Events) > limit {
+		page.Events = page.Events[:limit]
+		page.NextCursor = page.Events[limit-1].ID
+	}
+
+	return page, nil
+}
+
+func (r *auditRepository) EnsureIndexes() error {
+	_, err := r.mongoCollection.Indexes().CreateMany(context.Background(), []mongo.IndexModel{
+		{Keys: bson.D{{Key: "tenant", Value: 1}, {Key: "_id", Value: -1}}},
+		{Keys: bson.D{{Key: "tenant", Value: 1}, {Key: "actor", Value: 1}, {Key: "_id", Value: -1}}},
+		{Keys: bson.D{{Key: "tenant", Value: 1}, {Key: "action", Value: 1}, {Key: "_id", Value: -1}}},
+		{Keys: bson.D{{Key: "tenant", Value: 1}, {Key: "target_ids", Value: 1}, {Key: "_id", Value: -1}}},
+	})
+	if err != nil {
+		return fmt.Errorf("error creating audit events indexes: %w", err)
+	}
+
+	return nil
+}
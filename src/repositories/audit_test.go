@@ -0,0 +1,124 @@
+package repositories
+
+import (
+	"testing"
+	"time"
+
+	"github.com/tam-code/image-upload/src/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+	"gotest.tools/assert"
+)
+
+func TestInsertAuditEvent(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	defer mt.ClearCollections()
+
+	tests := []struct {
+		name        string
+		prepare     func(mt *mtest.T)
+		expectError bool
+	}{
+		{
+			name: "insert audit event",
+			prepare: func(mt *mtest.T) {
+				mt.AddMockResponses(mtest.CreateSuccessResponse())
+			},
+		},
+		{
+			name: "simple error",
+			prepare: func(mt *mtest.T) {
+				mt.AddMockResponses(bson.D{{Key: "ok", Value: 0}})
+			},
+			expectError: true,
+		},
+	}
+
+	for _, test := range tests {
+		mt.Run(test.name, func(mt *mtest.T) {
+			repo := auditRepository{
+				mongoCollection: mt.Coll,
+			}
+
+			test.prepare(mt)
+
+			event := &models.AuditEvent{Tenant: models.DefaultTenant, Action: models.AuditImageDelete, Time: time.Now()}
+			err := repo.InsertAuditEvent(event)
+			assert.Equal(t, test.expectError, err != nil)
+			assert.Equal(t, !test.expectError, event.ID != "")
+		})
+	}
+}
+
+func TestListAuditEvents(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	defer mt.ClearCollections()
+
+	firstID := primitive.NewObjectID()
+	secondID := primitive.NewObjectID()
+
+	tests := []struct {
+		name             string
+		filter           models.AuditFilter
+		prepare          func(mt *mtest.T)
+		expectError      error
+		expectEvents     int
+		expectNextCursor string
+	}{
+		{
+			name:   "list audit events with next page",
+			filter: models.AuditFilter{Tenant: models.DefaultTenant, Action: models.AuditImageDelete, Limit: 1},
+			prepare: func(mt *mtest.T) {
+				mt.AddMockResponses(mtest.CreateCursorResponse(0, "foo.bar", mtest.FirstBatch,
+					bson.D{{Key: "_id", Value: secondID}, {Key: "tenant", Value: models.DefaultTenant}, {Key: "action", Value: "image.delete"}},
+					bson.D{{Key: "_id", Value: firstID}, {Key: "tenant", Value: models.DefaultTenant}, {Key: "action", Value: "image.delete"}},
+				))
+			},
+			expectEvents:     1,
+			expectNextCursor: secondID.Hex(),
+		},
+		{
+			name:   "list last page",
+			filter: models.AuditFilter{Tenant: models.DefaultTenant, Cursor: secondID.Hex(), From: time.Now().Add(-time.Hour)},
+			prepare: func(mt *mtest.T) {
+				mt.AddMockResponses(mtest.CreateCursorResponse(0, "foo.bar", mtest.FirstBatch,
+					bson.D{{Key: "_id", Value: firstID}, {Key: "tenant", Value: models.DefaultTenant}, {Key: "action", Value: "image.delete"}},
+				))
+			},
+			expectEvents: 1,
+		},
+		{
+			name:        "invalid cursor",
+			filter:      models.AuditFilter{Tenant: models.DefaultTenant, Cursor: "invalid"},
+			prepare:     func(mt *mtest.T) {},
+			expectError: ErrInvalidCursor,
+		},
+		{
+			name:        "missing tenant",
+			filter:      models.AuditFilter{Limit: 1},
+			prepare:     func(mt *mtest.T) {},
+			expectError: ErrMissingTenant,
+		},
+	}
+
+	for _, test := range tests {
+		mt.Run(test.name, func(mt *mtest.T) {
+			repo := auditRepository{
+				mongoCollection: mt.Coll,
+			}
+
+			test.prepare(mt)
+
+			page, err := repo.ListAuditEvents(test.filter)
+			assert.Equal(t, test.expectError, err)
+			if err != nil {
+				return
+			}
+
+			assert.Equal(t, test.expectEvents, len(page.Events))
+			assert.Equal(t, test.expectNextCursor, page.NextCursor)
+			assert.Equal(t, models.AuditImageDelete, page.Events[0].Action)
+		})
+	}
+}
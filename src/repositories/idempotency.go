@@ -0,0 +1,112 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/tam-code/image-upload/src/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type (
+	IdempotencyRepository interface {
+		ReserveIdempotencyKey(record *models.IdempotencyRecord, lockedBefore time.Time) (*models.IdempotencyRecord, error)
+		CompleteIdempotencyKey(record *models.IdempotencyRecord) error
+		ReleaseIdempotencyKey(key, scope string) error
+		EnsureIndexes() error
+	}
+
+	idempotencyRepository struct {
+		mongoCollection *mongo.Collection
+	}
+)
+
+func newIdempotencyRepository(mongoDB mongo.Database) IdempotencyRepository {
+	return &idempotencyRepository{
+		mongoCollection: mongoDB.Collection("idempotency_keys"),
+	}
+}
+
+// ReserveIdempotencyKey inserts the record unless its key is already taken for
+// the scope, in which case the existing record is returned. Expired records the
+// TTL monitor didn't remove yet and requests still in progress since before
+// lockedBefore are abandoned and taken over.
+func (r *idempotencyRepository) ReserveIdempotencyKey(record *models.IdempotencyRecord, lockedBefore time.Time) (*models.IdempotencyRecord, error) {
+	for attempt := 0; attempt < 2; attempt++ {
+		_, err := r.mongoCollection.InsertOne(context.Background(), record)
+		if err == nil {
+			return nil, nil
+		}
+
+		if !mongo.IsDuplicateKeyError(err) {
+			return nil, fmt.Errorf("error reserving idempotency key: %w", err)
+		}
+
+		var existing models.IdempotencyRecord
+		err = r.mongoCollection.FindOne(context.Background(), bson.M{"key": record.Key, "scope": record.Scope}).Decode(&existing)
+		if err == mongo.ErrNoDocuments {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("error getting idempotency key: %w", err)
+		}
+
+		if existing.ExpiresAt.After(record.CreatedAt) && (existing.Completed || existing.CreatedAt.After(lockedBefore)) {
+			return &existing, nil
+		}
+
+		_, err = r.mongoCollection.DeleteOne(context.Background(), bson.M{
+			"key":        existing.Key,
+			"scope":      existing.Scope,
+			"created_at": existing.CreatedAt,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("error deleting abandoned idempotency key: %w", err)
+		}
+	}
+
+	return nil, fmt.Errorf("error reserving idempotency key %s: concurrently taken", record.Key)
+}
+
+func (r *idempotencyRepository) CompleteIdempotencyKey(record *models.IdempotencyRecord) error {
+	_, err := r.mongoCollection.UpdateOne(context.Background(),
+		bson.M{"key": record.Key, "scope": record.Scope},
+		bson.M{"$set": bson.M{
+			"completed":    true,
+			"status_code":  record.StatusCode,
+			"content_type": record.ContentType,
+			"body":         record.Body,
+		}},
+	)
+	if err != nil {
+		return fmt.Errorf("error completing idempotency key: %w", err)
+	}
+
+	return nil
+}
+
+// ReleaseIdempotencyKey drops the reservation so a retry runs the request again.
+func (r *idempotencyRepository) ReleaseIdempotencyKey(key, scope string) error {
+	_, err := r.mongoCollection.DeleteOne(context.Background(), bson.M{"key": key, "scope": scope})
+	if err != nil {
+		return fmt.Errorf("error releasing idempotency key: %w", err)
+	}
+
+	return nil
+}
+
+func (r *idempotencyRepository) EnsureIndexes() error {
+	_, err := r.mongoCollection.Indexes().CreateMany(context.Background(), []mongo.IndexModel{
+		{Keys: bson.D{{Key: "key", Value: 1}, {Key: "scope", Value: 1}}, Options: options.Index().SetUnique(true)},
+		// records are removed once expires_at passed, so the retention follows the configured ttl
+		{Keys: bson.D{{Key: "expires_at", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(0)},
+	})
+	if err != nil {
+		return fmt.Errorf("error creating idempotency keys indexes: %w", err)
+	}
+
+	return nil
+}
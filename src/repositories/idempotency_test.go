@@ -0,0 +1,108 @@
+package repositories
+
+import (
+	"testing"
+	"time"
+
+	"github.com/tam-code/image-upload/src/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+	"gotest.tools/assert"
+)
+
+func TestReserveIdempotencyKey(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	defer mt.ClearCollections()
+
+	now := time.Now().Truncate(time.Millisecond)
+	record := models.IdempotencyRecord{
+		Key:         "key",
+		Scope:       "POST /upload-link",
+		RequestHash: "hash",
+		CreatedAt:   now,
+		ExpiresAt:   now.Add(time.Hour),
+	}
+
+	duplicateKeyError := mtest.CreateWriteErrorsResponse(mtest.WriteError{Index: 0, Code: 11000, Message: "duplicate key"})
+	existingResponse := func(completed bool, createdAt, expiresAt time.Time) bson.D {
+		return mtest.CreateCursorResponse(0, "foo.bar", mtest.FirstBatch, bson.D{
+			{Key: "key", Value: "key"},
+			{Key: "scope", Value: "POST /upload-link"},
+			{Key: "request_hash", Value: "hash"},
+			{Key: "completed", Value: completed},
+			{Key: "created_at", Value: createdAt},
+			{Key: "expires_at", Value: expiresAt},
+		})
+	}
+
+	tests := []struct {
+		name           string
+		prepare        func(mt *mtest.T)
+		expectError    bool
+		expectExisting bool
+	}{
+		{
+			name: "reserve new key",
+			prepare: func(mt *mtest.T) {
+				mt.AddMockResponses(mtest.CreateSuccessResponse())
+			},
+		},
+		{
+			name: "key already completed",
+			prepare: func(mt *mtest.T) {
+				mt.AddMockResponses(duplicateKeyError, existingResponse(true, now.Add(-time.Hour), now.Add(time.Hour)))
+			},
+			expectExisting: true,
+		},
+		{
+			name: "key in progress",
+			prepare: func(mt *mtest.T) {
+				mt.AddMockResponses(duplicateKeyError, existingResponse(false, now.Add(-time.Second), now.Add(time.Hour)))
+			},
+			expectExisting: true,
+		},
+		{
+			name: "abandoned key is taken over",
+			prepare: func(mt *mtest.T) {
+				mt.AddMockResponses(
+					duplicateKeyError,
+					existingResponse(false, now.Add(-time.Hour), now.Add(time.Hour)),
+					mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}),
+					mtest.CreateSuccessResponse(),
+				)
+			},
+		},
+		{
+			name: "expired key is taken over",
+			prepare: func(mt *mtest.T) {
+				mt.AddMockResponses(
+					duplicateKeyError,
+					existingResponse(true, now.Add(-2*time.Hour), now.Add(-time.Hour)),
+					mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}),
+					mtest.CreateSuccessResponse(),
+				)
+			},
+		},
+		{
+			name: "insert error",
+			prepare: func(mt *mtest.T) {
+				mt.AddMockResponses(bson.D{{Key: "ok", Value: 0}})
+			},
+			expectError: true,
+		},
+	}
+
+	for _, test := range tests {
+		mt.Run(test.name, func(mt *mtest.T) {
+			repo := idempotencyRepository{
+				mongoCollection: mt.Coll,
+			}
+
+			test.prepare(mt)
+
+			existing, err := repo.ReserveIdempotencyKey(&record, now.Add(-time.Minute))
+			assert.Equal(t, test.expectError, err != nil)
+			assert.Equal(t, test.expectExisting, existing != nil)
+		})
+	}
+}
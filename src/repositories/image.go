@@ -2,26 +2,82 @@ package repositories
 
 import (
 	"context"
+	"encoding/base64"
+	"errors"
 	"fmt"
+	"strings"
+	"time"
 
 	"github.com/tam-code/image-upload/src/models"
+	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	defaultImagesSort  = "-uploadTime"
+	defaultImagesLimit = 50
+	maxImagesLimit     = 200
+)
+
+var (
+	ErrInvalidCursor = errors.New("invalid cursor")
+	ErrInvalidSort   = errors.New("invalid sort")
+
+	// notDeleted excludes the soft deleted images waiting to be purged
+	notDeleted = bson.E{Key: "deleted_at", Value: bson.M{"$exists": false}}
+
+	// imagesSortFields maps the sortable json fields to their bson fields
+	imagesSortFields = map[string]string{
+		"uploadTime":  "upload_time",
+		"name":        "name",
+		"imageWidth":  "image_width",
+		"imageHeight": "image_height",
+	}
 )
 
 type (
 	ImageRepository interface {
-		InsertImages([]interface{}) ([]string, error)
-		GetImageByID(string) (*models.Image, error)
-		GetImageByName(string) (*models.Image, error)
-		GetImagesByIDs([]string) ([]models.Image, error)
-		UpdateImage(*models.Image) error
-		GetImageByNameAndUploadLinkID(string, string) (*models.Image, error)
+		InsertImages(context.Context, []interface{}) ([]string, error)
+		GetImageByID(context.Context, string, string) (*models.Image, error)
+		GetImageByName(context.Context, string, string) (*models.Image, error)
+		GetImagesByIDs(context.Context, string, []string) ([]models.Image, error)
+		MarkImagesCounted(context.Context, string, []string, bool) ([]string, error)
+		RestoreImagesCounted(context.Context, string, []string, bool) error
+		ResetImagesCounted(context.Context, string) (int, error)
+		UpdateImage(context.Context, string, *models.Image) error
+		GetImageByNameAndUploadLinkID(context.Context, string, string, string) (*models.Image, error)
+		ListImages(context.Context, models.ImageFilter) (*models.ImagePage, error)
+		GetImagesWithin(context.Context, string, *models.GeoPolygon, int) ([]models.Image, error)
+		GetImagesInBoundingBox(context.Context, string, *models.BoundingBox, int) ([]models.Image, error)
+		GetImagesNear(context.Context, string, *models.GeoPoint, float64, int) ([]models.Image, error)
+		GetGeotaggedImagesByUploadLinkID(context.Context, string, string) ([]models.Image, error)
+		SoftDeleteImage(context.Context, string, string, time.Time) (*models.Image, error)
+		SoftDeleteImagesByUploadLinkID(context.Context, string, string, time.Time) ([]string, error)
+		GetImagesAfter(context.Context, models.ImageFilter, string, int) ([]models.Image, error)
+		GetImagesDeletedBefore(context.Context, time.Time, int) ([]models.Image, error)
+		DeleteImage(context.Context, string, string) error
+		CountImagesByUploadLinkID(context.Context, string, string) (int, error)
+		GetImagePathsByUploadLinkID(context.Context, string) ([]string, error)
+		EnsureIndexes() error
 	}
 
 	imageRepository struct {
 		mogoCollection *mongo.Collection
 	}
+
+	// imageDocument decodes the object id that models.Image doesn't map
+	imageDocument struct {
+		ObjectID     primitive.ObjectID `bson:"_id"`
+		models.Image `bson:",inline"`
+	}
+
+	imagesCursor struct {
+		Sort  string             `bson:"sort"`
+		Value interface{}        `bson:"value"`
+		ID    primitive.ObjectID `bson:"id"`
+	}
 )
 
 func newImageRepository(mongoDB mongo.Database) ImageRepository {
@@ -30,9 +86,9 @@ func newImageRepository(mongoDB mongo.Database) ImageRepository {
 	}
 }
 
-func (r *imageRepository) InsertImages(images []interface{}) ([]string, error) {
+func (r *imageRepository) InsertImages(ctx context.Context, images []interface{}) ([]string, error) {
 
-	insertedData, err := r.mogoCollection.InsertMany(context.Background(), images)
+	insertedData, err := r.mogoCollection.InsertMany(ctx, images)
 	if err != nil {
 		return nil, err
 	}
@@ -45,7 +101,9 @@ func (r *imageRepository) InsertImages(images []interface{}) ([]string, error) {
 	return insertedImages, nil
 }
 
-func (r *imageRepository) GetImageByID(id string) (*models.Image, error) {
+// GetImageByID returns the image of the tenant, an error when it doesn't
+// exist, belongs to another tenant or is deleted.
+func (r *imageRepository) GetImageByID(ctx context.Context, tenant, id string) (*models.Image, error) {
 	objectID, err := primitive.ObjectIDFromHex(id)
 	if err != nil {
 		if err == mongo.ErrNoDocuments {
@@ -55,7 +113,7 @@ func (r *imageRepository) GetImageByID(id string) (*models.Image, error) {
 	}
 
 	var image models.Image
-	err = r.mogoCollection.FindOne(context.Background(), primitive.M{"_id": objectID}).Decode(&image)
+	err = r.mogoCollection.FindOne(ctx, bson.D{{Key: "_id", Value: objectID}, {Key: "tenant", Value: tenant}, notDeleted}).Decode(&image)
 	if err != nil {
 		return nil, fmt.Errorf("error getting image by id: %w", err)
 	}
@@ -65,9 +123,9 @@ func (r *imageRepository) GetImageByID(id string) (*models.Image, error) {
 	return &image, nil
 }
 
-func (r *imageRepository) GetImageByName(name string) (*models.Image, error) {
+func (r *imageRepository) GetImageByName(ctx context.Context, tenant, name string) (*models.Image, error) {
 	var image models.Image
-	err := r.mogoCollection.FindOne(context.Background(), primitive.M{"name": name}).Decode(&image)
+	err := r.mogoCollection.FindOne(ctx, bson.D{{Key: "name", Value: name}, {Key: "tenant", Value: tenant}, notDeleted}).Decode(&image)
 	if err != nil {
 		return nil, fmt.Errorf("error getting image by name: %w", err)
 	}
@@ -75,7 +133,9 @@ func (r *imageRepository) GetImageByName(name string) (*models.Image, error) {
 	return &image, nil
 }
 
-func (r *imageRepository) GetImagesByIDs(ids []string) ([]models.Image, error) {
+// GetImagesByIDs returns the images of the tenant among the ids, the soft
+// deleted ones included.
+func (r *imageRepository) GetImagesByIDs(ctx context.Context, tenant string, ids []string) ([]models.Image, error) {
 	var objectIDs []primitive.ObjectID
 	for _, id := range ids {
 		objectID, err := primitive.ObjectIDFromHex(id)
@@ -86,27 +146,91 @@ func (r *imageRepository) GetImagesByIDs(ids []string) ([]models.Image, error) {
 		objectIDs = append(objectIDs, objectID)
 	}
 
-	cursor, err := r.mogoCollection.Find(context.Background(), primitive.M{"_id": primitive.M{"$in": objectIDs}})
+	images, err := r.findImages(ctx, bson.D{{Key: "_id", Value: bson.M{"$in": objectIDs}}, {Key: "tenant", Value: tenant}})
 	if err != nil {
 		return nil, fmt.Errorf("error getting images by ids: %w", err)
 	}
 
-	var images []models.Image
-	err = cursor.All(context.Background(), &images)
+	return images, nil
+}
+
+// MarkImagesCounted sets whether the images of the tenant are counted in the
+// statistics and returns the ids of the images whose mark changed, so an
+// image is counted once however many times its events are handled. Deleted
+// images are never marked counted. The ids marked before an error are
+// returned with it.
+func (r *imageRepository) MarkImagesCounted(ctx context.Context, tenant string, ids []string, counted bool) ([]string, error) {
+	var marked []string
+	for _, id := range ids {
+		objectID, err := primitive.ObjectIDFromHex(id)
+		if err != nil {
+			return marked, fmt.Errorf("error converting id to object id: %w", err)
+		}
+
+		// each image is marked on its own so the changed ones are known
+		query := bson.D{{Key: "_id", Value: objectID}, {Key: "tenant", Value: tenant}, {Key: "statistics_counted", Value: bson.M{"$ne": counted}}}
+		if counted {
+			query = append(query, notDeleted)
+		}
+
+		result, err := r.mogoCollection.UpdateOne(ctx, query, bson.M{"$set": bson.M{"statistics_counted": counted}})
+		if err != nil {
+			return marked, fmt.Errorf("error marking image counted: %w", err)
+		}
+
+		if result.ModifiedCount > 0 {
+			marked = append(marked, id)
+		}
+	}
+
+	return marked, nil
+}
+
+// RestoreImagesCounted sets the mark of the images back once their
+// statistics couldn't be applied, deleted images included, so the event is
+// applied again when it is retried.
+func (r *imageRepository) RestoreImagesCounted(ctx context.Context, tenant string, ids []string, counted bool) error {
+	objectIDs := make([]primitive.ObjectID, 0, len(ids))
+	for _, id := range ids {
+		objectID, err := primitive.ObjectIDFromHex(id)
+		if err != nil {
+			return fmt.Errorf("error converting id to object id: %w", err)
+		}
+		objectIDs = append(objectIDs, objectID)
+	}
+
+	query := bson.D{{Key: "_id", Value: bson.M{"$in": objectIDs}}, {Key: "tenant", Value: tenant}}
+	if _, err := r.mogoCollection.UpdateMany(ctx, query, bson.M{"$set": bson.M{"statistics_counted": counted}}); err != nil {
+		return fmt.Errorf("error restoring images counted: %w", err)
+	}
+
+	return nil
+}
+
+// ResetImagesCounted marks the images of the tenant, of all tenants when
+// empty, not counted so a replay counts them in the statistics again. It
+// returns how many images were reset.
+func (r *imageRepository) ResetImagesCounted(ctx context.Context, tenant string) (int, error) {
+	query := bson.D{{Key: "statistics_counted", Value: true}}
+	if tenant != "" {
+		query = append(query, bson.E{Key: "tenant", Value: tenant})
+	}
+
+	result, err := r.mogoCollection.UpdateMany(ctx, query, bson.M{"$set": bson.M{"statistics_counted": false}})
 	if err != nil {
-		return nil, fmt.Errorf("error getting images by ids: %w", err)
+		return 0, fmt.Errorf("error resetting images counted: %w", err)
 	}
 
-	return images, nil
+	return int(result.ModifiedCount), nil
 }
 
-func (r *imageRepository) UpdateImage(image *models.Image) error {
+func (r *imageRepository) UpdateImage(ctx context.Context, tenant string, image *models.Image) error {
 	objectID, err := primitive.ObjectIDFromHex(image.ID)
 	if err != nil {
 		return fmt.Errorf("error converting id to object id: %w", err)
 	}
 
-	_, err = r.mogoCollection.UpdateOne(context.Background(), primitive.M{"_id": objectID}, primitive.M{"$set": image})
+	_, err = r.mogoCollection.UpdateOne(ctx, bson.D{{Key: "_id", Value: objectID}, {Key: "tenant", Value: tenant}}, primitive.M{"$set": image})
 	if err != nil {
 		return fmt.Errorf("error updating image: %w", err)
 	}
@@ -114,9 +238,9 @@ func (r *imageRepository) UpdateImage(image *models.Image) error {
 	return nil
 }
 
-func (r *imageRepository) GetImageByNameAndUploadLinkID(name, uploadLinkID string) (*models.Image, error) {
+func (r *imageRepository) GetImageByNameAndUploadLinkID(ctx context.Context, tenant, name, uploadLinkID string) (*models.Image, error) {
 	var image models.Image
-	err := r.mogoCollection.FindOne(context.Background(), primitive.M{"name": name, "upload_link_id": uploadLinkID}).Decode(&image)
+	err := r.mogoCollection.FindOne(ctx, bson.D{{Key: "name", Value: name}, {Key: "upload_link_id", Value: uploadLinkID}, {Key: "tenant", Value: tenant}, notDeleted}).Decode(&image)
 	if err != nil {
 		if err == mongo.ErrNoDocuments {
 			return nil, nil
@@ -126,3 +250,482 @@ func (r *imageRepository) GetImageByNameAndUploadLinkID(name, uploadLinkID strin
 
 	return &image, nil
 }
+
+func (r *imageRepository) ListImages(ctx context.Context, filter models.ImageFilter) (*models.ImagePage, error) {
+	if filter.Tenant == "" {
+		return nil, ErrMissingTenant
+	}
+
+	sort := filter.Sort
+	if sort == "" {
+		sort = defaultImagesSort
+	}
+
+	sortField, sortDirection, err := parseImagesSort(sort)
+	if err != nil {
+		return nil, err
+	}
+
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = defaultImagesLimit
+	}
+	if limit > maxImagesLimit {
+		limit = maxImagesLimit
+	}
+
+	query := imagesFilterQuery(filter)
+	if filter.Cursor != "" {
+		cursor, err := decodeImagesCursor(filter.Cursor)
+		if err != nil || cursor.Sort != sort {
+			return nil, ErrInvalidCursor
+		}
+
+		operator := "$gt"
+		if sortDirection < 0 {
+			operator = "$lt"
+		}
+
+		query = append(query, bson.E{Key: "$or", Value: bson.A{
+			bson.M{sortField: bson.M{operator: cursor.Value}},
+			bson.M{sortField: cursor.Value, "_id": bson.M{operator: cursor.ID}},
+		}})
+	}
+
+	findOptions := options.Find().
+		SetSort(bson.D{{Key: sortField, Value: sortDirection}, {Key: "_id", Value: sortDirection}}).
+		SetLimit(int64(limit + 1))
+
+	images, err := r.findImages(ctx, query, findOptions)
+	if err != nil {
+		return nil, fmt.Errorf("error listing images: %w", err)
+	}
+
+	page := &models.ImagePage{Images: images}
+	if len(images) > limit {
+		page.Images = images[:limit]
+
+		last := images[limit-1]
+		lastID, _ := primitive.ObjectIDFromHex(last.ID)
+		page.NextCursor, err = encodeImagesCursor(imagesCursor{
+			Sort:  sort,
+			Value: imageSortValue(last, sortField),
+			ID:    lastID,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("error encoding cursor: %w", err)
+		}
+	}
+
+	return page, nil
+}
+
+func (r *imageRepository) GetImagesWithin(ctx context.Context, tenant string, polygon *models.GeoPolygon, limit int) ([]models.Image, error) {
+	query := bson.D{{Key: "tenant", Value: tenant}, {Key: "location", Value: bson.M{"$geoWithin": bson.M{"$geometry": polygon}}}, notDeleted}
+
+	images, err := r.findImages(ctx, query, options.Find().SetLimit(int64(limit)))
+	if err != nil {
+		return nil, fmt.Errorf("error getting images within polygon: %w", err)
+	}
+
+	return images, nil
+}
+
+// GetImagesInBoundingBox returns the images located in the box. The box is
+// matched on the coordinates of the locations, a $geometry polygon would have
+// geodesic edges bulging away from the parallels of the box.
+func (r *imageRepository) GetImagesInBoundingBox(ctx context.Context, tenant string, box *models.BoundingBox, limit int) ([]models.Image, error) {
+	query := append(bson.D{{Key: "tenant", Value: tenant}}, boundingBoxQuery(box)...)
+	query = append(query, notDeleted)
+
+	images, err := r.findImages(ctx, query, options.Find().SetLimit(int64(limit)))
+	if err != nil {
+		return nil, fmt.Errorf("error getting images in bounding box: %w", err)
+	}
+
+	return images, nil
+}
+
+// GetImagesNear returns the images within radius meters of the point, nearest first.
+func (r *imageRepository) GetImagesNear(ctx context.Context, tenant string, point *models.GeoPoint, radius float64, limit int) ([]models.Image, error) {
+	query := bson.D{{Key: "tenant", Value: tenant}, {Key: "location", Value: bson.M{"$nearSphere": bson.M{"$geometry": point, "$maxDistance": radius}}}, notDeleted}
+
+	images, err := r.findImages(ctx, query, options.Find().SetLimit(int64(limit)))
+	if err != nil {
+		return nil, fmt.Errorf("error getting images near point: %w", err)
+	}
+
+	return images, nil
+}
+
+func (r *imageRepository) GetGeotaggedImagesByUploadLinkID(ctx context.Context, tenant, uploadLinkID string) ([]models.Image, error) {
+	query := bson.D{{Key: "tenant", Value: tenant}, {Key: "upload_link_id", Value: uploadLinkID}, {Key: "location", Value: bson.M{"$exists": true}}, notDeleted}
+
+	images, err := r.findImages(ctx, query, options.Find().SetSort(bson.D{{Key: "upload_time", Value: 1}}))
+	if err != nil {
+		return nil, fmt.Errorf("error getting geotagged images by upload link id: %w", err)
+	}
+
+	return images, nil
+}
+
+// SoftDeleteImage marks the image of the tenant deleted, it is hidden from
+// every query but GetImagesByIDs until purged. It returns nil when the image
+// doesn't exist, belongs to another tenant or is already deleted.
+func (r *imageRepository) SoftDeleteImage(ctx context.Context, tenant, id string, deletedAt time.Time) (*models.Image, error) {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, nil
+	}
+
+	var image models.Image
+	err = r.mogoCollection.FindOneAndUpdate(ctx,
+		bson.D{{Key: "_id", Value: objectID}, {Key: "tenant", Value: tenant}, notDeleted},
+		bson.M{"$set": bson.M{"deleted_at": deletedAt}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&image)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("error deleting image: %w", err)
+	}
+
+	image.ID = objectID.Hex()
+
+	return &image, nil
+}
+
+// SoftDeleteImagesByUploadLinkID marks every image of the tenant upload link
+// deleted and returns the ids of the images it marked.
+func (r *imageRepository) SoftDeleteImagesByUploadLinkID(ctx context.Context, tenant, uploadLinkID string, deletedAt time.Time) ([]string, error) {
+	images, err := r.findImages(ctx, bson.D{{Key: "upload_link_id", Value: uploadLinkID}, {Key: "tenant", Value: tenant}, notDeleted},
+		options.Find().SetProjection(bson.M{"_id": 1}))
+	if err != nil {
+		return nil, fmt.Errorf("error getting images by upload link id: %w", err)
+	}
+
+	var ids []string
+	var objectIDs []primitive.ObjectID
+	for _, image := range images {
+		objectID, _ := primitive.ObjectIDFromHex(image.ID)
+		ids = append(ids, image.ID)
+		objectIDs = append(objectIDs, objectID)
+	}
+
+	if len(objectIDs) == 0 {
+		return ids, nil
+	}
+
+	_, err = r.mogoCollection.UpdateMany(ctx,
+		bson.D{{Key: "_id", Value: bson.M{"$in": objectIDs}}, {Key: "tenant", Value: tenant}, notDeleted},
+		bson.M{"$set": bson.M{"deleted_at": deletedAt}},
+	)
+	if err != nil {
+		return nil, fmt.Errorf("error deleting images by upload link id: %w", err)
+	}
+
+	return ids, nil
+}
+
+// GetImagesAfter returns up to limit images matching the filter whose id is
+// greater than afterID, in id order, so all the matching images are read by
+// passing the id of the last one. The images of every tenant match when the
+// filter has none.
+func (r *imageRepository) GetImagesAfter(ctx context.Context, filter models.ImageFilter, afterID string, limit int) ([]models.Image, error) {
+	query := imagesFilterQuery(filter)
+	if afterID != "" {
+		objectID, err := primitive.ObjectIDFromHex(afterID)
+		if err != nil {
+			return nil, fmt.Errorf("error converting id to object id: %w", err)
+		}
+
+		query = append(query, bson.E{Key: "_id", Value: bson.M{"$gt": objectID}})
+	}
+
+	images, err := r.findImages(ctx, query, options.Find().SetSort(bson.D{{Key: "_id", Value: 1}}).SetLimit(int64(limit)))
+	if err != nil {
+		return nil, fmt.Errorf("error getting images: %w", err)
+	}
+
+	return images, nil
+}
+
+// GetImagesDeletedBefore returns up to limit soft deleted images, oldest deletions first.
+func (r *imageRepository) GetImagesDeletedBefore(ctx context.Context, before time.Time, limit int) ([]models.Image, error) {
+	query := bson.M{"deleted_at": bson.M{"$lte": before}}
+
+	images, err := r.findImages(ctx, query, options.Find().SetSort(bson.D{{Key: "deleted_at", Value: 1}}).SetLimit(int64(limit)))
+	if err != nil {
+		return nil, fmt.Errorf("error getting deleted images: %w", err)
+	}
+
+	return images, nil
+}
+
+func (r *imageRepository) DeleteImage(ctx context.Context, tenant, id string) error {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return fmt.Errorf("error converting id to object id: %w", err)
+	}
+
+	_, err = r.mogoCollection.DeleteOne(ctx, bson.D{{Key: "_id", Value: objectID}, {Key: "tenant", Value: tenant}})
+	if err != nil {
+		return fmt.Errorf("error purging image: %w", err)
+	}
+
+	return nil
+}
+
+func (r *imageRepository) CountImagesByUploadLinkID(ctx context.Context, tenant, uploadLinkID string) (int, error) {
+	count, err := r.mogoCollection.CountDocuments(ctx, bson.D{{Key: "upload_link_id", Value: uploadLinkID}, {Key: "tenant", Value: tenant}, notDeleted})
+	if err != nil {
+		return 0, fmt.Errorf("error counting images by upload link id: %w", err)
+	}
+
+	return int(count), nil
+}
+
+// GetImagePathsByUploadLinkID returns the stored file paths of the upload link
+// images, soft deleted ones included since their files are kept until purged.
+// It serves the garbage collector, the paths are under the tenant directory.
+func (r *imageRepository) GetImagePathsByUploadLinkID(ctx context.Context, uploadLinkID string) ([]string, error) {
+	images, err := r.findImages(ctx, bson.M{"upload_link_id": uploadLinkID}, options.Find().SetProjection(bson.M{"path": 1}))
+	if err != nil {
+		return nil, fmt.Errorf("error getting image paths by upload link id: %w", err)
+	}
+
+	var paths []string
+	for _, image := range images {
+		paths = append(paths, image.Path)
+	}
+
+	return paths, nil
+}
+
+func (r *imageRepository) EnsureIndexes() error {
+	// backfill locations of images stored before they were kept as GeoJSON points
+	_, err := r.mogoCollection.UpdateMany(context.Background(),
+		bson.M{"latitude": bson.M{"$ne": 0}, "location": bson.M{"$exists": false}},
+		mongo.Pipeline{{{Key: "$set", Value: bson.M{"location": bson.M{
+			"type":        models.GeoJSONPoint,
+			"coordinates": bson.A{"$longitude", "$latitude"},
+		}}}}},
+	)
+	if err != nil {
+		return fmt.Errorf("error backfilling images locations: %w", err)
+	}
+
+	if err = backfillTenant(r.mogoCollection); err != nil {
+		return err
+	}
+
+	// the images stored before the mark existed were counted by their events
+	_, err = r.mogoCollection.UpdateMany(context.Background(),
+		bson.M{"statistics_counted": bson.M{"$exists": false}},
+		bson.M{"$set": bson.M{"statistics_counted": true}},
+	)
+	if err != nil {
+		return fmt.Errorf("error backfilling images statistics marks: %w", err)
+	}
+
+	_, err = r.mogoCollection.Indexes().CreateMany(context.Background(), []mongo.IndexModel{
+		{Keys: bson.D{{Key: "tenant", Value: 1}, {Key: "location", Value: "2dsphere"}}},
+		{Keys: bson.D{{Key: "upload_link_id", Value: 1}, {Key: "location", Value: "2dsphere"}}},
+		{Keys: bson.D{{Key: "upload_link_id", Value: 1}, {Key: "name", Value: 1}}},
+		{Keys: bson.D{{Key: "upload_link_id", Value: 1}, {Key: "upload_time", Value: -1}, {Key: "_id", Value: -1}}},
+		{Keys: bson.D{{Key: "tenant", Value: 1}, {Key: "upload_time", Value: -1}, {Key: "_id", Value: -1}}},
+		{Keys: bson.D{{Key: "tenant", Value: 1}, {Key: "camera_model", Value: 1}, {Key: "upload_time", Value: -1}}},
+		{Keys: bson.D{{Key: "tenant", Value: 1}, {Key: "image_format", Value: 1}, {Key: "upload_time", Value: -1}}},
+		{Keys: bson.D{{Key: "tenant", Value: 1}, {Key: "name", Value: 1}, {Key: "_id", Value: 1}}},
+		{Keys: bson.D{{Key: "tenant", Value: 1}, {Key: "image_width", Value: 1}, {Key: "_id", Value: 1}}},
+		{Keys: bson.D{{Key: "tenant", Value: 1}, {Key: "image_height", Value: 1}, {Key: "_id", Value: 1}}},
+		{Keys: bson.D{{Key: "tenant", Value: 1}, {Key: "metadata.captured_at", Value: -1}}},
+		{Keys: bson.D{{Key: "tenant", Value: 1}, {Key: "metadata.make", Value: 1}, {Key: "metadata.lens_model", Value: 1}}},
+		{Keys: bson.D{{Key: "tenant", Value: 1}, {Key: "metadata.keywords", Value: 1}}},
+		{Keys: bson.D{{Key: "deleted_at", Value: 1}}, Options: options.Index().SetSparse(true)},
+	})
+	if err != nil {
+		return fmt.Errorf("error creating images indexes: %w", err)
+	}
+
+	return nil
+}
+
+func (r *imageRepository) findImages(ctx context.Context, query interface{}, findOptions ...*options.FindOptions) ([]models.Image, error) {
+	cursor, err := r.mogoCollection.Find(ctx, query, findOptions...)
+	if err != nil {
+		return nil, err
+	}
+
+	var documents []imageDocument
+	if err = cursor.All(ctx, &documents); err != nil {
+		return nil, err
+	}
+
+	images := []models.Image{}
+	for _, document := range documents {
+		document.Image.ID = document.ObjectID.Hex()
+		images = append(images, document.Image)
+	}
+
+	return images, nil
+}
+
+// boundingBoxQuery matches the locations in the box, the boxes crossing the
+// antimeridian are matched as the two boxes on each side of it.
+func boundingBoxQuery(box *models.BoundingBox) bson.D {
+	latitude := bson.E{Key: "location.coordinates.1", Value: bson.M{"$gte": box.MinLatitude, "$lte": box.MaxLatitude}}
+
+	if !box.CrossesAntimeridian() {
+		return bson.D{latitude, {Key: "location.coordinates.0", Value: bson.M{"$gte": box.MinLongitude, "$lte": box.MaxLongitude}}}
+	}
+
+	return bson.D{latitude, {Key: "$or", Value: bson.A{
+		bson.M{"location.coordinates.0": bson.M{"$gte": box.MinLongitude}},
+		bson.M{"location.coordinates.0": bson.M{"$lte": box.MaxLongitude}},
+	}}}
+}
+
+// imagesFilterQuery matches the images of every tenant when the filter has
+// none, the listings scoped to a tenant check it is set.
+func imagesFilterQuery(filter models.ImageFilter) bson.D {
+	query := bson.D{notDeleted}
+	if filter.Tenant != "" {
+		query = append(query, bson.E{Key: "tenant", Value: filter.Tenant})
+	}
+
+	if filter.UploadLinkID != "" {
+		query = append(query, bson.E{Key: "upload_link_id", Value: filter.UploadLinkID})
+	}
+
+	if filter.CameraModel != "" {
+		query = append(query, bson.E{Key: "camera_model", Value: filter.CameraModel})
+	}
+
+	if filter.ImageFormat != "" {
+		query = append(query, bson.E{Key: "image_format", Value: filter.ImageFormat})
+	}
+
+	uploadTime := bson.M{}
+	if !filter.UploadedFrom.IsZero() {
+		uploadTime["$gte"] = filter.UploadedFrom
+	}
+	if !filter.UploadedTo.IsZero() {
+		uploadTime["$lte"] = filter.UploadedTo
+	}
+	if len(uploadTime) > 0 {
+		query = append(query, bson.E{Key: "upload_time", Value: uploadTime})
+	}
+
+	if width := rangeQuery(filter.MinWidth, filter.MaxWidth); width != nil {
+		query = append(query, bson.E{Key: "image_width", Value: width})
+	}
+
+	if height := rangeQuery(filter.MinHeight, filter.MaxHeight); height != nil {
+		query = append(query, bson.E{Key: "image_height", Value: height})
+	}
+
+	if filter.HasGPS != nil {
+		query = append(query, bson.E{Key: "location", Value: bson.M{"$exists": *filter.HasGPS}})
+	}
+
+	if filter.Make != "" {
+		query = append(query, bson.E{Key: "metadata.make", Value: filter.Make})
+	}
+
+	if filter.LensModel != "" {
+		query = append(query, bson.E{Key: "metadata.lens_model", Value: filter.LensModel})
+	}
+
+	if filter.Keyword != "" {
+		query = append(query, bson.E{Key: "metadata.keywords", Value: filter.Keyword})
+	}
+
+	capturedAt := bson.M{}
+	if !filter.CapturedFrom.IsZero() {
+		capturedAt["$gte"] = filter.CapturedFrom
+	}
+	if !filter.CapturedTo.IsZero() {
+		capturedAt["$lte"] = filter.CapturedTo
+	}
+	if len(capturedAt) > 0 {
+		query = append(query, bson.E{Key: "metadata.captured_at", Value: capturedAt})
+	}
+
+	if iso := rangeQuery(filter.MinISO, filter.MaxISO); iso != nil {
+		query = append(query, bson.E{Key: "metadata.iso", Value: iso})
+	}
+
+	if filter.FlashFired != nil {
+		query = append(query, bson.E{Key: "metadata.flash_fired", Value: *filter.FlashFired})
+	}
+
+	return query
+}
+
+func rangeQuery(min, max int) bson.M {
+	query := bson.M{}
+	if min > 0 {
+		query["$gte"] = min
+	}
+	if max > 0 {
+		query["$lte"] = max
+	}
+	if len(query) == 0 {
+		return nil
+	}
+
+	return query
+}
+
+func parseImagesSort(sort string) (string, int, error) {
+	direction := 1
+	if strings.HasPrefix(sort, "-") {
+		direction = -1
+		sort = strings.TrimPrefix(sort, "-")
+	}
+
+	field, ok := imagesSortFields[sort]
+	if !ok {
+		return "", 0, ErrInvalidSort
+	}
+
+	return field, direction, nil
+}
+
+func imageSortValue(image models.Image, field string) interface{} {
+	switch field {
+	case "name":
+		return image.Name
+	case "image_width":
+		return image.ImageWidth
+	case "image_height":
+		return image.ImageHeight
+	default:
+		return image.UploadedAt
+	}
+}
+
+func encodeImagesCursor(cursor imagesCursor) (string, error) {
+	data, err := bson.Marshal(cursor)
+	if err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+func decodeImagesCursor(token string) (*imagesCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, err
+	}
+
+	var cursor imagesCursor
+	if err = bson.Unmarshal(data, &cursor); err != nil {
+		return nil, err
+	}
+
+	return &cursor, nil
+}
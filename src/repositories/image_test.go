@@ -0,0 +1,382 @@
+package repositories
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/tam-code/image-upload/src/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+	"gotest.tools/assert"
+)
+
+func TestListImages(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	defer mt.ClearCollections()
+
+	firstID := primitive.NewObjectID()
+	secondID := primitive.NewObjectID()
+	uploadTime := time.Now().Truncate(time.Millisecond)
+
+	validCursor, _ := encodeImagesCursor(imagesCursor{Sort: defaultImagesSort, Value: uploadTime, ID: firstID})
+	otherSortCursor, _ := encodeImagesCursor(imagesCursor{Sort: "name", Value: "a.jpg", ID: firstID})
+
+	tests := []struct {
+		name             string
+		filter           models.ImageFilter
+		prepare          func(mt *mtest.T)
+		expectError      error
+		expectImages     int
+		expectNextCursor bool
+	}{
+		{
+			name:   "list images with next page",
+			filter: models.ImageFilter{Tenant: models.DefaultTenant, Limit: 1},
+			prepare: func(mt *mtest.T) {
+				mt.AddMockResponses(mtest.CreateCursorResponse(0, "foo.bar", mtest.FirstBatch,
+					bson.D{{Key: "_id", Value: firstID}, {Key: "name", Value: "a.jpg"}, {Key: "upload_time", Value: uploadTime}},
+					bson.D{{Key: "_id", Value: secondID}, {Key: "name", Value: "b.jpg"}, {Key: "upload_time", Value: uploadTime}},
+				))
+			},
+			expectImages:     1,
+			expectNextCursor: true,
+		},
+		{
+			name:   "list last page",
+			filter: models.ImageFilter{Tenant: models.DefaultTenant, Cursor: validCursor, Limit: 2},
+			prepare: func(mt *mtest.T) {
+				mt.AddMockResponses(mtest.CreateCursorResponse(0, "foo.bar", mtest.FirstBatch,
+					bson.D{{Key: "_id", Value: secondID}, {Key: "name", Value: "b.jpg"}, {Key: "upload_time", Value: uploadTime}},
+				))
+			},
+			expectImages: 1,
+		},
+		{
+			name:        "invalid sort",
+			filter:      models.ImageFilter{Tenant: models.DefaultTenant, Sort: "size"},
+			prepare:     func(mt *mtest.T) {},
+			expectError: ErrInvalidSort,
+		},
+		{
+			name:        "invalid cursor",
+			filter:      models.ImageFilter{Tenant: models.DefaultTenant, Cursor: "invalid"},
+			prepare:     func(mt *mtest.T) {},
+			expectError: ErrInvalidCursor,
+		},
+		{
+			name:        "cursor from another sort",
+			filter:      models.ImageFilter{Tenant: models.DefaultTenant, Cursor: otherSortCursor},
+			prepare:     func(mt *mtest.T) {},
+			expectError: ErrInvalidCursor,
+		},
+		{
+			name:        "missing tenant",
+			filter:      models.ImageFilter{Limit: 1},
+			prepare:     func(mt *mtest.T) {},
+			expectError: ErrMissingTenant,
+		},
+	}
+
+	for _, test := range tests {
+		mt.Run(test.name, func(mt *mtest.T) {
+			repo := imageRepository{
+				mogoCollection: mt.Coll,
+			}
+
+			test.prepare(mt)
+
+			page, err := repo.ListImages(context.Background(), test.filter)
+			assert.Equal(t, test.expectError, err)
+			if err != nil {
+				return
+			}
+
+			assert.Equal(t, test.expectImages, len(page.Images))
+			assert.Equal(t, test.expectNextCursor, page.NextCursor != "")
+			assert.Equal(t, firstID.Hex() == page.Images[0].ID || secondID.Hex() == page.Images[0].ID, true)
+		})
+	}
+}
+
+func TestImagesCursor(t *testing.T) {
+	id := primitive.NewObjectID()
+	uploadTime := time.Now().Truncate(time.Millisecond)
+
+	token, err := encodeImagesCursor(imagesCursor{Sort: defaultImagesSort, Value: uploadTime, ID: id})
+	assert.NilError(t, err)
+
+	cursor, err := decodeImagesCursor(token)
+	assert.NilError(t, err)
+	assert.Equal(t, defaultImagesSort, cursor.Sort)
+	assert.Equal(t, id, cursor.ID)
+	assert.Equal(t, primitive.NewDateTimeFromTime(uploadTime), cursor.Value)
+}
+
+func TestSoftDeleteImagesByUploadLinkID(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	defer mt.ClearCollections()
+
+	firstID := primitive.NewObjectID()
+	secondID := primitive.NewObjectID()
+
+	tests := []struct {
+		name        string
+		prepare     func(mt *mtest.T)
+		expectError bool
+		expectIDs   []string
+	}{
+		{
+			name: "delete upload link images",
+			prepare: func(mt *mtest.T) {
+				mt.AddMockResponses(
+					mtest.CreateCursorResponse(0, "foo.bar", mtest.FirstBatch,
+						bson.D{{Key: "_id", Value: firstID}},
+						bson.D{{Key: "_id", Value: secondID}},
+					),
+					mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 2}, bson.E{Key: "nModified", Value: 2}),
+				)
+			},
+			expectIDs: []string{firstID.Hex(), secondID.Hex()},
+		},
+		{
+			name: "no images",
+			prepare: func(mt *mtest.T) {
+				mt.AddMockResponses(mtest.CreateCursorResponse(0, "foo.bar", mtest.FirstBatch))
+			},
+		},
+		{
+			name: "update error",
+			prepare: func(mt *mtest.T) {
+				mt.AddMockResponses(
+					mtest.CreateCursorResponse(0, "foo.bar", mtest.FirstBatch, bson.D{{Key: "_id", Value: firstID}}),
+					bson.D{{Key: "ok", Value: 0}},
+				)
+			},
+			expectError: true,
+		},
+	}
+
+	for _, test := range tests {
+		mt.Run(test.name, func(mt *mtest.T) {
+			repo := imageRepository{
+				mogoCollection: mt.Coll,
+			}
+
+			test.prepare(mt)
+
+			ids, err := repo.SoftDeleteImagesByUploadLinkID(context.Background(), models.DefaultTenant, "link", time.Now())
+			assert.Equal(t, test.expectError, err != nil)
+			assert.DeepEqual(t, test.expectIDs, ids)
+		})
+	}
+}
+
+func TestGetImageByIDTenant(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	defer mt.ClearCollections()
+
+	imageID := primitive.NewObjectID()
+
+	tests := []struct {
+		name        string
+		prepare     func(mt *mtest.T)
+		expectError bool
+	}{
+		{
+			name: "image of the tenant",
+			prepare: func(mt *mtest.T) {
+				mt.AddMockResponses(mtest.CreateCursorResponse(0, "foo.bar", mtest.FirstBatch,
+					bson.D{{Key: "_id", Value: imageID}, {Key: "tenant", Value: "marketing"}},
+				))
+			},
+		},
+		{
+			name: "image of another tenant",
+			prepare: func(mt *mtest.T) {
+				mt.AddMockResponses(mtest.CreateCursorResponse(0, "foo.bar", mtest.FirstBatch))
+			},
+			expectError: true,
+		},
+	}
+
+	for _, test := range tests {
+		mt.Run(test.name, func(mt *mtest.T) {
+			repo := imageRepository{
+				mogoCollection: mt.Coll,
+			}
+
+			test.prepare(mt)
+
+			image, err := repo.GetImageByID(context.Background(), "marketing", imageID.Hex())
+			assert.Equal(t, test.expectError, err != nil)
+			if !test.expectError {
+				assert.Equal(t, imageID.Hex(), image.ID)
+			}
+
+			// the tenant is part of the query, not checked once the image is read
+			filter := mt.GetStartedEvent().Command.Lookup("filter").Document()
+			assert.Equal(t, "marketing", filter.Lookup("tenant").StringValue())
+		})
+	}
+}
+
+func TestMarkImagesCounted(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	defer mt.ClearCollections()
+
+	firstID := primitive.NewObjectID().Hex()
+	secondID := primitive.NewObjectID().Hex()
+
+	tests := []struct {
+		name        string
+		responses   []bson.D
+		expectError bool
+		expectedIDs []string
+	}{
+		{
+			name: "image already counted",
+			responses: []bson.D{
+				mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}, bson.E{Key: "nModified", Value: 1}),
+				mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 0}, bson.E{Key: "nModified", Value: 0}),
+			},
+			expectedIDs: []string{firstID},
+		},
+		{
+			name: "error keeps the marked images",
+			responses: []bson.D{
+				mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}, bson.E{Key: "nModified", Value: 1}),
+				{{Key: "ok", Value: 0}},
+			},
+			expectError: true,
+			expectedIDs: []string{firstID},
+		},
+	}
+
+	for _, test := range tests {
+		mt.Run(test.name, func(mt *mtest.T) {
+			repo := imageRepository{
+				mogoCollection: mt.Coll,
+			}
+
+			mt.AddMockResponses(test.responses...)
+
+			ids, err := repo.MarkImagesCounted(context.Background(), "marketing", []string{firstID, secondID}, true)
+			assert.Equal(t, test.expectError, err != nil)
+			assert.DeepEqual(t, test.expectedIDs, ids)
+		})
+	}
+}
+
+func TestGetImagesAfter(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	defer mt.ClearCollections()
+
+	firstID := primitive.NewObjectID()
+	secondID := primitive.NewObjectID()
+
+	tests := []struct {
+		name        string
+		filter      models.ImageFilter
+		afterID     string
+		prepare     func(mt *mtest.T)
+		expectError bool
+		expectIDs   []string
+		expectQuery []string
+	}{
+		{
+			name:   "first page of every tenant",
+			filter: models.ImageFilter{ImageFormat: "jpeg"},
+			prepare: func(mt *mtest.T) {
+				mt.AddMockResponses(mtest.CreateCursorResponse(0, "foo.bar", mtest.FirstBatch,
+					bson.D{{Key: "_id", Value: firstID}},
+					bson.D{{Key: "_id", Value: secondID}},
+				))
+			},
+			expectIDs:   []string{firstID.Hex(), secondID.Hex()},
+			expectQuery: []string{"deleted_at", "image_format"},
+		},
+		{
+			name:    "next page of a tenant",
+			filter:  models.ImageFilter{Tenant: "marketing"},
+			afterID: firstID.Hex(),
+			prepare: func(mt *mtest.T) {
+				mt.AddMockResponses(mtest.CreateCursorResponse(0, "foo.bar", mtest.FirstBatch, bson.D{{Key: "_id", Value: secondID}}))
+			},
+			expectIDs:   []string{secondID.Hex()},
+			expectQuery: []string{"deleted_at", "tenant", "_id"},
+		},
+		{
+			name:        "invalid after id",
+			afterID:     "invalid",
+			prepare:     func(mt *mtest.T) {},
+			expectError: true,
+		},
+	}
+
+	for _, test := range tests {
+		mt.Run(test.name, func(mt *mtest.T) {
+			repo := imageRepository{
+				mogoCollection: mt.Coll,
+			}
+
+			test.prepare(mt)
+
+			images, err := repo.GetImagesAfter(context.Background(), test.filter, test.afterID, 2)
+			assert.Equal(t, test.expectError, err != nil)
+			if err != nil {
+				return
+			}
+
+			var ids []string
+			for _, image := range images {
+				ids = append(ids, image.ID)
+			}
+			assert.DeepEqual(t, test.expectIDs, ids)
+
+			var query []string
+			elements, _ := mt.GetStartedEvent().Command.Lookup("filter").Document().Elements()
+			for _, element := range elements {
+				query = append(query, element.Key())
+			}
+			assert.DeepEqual(t, test.expectQuery, query)
+		})
+	}
+}
+
+func TestBoundingBoxQuery(t *testing.T) {
+	tests := []struct {
+		name     string
+		box      *models.BoundingBox
+		expected bson.D
+	}{
+		{
+			// a geodesic edge between (0, 60) and (60, 60) reaches 63.4 degrees
+			// north, the points above the parallel must not match
+			name: "edges follow the parallels",
+			box:  models.NewBoundingBox(0, 50, 60, 60),
+			expected: bson.D{
+				{Key: "location.coordinates.1", Value: bson.M{"$gte": 50.0, "$lte": 60.0}},
+				{Key: "location.coordinates.0", Value: bson.M{"$gte": 0.0, "$lte": 60.0}},
+			},
+		},
+		{
+			name: "box crossing the antimeridian",
+			box:  models.NewBoundingBox(170, -20, -170, -10),
+			expected: bson.D{
+				{Key: "location.coordinates.1", Value: bson.M{"$gte": -20.0, "$lte": -10.0}},
+				{Key: "$or", Value: bson.A{
+					bson.M{"location.coordinates.0": bson.M{"$gte": 170.0}},
+					bson.M{"location.coordinates.0": bson.M{"$lte": -170.0}},
+				}},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.DeepEqual(t, test.expected, boundingBoxQuery(test.box))
+		})
+	}
+}
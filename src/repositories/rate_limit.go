@@ -0,0 +1,80 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/tam-code/image-upload/src/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type (
+	RateLimitRepository interface {
+		TakeRateLimitToken(key string, limit models.RateLimit, now time.Time) (*models.RateLimitResult, error)
+		EnsureIndexes() error
+	}
+
+	rateLimitRepository struct {
+		mongoCollection *mongo.Collection
+	}
+
+	rateLimitBucket struct {
+		Tokens  float64 `bson:"tokens"`
+		Allowed bool    `bson:"allowed"`
+	}
+)
+
+func newRateLimitRepository(mongoDB mongo.Database) RateLimitRepository {
+	return &rateLimitRepository{
+		mongoCollection: mongoDB.Collection("rate_limits"),
+	}
+}
+
+// TakeRateLimitToken refills the bucket of the key for the time elapsed since
+// it was last used and takes a token if one is left. The bucket is updated by
+// a single pipeline so concurrent instances never take the same token.
+func (r *rateLimitRepository) TakeRateLimitToken(key string, limit models.RateLimit, now time.Time) (*models.RateLimitResult, error) {
+	burst := float64(limit.Burst)
+
+	// an unused bucket is full again once it could have refilled every token
+	expiresAt := now.Add(limit.Result(0, true).Reset)
+
+	refilled := bson.M{"$min": bson.A{burst, bson.M{"$add": bson.A{
+		bson.M{"$ifNull": bson.A{"$tokens", burst}},
+		bson.M{"$multiply": bson.A{
+			bson.M{"$divide": bson.A{bson.M{"$max": bson.A{0, bson.M{"$subtract": bson.A{now, bson.M{"$ifNull": bson.A{"$updated_at", now}}}}}}, 1000}},
+			limit.RefillRate,
+		}},
+	}}}}
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$set", Value: bson.M{"tokens": refilled, "updated_at": now, "expires_at": expiresAt}}},
+		{{Key: "$set", Value: bson.M{"allowed": bson.M{"$gte": bson.A{"$tokens", 1}}}}},
+		{{Key: "$set", Value: bson.M{"tokens": bson.M{"$cond": bson.A{"$allowed", bson.M{"$subtract": bson.A{"$tokens", 1}}, "$tokens"}}}}},
+	}
+
+	var bucket rateLimitBucket
+	err := r.mongoCollection.FindOneAndUpdate(context.Background(), bson.M{"_id": key}, pipeline,
+		options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After),
+	).Decode(&bucket)
+	if err != nil {
+		return nil, fmt.Errorf("error taking rate limit token: %w", err)
+	}
+
+	return limit.Result(bucket.Tokens, bucket.Allowed), nil
+}
+
+func (r *rateLimitRepository) EnsureIndexes() error {
+	_, err := r.mongoCollection.Indexes().CreateMany(context.Background(), []mongo.IndexModel{
+		// full buckets are dropped, a missing bucket is full
+		{Keys: bson.D{{Key: "expires_at", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(0)},
+	})
+	if err != nil {
+		return fmt.Errorf("error creating rate limits indexes: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,65 @@
+package repositories
+
+import (
+	"testing"
+	"time"
+
+	"github.com/tam-code/image-upload/src/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+	"gotest.tools/assert"
+)
+
+func TestTakeRateLimitToken(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	defer mt.ClearCollections()
+
+	limit := models.RateLimit{Burst: 10, RefillRate: 1}
+
+	tests := []struct {
+		name        string
+		prepare     func(mt *mtest.T)
+		expectError bool
+		expect      *models.RateLimitResult
+	}{
+		{
+			name: "token taken",
+			prepare: func(mt *mtest.T) {
+				mt.AddMockResponses(bson.D{{Key: "ok", Value: 1}, {Key: "value", Value: bson.D{
+					{Key: "_id", Value: "clientIP:192.0.2.1"}, {Key: "tokens", Value: 9.0}, {Key: "allowed", Value: true},
+				}}})
+			},
+			expect: &models.RateLimitResult{Allowed: true, Limit: 10, Remaining: 9, Reset: time.Second},
+		},
+		{
+			name: "empty bucket",
+			prepare: func(mt *mtest.T) {
+				mt.AddMockResponses(bson.D{{Key: "ok", Value: 1}, {Key: "value", Value: bson.D{
+					{Key: "_id", Value: "clientIP:192.0.2.1"}, {Key: "tokens", Value: 0.5}, {Key: "allowed", Value: false},
+				}}})
+			},
+			expect: &models.RateLimitResult{Allowed: false, Limit: 10, Remaining: 0, Reset: 9500 * time.Millisecond, RetryAfter: 500 * time.Millisecond},
+		},
+		{
+			name: "simple error",
+			prepare: func(mt *mtest.T) {
+				mt.AddMockResponses(bson.D{{Key: "ok", Value: 0}})
+			},
+			expectError: true,
+		},
+	}
+
+	for _, test := range tests {
+		mt.Run(test.name, func(mt *mtest.T) {
+			repo := rateLimitRepository{
+				mongoCollection: mt.Coll,
+			}
+
+			test.prepare(mt)
+
+			result, err := repo.TakeRateLimitToken("clientIP:192.0.2.1", limit, time.Now())
+			assert.Equal(t, test.expectError, err != nil)
+			assert.DeepEqual(t, test.expect, result)
+		})
+	}
+}
@@ -3,15 +3,51 @@ package repositories
 import "go.mongodb.org/mongo-driver/mongo"
 
 type Repositories struct {
-	UploadLink UploadLinkRepository
-	Image      ImageRepository
-	Statistics StatisticsRepository
+	UploadLink  UploadLinkRepository
+	Image       ImageRepository
+	Statistics  StatisticsRepository
+	Idempotency IdempotencyRepository
+	APIKey      APIKeyRepository
+	Audit       AuditRepository
+	RateLimit   RateLimitRepository
 }
 
 func NewRepositories(mongodb *mongo.Database) *Repositories {
 	return &Repositories{
-		UploadLink: newUploadLinkRepository(*mongodb),
-		Image:      newImageRepository(*mongodb),
-		Statistics: newStatisticsRepository(*mongodb),
+		UploadLink:  newUploadLinkRepository(*mongodb),
+		Image:       newImageRepository(*mongodb),
+		Statistics:  newStatisticsRepository(*mongodb),
+		Idempotency: newIdempotencyRepository(*mongodb),
+		APIKey:      newAPIKeyRepository(*mongodb),
+		Audit:       newAuditRepository(*mongodb),
+		RateLimit:   newRateLimitRepository(*mongodb),
 	}
 }
+
+func (r *Repositories) EnsureIndexes() error {
+	if err := r.UploadLink.EnsureIndexes(); err != nil {
+		return err
+	}
+
+	if err := r.Image.EnsureIndexes(); err != nil {
+		return err
+	}
+
+	if err := r.Statistics.EnsureIndexes(); err != nil {
+		return err
+	}
+
+	if err := r.Idempotency.EnsureIndexes(); err != nil {
+		return err
+	}
+
+	if err := r.APIKey.EnsureIndexes(); err != nil {
+		return err
+	}
+
+	if err := r.Audit.EnsureIndexes(); err != nil {
+		return err
+	}
+
+	return r.RateLimit.EnsureIndexes()
+}
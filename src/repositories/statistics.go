@@ -2,23 +2,38 @@ package repositories
 
 import (
 	"context"
+	"errors"
+	"fmt"
 
 	"github.com/tam-code/image-upload/src/models"
+	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	namespaceNotFoundCode = 26
+	indexNotFoundCode     = 27
 )
 
 type (
 	StatisticsRepository interface {
-		InsertStatistics(statistics *models.Statistics) error
-		GetStatistics(statisticsType models.StatisticsType, name string) (*models.Statistics, error)
-		UpdateStatistics(statistics *models.Statistics) error
-		GetStatisticsFrequency(statisticsType models.StatisticsType, limit int) ([]models.Statistics, error)
-		GetStatisticsSortedByCount(statisticsType models.StatisticsType, limit int) ([]models.Statistics, error)
+		IncrementStatistics(ctx context.Context, tenant string, statisticsType models.StatisticsType, name string, delta int) (int, error)
+		GetStatisticsFrequency(ctx context.Context, tenant string, statisticsType models.StatisticsType, limit int) ([]models.Statistics, error)
+		GetStatisticsSortedByCount(ctx context.Context, tenant string, statisticsType models.StatisticsType, limit int) ([]models.Statistics, error)
+		DeleteStatistics(ctx context.Context, tenant string) (int, error)
+		EnsureIndexes() error
 	}
 
 	statisticsRepository struct {
 		mongoCollection *mongo.Collection
 	}
+
+	// duplicateStatistics are the buckets of the same tenant, type and name
+	duplicateStatistics struct {
+		IDs   []interface{} `bson:"ids"`
+		Count int           `bson:"count"`
+	}
 )
 
 func newStatisticsRepository(mongoDB mongo.Database) StatisticsRepository {
@@ -27,73 +42,156 @@ func newStatisticsRepository(mongoDB mongo.Database) StatisticsRepository {
 	}
 }
 
-func (r *statisticsRepository) InsertStatistics(statistics *models.Statistics) error {
-	_, err := r.mongoCollection.InsertOne(context.Background(), statistics)
-	if err != nil {
-		return err
+// IncrementStatistics adds delta to the count of the bucket in a single
+// update, so the handlers running concurrently don't lose counts. A missing
+// bucket is created on increments only, and decrements never take the count
+// below zero. It returns the count before the update.
+func (r *statisticsRepository) IncrementStatistics(ctx context.Context, tenant string, statisticsType models.StatisticsType, name string, delta int) (int, error) {
+	filter := bson.D{{Key: "tenant", Value: tenant}, {Key: "type", Value: statisticsType}, {Key: "name", Value: name}}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.Before)
+
+	var update interface{}
+	if delta >= 0 {
+		update = bson.M{"$inc": bson.M{"count": delta}}
+		opts.SetUpsert(true)
+	} else {
+		update = mongo.Pipeline{{{Key: "$set", Value: bson.M{"count": bson.M{"$max": bson.A{0, bson.M{"$add": bson.A{"$count", delta}}}}}}}}
 	}
 
-	return nil
-}
-
-func (r *statisticsRepository) GetStatistics(statisticsType models.StatisticsType, name string) (*models.Statistics, error) {
 	var statistics models.Statistics
-	err := r.mongoCollection.FindOne(context.Background(), map[string]interface{}{"type": statisticsType, "name": name}).Decode(&statistics)
-	if err != nil {
-		if err == mongo.ErrNoDocuments {
-			return nil, nil
-		}
-		return nil, err
+	err := r.mongoCollection.FindOneAndUpdate(ctx, filter, update, opts).Decode(&statistics)
+	if mongo.IsDuplicateKeyError(err) {
+		// another handler created the bucket first, it is incremented now
+		err = r.mongoCollection.FindOneAndUpdate(ctx, filter, update, opts).Decode(&statistics)
+	}
+	if err == mongo.ErrNoDocuments {
+		return 0, nil
 	}
-
-	return &statistics, nil
-}
-
-func (r *statisticsRepository) UpdateStatistics(statistics *models.Statistics) error {
-	_, err := r.mongoCollection.UpdateOne(context.Background(), map[string]interface{}{"type": statistics.Type, "name": statistics.Name}, map[string]interface{}{"$set": statistics})
 	if err != nil {
-		return err
+		return 0, err
 	}
 
-	return nil
+	return statistics.Count, nil
 }
 
-func (r *statisticsRepository) GetStatisticsFrequency(statisticsType models.StatisticsType, limit int) ([]models.Statistics, error) {
+func (r *statisticsRepository) GetStatisticsFrequency(ctx context.Context, tenant string, statisticsType models.StatisticsType, limit int) ([]models.Statistics, error) {
 	var statistics []models.Statistics
 	limit64 := int64(limit)
 	pipeline := mongo.Pipeline{
-		{{"$match", map[string]interface{}{"type": statisticsType}}},
-		{{"$sort", map[string]interface{}{"name": -1}}},
-		{{"$limit", limit64}},
+		{{Key: "$match", Value: map[string]interface{}{"tenant": tenant, "type": statisticsType}}},
+		{{Key: "$sort", Value: map[string]interface{}{"name": -1}}},
+		{{Key: "$limit", Value: limit64}},
 	}
-	cursor, err := r.mongoCollection.Aggregate(context.Background(), pipeline)
+	cursor, err := r.mongoCollection.Aggregate(ctx, pipeline)
 	if err != nil {
 		return nil, err
 	}
 
-	if err = cursor.All(context.Background(), &statistics); err != nil {
+	if err = cursor.All(ctx, &statistics); err != nil {
 		return nil, err
 	}
 
 	return statistics, nil
 }
 
-func (r *statisticsRepository) GetStatisticsSortedByCount(statisticsType models.StatisticsType, limit int) ([]models.Statistics, error) {
+func (r *statisticsRepository) GetStatisticsSortedByCount(ctx context.Context, tenant string, statisticsType models.StatisticsType, limit int) ([]models.Statistics, error) {
 	var statistics []models.Statistics
 	limit64 := int64(limit)
 	pipeline := mongo.Pipeline{
-		{{"$match", map[string]interface{}{"type": statisticsType}}},
-		{{"$sort", map[string]interface{}{"count": -1}}},
-		{{"$limit", limit64}},
+		{{Key: "$match", Value: map[string]interface{}{"tenant": tenant, "type": statisticsType}}},
+		{{Key: "$sort", Value: map[string]interface{}{"count": -1}}},
+		{{Key: "$limit", Value: limit64}},
 	}
-	cursor, err := r.mongoCollection.Aggregate(context.Background(), pipeline)
+	cursor, err := r.mongoCollection.Aggregate(ctx, pipeline)
 	if err != nil {
 		return nil, err
 	}
 
-	if err = cursor.All(context.Background(), &statistics); err != nil {
+	if err = cursor.All(ctx, &statistics); err != nil {
 		return nil, err
 	}
 
 	return statistics, nil
 }
+
+// DeleteStatistics deletes the statistics of the tenant, of all tenants when
+// empty, before they are rebuilt. It returns how many buckets were deleted.
+func (r *statisticsRepository) DeleteStatistics(ctx context.Context, tenant string) (int, error) {
+	filter := bson.D{}
+	if tenant != "" {
+		filter = append(filter, bson.E{Key: "tenant", Value: tenant})
+	}
+
+	result, err := r.mongoCollection.DeleteMany(ctx, filter)
+	if err != nil {
+		return 0, fmt.Errorf("error deleting statistics: %w", err)
+	}
+
+	return int(result.DeletedCount), nil
+}
+
+func (r *statisticsRepository) EnsureIndexes() error {
+	if err := backfillTenant(r.mongoCollection); err != nil {
+		return err
+	}
+
+	if err := r.mergeDuplicateStatistics(context.Background()); err != nil {
+		return err
+	}
+
+	// the buckets are upserted, the unique index keeps concurrent upserts from
+	// creating a bucket twice, it replaces the former index of the same keys
+	if _, err := r.mongoCollection.Indexes().DropOne(context.Background(), "tenant_1_type_1_name_1"); err != nil && !isIndexNotFound(err) {
+		return fmt.Errorf("error dropping statistics index: %w", err)
+	}
+
+	_, err := r.mongoCollection.Indexes().CreateMany(context.Background(), []mongo.IndexModel{
+		{Keys: bson.D{{Key: "tenant", Value: 1}, {Key: "type", Value: 1}, {Key: "name", Value: 1}}, Options: options.Index().SetName("tenant_type_name_unique").SetUnique(true)},
+		{Keys: bson.D{{Key: "tenant", Value: 1}, {Key: "type", Value: 1}, {Key: "count", Value: -1}}},
+	})
+	if err != nil {
+		return fmt.Errorf("error creating statistics indexes: %w", err)
+	}
+
+	return nil
+}
+
+// mergeDuplicateStatistics sums the buckets created twice by the concurrent
+// upserts before the unique index, into the first one, and deletes the
+// others, so the unique index can be built.
+func (r *statisticsRepository) mergeDuplicateStatistics(ctx context.Context) error {
+	pipeline := mongo.Pipeline{
+		{{Key: "$group", Value: bson.D{
+			{Key: "_id", Value: bson.D{{Key: "tenant", Value: "$tenant"}, {Key: "type", Value: "$type"}, {Key: "name", Value: "$name"}}},
+			{Key: "ids", Value: bson.D{{Key: "$push", Value: "$_id"}}},
+			{Key: "count", Value: bson.D{{Key: "$sum", Value: "$count"}}},
+		}}},
+		{{Key: "$match", Value: bson.D{{Key: "ids.1", Value: bson.D{{Key: "$exists", Value: true}}}}}},
+	}
+	cursor, err := r.mongoCollection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return fmt.Errorf("error finding duplicate statistics: %w", err)
+	}
+
+	var duplicates []duplicateStatistics
+	if err = cursor.All(ctx, &duplicates); err != nil {
+		return fmt.Errorf("error finding duplicate statistics: %w", err)
+	}
+
+	for _, duplicate := range duplicates {
+		if _, err := r.mongoCollection.UpdateOne(ctx, bson.D{{Key: "_id", Value: duplicate.IDs[0]}}, bson.M{"$set": bson.M{"count": duplicate.Count}}); err != nil {
+			return fmt.Errorf("error merging duplicate statistics: %w", err)
+		}
+
+		if _, err := r.mongoCollection.DeleteMany(ctx, bson.D{{Key: "_id", Value: bson.M{"$in": duplicate.IDs[1:]}}}); err != nil {
+			return fmt.Errorf("error deleting duplicate statistics: %w", err)
+		}
+	}
+
+	return nil
+}
+
+func isIndexNotFound(err error) bool {
+	var commandErr mongo.CommandError
+	return errors.As(err, &commandErr) && (commandErr.Code == indexNotFoundCode || commandErr.Code == namespaceNotFoundCode)
+}
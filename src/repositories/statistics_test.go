@@ -0,0 +1,138 @@
+package repositories
+
+import (
+	"context"
+	"testing"
+
+	"github.com/tam-code/image-upload/src/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+	"gotest.tools/assert"
+)
+
+func TestIncrementStatistics(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	defer mt.ClearCollections()
+
+	tests := []struct {
+		name            string
+		delta           int
+		prepare         func(mt *mtest.T)
+		expectError     bool
+		expectPrevious  int
+		expectUpsert    bool
+		expectIncrement bool
+	}{
+		{
+			name:  "increment of a bucket",
+			delta: 2,
+			prepare: func(mt *mtest.T) {
+				mt.AddMockResponses(bson.D{{Key: "ok", Value: 1}, {Key: "value", Value: bson.D{{Key: "name", Value: "jpeg"}, {Key: "count", Value: 3}}}})
+			},
+			expectPrevious:  3,
+			expectUpsert:    true,
+			expectIncrement: true,
+		},
+		{
+			name:  "increment creating the bucket",
+			delta: 1,
+			prepare: func(mt *mtest.T) {
+				mt.AddMockResponses(bson.D{{Key: "ok", Value: 1}, {Key: "value", Value: nil}})
+			},
+			expectUpsert:    true,
+			expectIncrement: true,
+		},
+		{
+			name:  "decrement clamped in the update",
+			delta: -1,
+			prepare: func(mt *mtest.T) {
+				mt.AddMockResponses(bson.D{{Key: "ok", Value: 1}, {Key: "value", Value: bson.D{{Key: "name", Value: "jpeg"}, {Key: "count", Value: 0}}}})
+			},
+		},
+		{
+			name:  "simple error",
+			delta: 1,
+			prepare: func(mt *mtest.T) {
+				mt.AddMockResponses(bson.D{{Key: "ok", Value: 0}})
+			},
+			expectError:     true,
+			expectUpsert:    true,
+			expectIncrement: true,
+		},
+	}
+
+	for _, test := range tests {
+		mt.Run(test.name, func(mt *mtest.T) {
+			repo := statisticsRepository{
+				mongoCollection: mt.Coll,
+			}
+
+			test.prepare(mt)
+
+			previous, err := repo.IncrementStatistics(context.Background(), models.DefaultTenant, models.ImageFormatType, "jpeg", test.delta)
+			assert.Equal(t, test.expectError, err != nil)
+			assert.Equal(t, test.expectPrevious, previous)
+
+			command := mt.GetStartedEvent().Command
+			upsert, _ := command.Lookup("upsert").BooleanOK()
+			assert.Equal(t, test.expectUpsert, upsert)
+			// the decrements are an update pipeline
+			update, ok := command.Lookup("update").DocumentOK()
+			_, increment := update.Lookup("$inc").DocumentOK()
+			assert.Equal(t, test.expectIncrement, ok && increment)
+		})
+	}
+}
+
+func TestEnsureStatisticsIndexesMergesDuplicates(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	defer mt.ClearCollections()
+
+	mt.Run("duplicate buckets", func(mt *mtest.T) {
+		repo := statisticsRepository{
+			mongoCollection: mt.Coll,
+		}
+
+		firstID := primitive.NewObjectID()
+		secondID := primitive.NewObjectID()
+		thirdID := primitive.NewObjectID()
+
+		mt.AddMockResponses(
+			// the tenant backfill
+			mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 0}, bson.E{Key: "nModified", Value: 0}),
+			// the jpeg bucket of the tenant was created three times
+			mtest.CreateCursorResponse(0, "db.statistics", mtest.FirstBatch, bson.D{
+				{Key: "_id", Value: bson.D{{Key: "tenant", Value: "marketing"}, {Key: "type", Value: models.ImageFormatType}, {Key: "name", Value: "jpeg"}}},
+				{Key: "ids", Value: bson.A{firstID, secondID, thirdID}},
+				{Key: "count", Value: 6},
+			}),
+			mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}, bson.E{Key: "nModified", Value: 1}),
+			mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 2}),
+			mtest.CreateSuccessResponse(),
+			mtest.CreateSuccessResponse(),
+		)
+
+		assert.NilError(t, repo.EnsureIndexes())
+
+		mt.GetStartedEvent()
+		mt.GetStartedEvent()
+
+		update := mt.GetStartedEvent().Command
+		updates, _ := update.Lookup("updates").ArrayOK()
+		merged := updates.Index(0).Value().Document()
+		assert.Equal(t, firstID, merged.Lookup("q", "_id").ObjectID())
+		assert.Equal(t, int32(6), merged.Lookup("u", "$set", "count").Int32())
+
+		deletion := mt.GetStartedEvent().Command
+		deletes, _ := deletion.Lookup("deletes").ArrayOK()
+		deleted, _ := deletes.Index(0).Value().Document().Lookup("q", "_id", "$in").ArrayOK()
+		values, _ := deleted.Values()
+		assert.Equal(t, 2, len(values))
+		assert.Equal(t, secondID, values[0].ObjectID())
+		assert.Equal(t, thirdID, values[1].ObjectID())
+
+		assert.Equal(t, "dropIndexes", mt.GetStartedEvent().CommandName)
+		assert.Equal(t, "createIndexes", mt.GetStartedEvent().CommandName)
+	})
+}
@@ -0,0 +1,27 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/tam-code/image-upload/src/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+var ErrMissingTenant = errors.New("missing tenant")
+
+// backfillTenant assigns the default tenant to the documents stored before
+// tenants were introduced.
+func backfillTenant(collection *mongo.Collection) error {
+	_, err := collection.UpdateMany(context.Background(),
+		bson.M{"tenant": bson.M{"$exists": false}},
+		bson.M{"$set": bson.M{"tenant": models.DefaultTenant}},
+	)
+	if err != nil {
+		return fmt.Errorf("error backfilling %s tenant: %w", collection.Name(), err)
+	}
+
+	return nil
+}
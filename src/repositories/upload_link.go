@@ -3,21 +3,33 @@ package repositories
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"github.com/tam-code/image-upload/src/models"
+	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
 )
 
 type (
 	UploadLinkRepository interface {
-		CreateUploadLink(models.UploadLink) (*models.UploadLink, error)
-		GetUploadLinkByID(string) (*models.UploadLink, error)
+		CreateUploadLink(context.Context, models.UploadLink) (*models.UploadLink, error)
+		GetUploadLinkByID(context.Context, string) (*models.UploadLink, error)
+		GetTenantUploadLinkByID(context.Context, string, string) (*models.UploadLink, error)
+		GetUploadLinksExpiredBefore(context.Context, time.Time) ([]models.UploadLink, error)
+		DeleteUploadLink(context.Context, string, string) error
+		EnsureIndexes() error
 	}
 
 	uploadLinkRepository struct {
 		mongoCollection *mongo.Collection
 	}
+
+	// uploadLinkDocument decodes the object id that models.UploadLink doesn't map
+	uploadLinkDocument struct {
+		ObjectID          primitive.ObjectID `bson:"_id"`
+		models.UploadLink `bson:",inline"`
+	}
 )
 
 func newUploadLinkRepository(mongodb mongo.Database) UploadLinkRepository {
@@ -26,8 +38,8 @@ func newUploadLinkRepository(mongodb mongo.Database) UploadLinkRepository {
 	}
 }
 
-func (r *uploadLinkRepository) CreateUploadLink(uploadLink models.UploadLink) (*models.UploadLink, error) {
-	insertedData, err := r.mongoCollection.InsertOne(context.Background(), uploadLink)
+func (r *uploadLinkRepository) CreateUploadLink(ctx context.Context, uploadLink models.UploadLink) (*models.UploadLink, error) {
+	insertedData, err := r.mongoCollection.InsertOne(ctx, uploadLink)
 	if err != nil {
 		return nil, fmt.Errorf("error inserting status update log: %w", err)
 	}
@@ -37,7 +49,17 @@ func (r *uploadLinkRepository) CreateUploadLink(uploadLink models.UploadLink) (*
 	return &uploadLink, nil
 }
 
-func (r *uploadLinkRepository) GetUploadLinkByID(id string) (*models.UploadLink, error) {
+// GetUploadLinkByID serves the public upload route, the link id is enough to
+// upload whatever the tenant of the link.
+func (r *uploadLinkRepository) GetUploadLinkByID(ctx context.Context, id string) (*models.UploadLink, error) {
+	return r.getUploadLink(ctx, id, primitive.M{})
+}
+
+func (r *uploadLinkRepository) GetTenantUploadLinkByID(ctx context.Context, tenant, id string) (*models.UploadLink, error) {
+	return r.getUploadLink(ctx, id, primitive.M{"tenant": tenant})
+}
+
+func (r *uploadLinkRepository) getUploadLink(ctx context.Context, id string, query primitive.M) (*models.UploadLink, error) {
 	objectID, err := primitive.ObjectIDFromHex(id)
 	if err != nil {
 		if err == mongo.ErrNoDocuments {
@@ -46,11 +68,51 @@ func (r *uploadLinkRepository) GetUploadLinkByID(id string) (*models.UploadLink,
 		return nil, fmt.Errorf("error converting id to object id: %w", err)
 	}
 
+	query["_id"] = objectID
+
 	var uploadLink models.UploadLink
-	err = r.mongoCollection.FindOne(context.Background(), primitive.M{"_id": objectID}).Decode(&uploadLink)
+	err = r.mongoCollection.FindOne(ctx, query).Decode(&uploadLink)
 	if err != nil {
 		return nil, fmt.Errorf("error getting upload link by id: %w", err)
 	}
 
 	return &uploadLink, nil
 }
+
+func (r *uploadLinkRepository) GetUploadLinksExpiredBefore(ctx context.Context, before time.Time) ([]models.UploadLink, error) {
+	cursor, err := r.mongoCollection.Find(ctx, bson.M{"expiration_time": bson.M{"$lt": before}})
+	if err != nil {
+		return nil, fmt.Errorf("error getting expired upload links: %w", err)
+	}
+
+	var documents []uploadLinkDocument
+	if err = cursor.All(ctx, &documents); err != nil {
+		return nil, fmt.Errorf("error getting expired upload links: %w", err)
+	}
+
+	var uploadLinks []models.UploadLink
+	for _, document := range documents {
+		document.UploadLink.ID = document.ObjectID.Hex()
+		uploadLinks = append(uploadLinks, document.UploadLink)
+	}
+
+	return uploadLinks, nil
+}
+
+func (r *uploadLinkRepository) DeleteUploadLink(ctx context.Context, tenant, id string) error {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return fmt.Errorf("error converting id to object id: %w", err)
+	}
+
+	_, err = r.mongoCollection.DeleteOne(ctx, primitive.M{"_id": objectID, "tenant": tenant})
+	if err != nil {
+		return fmt.Errorf("error deleting upload link: %w", err)
+	}
+
+	return nil
+}
+
+func (r *uploadLinkRepository) EnsureIndexes() error {
+	return backfillTenant(r.mongoCollection)
+}
@@ -1,6 +1,7 @@
 package repositories
 
 import (
+	"context"
 	"testing"
 	"time"
 
@@ -50,7 +51,7 @@ func TestCreateUploadLink(t *testing.T) {
 
 			test.prepare(mt)
 
-			insertedLink, err := repo.CreateUploadLink(uploadLink)
+			insertedLink, err := repo.CreateUploadLink(context.Background(), uploadLink)
 			assert.Equal(t, test.expectError, err != nil)
 			assert.Equal(t, test.expectUploadLink, insertedLink != nil)
 		})
@@ -102,7 +103,7 @@ func TestGetUploadLinkByID(t *testing.T) {
 
 			test.prepare(mt)
 
-			getUploadLink, err := repo.GetUploadLinkByID(uploadLink.ID)
+			getUploadLink, err := repo.GetUploadLinkByID(context.Background(), uploadLink.ID)
 			assert.Equal(t, test.expectError, err != nil)
 			assert.Equal(t, test.expectUploadLink, getUploadLink != nil)
 		})
@@ -1,11 +1,23 @@
 package routes
 
 import (
+	"net/http"
+	"time"
+
 	"github.com/gorilla/mux"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gorilla/mux/otelmux"
 
+	"github.com/tam-code/image-upload/config"
+	"github.com/tam-code/image-upload/src/authenticators"
+	"github.com/tam-code/image-upload/src/broadcasters"
 	"github.com/tam-code/image-upload/src/controllers"
+	"github.com/tam-code/image-upload/src/healthcheckers"
+	"github.com/tam-code/image-upload/src/janitors"
+	"github.com/tam-code/image-upload/src/metrics"
 	"github.com/tam-code/image-upload/src/middleware"
+	"github.com/tam-code/image-upload/src/models"
 	"github.com/tam-code/image-upload/src/producers"
+	"github.com/tam-code/image-upload/src/ratelimiters"
 	"github.com/tam-code/image-upload/src/repositories"
 )
 
@@ -13,26 +25,127 @@ const (
 	pathPrefix     = "/api/v1"
 	imagePath      = "/images"
 	statisticsPath = "/statistics"
+	streamPath     = "/stream"
+	geoPath        = "/geo"
 	uploadLinkPath = "/upload-link"
+	janitorPath    = "/janitor"
+	apiKeyPath     = "/api-keys"
+	auditPath      = "/audit-events"
+	healthPath     = "/healthz"
+	readyPath      = "/readyz"
+	debugPath      = "/debug"
+	statusPath     = "/status"
 )
 
-func SetupRoutes(repositories *repositories.Repositories, producers *producers.Producers) *mux.Router {
+func SetupRoutes(cfg *config.Config, repositories *repositories.Repositories, producers *producers.Producers, broadcasters *broadcasters.Broadcasters, janitors *janitors.Janitors, rateLimitStore ratelimiters.Store, healthChecker healthcheckers.HealthChecker) *mux.Router {
 	router := mux.NewRouter()
+	// the server spans continue the trace context of the callers
+	router.Use(otelmux.Middleware(cfg.Tracing.ServiceName))
+	// after the tracing one so the request logger carries the trace id
+	router.Use(middleware.RequestID)
+	if cfg.Metrics.Enabled {
+		router.Use(middleware.Metrics)
+		router.Handle(cfg.Metrics.Path, metrics.Handler()).Methods("GET")
+	}
 
-	imageController := controllers.NewImageController(repositories, producers)
+	imageController := controllers.NewImageController(repositories, producers, cfg)
 	uploadLinkController := controllers.NewUploadLinkController(repositories, pathPrefix+imagePath)
-	statisticsController := controllers.NewStatisticsController(repositories)
+	geoController := controllers.NewGeoController(repositories)
+	janitorController := controllers.NewJanitorController(janitors)
+	apiKeyController := controllers.NewAPIKeyController(repositories)
+	auditController := controllers.NewAuditController(repositories)
+	statisticsController := controllers.NewStatisticsController(repositories, broadcasters, time.Duration(cfg.StatisticsStream.HeartbeatSeconds)*time.Second)
+	healthController := controllers.NewHealthController(healthChecker)
+
+	// the probes are outside of the api prefix and unauthenticated
+	router.HandleFunc(healthPath, healthController.Live).Methods("GET")
+	router.HandleFunc(readyPath, healthController.Ready).Methods("GET")
+
+	idempotency := middleware.Idempotency(repositories.Idempotency,
+		time.Duration(cfg.Idempotency.TTLSeconds)*time.Second,
+		time.Duration(cfg.Idempotency.LockTimeoutSeconds)*time.Second,
+		controllers.MaxUploadBytes,
+	)
+
+	// audited wraps the authentication and the scope check so rejected and
+	// denied attempts are recorded too
+	audited := func(action models.AuditAction, handler http.Handler) http.Handler {
+		return middleware.Audit(repositories.Audit, producers.AuditEvent, cfg.Audit.TrustForwardedFor, action)(handler)
+	}
 
 	subrouter := router.PathPrefix(pathPrefix).Subrouter()
+	if cfg.RateLimit.Enabled {
+		subrouter.Use(rateLimited(rateLimitStore, cfg.RateLimit.Public, cfg.RateLimit.TrustForwardedFor))
+	}
 
-	subrouter.HandleFunc(imagePath+"/{upload_link_id}", imageController.UploadImage).Methods("POST")
-	subrouter.HandleFunc(imagePath+"/{image_id}", imageController.GetImage).Methods("GET")
+	subrouter.Handle(imagePath+"/{upload_link_id}", audited(models.AuditImagesUpload, idempotency(http.HandlerFunc(imageController.UploadImage)))).Methods("POST")
+
+	var authentication []mux.MiddlewareFunc
+	if cfg.JWT.Enabled {
+		authentication = append(authentication, middleware.ValidateBearerToken(authenticators.NewJWTAuthenticator(cfg.JWT)))
+	}
+	authentication = append(authentication, middleware.ValidateAPIKey(repositories.APIKey))
+
+	// limited once authenticated so requests are counted by their api key
+	secretRateLimit := func(next http.Handler) http.Handler { return next }
+	if cfg.RateLimit.Enabled {
+		secretRateLimit = rateLimited(rateLimitStore, cfg.RateLimit.Secret, cfg.RateLimit.TrustForwardedFor)
+	}
+
+	// authenticated wraps every route of subrouterWithSecret, inside audited
+	// for the audited routes
+	authenticated := func(handler http.Handler) http.Handler {
+		handler = secretRateLimit(handler)
+		for i := len(authentication) - 1; i >= 0; i-- {
+			handler = authentication[i](handler)
+		}
+		return handler
+	}
 
 	subrouterWithSecret := router.PathPrefix(pathPrefix).Subrouter()
-	subrouterWithSecret.Use(middleware.ValidateSecretToken)
 
-	subrouterWithSecret.HandleFunc(statisticsPath, statisticsController.GetStatistics).Methods("GET")
-	subrouterWithSecret.HandleFunc(uploadLinkPath, uploadLinkController.CreateUploadLink).Methods("POST")
+	subrouterWithSecret.Handle(imagePath, authenticated(scoped(models.ScopeImagesRead, imageController.ListImages))).Methods("GET")
+	subrouterWithSecret.Handle(imagePath+"/{image_id}", authenticated(scoped(models.ScopeImagesRead, imageController.GetImage))).Methods("GET")
+	subrouterWithSecret.Handle(imagePath+"/{image_id}", audited(models.AuditImageDelete, authenticated(scoped(models.ScopeImagesDelete, imageController.DeleteImage)))).Methods("DELETE")
+	subrouterWithSecret.Handle(imagePath+geoPath+"/bbox", authenticated(scoped(models.ScopeImagesRead, geoController.GetImagesInBoundingBox))).Methods("GET")
+	subrouterWithSecret.Handle(imagePath+geoPath+"/near", authenticated(scoped(models.ScopeImagesRead, geoController.GetImagesNear))).Methods("GET")
+	subrouterWithSecret.Handle(imagePath+geoPath+"/polygon", authenticated(scoped(models.ScopeImagesRead, geoController.GetImagesInPolygon))).Methods("POST")
+	subrouterWithSecret.Handle(statisticsPath, authenticated(scoped(models.ScopeStatsRead, statisticsController.GetStatistics))).Methods("GET")
+	subrouterWithSecret.Handle(statisticsPath+streamPath, authenticated(scoped(models.ScopeStatsRead, statisticsController.StreamStatistics))).Methods("GET")
+	subrouterWithSecret.Handle(uploadLinkPath, audited(models.AuditUploadLinkCreate, authenticated(scoped(models.ScopeLinksCreate, idempotency(http.HandlerFunc(uploadLinkController.CreateUploadLink)).ServeHTTP)))).Methods("POST")
+	subrouterWithSecret.Handle(uploadLinkPath+"/{upload_link_id}"+geoPath, authenticated(scoped(models.ScopeImagesRead, geoController.ExportUploadLinkGeoJSON))).Methods("GET")
+	subrouterWithSecret.Handle(uploadLinkPath+"/{upload_link_id}"+imagePath, audited(models.AuditUploadLinkImagesDelete, authenticated(scoped(models.ScopeImagesDelete, imageController.DeleteUploadLinkImages)))).Methods("DELETE")
+	subrouterWithSecret.Handle(janitorPath+"/garbage-collection", audited(models.AuditGarbageCollection, authenticated(scoped(models.ScopeJanitorRun, janitorController.CollectGarbage)))).Methods("POST")
+	subrouterWithSecret.Handle(apiKeyPath, audited(models.AuditAPIKeyCreate, authenticated(scoped(models.ScopeKeysManage, apiKeyController.CreateAPIKey)))).Methods("POST")
+	subrouterWithSecret.Handle(apiKeyPath, authenticated(scoped(models.ScopeKeysManage, apiKeyController.ListAPIKeys))).Methods("GET")
+	subrouterWithSecret.Handle(apiKeyPath+"/{api_key_id}", audited(models.AuditAPIKeyRevoke, authenticated(scoped(models.ScopeKeysManage, apiKeyController.RevokeAPIKey)))).Methods("DELETE")
+	subrouterWithSecret.Handle(auditPath, authenticated(scoped(models.ScopeAuditRead, auditController.ListAuditEvents))).Methods("GET")
+
+	debugRouter := router.PathPrefix(debugPath).Subrouter()
+	debugRouter.Use(authentication...)
+	debugRouter.Handle(statusPath, scoped(models.ScopeStatusRead, healthController.Status)).Methods("GET")
 
 	return router
 }
+
+// scoped only lets the principals granted the scope through to the handler.
+func scoped(scope models.Scope, handler http.HandlerFunc) http.Handler {
+	return middleware.RequireScope(scope)(handler)
+}
+
+// rateLimited limits the requests of a route group, a group without requests
+// per minute isn't limited.
+func rateLimited(store ratelimiters.Store, group config.RateLimitGroupConfig, trustForwardedFor bool) mux.MiddlewareFunc {
+	if group.RequestsPerMinute <= 0 {
+		return func(next http.Handler) http.Handler { return next }
+	}
+
+	keys := make([]models.RateLimitKey, 0, len(group.Keys))
+	for _, key := range group.Keys {
+		keys = append(keys, models.RateLimitKey(key))
+	}
+
+	limit := models.RateLimit{Burst: max(1, group.Burst), RefillRate: float64(group.RequestsPerMinute) / 60}
+
+	return middleware.RateLimit(store, limit, keys, trustForwardedFor)
+}
@@ -0,0 +1,147 @@
+package storages
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	UploadPath = "./upload/"
+
+	// stagingDirectory holds the files of batches not committed yet, files a
+	// crash left behind are collected as orphans
+	stagingDirectory = ".staging"
+)
+
+type (
+	ImageStorage interface {
+		Path(tenant, uploadLinkID, name string) string
+		Stage(r io.Reader) (string, error)
+		Commit(stagedPath, path string) error
+		List() ([]StoredFile, error)
+		Remove(path string) error
+	}
+
+	// StoredFile is a file found under the storage root, Tenant and
+	// UploadLinkID are the directories it was stored in. Files stored before
+	// tenants were introduced have no tenant directory.
+	StoredFile struct {
+		Path         string
+		Tenant       string
+		UploadLinkID string
+		Size         int64
+		ModTime      time.Time
+	}
+
+	localImageStorage struct {
+		root string
+	}
+)
+
+func NewImageStorage(root string) ImageStorage {
+	return &localImageStorage{root: root}
+}
+
+// Path returns where the image of the upload link is stored once committed,
+// every tenant has its own directory.
+func (s *localImageStorage) Path(tenant, uploadLinkID, name string) string {
+	return filepath.Join(s.root, tenant, uploadLinkID, name)
+}
+
+// Stage writes the content under a temporary path, it isn't visible at its
+// final path until committed.
+func (s *localImageStorage) Stage(r io.Reader) (string, error) {
+	dir := filepath.Join(s.root, stagingDirectory)
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return "", err
+	}
+
+	dst, err := os.CreateTemp(dir, "upload-*")
+	if err != nil {
+		return "", err
+	}
+
+	_, err = io.Copy(dst, r)
+	if closeErr := dst.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(dst.Name())
+		return "", err
+	}
+
+	return dst.Name(), nil
+}
+
+// Commit moves the staged file to its final path.
+func (s *localImageStorage) Commit(stagedPath, path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		return err
+	}
+
+	return os.Rename(stagedPath, path)
+}
+
+// List walks the storage root, the paths match the ones recorded on images.
+func (s *localImageStorage) List() ([]StoredFile, error) {
+	var files []StoredFile
+	err := filepath.WalkDir(s.root, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) && path == s.root {
+				return filepath.SkipDir
+			}
+			return err
+		}
+
+		if entry.IsDir() {
+			return nil
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+
+		relative, err := filepath.Rel(s.root, path)
+		if err != nil {
+			return err
+		}
+
+		tenant, uploadLinkID := "", ""
+		switch parts := strings.Split(relative, string(filepath.Separator)); {
+		case len(parts) > 2:
+			tenant, uploadLinkID = parts[0], parts[1]
+		case len(parts) > 1:
+			uploadLinkID = parts[0]
+		}
+
+		files = append(files, StoredFile{
+			Path:         filepath.Clean(path),
+			Tenant:       tenant,
+			UploadLinkID: uploadLinkID,
+			Size:         info.Size(),
+			ModTime:      info.ModTime(),
+		})
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return files, nil
+}
+
+// Remove deletes the file, files that are already gone aren't an error.
+func (s *localImageStorage) Remove(path string) error {
+	if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+
+	return nil
+}